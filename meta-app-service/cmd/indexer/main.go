@@ -14,14 +14,21 @@ import (
 	"meta-app-service/conf"
 	"meta-app-service/controller"
 	"meta-app-service/database"
+	"meta-app-service/indexer"
+	"meta-app-service/service/common_service/metaid_protocols"
 	"meta-app-service/service/indexer_service"
 	"meta-app-service/service/temp_deploy_service"
+	"meta-app-service/tool"
 )
 
-var ENV string
+var (
+	ENV    string
+	repair bool
+)
 
 func init() {
 	flag.StringVar(&ENV, "env", "mainnet", "Environment: loc/mainnet/testnet")
+	flag.BoolVar(&repair, "repair", false, "Verify database indexes at startup and repair inconsistencies left by a crash")
 }
 
 // @title           Meta App Service Indexer API
@@ -43,12 +50,15 @@ func init() {
 
 func main() {
 	// Initialize all components
-	indexerService, srv, cleanup := initAll()
+	indexerServices, srv, cleanup := initAll()
 	defer cleanup()
 
-	// Start indexer service (in goroutine)
-	go indexerService.Start()
-	log.Println("Indexer service started successfully")
+	// Start the shared deploy processor once, then each chain's scanner in its own goroutine
+	indexerServices[0].StartDeployProcessor()
+	for _, indexerService := range indexerServices {
+		go indexerService.StartScanning()
+	}
+	log.Printf("Indexer service started successfully (%d chain(s))", len(indexerServices))
 
 	// Start HTTP API service (in goroutine)
 	go startServer(srv)
@@ -58,6 +68,9 @@ func main() {
 	go startTempAppCleanupService()
 	log.Println("Temp app cleanup service started successfully")
 
+	// Reload the reloadable subset of the config file on SIGHUP
+	go handleReloadSignal()
+
 	// Wait for shutdown signal
 	waitForShutdown()
 
@@ -84,7 +97,7 @@ func initEnv() {
 }
 
 // initAll initialize all components
-func initAll() (*indexer_service.IndexerService, *http.Server, func()) {
+func initAll() ([]*indexer_service.IndexerService, *http.Server, func()) {
 	// Parse command line parameters
 	flag.Parse()
 
@@ -95,24 +108,50 @@ func initAll() (*indexer_service.IndexerService, *http.Server, func()) {
 	if err := conf.InitConfig(); err != nil {
 		log.Fatalf("Failed to initialize config: %v", err)
 	}
-	log.Printf("Configuration loaded: env=%s, net=%s, port=%s", ENV, conf.Cfg.Net, conf.Cfg.Indexer.Port)
+	log.Printf("Configuration loaded: env=%s, net=%s, port=%s", ENV, conf.Cfg().Net, conf.Cfg().Indexer.Port)
+	if (conf.Cfg().Indexer.TLSCert == "") != (conf.Cfg().Indexer.TLSKey == "") {
+		log.Fatalf("indexer.tls_cert and indexer.tls_key must be set together")
+	}
+
+	// Shared outbound HTTP client (metafs downloads, node RPC)
+	tool.ConfigureHTTPClient(conf.Cfg().HTTPClient.UserAgent, conf.Cfg().HTTPClient.MaxIdleConnsPerHost)
+
+	// Protocol paths recognized by the indexer
+	metaid_protocols.SetProtocolList(conf.Cfg().Indexer.Protocols)
+	log.Printf("Indexed protocols: %v", metaid_protocols.ProtocolList)
 
 	// Initialize database
 	if err := initDatabase(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	// Create indexer service
-	indexerService, err := indexer_service.NewIndexerService()
-	if err != nil {
-		log.Fatalf("Failed to create indexer service: %v", err)
+	if repair {
+		repairDatabase()
+	}
+	// Create one indexer service per enabled chain
+	indexerServices := make([]*indexer_service.IndexerService, 0, len(conf.Cfg().Indexer.Chains))
+	seenChains := make(map[string]bool)
+	for _, chainName := range conf.Cfg().Indexer.Chains {
+		if seenChains[chainName] {
+			continue
+		}
+		seenChains[chainName] = true
+		chainType := indexer.ChainType(chainName)
+		if chainType != indexer.ChainTypeBTC && chainType != indexer.ChainTypeMVC {
+			log.Fatalf("Unsupported chain in indexer.chains: %s", chainName)
+		}
+		indexerService, err := indexer_service.NewIndexerServiceWithChain(chainType)
+		if err != nil {
+			log.Fatalf("Failed to create indexer service for %s chain: %v", chainName, err)
+		}
+		indexerServices = append(indexerServices, indexerService)
 	}
 
-	// Setup indexer service router (pass indexerService for scanner access)
-	router := controller.SetupIndexerRouter(indexerService)
+	// Setup indexer service router (pass indexerServices for scanner access)
+	router := controller.SetupIndexerRouter(indexerServices...)
 
 	// Create HTTP server
 	srv := &http.Server{
-		Addr:    ":" + conf.Cfg.Indexer.Port,
+		Addr:    conf.Cfg().Indexer.Addr(),
 		Handler: router,
 	}
 
@@ -123,17 +162,18 @@ func initAll() (*indexer_service.IndexerService, *http.Server, func()) {
 		}
 	}
 
-	return indexerService, srv, cleanup
+	return indexerServices, srv, cleanup
 }
 
 // initDatabase initialize database based on configuration
 func initDatabase() error {
-	dbType := database.DBType(conf.Cfg.Database.IndexerType)
+	dbType := database.DBType(conf.Cfg().Database.IndexerType)
 
 	switch dbType {
 	case database.DBTypePebble:
 		config := &database.PebbleConfig{
-			DataDir: conf.Cfg.Database.DataDir,
+			DataDir:          conf.Cfg().Database.DataDir,
+			MaxHistoryPerApp: conf.Cfg().Database.MaxHistoryPerApp,
 		}
 		return database.InitDatabase(database.DBTypePebble, config)
 	default:
@@ -141,10 +181,31 @@ func initDatabase() error {
 	}
 }
 
+// repairDatabase verify the MetaApp indexes against the pin records and repair them (--repair)
+func repairDatabase() {
+	log.Println("Checking database integrity...")
+	report, err := database.DB.CheckIntegrity(true)
+	if err != nil {
+		log.Fatalf("Database integrity check failed: %v", err)
+	}
+	log.Printf("Database integrity check completed: %d apps, %d history entries dropped, %d latest records repaired, %d latest records dropped, %d dangling index entries removed, %d index entries rewritten",
+		report.Apps, report.HistoryEntriesDropped, report.LatestRepaired, report.LatestDropped, report.DanglingIndexEntries, report.IndexEntriesRewritten)
+	if report.Repaired {
+		log.Printf("Database repaired (%d problems fixed)", report.Problems())
+	}
+}
+
 // startServer start HTTP server
 func startServer(srv *http.Server) {
-	log.Printf("Indexer API service starting on port %s...", conf.Cfg.Indexer.Port)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	var err error
+	if conf.Cfg().Indexer.TLSEnabled() {
+		log.Printf("Indexer API service starting on %s (HTTPS)...", srv.Addr)
+		err = srv.ListenAndServeTLS(conf.Cfg().Indexer.TLSCert, conf.Cfg().Indexer.TLSKey)
+	} else {
+		log.Printf("Indexer API service starting on %s...", srv.Addr)
+		err = srv.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
@@ -156,6 +217,22 @@ func waitForShutdown() {
 	<-sigChan
 }
 
+// handleReloadSignal reload configuration on every SIGHUP
+func handleReloadSignal() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	for range sigChan {
+		changed, err := conf.ReloadConfig()
+		if err != nil {
+			log.Printf("Failed to reload config: %v", err)
+			continue
+		}
+		if len(changed) == 0 {
+			log.Println("Config reloaded, no reloadable setting changed")
+		}
+	}
+}
+
 // shutdownServer gracefully shutdown server
 func shutdownServer(srv *http.Server) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -167,24 +244,61 @@ func shutdownServer(srv *http.Server) {
 }
 
 // startTempAppCleanupService 启动临时应用清理服务
-// 每小时执行一次清理过期临时应用
+// 按配置的间隔（默认每小时）执行一次清理过期临时应用
 func startTempAppCleanupService() {
 	cleanupService := temp_deploy_service.NewTempDeployService()
 
+	interval := time.Duration(conf.Cfg().TempApp.CleanupIntervalMinutes) * time.Minute
+	log.Printf("Temp app cleanup service started (interval: %s, dry run: %v)", interval, conf.Cfg().TempApp.CleanupDryRun)
+
 	// 立即执行一次清理
-	if err := cleanupService.CleanupExpiredTempApps(); err != nil {
-		log.Printf("Failed to cleanup expired temp apps: %v", err)
-	}
+	runTempAppCleanup(cleanupService)
 
-	// 创建定时器，每小时执行一次
-	ticker := time.NewTicker(1 * time.Hour)
+	// 创建定时器，按配置的间隔执行
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		if err := cleanupService.CleanupExpiredTempApps(); err != nil {
-			log.Printf("Failed to cleanup expired temp apps: %v", err)
-		} else {
-			log.Println("Temp app cleanup completed successfully")
+		runTempAppCleanup(cleanupService)
+
+		// 配置重新加载后按新的间隔执行
+		if next := time.Duration(conf.Cfg().TempApp.CleanupIntervalMinutes) * time.Minute; next != interval {
+			interval = next
+			ticker.Reset(interval)
+			log.Printf("Temp app cleanup interval changed to %s", interval)
 		}
 	}
 }
+
+// runTempAppCleanup 执行一次临时应用清理并记录结果
+func runTempAppCleanup(cleanupService *temp_deploy_service.TempDeployService) {
+	dryRun := conf.Cfg().TempApp.CleanupDryRun
+	result, err := cleanupService.CleanupExpiredTempApps(dryRun)
+	if err != nil {
+		log.Printf("Failed to cleanup expired temp apps: %v", err)
+		return
+	}
+
+	if dryRun {
+		log.Printf("Temp app cleanup dry run completed: %d records would be deleted, %d bytes would be freed",
+			result.DeletedRecords, result.FreedBytes)
+	} else {
+		log.Printf("Temp app cleanup completed: %d records deleted, %d bytes freed, %d failed",
+			result.DeletedRecords, result.FreedBytes, result.Failed)
+	}
+
+	// 清理废弃的分片上传
+	chunkResult, err := cleanupService.CleanupStaleChunkUploads(dryRun)
+	if err != nil {
+		log.Printf("Failed to cleanup stale chunk uploads: %v", err)
+		return
+	}
+
+	if dryRun {
+		log.Printf("Chunk upload cleanup dry run completed: %d records would be deleted, %d bytes would be freed",
+			chunkResult.DeletedRecords, chunkResult.FreedBytes)
+		return
+	}
+	log.Printf("Chunk upload cleanup completed: %d records deleted, %d bytes freed, %d failed",
+		chunkResult.DeletedRecords, chunkResult.FreedBytes, chunkResult.Failed)
+}
@@ -32,6 +32,13 @@ type TxOutput struct {
 	Amount  int64
 }
 
+const (
+	// mvcDustLimit change at or below this value is dropped and left as fee
+	mvcDustLimit = 600
+	// mvcP2PKHUnlockingScriptSize estimated P2PKH unlocking script size (varint + DER signature + compressed pubkey)
+	mvcP2PKHUnlockingScriptSize = 107
+)
+
 type SignMode string
 
 const (
@@ -40,7 +47,157 @@ const (
 	SignModeLegacy  SignMode = "legacy"
 )
 
+// MetaIdTxParams MetaID inscription fields, zero values fall back to DefaultMetaIdTxParams
+type MetaIdTxParams struct {
+	Operation   string // create / modify / revoke ...
+	Path        string
+	Version     string
+	ContentType string
+	Encryption  string // "0" means not encrypted
+	ChunkSize   int    // Max payload bytes per pushdata (520 is the standard script element limit)
+}
+
+// DefaultMetaIdTxParams default inscription fields used by BuildMvcCommonMetaIdTx
+func DefaultMetaIdTxParams() MetaIdTxParams {
+	return MetaIdTxParams{
+		Operation:   "create",
+		Version:     "0.0.1",
+		ContentType: "application/json",
+		Encryption:  "0",
+		ChunkSize:   520,
+	}
+}
+
+// withDefaults fill zero-value fields with DefaultMetaIdTxParams
+func (p MetaIdTxParams) withDefaults() MetaIdTxParams {
+	def := DefaultMetaIdTxParams()
+	if p.Operation == "" {
+		p.Operation = def.Operation
+	}
+	if p.Version == "" {
+		p.Version = def.Version
+	}
+	if p.ContentType == "" {
+		p.ContentType = def.ContentType
+	}
+	if p.Encryption == "" {
+		p.Encryption = def.Encryption
+	}
+	if p.ChunkSize <= 0 {
+		p.ChunkSize = def.ChunkSize
+	}
+	return p
+}
+
+// buildMetaIdInscriptionScript build OP_0 OP_RETURN metaid <operation> <path> <encryption> <version> <content-type> <payload...>
+func buildMetaIdInscriptionScript(params MetaIdTxParams, content []byte) ([]byte, error) {
+	params = params.withDefaults()
+	inscriptionBuilder := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).
+		AddOp(txscript.OP_RETURN).
+		AddData([]byte("metaid")).        //<metaid_flag>
+		AddData([]byte(params.Operation)) //<operation>
+
+	inscriptionBuilder.AddData([]byte(params.Path))        //<path>
+	inscriptionBuilder.AddData([]byte(params.Encryption))  //<Encryption>
+	inscriptionBuilder.AddData([]byte(params.Version))     //<version>
+	inscriptionBuilder.AddData([]byte(params.ContentType)) //<content-type>
+
+	bodySize := len(content)
+	for i := 0; i < bodySize; i += params.ChunkSize {
+		end := i + params.ChunkSize
+		if end > bodySize {
+			end = bodySize
+		}
+		inscriptionBuilder.AddFullData(content[i:end]) //<payload>
+	}
+
+	return inscriptionBuilder.Script()
+}
+
+// MvcTxEstimate estimated size and fee of a MetaID tx
+type MvcTxEstimate struct {
+	Size int   // Estimated serialized size in bytes, including unlocking scripts
+	Fee  int64 // Size * feeRate in satoshis
+}
+
+const (
+	// MaxEstimateContentSize max payload size accepted by EstimateMvcMetaIdTxFee
+	MaxEstimateContentSize = 100 * 1024 * 1024
+	// MaxEstimateTxIO max input / output count accepted by EstimateMvcMetaIdTxFee
+	MaxEstimateTxIO = 10000
+
+	// mvcTxInSize serialized input size without unlocking script (outpoint + empty script varint + sequence)
+	mvcTxInSize = 32 + 4 + 1 + 4
+	// mvcP2PKHTxOutSize serialized P2PKH output size (value + script varint + 25-byte script)
+	mvcP2PKHTxOutSize = 8 + 1 + 25
+)
+
+// EstimateMvcMetaIdTxFee estimate size and fee of a MetaID tx built by BuildMvcMetaIdTxWithParams
+// with contentSize payload bytes, inputCount P2PKH inputs, outputCount P2PKH outputs (besides the inscription) and an optional change output.
+// The size is computed arithmetically, the payload is never allocated
+func EstimateMvcMetaIdTxFee(params MetaIdTxParams, contentSize, inputCount, outputCount int, withChange bool, feeRate int64) (*MvcTxEstimate, error) {
+	if contentSize < 0 || inputCount < 0 || outputCount < 0 {
+		return nil, errors.New("invalid estimate parameters")
+	}
+	if contentSize > MaxEstimateContentSize {
+		return nil, fmt.Errorf("content_size exceeds %d bytes", MaxEstimateContentSize)
+	}
+	if inputCount > MaxEstimateTxIO || outputCount > MaxEstimateTxIO {
+		return nil, fmt.Errorf("inputs and outputs must not exceed %d", MaxEstimateTxIO)
+	}
+
+	// Inscription script without payload, then one pushdata per chunk
+	params = params.withDefaults()
+	inscriptionScript, err := buildMetaIdInscriptionScript(params, nil)
+	if err != nil {
+		return nil, err
+	}
+	scriptSize := len(inscriptionScript)
+	for remaining := contentSize; remaining > 0; remaining -= params.ChunkSize {
+		scriptSize += pushDataSize(min(remaining, params.ChunkSize))
+	}
+
+	p2pkhOutputs := outputCount
+	if withChange {
+		p2pkhOutputs++
+	}
+	size := 4 + 4 + // version + locktime
+		wire2.VarIntSerializeSize(uint64(inputCount)) + wire2.VarIntSerializeSize(uint64(p2pkhOutputs+1)) +
+		inputCount*mvcTxInSize + p2pkhOutputs*mvcP2PKHTxOutSize +
+		8 + wire2.VarIntSerializeSize(uint64(scriptSize)) + scriptSize
+	// Same sizing as applyMvcFeeAndChange: unsigned inputs count an estimated P2PKH unlocking script
+	size += inputCount * mvcP2PKHUnlockingScriptSize
+	return &MvcTxEstimate{
+		Size: size,
+		Fee:  int64(size) * feeRate,
+	}, nil
+}
+
+// pushDataSize script size of pushing dataLen bytes (opcode / length prefix + data)
+func pushDataSize(dataLen int) int {
+	switch {
+	case dataLen < txscript.OP_PUSHDATA1:
+		return 1 + dataLen
+	case dataLen <= 0xff:
+		return 2 + dataLen
+	case dataLen <= 0xffff:
+		return 3 + dataLen
+	default:
+		return 5 + dataLen
+	}
+}
+
+// BuildMvcCommonMetaIdTx build MetaID JSON tx with default version/content type (see DefaultMetaIdTxParams)
 func BuildMvcCommonMetaIdTx(netParam *chaincfg2.Params, ins []*TxInputUtxo, outs, otherOuts []*TxOutput, operation, path string, content []byte, changeAddress string, feeRate int64, isUnSign bool) (*wire2.MsgTx, error) {
+	params := DefaultMetaIdTxParams()
+	params.Operation = operation
+	params.Path = path
+	return BuildMvcMetaIdTxWithParams(netParam, ins, outs, otherOuts, params, content, changeAddress, feeRate, isUnSign)
+}
+
+// BuildMvcMetaIdTxWithParams build MetaID tx with custom inscription fields (operation, path, version, content type, encryption, chunk size)
+func BuildMvcMetaIdTxWithParams(netParam *chaincfg2.Params, ins []*TxInputUtxo, outs, otherOuts []*TxOutput, params MetaIdTxParams, content []byte, changeAddress string, feeRate int64, isUnSign bool) (*wire2.MsgTx, error) {
 	tx := wire2.NewMsgTx(10)
 	totalAmount := int64(0)
 	outAmount := int64(0)
@@ -57,33 +214,7 @@ func BuildMvcCommonMetaIdTx(netParam *chaincfg2.Params, ins []*TxInputUtxo, outs
 		outAmount = outAmount + out.Amount
 	}
 
-	if operation == "" {
-		operation = "create"
-	}
-	inscriptionBuilder := txscript.NewScriptBuilder().
-		AddOp(txscript.OP_0).
-		AddOp(txscript.OP_RETURN).
-		AddData([]byte("metaid")). //<metaid_flag>
-		AddData([]byte(operation)) //<operation>
-
-	//inscriptionBuilder.AddData([]byte("/file/my-bitcoin.png")) //<path>
-	inscriptionBuilder.AddData([]byte(path))    //<path>
-	inscriptionBuilder.AddData([]byte("0"))     //<Encryption>
-	inscriptionBuilder.AddData([]byte("0.0.1")) //<version>
-	//inscriptionBuilder.AddData([]byte("image/jpeg;binary"))    //<content-type>
-	inscriptionBuilder.AddData([]byte("application/json")) //<content-type>
-
-	maxChunkSize := 520
-	bodySize := len(content)
-	for i := 0; i < bodySize; i += maxChunkSize {
-		end := i + maxChunkSize
-		if end > bodySize {
-			end = bodySize
-		}
-		inscriptionBuilder.AddFullData(content[i:end]) //<payload>
-	}
-
-	inscriptionScript, err := inscriptionBuilder.Script()
+	inscriptionScript, err := buildMetaIdInscriptionScript(params, content)
 	if err != nil {
 		return nil, err
 	}
@@ -125,21 +256,8 @@ func BuildMvcCommonMetaIdTx(netParam *chaincfg2.Params, ins []*TxInputUtxo, outs
 		totalAmount = totalAmount + int64(in.Amount)
 
 	}
-	txTotalSize := tx.SerializeSize()
-
-	//txSize := tx.SerializeSize() + SpendSize*len(tx.TxIn)
-	txFee := int64(txTotalSize) * feeRate
-
-	fmt.Printf("txTotalSize:%d, txFee:%d, feeRate:%d, totalAmount:%d, outAmount:%d\n", txTotalSize, txFee, feeRate, totalAmount, outAmount)
-	if totalAmount-outAmount < int64(txFee) {
-		return nil, errors.New("insufficient fee")
-	}
-
-	changeVal := totalAmount - outAmount - int64(txFee)
-	if changeVal >= 600 && changeAddress != "" {
-		tx.TxOut[len(tx.TxOut)-1].Value = changeVal
-	} else {
-		tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
+	if err := applyMvcFeeAndChange(tx, totalAmount, outAmount, feeRate, changeAddress != ""); err != nil {
+		return nil, err
 	}
 
 	if !isUnSign {
@@ -169,6 +287,32 @@ func BuildMvcCommonMetaIdTx(netParam *chaincfg2.Params, ins []*TxInputUtxo, outs
 	return tx, nil
 }
 
+// applyMvcFeeAndChange compute fee from the serialized size (plus an estimated unlocking script for unsigned inputs) and feeRate,
+// then set the change output (last output when hasChange) or drop it when at or below dust
+func applyMvcFeeAndChange(tx *wire2.MsgTx, totalAmount, outAmount, feeRate int64, hasChange bool) error {
+	txTotalSize := tx.SerializeSize()
+	for _, in := range tx.TxIn {
+		if len(in.SignatureScript) == 0 {
+			txTotalSize += mvcP2PKHUnlockingScriptSize
+		}
+	}
+	txFee := int64(txTotalSize) * feeRate
+	if totalAmount-outAmount < txFee {
+		return errors.New("insufficient fee")
+	}
+
+	if !hasChange {
+		return nil
+	}
+	changeVal := totalAmount - outAmount - txFee
+	if changeVal > mvcDustLimit {
+		tx.TxOut[len(tx.TxOut)-1].Value = changeVal
+	} else {
+		tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
+	}
+	return nil
+}
+
 func BuildMvcCommonMetaIdTxForUnkwonInput(netParam *chaincfg2.Params, ins []*TxInputUtxo, outs, otherOuts []*TxOutput, operation, path string, content []byte, contentType string, changeAddress string, feeRate int64, isUnSign bool) (*wire2.MsgTx, error) {
 	tx := wire2.NewMsgTx(10)
 	totalAmount := int64(0)
@@ -186,33 +330,12 @@ func BuildMvcCommonMetaIdTxForUnkwonInput(netParam *chaincfg2.Params, ins []*TxI
 		outAmount = outAmount + out.Amount
 	}
 
-	if operation == "" {
-		operation = "create"
-	}
-	inscriptionBuilder := txscript.NewScriptBuilder().
-		AddOp(txscript.OP_0).
-		AddOp(txscript.OP_RETURN).
-		AddData([]byte("metaid")). //<metaid_flag>
-		AddData([]byte(operation)) //<operation>
-
-	//inscriptionBuilder.AddData([]byte("/file/my-bitcoin.png")) //<path>
-	inscriptionBuilder.AddData([]byte(path))    //<path>
-	inscriptionBuilder.AddData([]byte("0"))     //<Encryption>
-	inscriptionBuilder.AddData([]byte("1.0.0")) //<version>
-	//inscriptionBuilder.AddData([]byte("image/jpeg;binary"))    //<content-type>
-	inscriptionBuilder.AddData([]byte(contentType)) //<content-type>
-
-	maxChunkSize := 520
-	bodySize := len(content)
-	for i := 0; i < bodySize; i += maxChunkSize {
-		end := i + maxChunkSize
-		if end > bodySize {
-			end = bodySize
-		}
-		inscriptionBuilder.AddFullData(content[i:end]) //<payload>
-	}
-
-	inscriptionScript, err := inscriptionBuilder.Script()
+	inscriptionScript, err := buildMetaIdInscriptionScript(MetaIdTxParams{
+		Operation:   operation,
+		Path:        path,
+		Version:     "1.0.0",
+		ContentType: contentType,
+	}, content)
 	if err != nil {
 		return nil, err
 	}
@@ -253,22 +376,9 @@ func BuildMvcCommonMetaIdTxForUnkwonInput(netParam *chaincfg2.Params, ins []*TxI
 		tx.AddTxIn(txIn)
 		totalAmount = totalAmount + int64(in.Amount)
 	}
-	// txTotalSize := tx.SerializeSize()
-
-	// //txSize := tx.SerializeSize() + SpendSize*len(tx.TxIn)
-	// txFee := int64(txTotalSize) * feeRate
-
-	// fmt.Printf("txTotalSize:%d, txFee:%d, feeRate:%d, totalAmount:%d, outAmount:%d\n", txTotalSize, txFee, feeRate, totalAmount, outAmount)
-	// if totalAmount-outAmount < int64(txFee) {
-	// 	return nil, errors.New("insufficient fee")
-	// }
-
-	// changeVal := totalAmount - outAmount - int64(txFee)
-	// if changeVal >= 600 && changeAddress != "" {
-	// 	tx.TxOut[len(tx.TxOut)-1].Value = changeVal
-	// } else {
-	// 	tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
-	// }
+	if err := applyMvcFeeAndChange(tx, totalAmount, outAmount, feeRate, changeAddress != ""); err != nil {
+		return nil, err
+	}
 
 	if !isUnSign {
 		for i, in := range ins {
@@ -0,0 +1,159 @@
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	bsvec2 "github.com/bitcoinsv/bsvd/bsvec"
+	chaincfg2 "github.com/bitcoinsv/bsvd/chaincfg"
+	chainhash2 "github.com/bitcoinsv/bsvd/chaincfg/chainhash"
+	txscript2 "github.com/bitcoinsv/bsvd/txscript"
+	wire2 "github.com/bitcoinsv/bsvd/wire"
+	bsvutil2 "github.com/bitcoinsv/bsvutil"
+)
+
+// mvcTestP2PKHScript placeholder 25-byte P2PKH output script
+func mvcTestP2PKHScript() []byte {
+	script, _ := txscript2.NewScriptBuilder().
+		AddOp(txscript2.OP_DUP).AddOp(txscript2.OP_HASH160).AddData(bytes.Repeat([]byte{0x01}, 20)).
+		AddOp(txscript2.OP_EQUALVERIFY).AddOp(txscript2.OP_CHECKSIG).Script()
+	return script
+}
+
+// mvcFeeSize size applyMvcFeeAndChange charges for tx (unsigned inputs count an estimated unlocking script)
+func mvcFeeSize(tx *wire2.MsgTx) int64 {
+	size := tx.SerializeSize()
+	for _, in := range tx.TxIn {
+		if len(in.SignatureScript) == 0 {
+			size += mvcP2PKHUnlockingScriptSize
+		}
+	}
+	return int64(size)
+}
+
+// TestApplyMvcFeeAndChange sets change above dust, folds change at or below dust into the fee,
+// leaves outputs alone without a change address and rejects insufficient funds
+func TestApplyMvcFeeAndChange(t *testing.T) {
+	const feeRate, outAmount = 2, 1000
+	newTx := func(withChange bool) *wire2.MsgTx {
+		tx := wire2.NewMsgTx(10)
+		prevHash := chainhash2.DoubleHashH([]byte("funding"))
+		tx.AddTxIn(wire2.NewTxIn(wire2.NewOutPoint(&prevHash, 0), nil))
+		tx.AddTxOut(wire2.NewTxOut(outAmount, mvcTestP2PKHScript()))
+		if withChange {
+			tx.AddTxOut(wire2.NewTxOut(0, mvcTestP2PKHScript()))
+		}
+		return tx
+	}
+	// Fee of the tx with a change output, before any output is dropped
+	fullFee := mvcFeeSize(newTx(true)) * feeRate
+
+	tests := []struct {
+		name       string
+		withChange bool
+		total      int64
+		wantOuts   []int64
+		wantErr    bool
+	}{
+		{"change above dust", true, outAmount + fullFee + 5000, []int64{outAmount, 5000}, false},
+		{"change at dust", true, outAmount + fullFee + mvcDustLimit, []int64{outAmount}, false},
+		{"change below dust", true, outAmount + fullFee + 1, []int64{outAmount}, false},
+		{"no change address", false, outAmount + fullFee + 5000, []int64{outAmount}, false},
+		{"insufficient funds", true, outAmount + fullFee - 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newTx(tt.withChange)
+			err := applyMvcFeeAndChange(tx, tt.total, outAmount, feeRate, tt.withChange)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an insufficient fee error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyMvcFeeAndChange failed: %v", err)
+			}
+
+			if len(tx.TxOut) != len(tt.wantOuts) {
+				t.Fatalf("got %d outputs, want %d", len(tx.TxOut), len(tt.wantOuts))
+			}
+			var outSum int64
+			for i, out := range tx.TxOut {
+				if out.Value != tt.wantOuts[i] {
+					t.Fatalf("output %d is %d, want %d", i, out.Value, tt.wantOuts[i])
+				}
+				outSum += out.Value
+			}
+			if fee, minFee := tt.total-outSum, mvcFeeSize(tx)*feeRate; fee < minFee {
+				t.Fatalf("fee %d is below size x fee rate %d", fee, minFee)
+			}
+		})
+	}
+}
+
+// TestEstimateMvcMetaIdTxFeeMatchesBuiltTx estimates the size of a signed tx built with the same parameters:
+// the estimate may only exceed the real size by the DER signature slack of each input
+func TestEstimateMvcMetaIdTxFeeMatchesBuiltTx(t *testing.T) {
+	const feeRate = 3
+	netParams := &chaincfg2.MainNetParams
+	privKey, _ := bsvec2.PrivKeyFromBytes(bsvec2.S256(), bytes.Repeat([]byte{0x03}, 32))
+	addr, err := bsvutil2.NewAddressPubKeyHash(bsvutil2.Hash160(privKey.PubKey().SerializeCompressed()), netParams)
+	if err != nil {
+		t.Fatalf("failed to derive address: %v", err)
+	}
+	pkScript, err := txscript2.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("failed to build pkScript: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		contentSize int
+		inputs      int
+		outputs     int
+	}{
+		{"small content", 100, 1, 0},
+		{"chunked content", 1500, 2, 1},
+		{"large content", 70000, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := DefaultMetaIdTxParams()
+			params.Operation = "create"
+			params.Path = "/protocols/metaapp"
+
+			ins := make([]*TxInputUtxo, 0, tt.inputs)
+			for i := 0; i < tt.inputs; i++ {
+				ins = append(ins, &TxInputUtxo{
+					TxId:     chainhash2.DoubleHashH([]byte{byte(i)}).String(),
+					Amount:   10_000_000,
+					PkScript: hex.EncodeToString(pkScript),
+					PriHex:   hex.EncodeToString(privKey.Serialize()),
+				})
+			}
+			outs := make([]*TxOutput, 0, tt.outputs)
+			for i := 0; i < tt.outputs; i++ {
+				outs = append(outs, &TxOutput{Address: addr.EncodeAddress(), Amount: 1000})
+			}
+
+			tx, err := BuildMvcMetaIdTxWithParams(netParams, ins, outs, nil, params, bytes.Repeat([]byte{'a'}, tt.contentSize), addr.EncodeAddress(), feeRate, false)
+			if err != nil {
+				t.Fatalf("BuildMvcMetaIdTxWithParams failed: %v", err)
+			}
+			estimate, err := EstimateMvcMetaIdTxFee(params, tt.contentSize, tt.inputs, tt.outputs, true, feeRate)
+			if err != nil {
+				t.Fatalf("EstimateMvcMetaIdTxFee failed: %v", err)
+			}
+
+			size := tx.SerializeSize()
+			if estimate.Size < size || estimate.Size > size+2*tt.inputs {
+				t.Fatalf("estimated %d bytes, real tx has %d (allowed slack %d)", estimate.Size, size, 2*tt.inputs)
+			}
+			if estimate.Fee < int64(size)*feeRate {
+				t.Fatalf("estimated fee %d is below size x fee rate %d", estimate.Fee, int64(size)*feeRate)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package conf
 
 import (
 	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
 
 	"github.com/spf13/viper"
 )
@@ -17,6 +20,9 @@ type Config struct {
 	// Blockchain configuration
 	Chain ChainConfig
 
+	// Per-chain node configuration (keyed by chain name: btc, mvc), falls back to Chain when a chain has no entry
+	Chains map[string]ChainConfig
+
 	// Indexer configuration
 	Indexer IndexerConfig
 
@@ -28,6 +34,9 @@ type Config struct {
 
 	// Metafs configuration
 	Metafs MetafsConfig
+
+	// Outbound HTTP client configuration (metafs downloads, node RPC)
+	HTTPClient HTTPClientConfig
 }
 
 // DatabaseConfig database configuration
@@ -37,6 +46,8 @@ type DatabaseConfig struct {
 	MaxOpenConns int    // MySQL max open connections
 	MaxIdleConns int    // MySQL max idle connections
 	DataDir      string // PebbleDB data directory
+
+	MaxHistoryPerApp int // Versions kept in an app's inline history, older versions move to the history archive (0 = unlimited)
 }
 
 // ChainConfig blockchain configuration
@@ -45,6 +56,13 @@ type ChainConfig struct {
 	RpcUser     string
 	RpcPass     string
 	StartHeight int64
+	ZmqAddress  string // ZMQ server address of this chain's node (per-chain entries only)
+	RestUrl     string // Base URL of the node's REST interface, blocks are fetched as raw binary from it instead of JSON-RPC hex (empty disables)
+
+	RpcTimeoutSeconds int // HTTP timeout of a single RPC call in seconds
+	RpcMaxRetries     int // Max retries of an RPC call on transient errors (connection refused, 5xx)
+
+	StartupCheckAttempts int // Node reachability checks at startup before giving up (0 skips the check)
 }
 
 // StorageConfig storage configuration
@@ -70,23 +88,94 @@ type OSSStorageConfig struct {
 
 // IndexerConfig indexer configuration
 type IndexerConfig struct {
-	Port               string // Indexer service port
-	ScanInterval       int    // Scan interval in seconds
-	BatchSize          int    // Batch size for processing
-	StartHeight        int64  // Start block height
-	MvcInitBlockHeight int64  // MVC chain initial block height to start scanning from
-	BtcInitBlockHeight int64  // BTC chain initial block height to start scanning from
-	SwaggerBaseUrl     string // Swagger API base URL
-	ZmqEnabled         bool   // Enable ZMQ real-time monitoring
-	ZmqAddress         string // ZMQ server address
-	PathPrefix         string // Path prefix for reverse proxy (e.g., "/metaapp")
+	Port               string   // Indexer service port
+	ListenAddr         string   // Bind address: a host/IP combined with Port, or a full "host:port" (empty binds all interfaces)
+	TLSCert            string   // TLS certificate file; with TLSKey the API is served over HTTPS
+	TLSKey             string   // TLS private key file
+	ScanInterval       int      // Scan interval in seconds
+	BatchSize          int      // Number of blocks handled between sync height commits during catch-up
+	StartHeight        int64    // Start block height
+	MvcInitBlockHeight int64    // MVC chain initial block height to start scanning from
+	BtcInitBlockHeight int64    // BTC chain initial block height to start scanning from
+	SwaggerBaseUrl     string   // Swagger API base URL
+	ZmqEnabled         bool     // Enable ZMQ real-time monitoring
+	ZmqAddress         string   // ZMQ server address
+	PathPrefix         string   // Path prefix all routes are registered under, for a reverse proxy at a sub-path (e.g., "/metaapp")
+	MaxSyncLag         int64    // Max blocks any indexed chain may be behind its node tip before /ready reports unavailable
+	ScanConcurrency    int      // Number of blocks fetched concurrently during catch-up
+	RawTxCacheSize     int      // Number of raw transactions kept in the creator lookup LRU cache
+	RpcBatchEnabled    bool     // Fetch blocks with batched JSON-RPC calls (node must support batch requests)
+	Confirmations      int64    // Number of confirmations required before a block is indexed (0 = index the tip)
+	CorsOrigins        []string // Allowed CORS origins; empty or "*" allows any origin without credentials
+	TrustedProxies     []string // Reverse proxy IPs/CIDRs whose X-Forwarded-For / X-Real-IP headers are trusted for the client IP (empty trusts none)
+	AdminApiKey        string   // API key required by write/admin endpoints (empty disables authentication)
+	Chains             []string // Chains indexed by this process (btc, mvc), each with its own scanner
+	Protocols          []string // Protocol paths whose PINs are indexed (default: /protocols/metaapp)
+	CreatorAllowlist   []string // Creator addresses or MetaIDs whose PINs are indexed; empty allows every creator
+	CreatorDenylist    []string // Creator addresses or MetaIDs whose PINs are never indexed (checked after the allowlist)
+
+	PendingModifyExpireHours int   // Hours a modify whose referenced PIN is not indexed yet, or a MetaApp whose descriptor could not be fetched, is kept for reprocessing (0 drops them)
+	CheckpointInterval       int64 // Blocks between redundant resume checkpoints (height + block hash) kept apart from the sync status (0 disables)
+}
+
+// Addr address the API server listens on: ListenAddr when it already includes a port, otherwise ListenAddr:Port
+func (c IndexerConfig) Addr() string {
+	listenAddr := strings.TrimSpace(c.ListenAddr)
+	if _, _, err := net.SplitHostPort(listenAddr); err == nil {
+		return listenAddr
+	}
+	return net.JoinHostPort(strings.Trim(listenAddr, "[]"), c.Port)
+}
+
+// TLSEnabled whether the API server is served over HTTPS (both TLSCert and TLSKey are set)
+func (c IndexerConfig) TLSEnabled() bool {
+	return c.TLSCert != "" && c.TLSKey != ""
+}
+
+// normalizePathPrefix normalize a path prefix to "/segment[/segment]" (leading slash, no trailing slash); "" and "/" mean no prefix
+func normalizePathPrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
 }
 
 // MetaAppConfig MetaApp configuration
 type MetaAppConfig struct {
-	DeployFilePath string // Deploy file path for MetaApp
+	DeployFilePath       string // Deploy file path for MetaApp
+	SkipContentHashCheck bool   // Skip verifying downloaded code against MetaApp ContentHash
+	DeployWorkers        int    // Number of concurrent deploy workers
+	MaxDeployRetries     int    // Max deploy attempts before an item is dropped from the queue
+	DecryptionSecret     string // Secret used to derive the AES key for encrypted PIN content (owner address is used when empty)
+	StaticMaxAge         int    // Cache-Control max-age in seconds for served static assets
+	StaticIndexMaxAge    int    // Cache-Control max-age in seconds for HTML entry files (index.html)
+	DeployEnabled        bool   // Download and host app files; false runs the indexer in index-only mode
+	MaxExtractSizeMB     int    // Max total uncompressed size (MB) when extracting a deployed archive
+	MaxExtractFiles      int    // Max number of files when extracting a deployed archive
+	MaxDeployDirSizeMB   int    // Quota (MB) of the deploy directory, least recently served apps are evicted when exceeded (0 = unlimited)
+	MaxZipSizeMB         int    // Max deployed app size (MB) that can be zipped on the fly for download (0 = unlimited)
+	KeepFailedDeploys    bool   // Move the staging directory of a failed deploy to <deploy_file_path>/.failed with the error, instead of removing it
+	MaxFailedDeploys     int    // Kept failed deploys per app, older ones are removed when a new one is kept (0 = unlimited)
+
+	AllowedFileExtensions []string // Extensions (e.g., ".html") of app files that may be served; empty with AllowedContentTypes empty serves everything
+	AllowedContentTypes   []string // Content types (e.g., "text/html", or "image/" for a whole family) of app files that may be served
+
+	PublicURL               string // Public base URL of this service, used to build deploy URLs (e.g., "https://apps.example.com")
+	DeployWebhookURL        string // Webhook notified (POST JSON) when a deploy completes or permanently fails; empty disables it
+	DeployWebhookSecret     string // HMAC-SHA256 secret for the X-MetaApp-Signature header; empty sends unsigned requests
+	DeployWebhookMaxRetries int    // Retries of a failed webhook delivery, with exponential backoff
+
+	VersionPolicy string // Handling of a modify whose semantic Version is not greater than the latest version: off, flag or reject
 }
 
+// MetaApp modify version policies (meta_app.version_policy)
+const (
+	VersionPolicyOff    = "off"    // Accept every modify, the latest version is decided by timestamp
+	VersionPolicyFlag   = "flag"   // Accept the modify but record why its version is suspicious
+	VersionPolicyReject = "reject" // Record the modify as rejected, it does not become the latest version
+)
+
 // TempAppConfig 临时应用配置
 type TempAppConfig struct {
 	Enable         bool   // 是否启用临时应用
@@ -94,11 +183,49 @@ type TempAppConfig struct {
 	ExpireHours    int    // 过期时间（小时）
 	ChunkSize      int64  // 分片大小（字节，内部使用，从配置的 MB 转换而来）
 	ChunkSizeMB    int    // 分片大小（MB，配置使用）
+
+	CleanupIntervalMinutes int  // 过期清理间隔（分钟）
+	CleanupDryRun          bool // 清理 dry run 模式（只记录将要删除的内容，不实际删除）
+	ChunkUploadExpireHours int  // 未合并的分片上传保留时间（小时，超过后清理分片目录和记录）
+
+	MaxExtractSizeMB int // 解压后总大小上限（MB）
+	MaxExtractFiles  int // 解压文件数上限
+
+	MaxChunkUploadSizeMB int // 分片上传声明的文件总大小上限（MB）
+	MaxChunkCount        int // 分片上传的分片数上限
+
+	MaxActive           int // 未过期临时应用数量上限，达到后拒绝新上传（0 表示不限制）
+	UploadRatePerMinute int // 每个 IP 每分钟允许的上传请求数（上传、校验、分片初始化；交易广播单独计数，0 表示不限制）
+}
+
+// HTTPClientConfig outbound HTTP client configuration
+type HTTPClientConfig struct {
+	UserAgent           string // User-Agent sent on outbound requests (default "meta-app-service/<version>")
+	MaxIdleConnsPerHost int    // Idle connections kept per host for reuse
 }
 
 // MetafsConfig Metafs service configuration
 type MetafsConfig struct {
-	Domain string // Metafs service domain (e.g., "https://file.metaid.io")
+	Domain                string   // Metafs service domain (e.g., "https://file.metaid.io")
+	Gateways              []string // Alternate metafs domains tried in order when Domain fails (timeout, non-200, code != 0)
+	FileInfoPath          string   // File info API path, pinID is appended (e.g., "/api/v1/files")
+	AccelerateContentPath string   // Accelerated (CDN) content API path, tried first for downloads
+	ContentPath           string   // Plain content API path, used as fallback when the accelerated download fails; empty disables the fallback
+}
+
+// GatewayList metafs domains in failover order: Domain first, then Gateways (trimmed, duplicates removed)
+func (c MetafsConfig) GatewayList() []string {
+	gateways := make([]string, 0, len(c.Gateways)+1)
+	seen := make(map[string]bool)
+	for _, gateway := range append([]string{c.Domain}, c.Gateways...) {
+		gateway = strings.TrimRight(strings.TrimSpace(gateway), "/")
+		if gateway == "" || seen[gateway] {
+			continue
+		}
+		seen[gateway] = true
+		gateways = append(gateways, gateway)
+	}
+	return gateways
 }
 
 // UploaderConfig uploader configuration
@@ -119,8 +246,19 @@ type RpcConfig struct {
 // RpcConfigMap RPC configuration mapping (for multi-chain support)
 var RpcConfigMap = map[string]RpcConfig{}
 
-// Cfg global configuration instance
-var Cfg *Config
+// current global configuration instance, swapped atomically by ReloadConfig
+var current atomic.Pointer[Config]
+
+// Cfg current global configuration (nil before InitConfig)
+// Call it on every use instead of keeping the returned pointer, so reloaded values are picked up
+func Cfg() *Config {
+	return current.Load()
+}
+
+// SetCfg replace the global configuration as a whole (InitConfig, ReloadConfig and tests)
+func SetCfg(cfg *Config) {
+	current.Store(cfg)
+}
 
 // InitConfig initialize configuration
 func InitConfig() error {
@@ -128,9 +266,32 @@ func InitConfig() error {
 	if err := viper.ReadInConfig(); err != nil {
 		return fmt.Errorf("Fatal error config file: %s", err)
 	}
+	cfg := loadConfig()
+	SetCfg(cfg)
+
+	// Initialize RpcConfigMap (use currently configured chain)
+	RpcConfigMap[cfg.Net] = RpcConfig{
+		Url:      cfg.Chain.RpcUrl,
+		Username: cfg.Chain.RpcUser,
+		Password: cfg.Chain.RpcPass,
+	}
+	// Per-chain RPC configuration, keyed by chain name
+	for _, chainName := range cfg.Indexer.Chains {
+		chainCfg := GetChainConfig(chainName)
+		RpcConfigMap[chainName] = RpcConfig{
+			Url:      chainCfg.RpcUrl,
+			Username: chainCfg.RpcUser,
+			Password: chainCfg.RpcPass,
+		}
+	}
+
+	return nil
+}
 
+// loadConfig build configuration from the values viper has read, applying defaults
+func loadConfig() *Config {
 	// Create configuration instance
-	Cfg = &Config{
+	cfg := &Config{
 		Net: viper.GetString("net"),
 
 		Database: DatabaseConfig{
@@ -139,6 +300,8 @@ func InitConfig() error {
 			MaxOpenConns: viper.GetInt("database.max_open_conns"),
 			MaxIdleConns: viper.GetInt("database.max_idle_conns"),
 			DataDir:      viper.GetString("database.data_dir"),
+
+			MaxHistoryPerApp: viper.GetInt("database.max_history_per_app"),
 		},
 
 		Chain: ChainConfig{
@@ -146,10 +309,19 @@ func InitConfig() error {
 			RpcUser:     viper.GetString("chain.rpc_user"),
 			RpcPass:     viper.GetString("chain.rpc_pass"),
 			StartHeight: viper.GetInt64("chain.start_height"),
+			RestUrl:     strings.TrimRight(viper.GetString("chain.rest_url"), "/"),
+
+			RpcTimeoutSeconds: viper.GetInt("chain.rpc_timeout_seconds"),
+			RpcMaxRetries:     viper.GetInt("chain.rpc_max_retries"),
+
+			StartupCheckAttempts: viper.GetInt("chain.startup_check_attempts"),
 		},
 
 		Indexer: IndexerConfig{
 			Port:               viper.GetString("indexer.port"),
+			ListenAddr:         viper.GetString("indexer.listen_addr"),
+			TLSCert:            viper.GetString("indexer.tls_cert"),
+			TLSKey:             viper.GetString("indexer.tls_key"),
 			ScanInterval:       viper.GetInt("indexer.scan_interval"),
 			BatchSize:          viper.GetInt("indexer.batch_size"),
 			StartHeight:        viper.GetInt64("indexer.start_height"),
@@ -159,10 +331,48 @@ func InitConfig() error {
 			ZmqEnabled:         viper.GetBool("indexer.zmq_enabled"),
 			ZmqAddress:         viper.GetString("indexer.zmq_address"),
 			PathPrefix:         viper.GetString("indexer.path_prefix"),
+			MaxSyncLag:         viper.GetInt64("indexer.max_sync_lag"),
+			ScanConcurrency:    viper.GetInt("indexer.scan_concurrency"),
+			RawTxCacheSize:     viper.GetInt("indexer.raw_tx_cache_size"),
+			RpcBatchEnabled:    viper.GetBool("indexer.rpc_batch_enabled"),
+			Confirmations:      viper.GetInt64("indexer.confirmations"),
+			CorsOrigins:        viper.GetStringSlice("indexer.cors_origins"),
+			TrustedProxies:     viper.GetStringSlice("indexer.trusted_proxies"),
+			AdminApiKey:        viper.GetString("indexer.admin_api_key"),
+			Chains:             viper.GetStringSlice("indexer.chains"),
+			Protocols:          viper.GetStringSlice("indexer.protocols"),
+			CreatorAllowlist:   viper.GetStringSlice("indexer.creator_allowlist"),
+			CreatorDenylist:    viper.GetStringSlice("indexer.creator_denylist"),
+
+			PendingModifyExpireHours: viper.GetInt("indexer.pending_modify_expire_hours"),
+			CheckpointInterval:       viper.GetInt64("indexer.checkpoint_interval"),
 		},
 
 		MetaApp: MetaAppConfig{
-			DeployFilePath: viper.GetString("meta_app.deploy_file_path"),
+			DeployFilePath:       viper.GetString("meta_app.deploy_file_path"),
+			SkipContentHashCheck: viper.GetBool("meta_app.skip_content_hash_check"),
+			DeployWorkers:        viper.GetInt("meta_app.deploy_workers"),
+			MaxDeployRetries:     viper.GetInt("meta_app.max_deploy_retries"),
+			DecryptionSecret:     viper.GetString("meta_app.decryption_secret"),
+			StaticMaxAge:         viper.GetInt("meta_app.static_max_age"),
+			StaticIndexMaxAge:    viper.GetInt("meta_app.static_index_max_age"),
+			DeployEnabled:        viper.GetBool("meta_app.deploy_enabled"),
+			MaxExtractSizeMB:     viper.GetInt("meta_app.max_extract_size_mb"),
+			MaxExtractFiles:      viper.GetInt("meta_app.max_extract_files"),
+			MaxDeployDirSizeMB:   viper.GetInt("meta_app.max_deploy_dir_size_mb"),
+			MaxZipSizeMB:         viper.GetInt("meta_app.max_zip_size_mb"),
+			KeepFailedDeploys:    viper.GetBool("meta_app.keep_failed_deploys"),
+			MaxFailedDeploys:     viper.GetInt("meta_app.max_failed_deploys"),
+
+			AllowedFileExtensions: viper.GetStringSlice("meta_app.allowed_file_extensions"),
+			AllowedContentTypes:   viper.GetStringSlice("meta_app.allowed_content_types"),
+
+			PublicURL:               viper.GetString("meta_app.public_url"),
+			DeployWebhookURL:        viper.GetString("meta_app.deploy_webhook_url"),
+			DeployWebhookSecret:     viper.GetString("meta_app.deploy_webhook_secret"),
+			DeployWebhookMaxRetries: viper.GetInt("meta_app.deploy_webhook_max_retries"),
+
+			VersionPolicy: strings.ToLower(strings.TrimSpace(viper.GetString("meta_app.version_policy"))),
 		},
 
 		TempApp: TempAppConfig{
@@ -170,56 +380,211 @@ func InitConfig() error {
 			DeployFilePath: viper.GetString("temp_app.deploy_file_path"),
 			ExpireHours:    viper.GetInt("temp_app.expire_hours"),
 			ChunkSizeMB:    viper.GetInt("temp_app.chunk_size"),
+
+			CleanupIntervalMinutes: viper.GetInt("temp_app.cleanup_interval_minutes"),
+			CleanupDryRun:          viper.GetBool("temp_app.cleanup_dry_run"),
+			ChunkUploadExpireHours: viper.GetInt("temp_app.chunk_upload_expire_hours"),
+			MaxExtractSizeMB:       viper.GetInt("temp_app.max_extract_size_mb"),
+			MaxExtractFiles:        viper.GetInt("temp_app.max_extract_files"),
+			MaxChunkUploadSizeMB:   viper.GetInt("temp_app.max_chunk_upload_size_mb"),
+			MaxChunkCount:          viper.GetInt("temp_app.max_chunk_count"),
+			MaxActive:              viper.GetInt("temp_app.max_active"),
+			UploadRatePerMinute:    viper.GetInt("temp_app.upload_rate_per_minute"),
 		},
 
 		Metafs: MetafsConfig{
-			Domain: viper.GetString("metafs.domain"),
+			Domain:                viper.GetString("metafs.domain"),
+			Gateways:              viper.GetStringSlice("metafs.gateways"),
+			FileInfoPath:          viper.GetString("metafs.file_info_path"),
+			AccelerateContentPath: viper.GetString("metafs.accelerate_content_path"),
+			ContentPath:           viper.GetString("metafs.content_path"),
+		},
+
+		HTTPClient: HTTPClientConfig{
+			UserAgent:           viper.GetString("http_client.user_agent"),
+			MaxIdleConnsPerHost: viper.GetInt("http_client.max_idle_conns_per_host"),
 		},
 	}
 
 	// Set default values
-	if Cfg.Indexer.Port == "" {
-		Cfg.Indexer.Port = "7281"
+	if cfg.Indexer.Port == "" {
+		cfg.Indexer.Port = "7281"
+	}
+	if cfg.Indexer.ScanInterval == 0 {
+		cfg.Indexer.ScanInterval = 10
+	}
+	if cfg.Indexer.BatchSize == 0 {
+		cfg.Indexer.BatchSize = 100
+	}
+	if cfg.Indexer.MaxSyncLag <= 0 {
+		cfg.Indexer.MaxSyncLag = 10
+	}
+	if cfg.Indexer.ScanConcurrency <= 0 {
+		cfg.Indexer.ScanConcurrency = 1
+	}
+	if cfg.Indexer.RawTxCacheSize <= 0 {
+		cfg.Indexer.RawTxCacheSize = 10000
+	}
+	if !viper.IsSet("indexer.pending_modify_expire_hours") {
+		cfg.Indexer.PendingModifyExpireHours = 72
+	}
+	if !viper.IsSet("indexer.checkpoint_interval") {
+		cfg.Indexer.CheckpointInterval = 1000
+	}
+	if cfg.Database.MaxOpenConns == 0 {
+		cfg.Database.MaxOpenConns = 100
+	}
+	if cfg.Database.MaxIdleConns == 0 {
+		cfg.Database.MaxIdleConns = 10
 	}
-	if Cfg.Indexer.ScanInterval == 0 {
-		Cfg.Indexer.ScanInterval = 10
+	if !viper.IsSet("database.max_history_per_app") {
+		cfg.Database.MaxHistoryPerApp = 100
 	}
-	if Cfg.Indexer.BatchSize == 0 {
-		Cfg.Indexer.BatchSize = 100
+	if cfg.Indexer.SwaggerBaseUrl == "" {
+		cfg.Indexer.SwaggerBaseUrl = "localhost:" + cfg.Indexer.Port
 	}
-	if Cfg.Database.MaxOpenConns == 0 {
-		Cfg.Database.MaxOpenConns = 100
+	cfg.Indexer.PathPrefix = normalizePathPrefix(cfg.Indexer.PathPrefix)
+	if cfg.MetaApp.DeployFilePath == "" {
+		cfg.MetaApp.DeployFilePath = "./deploy_data"
 	}
-	if Cfg.Database.MaxIdleConns == 0 {
-		Cfg.Database.MaxIdleConns = 10
+	if cfg.MetaApp.DeployWorkers <= 0 {
+		cfg.MetaApp.DeployWorkers = 1
 	}
-	if Cfg.Indexer.SwaggerBaseUrl == "" {
-		Cfg.Indexer.SwaggerBaseUrl = "localhost:" + Cfg.Indexer.Port
+	if cfg.MetaApp.MaxDeployRetries <= 0 {
+		cfg.MetaApp.MaxDeployRetries = 3
 	}
-	if Cfg.MetaApp.DeployFilePath == "" {
-		Cfg.MetaApp.DeployFilePath = "./deploy_data"
+	if cfg.MetaApp.MaxExtractSizeMB <= 0 {
+		cfg.MetaApp.MaxExtractSizeMB = 1024 // 默认 1GB
 	}
-	if Cfg.TempApp.Enable == false {
-		Cfg.TempApp.Enable = true
+	if cfg.MetaApp.MaxExtractFiles <= 0 {
+		cfg.MetaApp.MaxExtractFiles = 10000
 	}
-	if Cfg.TempApp.DeployFilePath == "" {
-		Cfg.TempApp.DeployFilePath = "./temp_app_deploy_data"
+	if !viper.IsSet("meta_app.max_zip_size_mb") {
+		cfg.MetaApp.MaxZipSizeMB = 1024 // 默认 1GB
 	}
-	if Cfg.TempApp.ChunkSizeMB == 0 {
-		Cfg.TempApp.ChunkSizeMB = 5 // 默认 5MB
+	if !viper.IsSet("meta_app.max_failed_deploys") {
+		cfg.MetaApp.MaxFailedDeploys = 3
+	}
+	if !viper.IsSet("meta_app.deploy_webhook_max_retries") {
+		cfg.MetaApp.DeployWebhookMaxRetries = 3
+	}
+	if !viper.IsSet("meta_app.deploy_enabled") {
+		cfg.MetaApp.DeployEnabled = true // 默认下载并托管应用文件
+	}
+	if cfg.MetaApp.VersionPolicy == "" {
+		cfg.MetaApp.VersionPolicy = VersionPolicyOff
+	}
+	if cfg.MetaApp.StaticMaxAge <= 0 {
+		cfg.MetaApp.StaticMaxAge = 3600 // 默认 1 小时
+	}
+	if cfg.MetaApp.StaticIndexMaxAge <= 0 {
+		cfg.MetaApp.StaticIndexMaxAge = 60 // 默认 1 分钟
+	}
+	if cfg.TempApp.Enable == false {
+		cfg.TempApp.Enable = true
+	}
+	if cfg.TempApp.DeployFilePath == "" {
+		cfg.TempApp.DeployFilePath = "./temp_app_deploy_data"
+	}
+	if cfg.TempApp.ChunkSizeMB == 0 {
+		cfg.TempApp.ChunkSizeMB = 5 // 默认 5MB
 	}
 	// 将 MB 转换为字节
-	Cfg.TempApp.ChunkSize = int64(Cfg.TempApp.ChunkSizeMB) * 1024 * 1024
-	if Cfg.TempApp.ExpireHours == 0 {
-		Cfg.TempApp.ExpireHours = 24 // 默认 24 小时
+	cfg.TempApp.ChunkSize = int64(cfg.TempApp.ChunkSizeMB) * 1024 * 1024
+	if cfg.TempApp.ExpireHours == 0 {
+		cfg.TempApp.ExpireHours = 24 // 默认 24 小时
+	}
+	if cfg.TempApp.CleanupIntervalMinutes <= 0 {
+		cfg.TempApp.CleanupIntervalMinutes = 60 // 默认每小时清理一次
+	}
+	if cfg.TempApp.ChunkUploadExpireHours <= 0 {
+		cfg.TempApp.ChunkUploadExpireHours = 24 // 默认 24 小时
+	}
+	if cfg.TempApp.MaxExtractSizeMB <= 0 {
+		cfg.TempApp.MaxExtractSizeMB = 512 // 默认 512MB
+	}
+	if cfg.TempApp.MaxExtractFiles <= 0 {
+		cfg.TempApp.MaxExtractFiles = 10000
+	}
+	if cfg.TempApp.MaxChunkUploadSizeMB <= 0 {
+		cfg.TempApp.MaxChunkUploadSizeMB = 512 // 默认 512MB
+	}
+	if cfg.TempApp.MaxChunkCount <= 0 {
+		cfg.TempApp.MaxChunkCount = 1000
+	}
+	if !viper.IsSet("temp_app.max_active") {
+		cfg.TempApp.MaxActive = 1000
+	}
+	if !viper.IsSet("temp_app.upload_rate_per_minute") {
+		cfg.TempApp.UploadRatePerMinute = 10
 	}
 
-	// Initialize RpcConfigMap (use currently configured chain)
-	RpcConfigMap[Cfg.Net] = RpcConfig{
-		Url:      Cfg.Chain.RpcUrl,
-		Username: Cfg.Chain.RpcUser,
-		Password: Cfg.Chain.RpcPass,
+	if cfg.Metafs.FileInfoPath == "" {
+		cfg.Metafs.FileInfoPath = "/api/v1/files"
+	}
+	if cfg.Metafs.AccelerateContentPath == "" {
+		cfg.Metafs.AccelerateContentPath = "/api/v1/files/accelerate/content"
+	}
+	if !viper.IsSet("metafs.content_path") {
+		cfg.Metafs.ContentPath = "/api/v1/files/content"
 	}
 
-	return nil
+	if cfg.Chain.RpcTimeoutSeconds <= 0 {
+		cfg.Chain.RpcTimeoutSeconds = 30
+	}
+	if !viper.IsSet("chain.rpc_max_retries") {
+		cfg.Chain.RpcMaxRetries = 3
+	}
+	if !viper.IsSet("chain.startup_check_attempts") {
+		cfg.Chain.StartupCheckAttempts = 5
+	}
+	if len(cfg.Indexer.Chains) == 0 {
+		cfg.Indexer.Chains = []string{"mvc"}
+	}
+
+	// Per-chain node configuration (chains.<name>.*)
+	cfg.Chains = make(map[string]ChainConfig)
+	for _, chainName := range cfg.Indexer.Chains {
+		key := "chains." + chainName
+		if !viper.IsSet(key) {
+			continue
+		}
+		chainCfg := ChainConfig{
+			RpcUrl:      viper.GetString(key + ".rpc_url"),
+			RpcUser:     viper.GetString(key + ".rpc_user"),
+			RpcPass:     viper.GetString(key + ".rpc_pass"),
+			StartHeight: viper.GetInt64(key + ".start_height"),
+			ZmqAddress:  viper.GetString(key + ".zmq_address"),
+			RestUrl:     strings.TrimRight(viper.GetString(key+".rest_url"), "/"),
+
+			RpcTimeoutSeconds:    viper.GetInt(key + ".rpc_timeout_seconds"),
+			RpcMaxRetries:        viper.GetInt(key + ".rpc_max_retries"),
+			StartupCheckAttempts: viper.GetInt(key + ".startup_check_attempts"),
+		}
+		// RPC settings not set for the chain fall back to the chain section
+		if chainCfg.RpcTimeoutSeconds <= 0 {
+			chainCfg.RpcTimeoutSeconds = cfg.Chain.RpcTimeoutSeconds
+		}
+		if !viper.IsSet(key + ".rpc_max_retries") {
+			chainCfg.RpcMaxRetries = cfg.Chain.RpcMaxRetries
+		}
+		if !viper.IsSet(key + ".startup_check_attempts") {
+			chainCfg.StartupCheckAttempts = cfg.Chain.StartupCheckAttempts
+		}
+		cfg.Chains[chainName] = chainCfg
+	}
+
+	return cfg
+}
+
+// GetChainConfig get node configuration of a chain
+// Chains without a chains.<name> entry use the default chain config and indexer.zmq_address
+func GetChainConfig(chainName string) ChainConfig {
+	cfg := Cfg()
+	if chainCfg, ok := cfg.Chains[chainName]; ok {
+		return chainCfg
+	}
+	chainCfg := cfg.Chain
+	chainCfg.ZmqAddress = cfg.Indexer.ZmqAddress
+	return chainCfg
 }
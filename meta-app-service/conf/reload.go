@@ -0,0 +1,101 @@
+package conf
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []func(old, cur *Config)
+)
+
+// OnReload register a callback run after ReloadConfig swaps in a new configuration
+// Components that copy config values at startup (scan interval, deploy workers, CORS) use it to pick up changes
+func OnReload(hook func(old, cur *Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, hook)
+}
+
+// reloadableField a setting that can change without a restart
+type reloadableField struct {
+	key string
+	dst interface{} // pointer to the field in the new configuration
+	src interface{} // value read from the config file
+}
+
+// reloadableFields settings applied by ReloadConfig; everything else (database, chains, node RPC, ports,
+// admin API key, deploy paths, protocols) keeps its current value until restart
+func reloadableFields(next, loaded *Config) []reloadableField {
+	return []reloadableField{
+		{"indexer.scan_interval", &next.Indexer.ScanInterval, loaded.Indexer.ScanInterval},
+		{"indexer.max_sync_lag", &next.Indexer.MaxSyncLag, loaded.Indexer.MaxSyncLag},
+		{"indexer.cors_origins", &next.Indexer.CorsOrigins, loaded.Indexer.CorsOrigins},
+		{"indexer.pending_modify_expire_hours", &next.Indexer.PendingModifyExpireHours, loaded.Indexer.PendingModifyExpireHours},
+		{"indexer.checkpoint_interval", &next.Indexer.CheckpointInterval, loaded.Indexer.CheckpointInterval},
+		{"indexer.creator_allowlist", &next.Indexer.CreatorAllowlist, loaded.Indexer.CreatorAllowlist},
+		{"indexer.creator_denylist", &next.Indexer.CreatorDenylist, loaded.Indexer.CreatorDenylist},
+		{"meta_app.deploy_workers", &next.MetaApp.DeployWorkers, loaded.MetaApp.DeployWorkers},
+		{"meta_app.max_deploy_retries", &next.MetaApp.MaxDeployRetries, loaded.MetaApp.MaxDeployRetries},
+		{"meta_app.static_max_age", &next.MetaApp.StaticMaxAge, loaded.MetaApp.StaticMaxAge},
+		{"meta_app.static_index_max_age", &next.MetaApp.StaticIndexMaxAge, loaded.MetaApp.StaticIndexMaxAge},
+		{"meta_app.max_deploy_dir_size_mb", &next.MetaApp.MaxDeployDirSizeMB, loaded.MetaApp.MaxDeployDirSizeMB},
+		{"meta_app.max_zip_size_mb", &next.MetaApp.MaxZipSizeMB, loaded.MetaApp.MaxZipSizeMB},
+		{"meta_app.keep_failed_deploys", &next.MetaApp.KeepFailedDeploys, loaded.MetaApp.KeepFailedDeploys},
+		{"meta_app.max_failed_deploys", &next.MetaApp.MaxFailedDeploys, loaded.MetaApp.MaxFailedDeploys},
+		{"meta_app.allowed_file_extensions", &next.MetaApp.AllowedFileExtensions, loaded.MetaApp.AllowedFileExtensions},
+		{"meta_app.allowed_content_types", &next.MetaApp.AllowedContentTypes, loaded.MetaApp.AllowedContentTypes},
+		{"meta_app.deploy_webhook_url", &next.MetaApp.DeployWebhookURL, loaded.MetaApp.DeployWebhookURL},
+		{"meta_app.deploy_webhook_secret", &next.MetaApp.DeployWebhookSecret, loaded.MetaApp.DeployWebhookSecret},
+		{"meta_app.deploy_webhook_max_retries", &next.MetaApp.DeployWebhookMaxRetries, loaded.MetaApp.DeployWebhookMaxRetries},
+		{"meta_app.version_policy", &next.MetaApp.VersionPolicy, loaded.MetaApp.VersionPolicy},
+		{"temp_app.expire_hours", &next.TempApp.ExpireHours, loaded.TempApp.ExpireHours},
+		{"temp_app.chunk_upload_expire_hours", &next.TempApp.ChunkUploadExpireHours, loaded.TempApp.ChunkUploadExpireHours},
+		{"temp_app.cleanup_interval_minutes", &next.TempApp.CleanupIntervalMinutes, loaded.TempApp.CleanupIntervalMinutes},
+		{"temp_app.cleanup_dry_run", &next.TempApp.CleanupDryRun, loaded.TempApp.CleanupDryRun},
+		{"temp_app.max_active", &next.TempApp.MaxActive, loaded.TempApp.MaxActive},
+		{"temp_app.upload_rate_per_minute", &next.TempApp.UploadRatePerMinute, loaded.TempApp.UploadRatePerMinute},
+		{"temp_app.max_chunk_upload_size_mb", &next.TempApp.MaxChunkUploadSizeMB, loaded.TempApp.MaxChunkUploadSizeMB},
+		{"temp_app.max_chunk_count", &next.TempApp.MaxChunkCount, loaded.TempApp.MaxChunkCount},
+	}
+}
+
+// ReloadConfig re-read the config file and apply the reloadable subset of settings (see reloadableFields)
+// The new configuration is built on a copy and swapped in atomically (SetCfg), so readers never see a half-applied reload;
+// registered OnReload callbacks run afterwards. Returns the keys whose values changed.
+func ReloadConfig() ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	loaded := loadConfig()
+
+	old := Cfg()
+	next := *old
+	var changed []string
+	for _, field := range reloadableFields(&next, loaded) {
+		dst := reflect.ValueOf(field.dst).Elem()
+		if reflect.DeepEqual(dst.Interface(), field.src) {
+			continue
+		}
+		dst.Set(reflect.ValueOf(field.src))
+		changed = append(changed, field.key)
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	SetCfg(&next)
+	for _, hook := range reloadHooks {
+		hook(old, &next)
+	}
+	log.Printf("Configuration reloaded, changed: %v", changed)
+	return changed, nil
+}
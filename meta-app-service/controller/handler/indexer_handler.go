@@ -0,0 +1,317 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"meta-app-service/conf"
+	"meta-app-service/controller/respond"
+	"meta-app-service/database"
+	"meta-app-service/service/indexer_service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IndexerHandler 索引器控制处理器
+type IndexerHandler struct {
+	indexerService  *indexer_service.IndexerService            // 默认链的索引服务
+	indexerServices map[string]*indexer_service.IndexerService // 链名称 -> 索引服务
+}
+
+// NewIndexerHandler 创建索引器控制处理器实例（第一个索引服务为默认链）
+func NewIndexerHandler(indexerServices ...*indexer_service.IndexerService) *IndexerHandler {
+	h := &IndexerHandler{
+		indexerServices: make(map[string]*indexer_service.IndexerService),
+	}
+	for _, service := range indexerServices {
+		if service == nil {
+			continue
+		}
+		if h.indexerService == nil {
+			h.indexerService = service
+		}
+		h.indexerServices[string(service.ChainType())] = service
+	}
+	return h
+}
+
+// getIndexerService 根据 chain 查询参数获取索引服务（未指定时使用默认链），不可用时返回错误响应
+func (h *IndexerHandler) getIndexerService(c *gin.Context) (*indexer_service.IndexerService, bool) {
+	if chain := c.Query("chain"); chain != "" {
+		service, ok := h.indexerServices[chain]
+		if !ok {
+			respond.InvalidParam(c, "chain is not indexed: "+chain)
+			return nil, false
+		}
+		return service, true
+	}
+	if h.indexerService == nil {
+		respond.ServerError(c, "indexer service not available")
+		return nil, false
+	}
+	return h.indexerService, true
+}
+
+// PauseIndexer 暂停区块扫描
+// @Summary 暂停区块扫描
+// @Description 暂停区块扫描（不再推进同步高度），HTTP 服务和部署处理器保持运行，用于数据库压缩、节点升级等维护操作
+// @Tags Indexer Control
+// @Produce json
+// @Param chain query string false "链名称（btc/mvc），默认为第一个索引的链"
+// @Success 200 {object} respond.Response{data=indexer_service.IndexerState}
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/indexer/pause [post]
+func (h *IndexerHandler) PauseIndexer(c *gin.Context) {
+	indexerService, ok := h.getIndexerService(c)
+	if !ok {
+		return
+	}
+
+	indexerService.PauseScanning()
+	respond.Success(c, indexerService.GetState())
+}
+
+// ResumeIndexer 恢复区块扫描
+// @Summary 恢复区块扫描
+// @Description 恢复已暂停的区块扫描
+// @Tags Indexer Control
+// @Produce json
+// @Param chain query string false "链名称（btc/mvc），默认为第一个索引的链"
+// @Success 200 {object} respond.Response{data=indexer_service.IndexerState}
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/indexer/resume [post]
+func (h *IndexerHandler) ResumeIndexer(c *gin.Context) {
+	indexerService, ok := h.getIndexerService(c)
+	if !ok {
+		return
+	}
+
+	indexerService.ResumeScanning()
+	respond.Success(c, indexerService.GetState())
+}
+
+// GetIndexerState 获取索引器运行状态
+// @Summary 获取索引器运行状态
+// @Description 获取区块扫描是否暂停、当前扫描高度以及 ZMQ 是否运行
+// @Tags Indexer Control
+// @Produce json
+// @Param chain query string false "链名称（btc/mvc），默认为第一个索引的链"
+// @Success 200 {object} respond.Response{data=indexer_service.IndexerState}
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/indexer/state [get]
+func (h *IndexerHandler) GetIndexerState(c *gin.Context) {
+	indexerService, ok := h.getIndexerService(c)
+	if !ok {
+		return
+	}
+
+	respond.Success(c, indexerService.GetState())
+}
+
+// GetMempoolMetaApps 获取节点内存池中的 MetaApp PIN
+// @Summary 获取内存池中的 MetaApp PIN
+// @Description 预览即将上链的应用（需要管理员 API Key）。ZMQ 实时监听运行时返回 ZMQ 推送的 MetaApp PIN（最多 limit 条）；否则获取节点内存池交易并解析其中匹配 MetaApp 协议的 PIN，每笔交易需要一次节点请求，最多解析 limit 笔
+// @Tags Indexer Control
+// @Produce json
+// @Param chain query string false "链名称（btc/mvc），默认为第一个索引的链"
+// @Param limit query int false "最多返回的 PIN 数（ZMQ）或解析的内存池交易数" default(100) maximum(200)
+// @Success 200 {object} respond.Response{data=indexer_service.MempoolMetaApps}
+// @Failure 400 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/mempool/metaapps [get]
+func (h *IndexerHandler) GetMempoolMetaApps(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		respond.InvalidParam(c, "invalid limit")
+		return
+	}
+	indexerService, ok := h.getIndexerService(c)
+	if !ok {
+		return
+	}
+
+	result, err := indexerService.GetMempoolMetaApps(c.Request.Context(), limit)
+	if err != nil {
+		respond.ServerError(c, err.Error())
+		return
+	}
+	respond.Success(c, result)
+}
+
+// RescanRequest 重新扫描请求
+type RescanRequest struct {
+	From int64 `json:"from" binding:"min=0"` // 起始区块高度
+	To   int64 `json:"to" binding:"min=0"`   // 结束区块高度（包含，不能超过当前最新区块）
+}
+
+// RescanIndexer 重新扫描指定区块高度范围
+// @Summary 重新扫描区块
+// @Description 在后台重新扫描 [from, to] 区块范围并覆盖已索引的记录，扫描期间主扫描循环会暂停，可通过 /api/v1/indexer/state 查看进度
+// @Tags Indexer Control
+// @Accept json
+// @Produce json
+// @Param chain query string false "链名称（btc/mvc），默认为第一个索引的链"
+// @Param request body RescanRequest true "区块高度范围"
+// @Success 200 {object} respond.Response{data=indexer_service.IndexerState}
+// @Failure 400 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/indexer/rescan [post]
+func (h *IndexerHandler) RescanIndexer(c *gin.Context) {
+	indexerService, ok := h.getIndexerService(c)
+	if !ok {
+		return
+	}
+
+	var req RescanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.InvalidParam(c, "invalid request body: "+err.Error())
+		return
+	}
+
+	if err := indexerService.Rescan(req.From, req.To); err != nil {
+		if errors.Is(err, indexer_service.ErrInvalidRescanRange) || errors.Is(err, indexer_service.ErrRescanRunning) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, indexerService.GetState())
+}
+
+// RepairMetaApp 同步修复 MetaApp 部署文件
+// @Summary 修复 MetaApp 部署文件
+// @Description 部署文件损坏或被误删时使用：不经过部署队列，同步重新下载 first_pin_id 对应应用的最新版本，校验 ContentHash 并解压，成功后替换部署目录并返回部署记录；失败时原有部署保持不变
+// @Tags MetaApp
+// @Produce json
+// @Param firstPinId path string true "MetaApp FirstPinID"
+// @Param chain query string false "链名称（btc/mvc），默认为第一个索引的链"
+// @Success 200 {object} respond.Response{data=model.MetaAppDeployFileContent}
+// @Failure 400 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/first/{firstPinId}/repair [post]
+func (h *IndexerHandler) RepairMetaApp(c *gin.Context) {
+	indexerService, ok := h.getIndexerService(c)
+	if !ok {
+		return
+	}
+
+	firstPinID := c.Param("firstPinId")
+	if firstPinID == "" {
+		respond.InvalidParam(c, "firstPinId is required")
+		return
+	}
+
+	deployInfo, err := indexerService.RepairMetaApp(c.Request.Context(), firstPinID)
+	if err != nil {
+		if errors.Is(err, indexer_service.ErrMetaAppDisabled) || errors.Is(err, indexer_service.ErrDeployDisabled) || errors.Is(err, indexer_service.ErrNoServableVersion) || errors.Is(err, indexer_service.ErrDeploySkipped) {
+			respond.Error(c, respond.CodeInvalidParam, err.Error())
+			return
+		}
+		if errors.Is(err, database.ErrNotFound) {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		respond.ServerError(c, "failed to repair metaapp: "+err.Error())
+		return
+	}
+
+	respond.Success(c, deployInfo)
+}
+
+// ReloadConfig 重新加载配置文件
+// @Summary 重新加载配置
+// @Description 重新读取配置文件并应用可热更新的配置项（扫描间隔、部署 worker 数量、CORS 来源、webhook、静态资源缓存时间、临时应用过期和清理间隔等），不重新打开数据库也不重启扫描器；数据库、链节点、端口、API key 等配置需重启生效。与向进程发送 SIGHUP 效果相同
+// @Tags Indexer Control
+// @Produce json
+// @Success 200 {object} respond.Response{data=respond.ConfigReloadResponse}
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/config/reload [post]
+func (h *IndexerHandler) ReloadConfig(c *gin.Context) {
+	changed, err := conf.ReloadConfig()
+	if err != nil {
+		respond.ServerError(c, err.Error())
+		return
+	}
+	respond.Success(c, respond.ToConfigReloadResponse(changed))
+}
+
+// GetMetaAppIcon 通过索引器获取 MetaApp 图标
+// @Summary 获取 MetaApp 图标
+// @Description 解析 MetaApp 的 icon（metafile://pinid），从 metafs 下载后由索引器直接返回图片内容（首次请求后缓存），前端无需访问 metafs；非图片内容返回 404
+// @Tags MetaApp
+// @Produce image/png,image/jpeg,image/gif,image/svg+xml,image/webp
+// @Param pinId path string true "MetaApp PinID"
+// @Success 200 {file} binary
+// @Failure 403 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/{pinId}/icon [get]
+func (h *IndexerHandler) GetMetaAppIcon(c *gin.Context) {
+	h.serveMetaAppImage(c, indexer_service.MetaAppImageIcon)
+}
+
+// GetMetaAppCover 通过索引器获取 MetaApp 封面图片
+// @Summary 获取 MetaApp 封面图片
+// @Description 解析 MetaApp 的 cover_img（metafile://pinid），从 metafs 下载后由索引器直接返回图片内容（首次请求后缓存）；非图片内容返回 404
+// @Tags MetaApp
+// @Produce image/png,image/jpeg,image/gif,image/svg+xml,image/webp
+// @Param pinId path string true "MetaApp PinID"
+// @Success 200 {file} binary
+// @Failure 403 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/{pinId}/cover [get]
+func (h *IndexerHandler) GetMetaAppCover(c *gin.Context) {
+	h.serveMetaAppImage(c, indexer_service.MetaAppImageCover)
+}
+
+// serveMetaAppImage 返回 MetaApp 的图标或封面图片
+func (h *IndexerHandler) serveMetaAppImage(c *gin.Context, kind string) {
+	indexerService, ok := h.getIndexerService(c)
+	if !ok {
+		return
+	}
+
+	pinID := c.Param("pinId")
+	if pinID == "" {
+		respond.InvalidParam(c, "pinId is required")
+		return
+	}
+
+	filePath, err := indexerService.GetMetaAppImage(c.Request.Context(), pinID, kind)
+	if err != nil {
+		if errors.Is(err, indexer_service.ErrMetaAppDisabled) {
+			respond.Forbidden(c, "metaapp disabled")
+			return
+		}
+		if errors.Is(err, database.ErrNotFound) {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		if errors.Is(err, indexer_service.ErrMetaAppImageNotSet) {
+			respond.NotFound(c, "metaapp has no "+kind)
+			return
+		}
+		respond.ServerError(c, "failed to get metaapp "+kind+": "+err.Error())
+		return
+	}
+
+	// 只返回图片，避免通过索引器域名提供任意内容（如 HTML）
+	contentType := getContentType(filePath)
+	if !strings.HasPrefix(contentType, "image/") {
+		respond.NotFound(c, "metaapp "+kind+" is not an image")
+		return
+	}
+
+	// 图片按 pinid 引用，内容不会变化
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	// SVG 可包含脚本，直接打开时禁止执行
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.File(filePath)
+}
@@ -1,17 +1,23 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"meta-app-service/conf"
 	"meta-app-service/controller/respond"
 	"meta-app-service/database"
+	"meta-app-service/indexer"
+	model "meta-app-service/models"
 	"meta-app-service/service/indexer_service"
 
 	"github.com/gin-gonic/gin"
@@ -37,15 +43,26 @@ func NewMetaAppHandler(syncStatusService *indexer_service.SyncStatusService) *Me
 // @Tags MetaApp
 // @Accept json
 // @Produce json
-// @Param cursor query int false "游标（从 0 开始）" default(0)
+// @Param cursor query string false "游标（上一页返回的 next_cursor，第一页为空）"
 // @Param size query int false "每页大小" default(20)
+// @Param chain query string false "链名称过滤: btc/mvc"
+// @Param runtime query string false "运行环境过滤: browser/android/ios/windows/macOS/Linux（不区分大小写）"
+// @Param unconfirmed query string false "未确认（mempool）记录过滤: include/exclude/only" default(include)
 // @Success 200 {object} respond.Response{data=respond.MetaAppListResponse}
+// @Failure 400 {object} respond.Response
 // @Router /api/v1/metaapps [get]
 func (h *MetaAppHandler) ListMetaApps(c *gin.Context) {
 	// 解析查询参数
-	cursor, _ := strconv.ParseInt(c.DefaultQuery("cursor", "0"), 10, 64)
+	cursor := c.Query("cursor")
 	size, _ := strconv.ParseInt(c.DefaultQuery("size", "20"), 10, 64)
 
+	// 解析过滤条件
+	filter, err := parseMetaAppListFilter(c)
+	if err != nil {
+		respond.InvalidParam(c, err.Error())
+		return
+	}
+
 	// 限制每页大小
 	if size <= 0 {
 		size = 20
@@ -55,8 +72,12 @@ func (h *MetaAppHandler) ListMetaApps(c *gin.Context) {
 	}
 
 	// 调用服务
-	apps, nextCursor, err := h.appService.ListMetaApps(cursor, size)
+	apps, nextCursor, err := h.appService.ListMetaApps(cursor, size, filter)
 	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
 		if err == database.ErrNotFound {
 			respond.NotFound(c, "no metaapps found")
 			return
@@ -66,12 +87,63 @@ func (h *MetaAppHandler) ListMetaApps(c *gin.Context) {
 	}
 
 	// 构建响应
-	hasMore := nextCursor > cursor+int64(len(apps))
+	hasMore := nextCursor != ""
 	response := respond.ToMetaAppListResponse(apps, nextCursor, hasMore)
 
 	respond.Success(c, response)
 }
 
+// GetMetaAppsByHeightRange 获取区块高度范围内索引的 MetaApp（高度倒序，可分页）
+// @Summary 按区块高度范围获取 MetaApp
+// @Description 获取区块高度在 [from, to] 内的 MetaApp，每个已确认版本一条（未确认的 mempool 记录没有区块高度，不会返回），按区块高度倒序排列，支持分页
+// @Tags MetaApp
+// @Accept json
+// @Produce json
+// @Param from query int true "起始区块高度（包含）"
+// @Param to query int true "结束区块高度（包含）"
+// @Param cursor query string false "游标（上一页返回的 next_cursor，第一页为空）"
+// @Param size query int false "每页大小" default(20)
+// @Success 200 {object} respond.Response{data=respond.MetaAppListResponse}
+// @Failure 400 {object} respond.Response
+// @Router /api/v1/metaapps/by-height [get]
+func (h *MetaAppHandler) GetMetaAppsByHeightRange(c *gin.Context) {
+	fromHeight, err := strconv.ParseInt(c.Query("from"), 10, 64)
+	if err != nil || fromHeight < 0 {
+		respond.InvalidParam(c, "invalid from")
+		return
+	}
+	toHeight, err := strconv.ParseInt(c.Query("to"), 10, 64)
+	if err != nil || toHeight < 0 {
+		respond.InvalidParam(c, "invalid to")
+		return
+	}
+	if fromHeight > toHeight {
+		respond.InvalidParam(c, "from must not be greater than to")
+		return
+	}
+
+	cursor := c.Query("cursor")
+	size, _ := strconv.ParseInt(c.DefaultQuery("size", "20"), 10, 64)
+	if size <= 0 {
+		size = 20
+	}
+	if size > 100 {
+		size = 100
+	}
+
+	apps, nextCursor, err := h.appService.GetMetaAppsByHeightRange(fromHeight, toHeight, cursor, size)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.ToMetaAppListResponse(apps, nextCursor, nextCursor != ""))
+}
+
 // GetMetaAppsByCreatorMetaID 根据 MetaID 获取 MetaApp 列表（包括部署情况，时间倒序，可分页）
 // @Summary 根据 MetaID 获取 MetaApp 列表
 // @Description 根据创建者 MetaID 获取 MetaApp 列表，包括部署情况，按时间倒序排列，支持分页
@@ -79,9 +151,13 @@ func (h *MetaAppHandler) ListMetaApps(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param metaId path string true "创建者 MetaID"
-// @Param cursor query int false "游标（从 0 开始）" default(0)
+// @Param cursor query string false "游标（上一页返回的 next_cursor，第一页为空）"
 // @Param size query int false "每页大小" default(20)
+// @Param chain query string false "链名称过滤: btc/mvc"
+// @Param runtime query string false "运行环境过滤: browser/android/ios/windows/macOS/Linux（不区分大小写）"
+// @Param unconfirmed query string false "未确认（mempool）记录过滤: include/exclude/only" default(include)
 // @Success 200 {object} respond.Response{data=respond.MetaAppListResponse}
+// @Failure 400 {object} respond.Response
 // @Router /api/v1/metaapps/creator/{metaId} [get]
 func (h *MetaAppHandler) GetMetaAppsByCreatorMetaID(c *gin.Context) {
 	metaID := c.Param("metaId")
@@ -91,9 +167,105 @@ func (h *MetaAppHandler) GetMetaAppsByCreatorMetaID(c *gin.Context) {
 	}
 
 	// 解析查询参数
-	cursor, _ := strconv.ParseInt(c.DefaultQuery("cursor", "0"), 10, 64)
+	cursor := c.Query("cursor")
+	size, _ := strconv.ParseInt(c.DefaultQuery("size", "20"), 10, 64)
+
+	// 解析过滤条件
+	filter, err := parseMetaAppListFilter(c)
+	if err != nil {
+		respond.InvalidParam(c, err.Error())
+		return
+	}
+
+	// 限制每页大小
+	if size <= 0 {
+		size = 20
+	}
+	if size > 100 {
+		size = 100
+	}
+
+	// 调用服务
+	apps, nextCursor, err := h.appService.GetMetaAppsByCreatorMetaID(metaID, cursor, size, filter)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "no metaapps found for this metaId")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	// 构建响应
+	hasMore := nextCursor != ""
+	response := respond.ToMetaAppListResponse(apps, nextCursor, hasMore)
+
+	respond.Success(c, response)
+}
+
+// CountMetaAppsByCreatorMetaID 统计创建者名下的 MetaApp 数量
+// @Summary 统计创建者的 MetaApp 数量
+// @Description 统计创建者 MetaID 名下的 MetaApp 数量（按 first_pin_id 去重，同一应用的多个版本只计一次）
+// @Tags MetaApp
+// @Accept json
+// @Produce json
+// @Param metaId path string true "创建者 MetaID"
+// @Success 200 {object} respond.Response{data=respond.MetaAppCountResponse}
+// @Failure 400 {object} respond.Response
+// @Router /api/v1/metaapps/creator/{metaId}/count [get]
+func (h *MetaAppHandler) CountMetaAppsByCreatorMetaID(c *gin.Context) {
+	metaID := c.Param("metaId")
+	if metaID == "" {
+		respond.InvalidParam(c, "metaId is required")
+		return
+	}
+
+	count, err := h.appService.CountMetaAppsByCreatorMetaID(metaID)
+	if err != nil {
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.ToMetaAppCountResponse(metaID, count))
+}
+
+// GetMetaAppsByOwnerMetaID 根据当前拥有者 MetaID 获取 MetaApp 列表（包括部署情况，时间倒序，可分页）
+// @Summary 根据拥有者 MetaID 获取 MetaApp 列表
+// @Description 根据当前拥有者 MetaID 获取 MetaApp 列表（拥有者可通过 modify 转移），包括部署情况，按时间倒序排列，支持分页
+// @Tags MetaApp
+// @Accept json
+// @Produce json
+// @Param metaId path string true "拥有者 MetaID"
+// @Param cursor query string false "游标（上一页返回的 next_cursor，第一页为空）"
+// @Param size query int false "每页大小" default(20)
+// @Param chain query string false "链名称过滤: btc/mvc"
+// @Param runtime query string false "运行环境过滤: browser/android/ios/windows/macOS/Linux（不区分大小写）"
+// @Param unconfirmed query string false "未确认（mempool）记录过滤: include/exclude/only" default(include)
+// @Success 200 {object} respond.Response{data=respond.MetaAppListResponse}
+// @Failure 400 {object} respond.Response
+// @Router /api/v1/metaapps/owner/{metaId} [get]
+func (h *MetaAppHandler) GetMetaAppsByOwnerMetaID(c *gin.Context) {
+	metaID := c.Param("metaId")
+	if metaID == "" {
+		respond.InvalidParam(c, "metaId is required")
+		return
+	}
+
+	// 解析查询参数
+	cursor := c.Query("cursor")
 	size, _ := strconv.ParseInt(c.DefaultQuery("size", "20"), 10, 64)
 
+	// 解析过滤条件
+	filter, err := parseMetaAppListFilter(c)
+	if err != nil {
+		respond.InvalidParam(c, err.Error())
+		return
+	}
+
 	// 限制每页大小
 	if size <= 0 {
 		size = 20
@@ -103,8 +275,12 @@ func (h *MetaAppHandler) GetMetaAppsByCreatorMetaID(c *gin.Context) {
 	}
 
 	// 调用服务
-	apps, nextCursor, err := h.appService.GetMetaAppsByCreatorMetaID(metaID, cursor, size)
+	apps, nextCursor, err := h.appService.GetMetaAppsByOwnerMetaID(metaID, cursor, size, filter)
 	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
 		if err == database.ErrNotFound {
 			respond.NotFound(c, "no metaapps found for this metaId")
 			return
@@ -114,7 +290,66 @@ func (h *MetaAppHandler) GetMetaAppsByCreatorMetaID(c *gin.Context) {
 	}
 
 	// 构建响应
-	hasMore := nextCursor > cursor+int64(len(apps))
+	hasMore := nextCursor != ""
+	response := respond.ToMetaAppListResponse(apps, nextCursor, hasMore)
+
+	respond.Success(c, response)
+}
+
+// GetMetaAppsByAppName 根据应用名称（精确匹配）获取 MetaApp 列表（包括部署情况，时间倒序，可分页）
+// @Summary 根据应用名称获取 MetaApp 列表
+// @Description 获取当前版本应用名称（app_name，区分大小写的精确匹配）为 appName 的所有 MetaApp，可能来自不同创建者，用于发现名称冲突；包括部署情况，按时间倒序排列，支持分页
+// @Tags MetaApp
+// @Accept json
+// @Produce json
+// @Param appName path string true "应用名称"
+// @Param cursor query string false "游标（上一页返回的 next_cursor，第一页为空）"
+// @Param size query int false "每页大小" default(20)
+// @Param chain query string false "链名称过滤: btc/mvc"
+// @Param runtime query string false "运行环境过滤: browser/android/ios/windows/macOS/Linux（不区分大小写）"
+// @Param unconfirmed query string false "未确认（mempool）记录过滤: include/exclude/only" default(include)
+// @Success 200 {object} respond.Response{data=respond.MetaAppListResponse}
+// @Failure 400 {object} respond.Response
+// @Router /api/v1/metaapps/by-name/{appName} [get]
+func (h *MetaAppHandler) GetMetaAppsByAppName(c *gin.Context) {
+	appName := c.Param("appName")
+	if appName == "" {
+		respond.InvalidParam(c, "appName is required")
+		return
+	}
+
+	// 解析查询参数
+	cursor := c.Query("cursor")
+	size, _ := strconv.ParseInt(c.DefaultQuery("size", "20"), 10, 64)
+
+	// 解析过滤条件
+	filter, err := parseMetaAppListFilter(c)
+	if err != nil {
+		respond.InvalidParam(c, err.Error())
+		return
+	}
+
+	// 限制每页大小
+	if size <= 0 {
+		size = 20
+	}
+	if size > 100 {
+		size = 100
+	}
+
+	// 调用服务
+	apps, nextCursor, err := h.appService.GetMetaAppsByAppName(appName, cursor, size, filter)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	// 构建响应
+	hasMore := nextCursor != ""
 	response := respond.ToMetaAppListResponse(apps, nextCursor, hasMore)
 
 	respond.Success(c, response)
@@ -150,9 +385,109 @@ func (h *MetaAppHandler) GetMetaAppByPinID(c *gin.Context) {
 	respond.Success(c, respond.ToMetaAppResponse(app))
 }
 
+// GetMetaAppContent 获取 MetaApp 链上写入的原始内容
+// @Summary 获取 MetaApp 原始内容
+// @Description 根据 PinID 返回链上写入的原始 PIN 内容（未解析、未规范化，加密的 PIN 为密文），用于核对索引结果。内容为合法 JSON 时以 application/json 返回，否则以 application/octet-stream 返回；PIN 声明的内容类型和加密方式在 X-Pin-Content-Type、X-Pin-Encryption 响应头中
+// @Tags MetaApp
+// @Produce json
+// @Produce octet-stream
+// @Param pinId path string true "MetaApp PinID"
+// @Success 200 {string} string "原始内容"
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/{pinId}/content [get]
+func (h *MetaAppHandler) GetMetaAppContent(c *gin.Context) {
+	pinID := c.Param("pinId")
+	if pinID == "" {
+		respond.InvalidParam(c, "pinId is required")
+		return
+	}
+
+	app, err := h.appService.GetMetaAppByPinID(pinID)
+	if err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	var scanner *indexer.BlockScanner
+	if h.syncStatusService != nil {
+		scanner = h.syncStatusService.GetBlockScanner(app.ChainName)
+	}
+	content, err := h.appService.GetMetaAppRawContent(c.Request.Context(), app.MetaApp, scanner)
+	if err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "raw content not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	// 链上声明的内容类型不可信（可能是 text/html），不直接作为响应的 Content-Type
+	contentType := "application/octet-stream"
+	if json.Valid(content.Content) {
+		contentType = "application/json"
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("X-Pin-Content-Type", content.ContentType)
+	if content.Encryption != "" {
+		c.Header("X-Pin-Encryption", content.Encryption)
+	}
+	c.Data(http.StatusOK, contentType, content.Content)
+}
+
+// GetMetaAppRawTx 获取 MetaApp 的源交易
+// @Summary 获取 MetaApp 的源交易
+// @Description 根据 PinID 从节点获取 MetaApp 所在交易的原始 hex，decode=true 时同时返回从交易中解析出的 PIN 数据，用于核对链上内容与索引结果
+// @Tags MetaApp
+// @Accept json
+// @Produce json
+// @Param pinId path string true "MetaApp PinID"
+// @Param decode query bool false "是否解析交易中的 PIN 数据"
+// @Success 200 {object} respond.Response{data=indexer_service.MetaAppRawTx}
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/{pinId}/raw-tx [get]
+func (h *MetaAppHandler) GetMetaAppRawTx(c *gin.Context) {
+	pinID := c.Param("pinId")
+	if pinID == "" {
+		respond.InvalidParam(c, "pinId is required")
+		return
+	}
+
+	if h.syncStatusService == nil {
+		respond.ServerError(c, "sync status service not available")
+		return
+	}
+
+	app, err := h.appService.GetMetaAppByPinID(pinID)
+	if err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	decode, _ := strconv.ParseBool(c.Query("decode"))
+	scanner := h.syncStatusService.GetBlockScanner(app.ChainName)
+	rawTx, err := h.appService.GetMetaAppRawTx(c.Request.Context(), app.MetaApp, scanner, decode)
+	if err != nil {
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, rawTx)
+}
+
 // GetSyncStatus 获取同步状态
 // @Summary 获取同步状态
-// @Description 获取索引器同步状态（包括从节点获取的最新区块高度）
+// @Description 获取索引器同步状态（包括从节点获取的最新区块高度、剩余区块数、扫描速度和追上最新区块的预计时间；eta_seconds 为 -1 表示尚未测得扫描速度）
 // @Tags Indexer Status
 // @Accept json
 // @Produce json
@@ -172,13 +507,120 @@ func (h *MetaAppHandler) GetSyncStatus(c *gin.Context) {
 	}
 
 	// Get latest block height from node
-	latestHeight, err := h.syncStatusService.GetLatestBlockHeight()
+	latestHeight, err := h.syncStatusService.GetLatestBlockHeight(c.Request.Context())
 	if err != nil {
 		// If failed to get from node, use 0 as fallback
 		latestHeight = 0
 	}
+	progress := h.syncStatusService.GetSyncProgress(status.CurrentSyncHeight, latestHeight)
+
+	respond.Success(c, respond.ToIndexerSyncStatusResponse(status, latestHeight, progress))
+}
+
+// GetAllSyncStatus 获取所有链的同步状态
+// @Summary 获取所有链的同步状态
+// @Description 获取每条已索引链的当前同步高度、节点最新区块高度、落后区块数、已同步区块的出块时间及落后秒数、扫描速度和追上最新区块的预计时间（单条链节点不可用时在该链的 error 字段中返回）
+// @Tags Indexer Status
+// @Accept json
+// @Produce json
+// @Success 200 {object} respond.Response{data=respond.AllChainsSyncStatusResponse}
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/status/all [get]
+func (h *MetaAppHandler) GetAllSyncStatus(c *gin.Context) {
+	if h.syncStatusService == nil {
+		respond.ServerError(c, "sync status service not available")
+		return
+	}
 
-	respond.Success(c, respond.ToIndexerSyncStatusResponse(status, latestHeight))
+	statuses, err := h.syncStatusService.GetAllChainSyncStatus(c.Request.Context())
+	if err != nil {
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.ToAllChainsSyncStatusResponse(statuses))
+}
+
+// GetHealth 健康检查（附带各链节点连通状态）
+// @Summary 健康检查
+// @Description 服务存活时始终返回 200；任一链节点不可达或扫描已停止（如节点已裁剪待扫描的区块）时 status 为 degraded，启动后尚未完成第一次节点 RPC 调用时为 starting，nodes 中给出各链节点的连通状态、最近错误和扫描停止原因
+// @Tags Indexer Status
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /health [get]
+func (h *MetaAppHandler) GetHealth(c *gin.Context) {
+	status := "ok"
+	nodes := map[string]indexer.NodeHealth{}
+	if h.syncStatusService != nil {
+		nodes = h.syncStatusService.GetNodeHealth()
+	}
+	// degraded 优先；节点尚未探测过时连通状态未知，报告 starting 而不是 degraded
+	for _, node := range nodes {
+		switch {
+		case node.ScanHalted != "" || (node.Probed && !node.Reachable):
+			status = "degraded"
+		case !node.Probed && status == "ok":
+			status = "starting"
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  status,
+		"service": "indexer",
+		"nodes":   nodes,
+	})
+}
+
+// GetReadiness 就绪检查（任一链同步落后区块数超过阈值或节点不可达时返回 503）
+// @Summary 就绪检查
+// @Description 逐条比较已索引链的当前同步高度与节点最新区块高度，任一链落后超过 indexer.max_sync_lag 或节点不可达时返回 503，用于负载均衡摘除实例；chains 中给出每条链的同步高度、最新区块高度、落后区块数和错误信息
+// @Tags Indexer Status
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /ready [get]
+func (h *MetaAppHandler) GetReadiness(c *gin.Context) {
+	maxLag := conf.Cfg().Indexer.MaxSyncLag
+	var lags []*indexer_service.ChainSyncLag
+	if h.syncStatusService != nil {
+		lags = h.syncStatusService.GetAllSyncLag(c.Request.Context())
+	}
+	if len(lags) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unavailable",
+			"service": "indexer",
+			"message": "no chain is being indexed",
+		})
+		return
+	}
+
+	status := "ok"
+	httpStatus := http.StatusOK
+	chains := make(map[string]gin.H, len(lags))
+	for _, lag := range lags {
+		ready := lag.Error == "" && lag.Lag <= maxLag
+		if !ready {
+			status = "unavailable"
+			httpStatus = http.StatusServiceUnavailable
+		}
+		chain := gin.H{
+			"ready":               ready,
+			"current_sync_height": lag.CurrentHeight,
+			"latest_block_height": lag.LatestHeight,
+			"lag":                 lag.Lag,
+		}
+		if lag.Error != "" {
+			chain["error"] = lag.Error
+		}
+		chains[lag.ChainName] = chain
+	}
+
+	c.JSON(httpStatus, gin.H{
+		"status":  status,
+		"service": "indexer",
+		"max_lag": maxLag,
+		"chains":  chains,
+	})
 }
 
 // GetStats 获取统计信息
@@ -222,35 +664,148 @@ func (h *MetaAppHandler) GetConfig(c *gin.Context) {
 // @Produce json
 // @Param pinId path string true "MetaApp PinID"
 // @Success 200 {object} respond.Response
-// @Failure 400 {object} respond.Response
+// @Failure 400 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/{pinId}/redeploy [post]
+func (h *MetaAppHandler) RedeployMetaApp(c *gin.Context) {
+	pinID := c.Param("pinId")
+	if pinID == "" {
+		respond.InvalidParam(c, "pinId is required")
+		return
+	}
+
+	// 调用服务重新部署
+	err := h.appService.RedeployMetaApp(pinID)
+	if err != nil {
+		// 检查是否是已在队列中、已被禁用或未开启部署的错误
+		if errors.Is(err, database.ErrAlreadyQueued) || err == indexer_service.ErrMetaAppDisabled || err == indexer_service.ErrDeployDisabled || err == indexer_service.ErrNoServableVersion || errors.Is(err, indexer_service.ErrDeploySkipped) {
+			respond.Error(c, respond.CodeInvalidParam, err.Error())
+			return
+		}
+		// 检查是否是未找到的错误
+		if err == database.ErrNotFound || strings.Contains(err.Error(), "not found") {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.SuccessWithMsg(c, "MetaApp added to deploy queue successfully", nil)
+}
+
+// GetMetaAppDeployHistory 获取 MetaApp 部署记录历史
+// @Summary 获取 MetaApp 部署记录历史
+// @Description 根据 PinID 获取每次部署尝试的状态、消息和时间（最新的在前，保留最近的若干条），用于排查部署失败
+// @Tags MetaApp
+// @Accept json
+// @Produce json
+// @Param pinId path string true "MetaApp PinID"
+// @Success 200 {object} respond.Response{data=respond.MetaAppDeployHistoryResponse}
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/{pinId}/deploy-history [get]
+func (h *MetaAppHandler) GetMetaAppDeployHistory(c *gin.Context) {
+	pinID := c.Param("pinId")
+	if pinID == "" {
+		respond.InvalidParam(c, "pinId is required")
+		return
+	}
+
+	// 调用服务
+	history, err := h.appService.GetDeployHistory(pinID)
+	if err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.MetaAppDeployHistoryResponse{
+		PinID:   pinID,
+		History: history,
+	})
+}
+
+// DisableMetaAppRequest 禁用 MetaApp 请求
+type DisableMetaAppRequest struct {
+	Reason      string `json:"reason"`       // 禁用原因（如钓鱼、恶意内容）
+	RemoveFiles bool   `json:"remove_files"` // 是否同时删除已部署的文件
+}
+
+// DisableMetaApp 禁用 MetaApp
+// @Summary 禁用 MetaApp
+// @Description 将 PinID 所属应用（按 first_pin_id）加入黑名单：静态文件访问返回 403，不再部署，可选删除已部署的文件
+// @Tags MetaApp
+// @Accept json
+// @Produce json
+// @Param pinId path string true "MetaApp PinID"
+// @Param request body DisableMetaAppRequest false "禁用原因及是否删除部署文件"
+// @Success 200 {object} respond.Response{data=model.MetaAppBlacklist}
+// @Failure 400 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/{pinId}/disable [post]
+func (h *MetaAppHandler) DisableMetaApp(c *gin.Context) {
+	pinID := c.Param("pinId")
+	if pinID == "" {
+		respond.InvalidParam(c, "pinId is required")
+		return
+	}
+
+	// 请求体可选
+	var req DisableMetaAppRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respond.InvalidParam(c, "invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	entry, err := h.appService.DisableMetaApp(pinID, req.Reason, req.RemoveFiles)
+	if err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.SuccessWithMsg(c, "MetaApp disabled successfully", entry)
+}
+
+// EnableMetaApp 重新启用 MetaApp
+// @Summary 重新启用 MetaApp
+// @Description 将 PinID 所属应用（按 first_pin_id）从黑名单移除；如果禁用时删除了部署文件，需要再调用 redeploy 重新部署
+// @Tags MetaApp
+// @Accept json
+// @Produce json
+// @Param pinId path string true "MetaApp PinID"
+// @Success 200 {object} respond.Response
 // @Failure 404 {object} respond.Response
 // @Failure 500 {object} respond.Response
-// @Router /api/v1/metaapps/{pinId}/redeploy [post]
-func (h *MetaAppHandler) RedeployMetaApp(c *gin.Context) {
+// @Router /api/v1/metaapps/{pinId}/enable [post]
+func (h *MetaAppHandler) EnableMetaApp(c *gin.Context) {
 	pinID := c.Param("pinId")
 	if pinID == "" {
 		respond.InvalidParam(c, "pinId is required")
 		return
 	}
 
-	// 调用服务重新部署
-	err := h.appService.RedeployMetaApp(pinID)
-	if err != nil {
-		// 检查是否是已在队列中的错误
-		if strings.Contains(err.Error(), "already in deploy queue") {
-			respond.Error(c, respond.CodeInvalidParam, err.Error())
-			return
-		}
-		// 检查是否是未找到的错误
-		if err == database.ErrNotFound || strings.Contains(err.Error(), "not found") {
-			respond.NotFound(c, "metaapp not found")
+	if err := h.appService.EnableMetaApp(pinID); err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "metaapp not found or not disabled")
 			return
 		}
 		respond.ServerError(c, err.Error())
 		return
 	}
 
-	respond.SuccessWithMsg(c, "MetaApp added to deploy queue successfully", nil)
+	respond.SuccessWithMsg(c, "MetaApp enabled successfully", nil)
 }
 
 // GetMetaAppByFirstPinID 根据 FirstPinID 获取最新的 MetaApp 详情（包括部署情况）
@@ -283,9 +838,43 @@ func (h *MetaAppHandler) GetMetaAppByFirstPinID(c *gin.Context) {
 	respond.Success(c, respond.ToMetaAppResponse(app))
 }
 
+// GetLatestServableMetaAppByFirstPinID 根据 FirstPinID 获取最新的可提供服务的 MetaApp 详情
+// @Summary 根据 FirstPinID 获取最新的可提供服务的 MetaApp
+// @Description 与 /api/v1/metaapps/first/{firstPinId}（最新版本）不同，跳过被创建者禁用（disabled）、revoke 和内容校验失败的版本，返回最近的可用版本，即部署和提供静态文件服务的版本
+// @Tags MetaApp
+// @Produce json
+// @Param firstPinId path string true "MetaApp FirstPinID"
+// @Success 200 {object} respond.Response{data=respond.MetaAppResponse}
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/first/{firstPinId}/latest-servable [get]
+func (h *MetaAppHandler) GetLatestServableMetaAppByFirstPinID(c *gin.Context) {
+	firstPinID := c.Param("firstPinId")
+	if firstPinID == "" {
+		respond.InvalidParam(c, "firstPinId is required")
+		return
+	}
+
+	app, err := h.appService.GetLatestServableMetaAppByFirstPinID(firstPinID)
+	if err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		if err == indexer_service.ErrNoServableVersion {
+			respond.NotFound(c, err.Error())
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.ToMetaAppResponse(app))
+}
+
 // DownloadMetaAppAsZip 根据 FirstPinID 下载 MetaApp 部署文件为 zip
 // @Summary 下载 MetaApp 部署文件为 zip
-// @Description 根据 FirstPinID 压缩对应的部署文件夹并下载为 zip 文件
+// @Description 根据 FirstPinID 压缩对应的部署文件夹并下载为 zip 文件。应用大小超出 meta_app.max_zip_size_mb 时返回 40000，客户端断开时中止压缩
 // @Tags MetaApp
 // @Accept json
 // @Produce application/zip
@@ -302,9 +891,20 @@ func (h *MetaAppHandler) DownloadMetaAppAsZip(c *gin.Context) {
 		return
 	}
 
-	// 调用服务生成 zip 文件
-	zipFilePath, err := h.appService.DownloadMetaAppAsZip(firstPinID)
+	appDeployDir, err := h.appService.GetMetaAppZipDir(firstPinID)
 	if err != nil {
+		if errors.Is(err, indexer_service.ErrZipTooLarge) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
+		if err == indexer_service.ErrMetaAppDisabled {
+			respond.Forbidden(c, "metaapp disabled")
+			return
+		}
+		if err == indexer_service.ErrDeployDisabled {
+			respond.Error(c, respond.CodeInvalidParam, err.Error())
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			respond.NotFound(c, err.Error())
 			return
@@ -313,35 +913,29 @@ func (h *MetaAppHandler) DownloadMetaAppAsZip(c *gin.Context) {
 		return
 	}
 
-	// 确保文件存在
-	if _, err := os.Stat(zipFilePath); os.IsNotExist(err) {
-		respond.NotFound(c, "zip file not found")
-		return
-	}
-
-	// 设置响应头
+	// 设置响应头后直接将 zip 流式写入响应（大小未知，使用 chunked 传输）
 	c.Header("Content-Type", "application/zip")
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", firstPinID))
+	c.Status(http.StatusOK)
 
-	// 发送文件
-	c.File(zipFilePath)
-
-	// 延迟删除临时文件
-	go func() {
-		// 等待一小段时间确保文件已发送
-		time.Sleep(5 * time.Second)
-		os.Remove(zipFilePath)
-	}()
+	// 响应头已发送，出错（包括客户端断开）时只能中断连接
+	if err := indexer_service.WriteMetaAppZip(c.Request.Context(), appDeployDir, c.Writer); err != nil {
+		log.Printf("Zip download of %s aborted: %v", firstPinID, err)
+		c.Abort()
+	}
 }
 
-// GetMetaAppHistoryByFirstPinID 根据 FirstPinID 获取 MetaApp 历史版本列表
+// GetMetaAppHistoryByFirstPinID 根据 FirstPinID 获取 MetaApp 历史版本列表（时间倒序，可分页）
 // @Summary 根据 FirstPinID 获取 MetaApp 历史版本列表
-// @Description 根据 FirstPinID 获取 MetaApp 的所有历史版本列表
+// @Description 根据 FirstPinID 获取 MetaApp 的所有历史版本列表（包括超出历史上限后归档的旧版本），按时间倒序排列，支持分页
 // @Tags MetaApp
 // @Accept json
 // @Produce json
 // @Param firstPinId path string true "MetaApp FirstPinID"
+// @Param cursor query string false "游标（上一页返回的 next_cursor，第一页为空）"
+// @Param size query int false "每页大小" default(100)
 // @Success 200 {object} respond.Response{data=respond.MetaAppHistoryResponse}
+// @Failure 400 {object} respond.Response
 // @Router /api/v1/metaapps/first/{firstPinId}/history [get]
 func (h *MetaAppHandler) GetMetaAppHistoryByFirstPinID(c *gin.Context) {
 	firstPinID := c.Param("firstPinId")
@@ -350,13 +944,23 @@ func (h *MetaAppHandler) GetMetaAppHistoryByFirstPinID(c *gin.Context) {
 		return
 	}
 
+	cursor := c.Query("cursor")
+	size, _ := strconv.ParseInt(c.DefaultQuery("size", "100"), 10, 64)
+	if size <= 0 || size > 100 {
+		size = 100
+	}
+
 	// 调用服务
-	history, err := h.appService.GetMetaAppHistoryByFirstPinID(firstPinID)
+	history, nextCursor, err := h.appService.GetMetaAppHistoryByFirstPinID(firstPinID, cursor, size)
 	if err != nil {
 		if err == database.ErrNotFound {
 			respond.NotFound(c, "metaapp history not found")
 			return
 		}
+		if errors.Is(err, database.ErrInvalidCursor) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
 		respond.ServerError(c, err.Error())
 		return
 	}
@@ -368,19 +972,61 @@ func (h *MetaAppHandler) GetMetaAppHistoryByFirstPinID(c *gin.Context) {
 	}
 
 	respond.Success(c, respond.MetaAppHistoryResponse{
-		History: result,
+		History:    result,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
 	})
 }
 
+// DiffMetaAppVersions 对比 MetaApp 两个版本的字段差异
+// @Summary 对比 MetaApp 两个版本
+// @Description 对比同一 FirstPinID 下两个版本（from、to 均需属于该应用的历史版本）的字段差异，包括版本号、代码 pinId、禁用标记和 metadata（JSON 对象按键对比）
+// @Tags MetaApp
+// @Produce json
+// @Param firstPinId path string true "MetaApp FirstPinID"
+// @Param from query string true "对比的起始版本 PinID"
+// @Param to query string true "对比的目标版本 PinID"
+// @Success 200 {object} respond.Response{data=indexer_service.MetaAppDiff}
+// @Failure 400 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/metaapps/first/{firstPinId}/diff [get]
+func (h *MetaAppHandler) DiffMetaAppVersions(c *gin.Context) {
+	firstPinID := c.Param("firstPinId")
+	fromPinID := c.Query("from")
+	toPinID := c.Query("to")
+	if firstPinID == "" || fromPinID == "" || toPinID == "" {
+		respond.InvalidParam(c, "firstPinId, from and to are required")
+		return
+	}
+
+	diff, err := h.appService.DiffMetaAppVersions(firstPinID, fromPinID, toPinID)
+	if err != nil {
+		if errors.Is(err, indexer_service.ErrVersionNotInHistory) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "metaapp history not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, diff)
+}
+
 // ListDeployQueue 获取部署队列列表（支持游标分页）
 // @Summary 获取部署队列列表
-// @Description 获取部署队列列表，按时间戳倒序排列，支持分页
+// @Description 获取部署队列列表，按时间戳倒序排列，支持分页，需要管理员 API key
 // @Tags Deploy Queue
 // @Accept json
 // @Produce json
 // @Param cursor query int false "游标（从 0 开始）" default(0)
 // @Param size query int false "每页大小" default(20)
 // @Success 200 {object} respond.Response{data=respond.DeployQueueListResponse}
+// @Failure 403 {object} respond.Response
 // @Failure 500 {object} respond.Response
 // @Router /api/v1/deploy-queue [get]
 func (h *MetaAppHandler) ListDeployQueue(c *gin.Context) {
@@ -419,6 +1065,123 @@ func (h *MetaAppHandler) ListDeployQueue(c *gin.Context) {
 	respond.Success(c, response)
 }
 
+// GetDeployQueueItem 获取部署队列项详情
+// @Summary 获取部署队列项
+// @Description 根据 PinID 获取部署队列项，包括重试次数、最近一次失败的错误信息和下次重试时间，需要管理员 API key
+// @Tags Deploy Queue
+// @Produce json
+// @Param pinId path string true "队列项 PinID"
+// @Success 200 {object} respond.Response{data=respond.DeployQueueResponse}
+// @Failure 403 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/deploy-queue/{pinId} [get]
+func (h *MetaAppHandler) GetDeployQueueItem(c *gin.Context) {
+	pinID := c.Param("pinId")
+	if pinID == "" {
+		respond.InvalidParam(c, "pinId is required")
+		return
+	}
+
+	queue, err := h.appService.GetDeployQueueItem(pinID)
+	if err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "deploy queue item not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.ToDeployQueueResponse(queue))
+}
+
+// RemoveDeployQueueItem 手动移除部署队列项
+// @Summary 移除部署队列项
+// @Description 根据 PinID 将队列项从部署队列中移除（例如反复失败的队列项），需要管理员 API key；正在部署中的队列项不会被中断
+// @Tags Deploy Queue
+// @Produce json
+// @Param pinId path string true "队列项 PinID"
+// @Success 200 {object} respond.Response
+// @Failure 403 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/deploy-queue/{pinId} [delete]
+func (h *MetaAppHandler) RemoveDeployQueueItem(c *gin.Context) {
+	pinID := c.Param("pinId")
+	if pinID == "" {
+		respond.InvalidParam(c, "pinId is required")
+		return
+	}
+
+	if err := h.appService.RemoveDeployQueueItem(pinID); err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "deploy queue item not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.SuccessWithMsg(c, "Removed from deploy queue successfully", nil)
+}
+
+// ListRecentDeploys 按最近一次部署成功时间获取 MetaApp 列表
+// @Summary 获取最近部署的 MetaApp 列表
+// @Description 按最近一次部署成功（文件下载并解压完成）的时间倒序返回 MetaApp，每个应用只出现一次；与按链上时间排序的 /api/v1/metaapps 不同，可用于查看部署进度
+// @Tags Deploy Queue
+// @Accept json
+// @Produce json
+// @Param cursor query string false "游标（上一页返回的 next_cursor，第一页为空）"
+// @Param size query int false "每页大小" default(20)
+// @Success 200 {object} respond.Response{data=respond.MetaAppListResponse}
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/deploy/recent [get]
+func (h *MetaAppHandler) ListRecentDeploys(c *gin.Context) {
+	cursor := c.Query("cursor")
+	size, _ := strconv.ParseInt(c.DefaultQuery("size", "20"), 10, 64)
+
+	// 限制每页大小
+	if size <= 0 {
+		size = 20
+	}
+	if size > 100 {
+		size = 100
+	}
+
+	apps, nextCursor, err := h.appService.ListRecentDeploys(cursor, size)
+	if err != nil {
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.ToMetaAppListResponse(apps, nextCursor, nextCursor != ""))
+}
+
+// GetDeployDiskStatus 核对部署目录与部署记录
+// @Summary 核对部署目录与部署记录
+// @Description 列出部署目录下的应用目录（目录名为 first_pin_id）并与部署记录对照：problem 为 orphaned 表示目录存在但没有部署记录，missing 表示部署记录为已完成但没有文件（因配额被清理的应用标记为 evicted，不算缺失），需要管理员 API key
+// @Tags Deploy Queue
+// @Produce json
+// @Success 200 {object} respond.Response{data=indexer_service.DeployDiskStatus}
+// @Failure 400 {object} respond.Response
+// @Failure 403 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/deploy/disk-status [get]
+func (h *MetaAppHandler) GetDeployDiskStatus(c *gin.Context) {
+	status, err := h.appService.GetDeployDiskStatus()
+	if err != nil {
+		if err == indexer_service.ErrDeployDisabled {
+			respond.Error(c, respond.CodeInvalidParam, err.Error())
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, status)
+}
+
 // ServeMetaAppStaticFiles 提供 MetaApp 部署的静态文件服务
 // 支持访问 /{pinId}/index.html 以及 /{pinId}/*filepath 下的所有静态资源
 func (h *MetaAppHandler) ServeMetaAppStaticFiles(c *gin.Context) {
@@ -449,6 +1212,69 @@ func (h *MetaAppHandler) ServeMetaAppStaticFiles(c *gin.Context) {
 		return
 	}
 
+	// 被禁用（黑名单）的应用返回 403
+	if indexer_service.IsMetaAppDisabled(pinID) {
+		respond.Forbidden(c, "metaapp disabled")
+		return
+	}
+
+	serveMetaAppDeployFiles(c, pinID)
+}
+
+// ServeLatestMetaAppStaticFiles 按 first_pin_id 提供 MetaApp 最新版本的静态文件服务
+// 支持访问 /app/{firstPinId}/*filepath，分享的链接始终指向最新部署的版本
+// 传入的也可以是任意版本的 PinID，会先解析出其 first_pin_id
+func (h *MetaAppHandler) ServeLatestMetaAppStaticFiles(c *gin.Context) {
+	firstPinID := c.Param("firstPinId")
+	matched, err := regexp.MatchString(`^[0-9a-f]{64}i\d+$`, firstPinID)
+	if err != nil || !matched {
+		respond.NotFound(c, "invalid firstPinId format")
+		return
+	}
+
+	if database.DB == nil {
+		respond.ServerError(c, "database not initialized")
+		return
+	}
+
+	// 解析最新版本
+	latest, err := database.DB.GetLatestMetaAppByFirstPinID(firstPinID)
+	if err != nil {
+		app, pinErr := database.DB.GetMetaAppByPinID(firstPinID)
+		if pinErr != nil || app.FirstPinId == "" {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+		if latest, err = database.DB.GetLatestMetaAppByFirstPinID(app.FirstPinId); err != nil {
+			respond.NotFound(c, "metaapp not found")
+			return
+		}
+	}
+
+	// 被禁用（黑名单）的应用返回 403
+	if indexer_service.IsMetaAppDisabled(latest.FirstPinId) {
+		respond.Forbidden(c, "metaapp disabled")
+		return
+	}
+
+	// 告知客户端当前提供的是哪个版本：部署目录中的版本以最近一次部署成功的记录为准（单条读取，不再逐个资源请求加载历史）
+	servedPinID := latest.PinID
+	if deploy, err := database.DB.GetLatestDeployByFirstPinID(latest.FirstPinId); err == nil {
+		servedPinID = deploy.PinID
+	}
+	c.Header("X-MetaApp-Pin-Id", servedPinID)
+
+	serveMetaAppDeployFiles(c, latest.FirstPinId)
+}
+
+// serveMetaAppDeployFiles 从 first_pin_id 对应的部署目录中提供请求的静态文件
+func serveMetaAppDeployFiles(c *gin.Context, pinID string) {
+	// 仅索引模式下不托管应用文件
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		respond.Error(c, respond.CodeNotFound, "metaapp hosting is disabled on this indexer (index-only mode)")
+		return
+	}
+
 	// 获取文件路径（如果请求的是 /{pinId}/index.html，filepath 会是 "/index.html"）
 	// 如果请求的是 /{pinId}，filepath 会是空字符串
 	requestedFilePath := c.Param("filepath")
@@ -457,7 +1283,7 @@ func (h *MetaAppHandler) ServeMetaAppStaticFiles(c *gin.Context) {
 	requestedFilePath = strings.TrimPrefix(requestedFilePath, "/")
 
 	// 获取部署基础目录
-	deployBaseDir := conf.Cfg.MetaApp.DeployFilePath
+	deployBaseDir := conf.Cfg().MetaApp.DeployFilePath
 	if deployBaseDir == "" {
 		deployBaseDir = "./meta_app_deploy_data"
 	}
@@ -467,17 +1293,26 @@ func (h *MetaAppHandler) ServeMetaAppStaticFiles(c *gin.Context) {
 
 	// 检查应用部署目录是否存在
 	if _, err := os.Stat(appDeployDir); os.IsNotExist(err) {
+		// 因超出磁盘配额被清理的应用，重新加入部署队列
+		if indexer_service.RestoreEvictedMetaApp(pinID) {
+			c.Header("Retry-After", "30")
+			respond.NotFound(c, "metaapp files were evicted and are being redeployed, retry shortly")
+			return
+		}
 		fmt.Printf("[ServeMetaAppStaticFiles] App directory not found: %s\n", appDeployDir)
 		respond.NotFound(c, "metaapp not deployed")
 		return
 	}
 
+	// 记录访问时间（磁盘配额按最近访问时间清理）
+	indexer_service.RecordMetaAppAccess(appDeployDir)
+
 	// 如果没有指定文件路径（即访问 /{pinId} 而不是 /{pinId}/），
 	// 则重定向到带斜杠的版本，这样可以确保浏览器的基础路径正确
 	// 避免前端资源路径解析错误
 	if requestedFilePath == "" {
-		// 获取完整的请求路径
-		fullPath := c.Request.URL.Path
+		// 获取路由路径（不含前缀，重定向时统一加上对外的路径前缀）
+		fullPath := routePath(c)
 		// 如果路径不以斜杠结尾，重定向到带斜杠的版本
 		if !strings.HasSuffix(fullPath, "/") {
 			// 301 永久重定向到带斜杠的版本
@@ -490,10 +1325,10 @@ func (h *MetaAppHandler) ServeMetaAppStaticFiles(c *gin.Context) {
 		fmt.Printf("[ServeMetaAppStaticFiles] Serving index.html for pinID: %s\n", pinID)
 	}
 
-	// 确定要服务的文件路径
+	// 确定要服务的文件路径（未指定时使用应用声明的 IndexFile，未声明时使用 index.html）
 	filePath := requestedFilePath
 	if filePath == "" {
-		filePath = "index.html"
+		filePath = resolveIndexFile(pinID, appDeployDir)
 	} else {
 		fmt.Printf("[ServeMetaAppStaticFiles] Requested filepath: %s for pinID: %s\n", filePath, pinID)
 	}
@@ -527,23 +1362,98 @@ func (h *MetaAppHandler) ServeMetaAppStaticFiles(c *gin.Context) {
 		return
 	}
 
-	// 设置正确的 Content-Type（根据文件扩展名）
+	// 设置正确的 Content-Type（根据文件扩展名，未知扩展名时根据文件内容判断）
 	// 这样可以避免浏览器自动重定向
 	contentType := getContentType(cleanFilePath)
+
+	// 配置了允许的文件类型时，其他类型的文件（如 .php、.exe）返回 403
+	if !isServableFile(cleanFilePath, contentType) {
+		respond.Forbidden(c, "file type not allowed")
+		return
+	}
+
 	if contentType != "" {
 		c.Header("Content-Type", contentType)
 	}
 
+	// 设置缓存头（ETag / Cache-Control），条件请求由 c.File 返回 304
+	setStaticCacheHeaders(c, cleanFilePath, fileInfo)
+
 	// 直接返回文件内容，不重定向
 	// 使用 c.File() 但确保不会重定向
 	c.File(cleanFilePath)
 }
 
-// getPathPrefix 获取路径前缀，优先级：配置 > X-Forwarded-Prefix 请求头 > 空字符串
+// isServableFile 检查文件是否允许对外提供
+// 未配置 meta_app.allowed_file_extensions 和 meta_app.allowed_content_types 时允许所有文件；
+// 否则扩展名或 Content-Type 匹配其中任一列表即可（以 "/" 结尾的类型匹配整个类别，如 "image/"）
+func isServableFile(filePath, contentType string) bool {
+	extensions := conf.Cfg().MetaApp.AllowedFileExtensions
+	contentTypes := conf.Cfg().MetaApp.AllowedContentTypes
+	if len(extensions) == 0 && len(contentTypes) == 0 {
+		return true
+	}
+
+	ext := strings.ToLower(filepath.Ext(filePath))
+	for _, allowed := range extensions {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if !strings.HasPrefix(allowed, ".") {
+			allowed = "." + allowed
+		}
+		if ext != "" && ext == allowed {
+			return true
+		}
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "" {
+		return false
+	}
+	for _, allowed := range contentTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveIndexFile 获取应用的入口文件（相对于部署目录）
+// 优先使用已索引记录中的 IndexFile；未设置，或单文件 HTML 应用下载时已被命名为 index.html 导致文件不存在时，使用 index.html
+func resolveIndexFile(pinID, appDeployDir string) string {
+	const defaultIndexFile = "index.html"
+	if database.DB == nil {
+		return defaultIndexFile
+	}
+
+	// 部署目录按 first_pin_id 命名，优先取最新版本，兼容直接使用 PinID 访问
+	app, err := database.DB.GetLatestMetaAppByFirstPinID(pinID)
+	if err != nil {
+		app, err = database.DB.GetMetaAppByPinID(pinID)
+		if err != nil {
+			return defaultIndexFile
+		}
+	}
+
+	indexFile := strings.TrimPrefix(strings.TrimSpace(app.IndexFile), "/")
+	if indexFile == "" {
+		return defaultIndexFile
+	}
+	if info, err := os.Stat(filepath.Join(appDeployDir, indexFile)); err != nil || info.IsDir() {
+		return defaultIndexFile
+	}
+	return indexFile
+}
+
+// getPathPrefix 获取对外的路径前缀（拼接在路由路径前构建返回的 URL 和重定向地址），优先级：配置 > X-Forwarded-Prefix 请求头 > 空字符串
+// 配置了 indexer.path_prefix 时所有路由都注册在该前缀下；未配置时反向代理去掉前缀后转发，通过 X-Forwarded-Prefix 告知前缀
 func getPathPrefix(c *gin.Context) string {
 	// 1. 优先使用配置
-	if conf.Cfg != nil && conf.Cfg.Indexer.PathPrefix != "" {
-		return conf.Cfg.Indexer.PathPrefix
+	if conf.Cfg() != nil && conf.Cfg().Indexer.PathPrefix != "" {
+		return conf.Cfg().Indexer.PathPrefix
 	}
 
 	// 2. 其次使用请求头（反向代理常用）
@@ -555,7 +1465,16 @@ func getPathPrefix(c *gin.Context) string {
 	return ""
 }
 
-// getContentType 根据文件扩展名返回 Content-Type
+// routePath 获取请求的路由路径（去掉 indexer.path_prefix 后的部分）
+func routePath(c *gin.Context) string {
+	fullPath := c.Request.URL.Path
+	if conf.Cfg() != nil && conf.Cfg().Indexer.PathPrefix != "" {
+		fullPath = strings.TrimPrefix(fullPath, conf.Cfg().Indexer.PathPrefix)
+	}
+	return fullPath
+}
+
+// getContentType 根据文件扩展名返回 Content-Type，未知扩展名时根据文件内容判断
 func getContentType(filePath string) string {
 	ext := strings.ToLower(filepath.Ext(filePath))
 	switch ext {
@@ -563,10 +1482,18 @@ func getContentType(filePath string) string {
 		return "text/html; charset=utf-8"
 	case ".js":
 		return "application/javascript; charset=utf-8"
+	case ".mjs":
+		return "text/javascript; charset=utf-8"
 	case ".css":
 		return "text/css; charset=utf-8"
-	case ".json":
+	case ".json", ".map":
+		// source map 也是 JSON
 		return "application/json; charset=utf-8"
+	case ".webmanifest":
+		return "application/manifest+json; charset=utf-8"
+	case ".wasm":
+		// WebAssembly.instantiateStreaming 要求 application/wasm
+		return "application/wasm"
 	case ".png":
 		return "image/png"
 	case ".jpg", ".jpeg":
@@ -575,15 +1502,121 @@ func getContentType(filePath string) string {
 		return "image/gif"
 	case ".svg":
 		return "image/svg+xml"
+	case ".webp":
+		return "image/webp"
+	case ".avif":
+		return "image/avif"
 	case ".ico":
 		return "image/x-icon"
-	case ".woff", ".woff2":
+	case ".woff":
+		return "font/woff"
+	case ".woff2":
 		return "font/woff2"
 	case ".ttf":
 		return "font/ttf"
 	case ".eot":
 		return "application/vnd.ms-fontobject"
+	// 音视频使用明确的类型（内容判断对 mov、m4a、aac 等格式不可靠），播放器按 Content-Type 和 Range 请求流式播放
+	case ".mp4", ".m4v":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".ogv":
+		return "video/ogg"
+	case ".mov":
+		return "video/quicktime"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".m4a":
+		return "audio/mp4"
+	case ".aac":
+		return "audio/aac"
+	case ".ogg", ".oga":
+		return "audio/ogg"
+	case ".opus":
+		return "audio/opus"
+	case ".wav":
+		return "audio/wav"
+	case ".flac":
+		return "audio/flac"
+	case ".vtt":
+		return "text/vtt; charset=utf-8"
 	default:
+		// 未知扩展名（包括无扩展名文件）根据文件内容判断
+		return sniffContentType(filePath)
+	}
+}
+
+// sniffContentType 读取文件前 512 字节，使用 http.DetectContentType 判断 Content-Type，读取失败时返回空字符串
+func sniffContentType(filePath string) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
 		return ""
 	}
+	return http.DetectContentType(buf[:n])
+}
+
+// hashedAssetPattern 匹配构建工具生成的带内容哈希的文件名（例如 app.3f9a1b2c.js、index-BX7kq2Lm.css）
+var hashedAssetPattern = regexp.MustCompile(`[.-][0-9a-zA-Z_]{8,}\.[0-9a-zA-Z]+$`)
+
+// setStaticCacheHeaders 设置静态文件的缓存头
+// ETag 由文件大小和修改时间生成（部署后文件不变，重新部署会改变修改时间），
+// c.File 内部的 http.ServeContent 会根据 ETag 和 Last-Modified 处理 If-None-Match / If-Modified-Since 并返回 304
+// 缓存时间：带内容哈希的资源长期缓存（immutable），HTML 入口使用较短的缓存时间，其他资源使用默认缓存时间
+func setStaticCacheHeaders(c *gin.Context, filePath string, fileInfo os.FileInfo) {
+	etag := fmt.Sprintf(`"%x-%x"`, fileInfo.Size(), fileInfo.ModTime().UnixNano())
+	c.Header("ETag", etag)
+
+	maxAge := conf.Cfg().MetaApp.StaticMaxAge
+	ext := strings.ToLower(filepath.Ext(filePath))
+	switch {
+	case ext == ".html" || ext == ".htm":
+		maxAge = conf.Cfg().MetaApp.StaticIndexMaxAge
+	case isHashedAsset(filepath.Base(filePath)):
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		return
+	}
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+}
+
+// isHashedAsset 判断文件名是否带内容哈希（哈希段需包含数字，排除 app.settings.js 这类普通文件名）
+func isHashedAsset(name string) bool {
+	hashPart := hashedAssetPattern.FindString(name)
+	return hashPart != "" && strings.ContainsAny(hashPart, "0123456789")
+}
+
+// parseMetaAppListFilter 解析列表过滤条件（chain / runtime / unconfirmed 查询参数）
+func parseMetaAppListFilter(c *gin.Context) (model.MetaAppListFilter, error) {
+	filter := model.MetaAppListFilter{
+		ChainName:   strings.TrimSpace(c.Query("chain")),
+		Runtime:     strings.TrimSpace(c.Query("runtime")),
+		Unconfirmed: strings.TrimSpace(c.Query("unconfirmed")),
+	}
+	if filter.ChainName != "" && !containsFold(model.MetaAppChainNames, filter.ChainName) {
+		return filter, fmt.Errorf("invalid chain: %s, accepted values: %s", filter.ChainName, strings.Join(model.MetaAppChainNames, "/"))
+	}
+	if filter.Runtime != "" && !containsFold(model.MetaAppRuntimes, filter.Runtime) {
+		return filter, fmt.Errorf("invalid runtime: %s, accepted values: %s", filter.Runtime, strings.Join(model.MetaAppRuntimes, "/"))
+	}
+	if filter.Unconfirmed != "" && !containsFold(model.MetaAppUnconfirmedModes, filter.Unconfirmed) {
+		return filter, fmt.Errorf("invalid unconfirmed: %s, accepted values: %s", filter.Unconfirmed, strings.Join(model.MetaAppUnconfirmedModes, "/"))
+	}
+	return filter, nil
+}
+
+// containsFold 判断列表中是否包含指定值（不区分大小写）
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
 }
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -12,10 +14,14 @@ import (
 	"meta-app-service/controller/respond"
 	"meta-app-service/database"
 	"meta-app-service/service/temp_deploy_service"
+	"meta-app-service/tool"
 
 	"github.com/gin-gonic/gin"
 )
 
+// chunkFormOverhead 分片上传请求中 multipart 表单的额外开销（边界、表头和 chunk_md5 字段）
+const chunkFormOverhead = 64 * 1024
+
 // TempAppHandler 临时应用处理器
 type TempAppHandler struct {
 	tempDeployService *temp_deploy_service.TempDeployService
@@ -30,22 +36,23 @@ func NewTempAppHandler() *TempAppHandler {
 
 // checkTempAppEnabled 检查临时应用功能是否启用
 func (h *TempAppHandler) checkTempAppEnabled(c *gin.Context) bool {
-	if conf.Cfg == nil || !conf.Cfg.TempApp.Enable {
+	if conf.Cfg() == nil || !conf.Cfg().TempApp.Enable {
 		respond.Error(c, respond.CodeInvalidParam, "temp app feature is disabled")
 		return false
 	}
 	return true
 }
 
-// UploadTempApp 上传临时应用 zip 包
-// @Summary 上传临时应用 zip 包
-// @Description 上传 zip 包，生成唯一 tokenId，解压并保存，返回部署信息
+// UploadTempApp 上传临时应用压缩包
+// @Summary 上传临时应用压缩包
+// @Description 上传 zip / tar.gz 压缩包，生成唯一 tokenId，解压并保存，返回部署信息。内容相同且未过期的临时应用已存在时直接返回该应用
 // @Tags TempApp
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "zip 文件"
+// @Param file formData file true "zip / tar.gz / tgz 文件"
 // @Success 200 {object} respond.Response{data=respond.TempAppDeployResponse}
 // @Failure 400 {object} respond.Response
+// @Failure 429 {object} respond.Response
 // @Failure 500 {object} respond.Response
 // @Router /api/v1/temp-apps/upload [post]
 func (h *TempAppHandler) UploadTempApp(c *gin.Context) {
@@ -62,8 +69,8 @@ func (h *TempAppHandler) UploadTempApp(c *gin.Context) {
 	}
 
 	// 验证文件扩展名
-	if !strings.HasSuffix(strings.ToLower(file.Filename), ".zip") {
-		respond.InvalidParam(c, "file must be a zip file")
+	if !tool.IsArchiveFileName(file.Filename) {
+		respond.InvalidParam(c, "file must be a zip or tar.gz file")
 		return
 	}
 
@@ -78,15 +85,76 @@ func (h *TempAppHandler) UploadTempApp(c *gin.Context) {
 	// 调用服务上传并解压
 	deploy, err := h.tempDeployService.UploadTempApp(src, file.Filename)
 	if err != nil {
+		if errors.Is(err, temp_deploy_service.ErrTooManyActiveTempApps) {
+			respond.TooManyRequests(c, err.Error())
+			return
+		}
 		respond.ServerError(c, err.Error())
 		return
 	}
 
 	// 转换为响应结构
-	response := respond.ToTempAppDeployResponse(deploy)
+	response := respond.ToTempAppDeployResponse(deploy, getPathPrefix(c))
 	respond.Success(c, response)
 }
 
+// ValidateTempApp 校验临时应用压缩包（dry run）
+// @Summary 校验临时应用压缩包
+// @Description 上传 zip / tar.gz 压缩包，解压到临时目录检查根目录是否包含入口文件，返回文件列表和总大小后删除，不创建临时部署。压缩包本身的问题在 data.errors 中返回
+// @Tags TempApp
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "zip / tar.gz / tgz 文件"
+// @Param index formData string false "入口文件（相对于压缩包根目录）" default(index.html)
+// @Success 200 {object} respond.Response{data=respond.TempAppValidateResponse}
+// @Failure 400 {object} respond.Response
+// @Failure 429 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/temp-apps/validate [post]
+func (h *TempAppHandler) ValidateTempApp(c *gin.Context) {
+	// 检查功能是否启用
+	if !h.checkTempAppEnabled(c) {
+		return
+	}
+
+	// 获取上传的文件
+	file, err := c.FormFile("file")
+	if err != nil {
+		respond.InvalidParam(c, "file is required")
+		return
+	}
+
+	// 验证文件扩展名
+	if !tool.IsArchiveFileName(file.Filename) {
+		respond.InvalidParam(c, "file must be a zip or tar.gz file")
+		return
+	}
+
+	// 入口文件必须是压缩包内的相对路径
+	indexFile := strings.TrimPrefix(c.DefaultPostForm("index", "index.html"), "/")
+	indexFile = filepath.ToSlash(filepath.Clean(indexFile))
+	if indexFile == "." || indexFile == ".." || strings.HasPrefix(indexFile, "../") {
+		respond.InvalidParam(c, "index must be a relative path inside the archive")
+		return
+	}
+
+	// 打开文件
+	src, err := file.Open()
+	if err != nil {
+		respond.ServerError(c, fmt.Sprintf("failed to open file: %v", err))
+		return
+	}
+	defer src.Close()
+
+	validation, err := h.tempDeployService.ValidateTempApp(src, file.Filename, indexFile)
+	if err != nil {
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.ToTempAppValidateResponse(validation))
+}
+
 // GetTempAppByTokenID 根据 TokenID 获取临时应用部署信息
 // @Summary 根据 TokenID 获取临时应用部署信息
 // @Description 根据 TokenID 获取临时应用的部署信息
@@ -122,10 +190,46 @@ func (h *TempAppHandler) GetTempAppByTokenID(c *gin.Context) {
 	}
 
 	// 转换为响应结构
-	response := respond.ToTempAppDeployResponse(deploy)
+	response := respond.ToTempAppDeployResponse(deploy, getPathPrefix(c))
 	respond.Success(c, response)
 }
 
+// DeleteTempApp 删除临时应用
+// @Summary 删除临时应用
+// @Description 根据 TokenID 立即删除临时应用的部署文件和记录（无需等待过期）
+// @Tags TempApp
+// @Accept json
+// @Produce json
+// @Param tokenId path string true "临时应用 TokenID"
+// @Success 200 {object} respond.Response
+// @Failure 404 {object} respond.Response
+// @Failure 500 {object} respond.Response
+// @Router /api/v1/temp-apps/{tokenId} [delete]
+func (h *TempAppHandler) DeleteTempApp(c *gin.Context) {
+	// 检查功能是否启用
+	if !h.checkTempAppEnabled(c) {
+		return
+	}
+
+	tokenID := c.Param("tokenId")
+	if tokenID == "" {
+		respond.InvalidParam(c, "tokenId is required")
+		return
+	}
+
+	// 调用服务删除
+	if err := h.tempDeployService.DeleteTempApp(tokenID); err != nil {
+		if err == database.ErrNotFound {
+			respond.NotFound(c, "temp app not found")
+			return
+		}
+		respond.ServerError(c, err.Error())
+		return
+	}
+
+	respond.Success(c, gin.H{"message": "temp app deleted successfully"})
+}
+
 // ServeTempAppStaticFiles 提供临时应用部署的静态文件服务
 // 支持访问 /temp/{tokenId}/index.html 以及 /temp/{tokenId}/*filepath 下的所有静态资源
 func (h *TempAppHandler) ServeTempAppStaticFiles(c *gin.Context) {
@@ -154,7 +258,7 @@ func (h *TempAppHandler) ServeTempAppStaticFiles(c *gin.Context) {
 	log.Println("requestedFilePath after trim", requestedFilePath)
 
 	// 获取部署基础目录
-	deployBaseDir := conf.Cfg.TempApp.DeployFilePath
+	deployBaseDir := conf.Cfg().TempApp.DeployFilePath
 	if deployBaseDir == "" {
 		deployBaseDir = "./temp_app_deploy_data"
 	}
@@ -173,8 +277,8 @@ func (h *TempAppHandler) ServeTempAppStaticFiles(c *gin.Context) {
 	// 如果没有指定文件路径（即访问 /temp/{tokenId} 而不是 /temp/{tokenId}/），
 	// 则重定向到带斜杠的版本
 	if requestedFilePath == "" {
-		// 获取完整的请求路径
-		fullPath := c.Request.URL.Path
+		// 获取路由路径（不含前缀，重定向时统一加上对外的路径前缀）
+		fullPath := routePath(c)
 		// 如果路径不以斜杠结尾，重定向到带斜杠的版本
 		if !strings.HasSuffix(fullPath, "/") {
 			// 301 永久重定向到带斜杠的版本
@@ -222,26 +326,31 @@ func (h *TempAppHandler) ServeTempAppStaticFiles(c *gin.Context) {
 		return
 	}
 
-	// 设置正确的 Content-Type（根据文件扩展名）
+	// 设置正确的 Content-Type（根据文件扩展名，未知扩展名时根据文件内容判断）
 	contentType := getContentType(cleanFilePath)
 	if contentType != "" {
 		c.Header("Content-Type", contentType)
 	}
 
+	// 设置缓存头（ETag / Cache-Control），条件请求由 c.File 返回 304
+	setStaticCacheHeaders(c, cleanFilePath, fileInfo)
+
 	// 直接返回文件内容
 	c.File(cleanFilePath)
 }
 
 // InitChunkUpload 初始化分片上传
 // @Summary 初始化分片上传
-// @Description 初始化分片上传，返回 uploadId 和分片信息
+// @Description 初始化分片上传，返回 uploadId 和分片信息；total_size 超出 temp_app.max_chunk_upload_size_mb 或分片数超出 temp_app.max_chunk_count 时拒绝
 // @Tags TempApp
 // @Accept json
 // @Produce json
 // @Param total_size formData int true "文件总大小（字节）"
 // @Param filename formData string false "文件名"
+// @Param chunk_md5s formData string false "每个分片的 MD5（逗号分隔，按分片索引排列，可选）"
 // @Success 200 {object} respond.Response{data=respond.TempAppChunkInitResponse}
 // @Failure 400 {object} respond.Response
+// @Failure 429 {object} respond.Response
 // @Failure 500 {object} respond.Response
 // @Router /api/v1/temp-apps/chunk/init [post]
 func (h *TempAppHandler) InitChunkUpload(c *gin.Context) {
@@ -265,9 +374,23 @@ func (h *TempAppHandler) InitChunkUpload(c *gin.Context) {
 
 	filename := c.PostForm("filename")
 
+	// 可选的分片 MD5 列表
+	var chunkMD5s []string
+	if chunkMD5sStr := strings.TrimSpace(c.PostForm("chunk_md5s")); chunkMD5sStr != "" {
+		chunkMD5s = strings.Split(chunkMD5sStr, ",")
+	}
+
 	// 调用服务初始化分片上传
-	upload, err := h.tempDeployService.InitChunkUpload(totalSize, filename)
+	upload, err := h.tempDeployService.InitChunkUpload(totalSize, filename, chunkMD5s)
 	if err != nil {
+		if errors.Is(err, temp_deploy_service.ErrInvalidChunkMD5) || errors.Is(err, temp_deploy_service.ErrChunkUploadTooLarge) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
+		if errors.Is(err, temp_deploy_service.ErrTooManyActiveTempApps) {
+			respond.TooManyRequests(c, err.Error())
+			return
+		}
 		respond.ServerError(c, err.Error())
 		return
 	}
@@ -286,6 +409,7 @@ func (h *TempAppHandler) InitChunkUpload(c *gin.Context) {
 // @Param uploadId path string true "上传 ID"
 // @Param chunkIndex path int true "分片索引（从 0 开始）"
 // @Param chunk formData file true "分片数据"
+// @Param X-Chunk-MD5 header string false "分片数据的 MD5（也可通过 chunk_md5 表单字段传递）"
 // @Success 200 {object} respond.Response
 // @Failure 400 {object} respond.Response
 // @Failure 500 {object} respond.Response
@@ -309,9 +433,17 @@ func (h *TempAppHandler) UploadChunk(c *gin.Context) {
 		return
 	}
 
+	// 限制请求体大小：一个分片加上 multipart 表单的开销，超出时不再读取（分片本身的大小由服务校验）
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, conf.Cfg().TempApp.ChunkSize+chunkFormOverhead)
+
 	// 获取分片数据
 	file, err := c.FormFile("chunk")
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respond.InvalidParam(c, fmt.Sprintf("chunk request exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
 		respond.InvalidParam(c, "chunk is required")
 		return
 	}
@@ -324,8 +456,18 @@ func (h *TempAppHandler) UploadChunk(c *gin.Context) {
 	}
 	defer src.Close()
 
+	// 分片 MD5（可选，优先使用请求头）
+	chunkMD5 := c.GetHeader("X-Chunk-MD5")
+	if chunkMD5 == "" {
+		chunkMD5 = c.PostForm("chunk_md5")
+	}
+
 	// 调用服务上传分片
-	if err := h.tempDeployService.UploadChunk(uploadID, chunkIndex, src); err != nil {
+	if err := h.tempDeployService.UploadChunk(uploadID, chunkIndex, src, chunkMD5); err != nil {
+		if errors.Is(err, temp_deploy_service.ErrInvalidChunkMD5) || errors.Is(err, temp_deploy_service.ErrChunkChecksumMismatch) || errors.Is(err, temp_deploy_service.ErrChunkUploadTooLarge) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
 		respond.ServerError(c, err.Error())
 		return
 	}
@@ -335,13 +477,14 @@ func (h *TempAppHandler) UploadChunk(c *gin.Context) {
 
 // MergeChunks 合并分片
 // @Summary 合并分片
-// @Description 合并所有分片并解压，创建临时应用部署
+// @Description 合并所有分片并解压，创建临时应用部署。内容相同且未过期的临时应用已存在时直接返回该应用
 // @Tags TempApp
 // @Accept json
 // @Produce json
 // @Param uploadId path string true "上传 ID"
 // @Success 200 {object} respond.Response{data=respond.TempAppDeployResponse}
 // @Failure 400 {object} respond.Response
+// @Failure 429 {object} respond.Response
 // @Failure 500 {object} respond.Response
 // @Router /api/v1/temp-apps/chunk/{uploadId}/merge [post]
 func (h *TempAppHandler) MergeChunks(c *gin.Context) {
@@ -359,12 +502,20 @@ func (h *TempAppHandler) MergeChunks(c *gin.Context) {
 	// 调用服务合并分片
 	deploy, err := h.tempDeployService.MergeChunks(uploadID)
 	if err != nil {
+		if errors.Is(err, temp_deploy_service.ErrChunkChecksumMismatch) {
+			respond.InvalidParam(c, err.Error())
+			return
+		}
+		if errors.Is(err, temp_deploy_service.ErrTooManyActiveTempApps) {
+			respond.TooManyRequests(c, err.Error())
+			return
+		}
 		respond.ServerError(c, err.Error())
 		return
 	}
 
 	// 转换为响应结构
-	response := respond.ToTempAppDeployResponse(deploy)
+	response := respond.ToTempAppDeployResponse(deploy, getPathPrefix(c))
 	respond.Success(c, response)
 }
 
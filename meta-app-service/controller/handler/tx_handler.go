@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"meta-app-service/common"
+	"meta-app-service/conf"
+	"meta-app-service/controller/respond"
+	"meta-app-service/service/indexer_service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TxHandler 交易处理器
+type TxHandler struct{}
+
+// NewTxHandler 创建交易处理器实例
+func NewTxHandler() *TxHandler {
+	return &TxHandler{}
+}
+
+// maxBroadcastBodySize 广播请求体的最大字节数（hex 编码的原始交易加 JSON 包装，约 5 MB 的交易）
+const maxBroadcastBodySize = 10 << 20
+
+// BroadcastTxRequest 广播交易请求
+type BroadcastTxRequest struct {
+	Chain string `json:"chain" example:"mvc"`                     // 链名称（btc/mvc），默认为第一个索引的链
+	RawTx string `json:"rawTx" binding:"required" example:"0a00"` // 已签名的原始交易（hex）
+}
+
+// BroadcastTx 广播已签名的交易
+// @Summary 广播交易
+// @Description 通过索引器连接的节点广播客户端构建并签名的交易，返回 txid。按客户端 IP 限流（temp_app.upload_rate_per_minute），请求体最大 10 MB。交易已在 mempool 中时视为成功；已上链返回 40900；手续费不足、输入缺失或被节点拒绝返回 42200
+// @Tags Transaction
+// @Accept json
+// @Produce json
+// @Param request body BroadcastTxRequest true "广播请求"
+// @Success 200 {object} respond.Response{data=respond.BroadcastTxResponse}
+// @Failure 400 {object} respond.Response
+// @Failure 429 {object} respond.Response
+// @Router /api/v1/tx/broadcast [post]
+func (h *TxHandler) BroadcastTx(c *gin.Context) {
+	// 限制请求体大小，超出时不再读取
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBroadcastBodySize)
+
+	var req BroadcastTxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respond.InvalidParam(c, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		respond.InvalidParam(c, "invalid request body: "+err.Error())
+		return
+	}
+
+	chain := req.Chain
+	if chain == "" && len(conf.Cfg().Indexer.Chains) > 0 {
+		chain = conf.Cfg().Indexer.Chains[0]
+	}
+	if !isIndexedChain(chain) {
+		respond.InvalidParam(c, "chain is not indexed: "+chain)
+		return
+	}
+
+	txID, err := indexer_service.BroadcastTx(chain, req.RawTx)
+	switch {
+	case err == nil:
+		respond.Success(c, respond.ToBroadcastTxResponse(chain, txID))
+	case errors.Is(err, indexer_service.ErrTxAlreadyInMempool):
+		respond.SuccessWithMsg(c, "transaction already in mempool", respond.ToBroadcastTxResponse(chain, txID))
+	case errors.Is(err, indexer_service.ErrTxAlreadyInChain):
+		respond.ErrorWithData(c, respond.CodeConflict, err.Error(), respond.ToBroadcastTxResponse(chain, txID))
+	case errors.Is(err, indexer_service.ErrTxInvalid):
+		respond.InvalidParam(c, err.Error())
+	case errors.Is(err, indexer_service.ErrTxInsufficientFee),
+		errors.Is(err, indexer_service.ErrTxMissingInputs),
+		errors.Is(err, indexer_service.ErrTxRejected):
+		respond.Error(c, respond.CodeTxRejected, err.Error())
+	default:
+		respond.ServerError(c, "failed to broadcast transaction: "+err.Error())
+	}
+}
+
+// defaultEstimateFeeRate 未指定 fee_rate 时使用的费率（sat/byte）
+const defaultEstimateFeeRate = 1
+
+// EstimateFee 估算 MetaApp 铭文交易的大小和手续费
+// @Summary 估算交易手续费
+// @Description 根据内容大小、输入输出数量估算 MetaID 铭文交易的序列化大小和手续费（按 P2PKH 输入、P2PKH 输出估算），便于前端在签名前展示费用。目前仅支持 mvc
+// @Tags Transaction
+// @Produce json
+// @Param chain query string false "链名称（目前仅支持 mvc），默认为第一个索引的链"
+// @Param content_size query int true "铭文内容大小（字节）" maximum(104857600)
+// @Param fee_rate query int false "费率（sat/byte）" default(1)
+// @Param inputs query int false "输入数量" default(1) maximum(10000)
+// @Param outputs query int false "除铭文和找零外的输出数量" default(0) maximum(10000)
+// @Param change query bool false "是否包含找零输出" default(true)
+// @Param path query string false "MetaID path"
+// @Param content_type query string false "内容类型" default(application/json)
+// @Success 200 {object} respond.Response{data=respond.EstimateFeeResponse}
+// @Failure 400 {object} respond.Response
+// @Router /api/v1/tx/estimate-fee [get]
+func (h *TxHandler) EstimateFee(c *gin.Context) {
+	chain := c.Query("chain")
+	if chain == "" && len(conf.Cfg().Indexer.Chains) > 0 {
+		chain = conf.Cfg().Indexer.Chains[0]
+	}
+	if chain != "mvc" {
+		respond.InvalidParam(c, "fee estimation is only supported for mvc")
+		return
+	}
+
+	contentSize, err := strconv.Atoi(c.Query("content_size"))
+	if err != nil || contentSize < 0 {
+		respond.InvalidParam(c, "content_size must be a non-negative integer")
+		return
+	}
+	feeRate, err := strconv.ParseInt(c.DefaultQuery("fee_rate", strconv.Itoa(defaultEstimateFeeRate)), 10, 64)
+	if err != nil || feeRate <= 0 {
+		respond.InvalidParam(c, "fee_rate must be a positive integer")
+		return
+	}
+	inputs, err := strconv.Atoi(c.DefaultQuery("inputs", "1"))
+	if err != nil || inputs < 1 {
+		respond.InvalidParam(c, "inputs must be a positive integer")
+		return
+	}
+	outputs, err := strconv.Atoi(c.DefaultQuery("outputs", "0"))
+	if err != nil || outputs < 0 {
+		respond.InvalidParam(c, "outputs must be a non-negative integer")
+		return
+	}
+	withChange, err := strconv.ParseBool(c.DefaultQuery("change", "true"))
+	if err != nil {
+		respond.InvalidParam(c, "change must be true or false")
+		return
+	}
+
+	params := common.DefaultMetaIdTxParams()
+	params.Path = c.Query("path")
+	if contentType := c.Query("content_type"); contentType != "" {
+		params.ContentType = contentType
+	}
+
+	estimate, err := common.EstimateMvcMetaIdTxFee(params, contentSize, inputs, outputs, withChange, feeRate)
+	if err != nil {
+		respond.InvalidParam(c, err.Error())
+		return
+	}
+
+	respond.Success(c, respond.ToEstimateFeeResponse(chain, feeRate, estimate.Size, estimate.Fee))
+}
+
+// isIndexedChain 判断链是否在 indexer.chains 中配置
+func isIndexedChain(chain string) bool {
+	for _, name := range conf.Cfg().Indexer.Chains {
+		if name == chain {
+			return true
+		}
+	}
+	return false
+}
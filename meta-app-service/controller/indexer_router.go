@@ -1,10 +1,17 @@
 package controller
 
 import (
+	"log"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
 	"meta-app-service/conf"
 	"meta-app-service/controller/handler"
 	"meta-app-service/controller/respond"
 	"meta-app-service/docs"
+	"meta-app-service/metrics"
 	"meta-app-service/service/indexer_service"
 
 	"github.com/gin-contrib/cors"
@@ -13,42 +20,124 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// newCorsConfig build CORS config from the configured allowed origins
+// Without a specific origin list any origin is allowed, but credentials are not (browsers reject "*" with credentials);
+// with a list, only matching origins are echoed back in Access-Control-Allow-Origin and credentials are allowed
+func newCorsConfig(origins []string) cors.Config {
+	config := cors.Config{
+		AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH", "HEAD"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-API-Key", "Accept", "Cache-Control", "X-Requested-With", "Range"},
+		ExposeHeaders: []string{"Content-Length", "Content-Type", "Content-Range", "Accept-Ranges"},
+		MaxAge:        12 * time.Hour,
+	}
+
+	allowed := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "*" {
+			allowed = nil
+			break
+		}
+		if origin != "" {
+			allowed = append(allowed, origin)
+		}
+	}
+
+	if len(allowed) == 0 {
+		config.AllowAllOrigins = true
+		return config
+	}
+	config.AllowOrigins = allowed
+	config.AllowCredentials = true
+	return config
+}
+
+// reloadableCors CORS middleware whose allowed origins follow indexer.cors_origins across config reloads
+func reloadableCors() gin.HandlerFunc {
+	var current atomic.Value
+	current.Store(cors.New(newCorsConfig(conf.Cfg().Indexer.CorsOrigins)))
+
+	conf.OnReload(func(old, cur *conf.Config) {
+		if reflect.DeepEqual(old.Indexer.CorsOrigins, cur.Indexer.CorsOrigins) {
+			return
+		}
+		config := newCorsConfig(cur.Indexer.CorsOrigins)
+		// cors.New panics on an invalid config, keep the previous origins instead
+		if err := config.Validate(); err != nil {
+			log.Printf("Ignoring reloaded indexer.cors_origins: %v", err)
+			return
+		}
+		current.Store(cors.New(config))
+	})
+
+	return func(c *gin.Context) {
+		current.Load().(gin.HandlerFunc)(c)
+	}
+}
+
 // SetupIndexerRouter setup indexer service router
-func SetupIndexerRouter(indexerService *indexer_service.IndexerService) *gin.Engine {
+// The first indexer service is the default chain; every service's scanner is used for per-chain sync status
+func SetupIndexerRouter(indexerServices ...*indexer_service.IndexerService) *gin.Engine {
 	// Set Swagger host from config
-	if conf.Cfg.Indexer.SwaggerBaseUrl != "" {
-		docs.SwaggerInfo.Host = conf.Cfg.Indexer.SwaggerBaseUrl
+	if conf.Cfg().Indexer.SwaggerBaseUrl != "" {
+		docs.SwaggerInfo.Host = conf.Cfg().Indexer.SwaggerBaseUrl
+	}
+	// Every route is registered under indexer.path_prefix, so the Swagger UI must call the API under it too
+	if conf.Cfg().Indexer.PathPrefix != "" {
+		docs.SwaggerInfo.BasePath = conf.Cfg().Indexer.PathPrefix
 	}
 
 	// Create Gin engine
 	r := gin.Default()
 
+	// Only take the client IP from X-Forwarded-For / X-Real-IP when the request comes from a configured proxy,
+	// otherwise any client could spoof it and bypass the per-IP rate limits
+	if err := r.SetTrustedProxies(conf.Cfg().Indexer.TrustedProxies); err != nil {
+		log.Printf("Invalid indexer.trusted_proxies, trusting no proxy: %v", err)
+		r.SetTrustedProxies(nil)
+	}
+
 	// Add CORS middleware
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"}, // Allow all origins, can be configured to specific domains
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH", "HEAD"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "Accept", "Cache-Control", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
-		AllowCredentials: true,
-		MaxAge:           12 * 3600, // 12 hours
-	}))
+	r.Use(reloadableCors())
 
 	// Add timing middleware
 	r.Use(respond.TimingMiddleware())
 
 	// Create sync status service instance
 	syncStatusService := indexer_service.NewSyncStatusService()
-	// Set scanner for getting latest block height
-	if indexerService != nil {
-		syncStatusService.SetBlockScanner(indexerService.GetScanner())
+	// Set scanners for getting latest block height
+	for _, indexerService := range indexerServices {
+		if indexerService != nil {
+			syncStatusService.SetBlockScanner(indexerService.GetScanner())
+		}
 	}
 
 	// Create handlers
 	metaAppHandler := handler.NewMetaAppHandler(syncStatusService)
 	tempAppHandler := handler.NewTempAppHandler()
+	indexerHandler := handler.NewIndexerHandler(indexerServices...)
+	txHandler := handler.NewTxHandler()
+
+	// Write/admin endpoints require the admin API key (read-only query endpoints stay public)
+	if conf.Cfg().Indexer.AdminApiKey == "" {
+		log.Println("Warning: indexer.admin_api_key is not set, write/admin endpoints are unauthenticated")
+	}
+	auth := respond.APIKeyMiddleware(conf.Cfg().Indexer.AdminApiKey)
+
+	// Per-IP limit on requests that write a temp app archive to disk (read on every request, reloadable)
+	tempUploadLimit := respond.RateLimitMiddleware(func() int {
+		return conf.Cfg().TempApp.UploadRatePerMinute
+	})
+	// Same per-IP limit on transaction broadcasts (every broadcast is relayed to the node), counted separately
+	broadcastLimit := respond.RateLimitMiddleware(func() int {
+		return conf.Cfg().TempApp.UploadRatePerMinute
+	})
+
+	// All routes are registered under indexer.path_prefix (empty: root path)
+	root := r.Group(conf.Cfg().Indexer.PathPrefix)
 
 	// API v1 route group
-	v1 := r.Group("/api/v1")
+	v1 := root.Group("/api/v1")
 	{
 		// MetaApp routes
 		metaapps := v1.Group("/metaapps")
@@ -56,20 +145,62 @@ func SetupIndexerRouter(indexerService *indexer_service.IndexerService) *gin.Eng
 			// Get MetaApp list (cursor pagination)
 			metaapps.GET("", metaAppHandler.ListMetaApps)
 
+			// Get MetaApps indexed within a block height range
+			metaapps.GET("/by-height", metaAppHandler.GetMetaAppsByHeightRange)
+
 			// Get MetaApps by creator MetaID (must be before /first/:firstPinId to avoid route conflict)
 			metaapps.GET("/creator/:metaId", metaAppHandler.GetMetaAppsByCreatorMetaID)
 
+			// Count MetaApps by creator MetaID
+			metaapps.GET("/creator/:metaId/count", metaAppHandler.CountMetaAppsByCreatorMetaID)
+
+			// Get MetaApps by current owner MetaID
+			metaapps.GET("/owner/:metaId", metaAppHandler.GetMetaAppsByOwnerMetaID)
+
+			// Get MetaApps whose current app_name is an exact match (apps from different creators may share a name)
+			metaapps.GET("/by-name/:appName", metaAppHandler.GetMetaAppsByAppName)
+
 			// Get MetaApp history by FirstPinID (must be before /first/:firstPinId to avoid route conflict)
 			metaapps.GET("/first/:firstPinId/history", metaAppHandler.GetMetaAppHistoryByFirstPinID)
 
+			// Field-level diff between two versions of a MetaApp (must be before /first/:firstPinId to avoid route conflict)
+			metaapps.GET("/first/:firstPinId/diff", metaAppHandler.DiffMetaAppVersions)
+
+			// Latest version that is not disabled/revoked, i.e. the one deployed and served (must be before /first/:firstPinId)
+			metaapps.GET("/first/:firstPinId/latest-servable", metaAppHandler.GetLatestServableMetaAppByFirstPinID)
+
 			// Download MetaApp as zip by FirstPinID (must be before /first/:firstPinId to avoid route conflict)
 			metaapps.GET("/first/:firstPinId/download", metaAppHandler.DownloadMetaAppAsZip)
 
 			// Get MetaApp by FirstPinID (must be before /:pinId to avoid route conflict)
 			metaapps.GET("/first/:firstPinId", metaAppHandler.GetMetaAppByFirstPinID)
 
-			// Redeploy MetaApp (must be before /:pinId to avoid route conflict)
-			metaapps.POST("/:pinId/redeploy", metaAppHandler.RedeployMetaApp)
+			// Protected MetaApp admin routes (must be before /:pinId to avoid route conflict)
+			protectedMetaapps := metaapps.Group("", auth)
+			{
+				// Redeploy MetaApp
+				protectedMetaapps.POST("/:pinId/redeploy", metaAppHandler.RedeployMetaApp)
+
+				// Re-download and re-extract a deployed MetaApp synchronously
+				protectedMetaapps.POST("/first/:firstPinId/repair", indexerHandler.RepairMetaApp)
+
+				// Disable (blacklist) / re-enable a MetaApp
+				protectedMetaapps.POST("/:pinId/disable", metaAppHandler.DisableMetaApp)
+				protectedMetaapps.POST("/:pinId/enable", metaAppHandler.EnableMetaApp)
+			}
+
+			// Icon / cover image of a MetaApp proxied from metafs (must be before /:pinId to avoid route conflict)
+			metaapps.GET("/:pinId/icon", indexerHandler.GetMetaAppIcon)
+			metaapps.GET("/:pinId/cover", indexerHandler.GetMetaAppCover)
+
+			// Get the PIN content of a MetaApp exactly as inscribed (must be before /:pinId to avoid route conflict)
+			metaapps.GET("/:pinId/content", metaAppHandler.GetMetaAppContent)
+
+			// Get the source transaction of a MetaApp (must be before /:pinId to avoid route conflict)
+			metaapps.GET("/:pinId/raw-tx", metaAppHandler.GetMetaAppRawTx)
+
+			// Get MetaApp deploy attempt history (must be before /:pinId to avoid route conflict)
+			metaapps.GET("/:pinId/deploy-history", metaAppHandler.GetMetaAppDeployHistory)
 
 			// Get MetaApp by PinID
 			metaapps.GET("/:pinId", metaAppHandler.GetMetaAppByPinID)
@@ -78,14 +209,63 @@ func SetupIndexerRouter(indexerService *indexer_service.IndexerService) *gin.Eng
 		// Sync status route
 		v1.GET("/status", metaAppHandler.GetSyncStatus)
 
+		// Sync status of all chains
+		v1.GET("/status/all", metaAppHandler.GetAllSyncStatus)
+
 		// Statistics route
 		v1.GET("/stats", metaAppHandler.GetStats)
 
 		// Config route
 		v1.GET("/config", metaAppHandler.GetConfig)
 
-		// Deploy queue route
-		v1.GET("/deploy-queue", metaAppHandler.ListDeployQueue)
+		// Reload the reloadable subset of the config file (same as SIGHUP)
+		v1.POST("/config/reload", auth, indexerHandler.ReloadConfig)
+
+		// Deploy queue routes (admin key: items carry deploy errors and retry state)
+		v1.GET("/deploy-queue", auth, metaAppHandler.ListDeployQueue)
+		v1.GET("/deploy-queue/:pinId", auth, metaAppHandler.GetDeployQueueItem)
+		v1.DELETE("/deploy-queue/:pinId", auth, metaAppHandler.RemoveDeployQueueItem)
+
+		// MetaApps ordered by last successful deploy
+		v1.GET("/deploy/recent", metaAppHandler.ListRecentDeploys)
+
+		// Reconcile deploy directories on disk against deploy records
+		v1.GET("/deploy/disk-status", auth, metaAppHandler.GetDeployDiskStatus)
+
+		// Indexer control routes
+		indexerGroup := v1.Group("/indexer")
+		{
+			// Get scanning state
+			indexerGroup.GET("/state", indexerHandler.GetIndexerState)
+
+			// Protected indexer control routes
+			protectedIndexer := indexerGroup.Group("", auth)
+			{
+				// Pause / resume block scanning
+				protectedIndexer.POST("/pause", indexerHandler.PauseIndexer)
+				protectedIndexer.POST("/resume", indexerHandler.ResumeIndexer)
+
+				// Rescan a block height range
+				protectedIndexer.POST("/rescan", indexerHandler.RescanIndexer)
+			}
+		}
+
+		// Transaction routes
+		txGroup := v1.Group("/tx")
+		{
+			// Broadcast a signed transaction through the node
+			txGroup.POST("/broadcast", broadcastLimit, txHandler.BroadcastTx)
+
+			// Estimate size and fee of a MetaID inscription tx
+			txGroup.GET("/estimate-fee", txHandler.EstimateFee)
+		}
+
+		// Mempool routes (admin key: without ZMQ every request polls the node's mempool)
+		mempoolGroup := v1.Group("/mempool", auth)
+		{
+			// Preview MetaApp PINs in unconfirmed transactions
+			mempoolGroup.GET("/metaapps", indexerHandler.GetMempoolMetaApps)
+		}
 
 		// TempApp routes
 		tempapps := v1.Group("/temp-apps")
@@ -93,66 +273,96 @@ func SetupIndexerRouter(indexerService *indexer_service.IndexerService) *gin.Eng
 			// Chunk upload routes (must be before /:tokenId to avoid route conflict)
 			chunk := tempapps.Group("/chunk")
 			{
-				// Initialize chunk upload
-				chunk.POST("/init", tempAppHandler.InitChunkUpload)
-
 				// Get chunk upload status
 				chunk.GET("/:uploadId/status", tempAppHandler.GetChunkUploadStatus)
 
-				// Merge chunks
-				chunk.POST("/:uploadId/merge", tempAppHandler.MergeChunks)
+				// Protected chunk upload routes
+				protectedChunk := chunk.Group("", auth)
+				{
+					// Initialize chunk upload
+					protectedChunk.POST("/init", tempUploadLimit, tempAppHandler.InitChunkUpload)
 
-				// Upload chunk
-				chunk.POST("/:uploadId/:chunkIndex", tempAppHandler.UploadChunk)
-			}
+					// Merge chunks
+					protectedChunk.POST("/:uploadId/merge", tempAppHandler.MergeChunks)
 
-			// Upload temp app zip file
-			tempapps.POST("/upload", tempAppHandler.UploadTempApp)
+					// Upload chunk
+					protectedChunk.POST("/:uploadId/:chunkIndex", tempAppHandler.UploadChunk)
+				}
+			}
 
 			// Get temp app by tokenId (must be last to avoid route conflict)
 			tempapps.GET("/:tokenId", tempAppHandler.GetTempAppByTokenID)
+
+			// Protected temp app routes
+			protectedTempapps := tempapps.Group("", auth)
+			{
+				// Upload temp app zip file
+				protectedTempapps.POST("/upload", tempUploadLimit, tempAppHandler.UploadTempApp)
+
+				// Validate a temp app archive without deploying it (dry run)
+				protectedTempapps.POST("/validate", tempUploadLimit, tempAppHandler.ValidateTempApp)
+
+				// Delete temp app by tokenId before it expires
+				protectedTempapps.DELETE("/:tokenId", tempAppHandler.DeleteTempApp)
+			}
 		}
 	}
 
 	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"service": "indexer",
-		})
-	})
+	root.GET("/health", metaAppHandler.GetHealth)
+
+	// Readiness check (returns 503 when indexer lags too far behind the node tip)
+	root.GET("/ready", metaAppHandler.GetReadiness)
+
+	// Prometheus metrics
+	root.GET("/metrics", metrics.Handler())
 
 	// Swagger documentation
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler,
+	root.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler,
 		ginSwagger.InstanceName("swagger")))
 
 	// Static files and web pages - 使用明确的 GET 路由确保优先匹配
 	// 这些路由必须在参数路由 /:pinId 之前注册
-	r.Static("/static", "./web/static")
-	r.GET("/", func(c *gin.Context) {
+	root.Static("/static", "./web/static")
+	root.GET("/", func(c *gin.Context) {
 		c.File("./web/indexer.html")
 	})
-	r.GET("/indexer.html", func(c *gin.Context) {
+	root.GET("/indexer.html", func(c *gin.Context) {
 		c.File("./web/indexer.html")
 	})
-	r.GET("/indexer.js", func(c *gin.Context) {
+	root.GET("/indexer.js", func(c *gin.Context) {
 		c.Header("Content-Type", "application/javascript; charset=utf-8")
 		c.File("./web/indexer.js")
 	})
 
+	// 静态文件压缩（根据 Accept-Encoding 使用 brotli / gzip，跳过图片等已压缩类型）
+	compress := respond.CompressionMiddleware()
+
+	// 静态文件路由同时注册 GET 和 HEAD：c.File 内部的 http.ServeContent 处理 HEAD（只返回头部）和 Range 请求
+	// （返回 206 和 Content-Range，应用包中的音视频可以拖动播放），压缩中间件对 HEAD 和 Range 请求不压缩
+	staticRoute := func(path string, handlers ...gin.HandlerFunc) {
+		root.GET(path, handlers...)
+		root.HEAD(path, handlers...)
+	}
+
 	// TempApp 静态文件服务路由（必须在 MetaApp 路由之前注册，避免路由冲突）
 	// 支持访问 /temp/{tokenId}/index.html 以及 /temp/{tokenId}/*filepath 下的所有静态资源
-	r.GET("/temp/:tokenId/*filepath", tempAppHandler.ServeTempAppStaticFiles)
-	r.GET("/temp/:tokenId", tempAppHandler.ServeTempAppStaticFiles)
+	staticRoute("/temp/:tokenId/*filepath", compress, tempAppHandler.ServeTempAppStaticFiles)
+	staticRoute("/temp/:tokenId", compress, tempAppHandler.ServeTempAppStaticFiles)
+
+	// MetaApp 最新版本静态文件服务路由：/app/{firstPinId}/*filepath 始终提供该应用最新部署的版本
+	// （必须在 /:pinId 通配路由之前注册）
+	staticRoute("/app/:firstPinId/*filepath", compress, metaAppHandler.ServeLatestMetaAppStaticFiles)
+	staticRoute("/app/:firstPinId", compress, metaAppHandler.ServeLatestMetaAppStaticFiles)
 
 	// MetaApp 静态文件服务路由（必须在所有特定路由之后注册，避免路由冲突）
 	// 支持访问 /{pinId}/index.html 以及 /{pinId}/*filepath 下的所有静态资源
 	// 注意：只使用通配符路由，避免与特定路由冲突
-	r.GET("/:pinId/*filepath", metaAppHandler.ServeMetaAppStaticFiles)
+	staticRoute("/:pinId/*filepath", compress, metaAppHandler.ServeMetaAppStaticFiles)
 
 	// 处理 /{pinId} 的直接访问（检查文件是否存在，如果存在则重定向到 /{pinId}/index.html）
 	// 如果文件不存在，返回 404
-	r.GET("/:pinId", metaAppHandler.ServeMetaAppStaticFiles)
+	staticRoute("/:pinId", compress, metaAppHandler.ServeMetaAppStaticFiles)
 
 	return r
 }
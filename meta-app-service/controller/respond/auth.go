@@ -0,0 +1,43 @@
+package respond
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CodeUnauthorized missing or invalid API key
+const CodeUnauthorized = 40100
+
+// APIKeyMiddleware require the admin API key on write/admin endpoints
+// The key is read from "X-API-Key" or "Authorization" (raw or "Bearer <key>"); requests without a valid key get HTTP 401.
+// An empty apiKey disables the check.
+func APIKeyMiddleware(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			key = strings.TrimSpace(c.GetHeader("Authorization"))
+			if len(key) > 7 && strings.EqualFold(key[:7], "Bearer ") {
+				key = strings.TrimSpace(key[7:])
+			}
+		}
+
+		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, Message{
+				Code:           CodeUnauthorized,
+				Message:        "unauthorized: missing or invalid API key",
+				ProcessingTime: getProcessingTime(c),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
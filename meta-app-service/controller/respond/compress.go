@@ -0,0 +1,197 @@
+package respond
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// compressMinSize responses smaller than this are not worth compressing
+const compressMinSize = 1024
+
+// compressibleTypes content type prefixes worth compressing
+// Images, fonts (woff/woff2), archives and media are already compressed and are served as is
+var compressibleTypes = []string{
+	"text/",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+	"image/x-icon",
+	"font/ttf",
+	"application/vnd.ms-fontobject",
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	},
+}
+
+var brotliWriterPool = sync.Pool{
+	New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	},
+}
+
+// CompressionMiddleware compress responses with brotli or gzip according to the client's Accept-Encoding
+// Only compressible content types are compressed; range requests are passed through untouched
+func CompressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Range") != "" {
+			c.Next()
+			return
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = cw
+		defer cw.Close()
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding pick the preferred supported encoding (br > gzip), ignoring encodings with q=0
+func negotiateEncoding(acceptEncoding string) string {
+	supportsGzip := false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if len(fields) > 1 && strings.ReplaceAll(strings.TrimSpace(fields[1]), " ", "") == "q=0" {
+			continue
+		}
+		switch name {
+		case "br":
+			return "br"
+		case "gzip":
+			supportsGzip = true
+		}
+	}
+	if supportsGzip {
+		return "gzip"
+	}
+	return ""
+}
+
+// isCompressibleType check whether the content type is worth compressing
+func isCompressibleType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter gin.ResponseWriter that compresses the body once headers show it is worth it
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding    string
+	decided     bool
+	compressing bool
+	writer      io.WriteCloser
+}
+
+// decide decide whether to compress, must be called before headers are written
+func (w *compressWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	header := w.ResponseWriter.Header()
+	status := w.ResponseWriter.Status()
+	if status != http.StatusOK || header.Get("Content-Encoding") != "" || !isCompressibleType(header.Get("Content-Type")) {
+		return
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < compressMinSize {
+		return
+	}
+
+	w.compressing = true
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	header.Set("Content-Encoding", w.encoding)
+	header.Add("Vary", "Accept-Encoding")
+	// The representation differs from the uncompressed one, so a strong ETag must not be reused
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+
+	switch w.encoding {
+	case "br":
+		bw := brotliWriterPool.Get().(*brotli.Writer)
+		bw.Reset(w.ResponseWriter)
+		w.writer = bw
+	default:
+		gw := gzipWriterPool.Get().(*gzip.Writer)
+		gw.Reset(w.ResponseWriter)
+		w.writer = gw
+	}
+}
+
+// WriteHeader decide compression before the status line goes out
+func (w *compressWriter) WriteHeader(code int) {
+	w.ResponseWriter.WriteHeader(code)
+	w.decide()
+}
+
+// Write write body, compressed when enabled
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if !w.compressing {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.writer.Write(data)
+}
+
+// WriteString write string body, compressed when enabled
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush flush compressed data to the client
+func (w *compressWriter) Flush() {
+	if w.compressing {
+		switch zw := w.writer.(type) {
+		case *gzip.Writer:
+			zw.Flush()
+		case *brotli.Writer:
+			zw.Flush()
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Close finish the compressed stream and return the writer to its pool
+func (w *compressWriter) Close() {
+	if !w.compressing {
+		return
+	}
+	w.writer.Close()
+	switch zw := w.writer.(type) {
+	case *gzip.Writer:
+		zw.Reset(io.Discard)
+		gzipWriterPool.Put(zw)
+	case *brotli.Writer:
+		zw.Reset(io.Discard)
+		brotliWriterPool.Put(zw)
+	}
+	w.writer = nil
+	w.compressing = false
+}
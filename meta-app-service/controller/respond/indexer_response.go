@@ -1,6 +1,8 @@
 package respond
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"meta-app-service/conf"
@@ -64,12 +66,15 @@ type IndexerSyncStatusResponse struct {
 	ChainName         string    `json:"chain_name" example:"mvc"`
 	CurrentSyncHeight int64     `json:"current_sync_height" example:"12345"`
 	LatestBlockHeight int64     `json:"latest_block_height" example:"12350"`
+	BlocksRemaining   int64     `json:"blocks_remaining" example:"5"`     // Blocks left to scan to reach the node tip
+	BlocksPerSecond   float64   `json:"blocks_per_second" example:"12.5"` // Recent scan rate (moving average)
+	EtaSeconds        int64     `json:"eta_seconds" example:"1"`          // Estimated seconds to catch up (0 caught up, -1 unknown)
 	CreatedAt         time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
 	UpdatedAt         time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
 }
 
 // ToIndexerSyncStatusResponse convert sync status to response
-func ToIndexerSyncStatusResponse(status *model.IndexerSyncStatus, latestHeight int64) IndexerSyncStatusResponse {
+func ToIndexerSyncStatusResponse(status *model.IndexerSyncStatus, latestHeight int64, progress indexer_service.SyncProgress) IndexerSyncStatusResponse {
 	if status == nil {
 		return IndexerSyncStatusResponse{
 			LatestBlockHeight: latestHeight,
@@ -80,11 +85,53 @@ func ToIndexerSyncStatusResponse(status *model.IndexerSyncStatus, latestHeight i
 		ChainName:         status.ChainName,
 		CurrentSyncHeight: status.CurrentSyncHeight,
 		LatestBlockHeight: latestHeight,
+		BlocksRemaining:   progress.BlocksRemaining,
+		BlocksPerSecond:   progress.BlocksPerSecond,
+		EtaSeconds:        progress.ETASeconds,
 		CreatedAt:         status.CreatedAt,
 		UpdatedAt:         status.UpdatedAt,
 	}
 }
 
+// ChainSyncStatusResponse per-chain sync status response structure
+type ChainSyncStatusResponse struct {
+	ChainName         string    `json:"chain_name" example:"btc"`
+	CurrentSyncHeight int64     `json:"current_sync_height" example:"12345"`
+	LatestBlockHeight int64     `json:"latest_block_height" example:"12350"`
+	Lag               int64     `json:"lag" example:"5"`
+	SyncedBlockTime   int64     `json:"synced_block_time" example:"1700000000"` // Block time of the last synced block (0 unknown)
+	LagSeconds        int64     `json:"lag_seconds" example:"3000"`             // Seconds between the last synced block and now (0 unknown)
+	BlocksPerSecond   float64   `json:"blocks_per_second" example:"12.5"`       // Recent scan rate (moving average)
+	EtaSeconds        int64     `json:"eta_seconds" example:"1"`                // Estimated seconds to catch up (0 caught up, -1 unknown)
+	Error             string    `json:"error,omitempty" example:""`
+	UpdatedAt         time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// AllChainsSyncStatusResponse sync status of all chains response structure
+type AllChainsSyncStatusResponse struct {
+	Chains []ChainSyncStatusResponse `json:"chains"`
+}
+
+// ToAllChainsSyncStatusResponse convert per-chain sync status to response
+func ToAllChainsSyncStatusResponse(statuses []*indexer_service.ChainSyncStatus) AllChainsSyncStatusResponse {
+	chains := make([]ChainSyncStatusResponse, 0, len(statuses))
+	for _, item := range statuses {
+		chains = append(chains, ChainSyncStatusResponse{
+			ChainName:         item.Status.ChainName,
+			CurrentSyncHeight: item.Status.CurrentSyncHeight,
+			LatestBlockHeight: item.LatestHeight,
+			Lag:               item.Lag,
+			SyncedBlockTime:   item.SyncedTime,
+			LagSeconds:        item.LagSeconds,
+			BlocksPerSecond:   item.Progress.BlocksPerSecond,
+			EtaSeconds:        item.Progress.ETASeconds,
+			Error:             item.Error,
+			UpdatedAt:         item.Status.UpdatedAt,
+		})
+	}
+	return AllChainsSyncStatusResponse{Chains: chains}
+}
+
 // IndexerFileListResponse file list response structure
 type IndexerFileListResponse struct {
 	Files      []IndexerFileResponse `json:"files"`
@@ -111,9 +158,57 @@ func ToIndexerStatsResponse(totalApps int64) IndexerStatsResponse {
 	}
 }
 
+// BroadcastTxResponse 广播交易响应结构
+type BroadcastTxResponse struct {
+	Chain string `json:"chain" example:"mvc"`
+	TxID  string `json:"txid" example:"5ea55a16ce4ecc795101f564b8c4f2e77aacddd2b256f031498d855432893530"`
+}
+
+// ToBroadcastTxResponse 转换广播结果为响应结构
+func ToBroadcastTxResponse(chain, txID string) BroadcastTxResponse {
+	return BroadcastTxResponse{
+		Chain: chain,
+		TxID:  txID,
+	}
+}
+
+// EstimateFeeResponse 手续费估算响应结构
+type EstimateFeeResponse struct {
+	Chain   string `json:"chain" example:"mvc"`
+	FeeRate int64  `json:"fee_rate" example:"1"` // 费率（sat/byte）
+	Size    int    `json:"size" example:"420"`   // 估算的交易大小（字节）
+	Fee     int64  `json:"fee" example:"420"`    // 估算的手续费（sat）
+}
+
+// ToEstimateFeeResponse 转换手续费估算结果为响应结构
+func ToEstimateFeeResponse(chain string, feeRate int64, size int, fee int64) EstimateFeeResponse {
+	return EstimateFeeResponse{
+		Chain:   chain,
+		FeeRate: feeRate,
+		Size:    size,
+		Fee:     fee,
+	}
+}
+
+// MetaAppCountResponse MetaApp 数量响应结构
+type MetaAppCountResponse struct {
+	MetaID string `json:"meta_id" example:"abc123"`
+	Count  int64  `json:"count" example:"3"`
+}
+
+// ToMetaAppCountResponse 转换 MetaApp 数量为响应结构
+func ToMetaAppCountResponse(metaID string, count int64) MetaAppCountResponse {
+	return MetaAppCountResponse{
+		MetaID: metaID,
+		Count:  count,
+	}
+}
+
 // MetaAppResponse MetaApp 响应结构
 type MetaAppResponse struct {
 	*model.MetaApp
+	Metadata   json.RawMessage                 `json:"metadata" swaggertype:"object"` // 元数据（覆盖 MetaApp.Metadata：合法的 JSON 对象/数组原样输出，否则输出原始字符串）
+	Confirmed  bool                            `json:"confirmed" example:"true"`      // 是否已上链确认（false 表示 ZMQ mempool 中的未确认记录）
 	DeployInfo *model.MetaAppDeployFileContent `json:"deploy_info,omitempty"`
 }
 
@@ -121,19 +216,32 @@ type MetaAppResponse struct {
 func ToMetaAppResponse(app *indexer_service.MetaAppWithDeploy) MetaAppResponse {
 	return MetaAppResponse{
 		MetaApp:    app.MetaApp,
+		Metadata:   structuredMetadata(app.MetaApp.Metadata),
+		Confirmed:  app.MetaApp.IsConfirmed(),
 		DeployInfo: app.DeployInfo,
 	}
 }
 
+// structuredMetadata 将元数据字符串转换为 JSON 输出
+// 合法的 JSON 对象或数组直接作为 JSON 输出，其他内容（包括空字符串）保留为 JSON 字符串
+func structuredMetadata(metadata string) json.RawMessage {
+	trimmed := strings.TrimSpace(metadata)
+	if trimmed != "" && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	raw, _ := json.Marshal(metadata)
+	return raw
+}
+
 // MetaAppListResponse MetaApp 列表响应结构
 type MetaAppListResponse struct {
 	Apps       []MetaAppResponse `json:"apps"`
-	NextCursor int64             `json:"next_cursor" example:"100"`
+	NextCursor string            `json:"next_cursor" example:"1700000000000_abc123i0"` // 下一页游标（没有更多时为空）
 	HasMore    bool              `json:"has_more" example:"true"`
 }
 
 // ToMetaAppListResponse 转换 MetaApp 列表为响应结构
-func ToMetaAppListResponse(apps []*indexer_service.MetaAppWithDeploy, nextCursor int64, hasMore bool) MetaAppListResponse {
+func ToMetaAppListResponse(apps []*indexer_service.MetaAppWithDeploy, nextCursor string, hasMore bool) MetaAppListResponse {
 	result := make([]MetaAppResponse, 0, len(apps))
 	for _, app := range apps {
 		result = append(result, ToMetaAppResponse(app))
@@ -147,7 +255,15 @@ func ToMetaAppListResponse(apps []*indexer_service.MetaAppWithDeploy, nextCursor
 
 // MetaAppHistoryResponse MetaApp 历史版本列表响应结构
 type MetaAppHistoryResponse struct {
-	History []MetaAppResponse `json:"history"`
+	History    []MetaAppResponse `json:"history"`
+	NextCursor string            `json:"next_cursor" example:"1700000000000_abc123i0"` // 下一页游标（没有更多时为空）
+	HasMore    bool              `json:"has_more" example:"true"`
+}
+
+// MetaAppDeployHistoryResponse MetaApp 部署记录历史响应结构
+type MetaAppDeployHistoryResponse struct {
+	PinID   string                            `json:"pin_id" example:"abc123def456i0"`
+	History []*model.MetaAppDeployFileContent `json:"history"` // 部署尝试记录（最新的在前）
 }
 
 // DeployQueueResponse 部署队列响应结构
@@ -160,6 +276,9 @@ type DeployQueueResponse struct {
 	ContentType string    `json:"content_type"`
 	Version     string    `json:"version"`
 	TryCount    int       `json:"try_count"`
+	LastError   string    `json:"last_error"`    // 最近一次部署失败的错误信息
+	NextRetryAt time.Time `json:"next_retry_at"` // 下次重试时间
+	LeaseUntil  time.Time `json:"lease_until"`   // 被 worker 领取时的租约到期时间（未领取时为零值）
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -177,6 +296,9 @@ func ToDeployQueueResponse(queue *model.MetaAppDeployQueue) DeployQueueResponse
 		ContentType: queue.ContentType,
 		Version:     queue.Version,
 		TryCount:    queue.TryCount,
+		LastError:   queue.LastError,
+		NextRetryAt: queue.NextRetryAt,
+		LeaseUntil:  queue.LeaseUntil,
 		CreatedAt:   queue.CreatedAt,
 	}
 }
@@ -203,16 +325,31 @@ func ToDeployQueueListResponse(queues []*model.MetaAppDeployQueue, nextCursor in
 
 // ConfigResponse 配置信息响应结构
 type ConfigResponse struct {
-	MetafsDomain string `json:"metafs_domain" example:"http://localhost:7281"`
+	MetafsDomain  string `json:"metafs_domain" example:"http://localhost:7281"`
+	DeployEnabled bool   `json:"deploy_enabled" example:"true"` // 是否托管应用文件（false 为仅索引模式）
 }
 
 // ToConfigResponse 转换配置为响应结构
 func ToConfigResponse() ConfigResponse {
 	metafsDomain := "http://localhost:7281" // 默认值
-	if conf.Cfg != nil && conf.Cfg.Metafs.Domain != "" {
-		metafsDomain = conf.Cfg.Metafs.Domain
+	if conf.Cfg() != nil && conf.Cfg().Metafs.Domain != "" {
+		metafsDomain = conf.Cfg().Metafs.Domain
 	}
 	return ConfigResponse{
-		MetafsDomain: metafsDomain,
+		MetafsDomain:  metafsDomain,
+		DeployEnabled: conf.Cfg() == nil || conf.Cfg().MetaApp.DeployEnabled,
+	}
+}
+
+// ConfigReloadResponse 重新加载配置响应结构
+type ConfigReloadResponse struct {
+	Changed []string `json:"changed" example:"indexer.scan_interval"` // 值发生变化并已生效的配置项
+}
+
+// ToConfigReloadResponse 转换重新加载结果为响应结构
+func ToConfigReloadResponse(changed []string) ConfigReloadResponse {
+	if changed == nil {
+		changed = []string{}
 	}
+	return ConfigReloadResponse{Changed: changed}
 }
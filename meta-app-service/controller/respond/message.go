@@ -1,6 +1,7 @@
 package respond
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -27,7 +28,10 @@ type Response struct {
 const (
 	CodeSuccess      = 0     // Success
 	CodeInvalidParam = 40000 // Parameter error
+	CodeForbidden    = 40300 // Access forbidden
 	CodeNotFound     = 40400 // Resource not found
+	CodeConflict     = 40900 // Conflict with current state (e.g. transaction already in block chain)
+	CodeTxRejected   = 42200 // Transaction rejected by the node (insufficient fee, missing inputs, ...)
 	CodeServerError  = 50000 // Server error
 )
 
@@ -79,6 +83,16 @@ func NotFound(c *gin.Context, message string) {
 	Error(c, CodeNotFound, message)
 }
 
+// Forbidden return access forbidden response
+// Unlike other errors it is sent with HTTP 403, as it is served in place of static app content
+func Forbidden(c *gin.Context, message string) {
+	c.JSON(http.StatusForbidden, Message{
+		Code:           CodeForbidden,
+		Message:        message,
+		ProcessingTime: getProcessingTime(c),
+	})
+}
+
 // ServerError return server error response
 func ServerError(c *gin.Context, message string) {
 	Error(c, CodeServerError, message)
@@ -0,0 +1,83 @@
+package respond
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CodeTooManyRequests rate limit or capacity exceeded
+const CodeTooManyRequests = 42900
+
+// rateLimitWindow window of the per-IP rate limit
+const rateLimitWindow = time.Minute
+
+// TooManyRequests return rate limit / capacity exceeded response
+// Like Forbidden it is sent with a real HTTP status (429), so clients and proxies can back off
+func TooManyRequests(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, Message{
+		Code:           CodeTooManyRequests,
+		Message:        message,
+		ProcessingTime: getProcessingTime(c),
+	})
+}
+
+// ipWindow request count of one client IP in the current window
+type ipWindow struct {
+	start time.Time
+	count int
+}
+
+// RateLimitMiddleware limit requests per client IP to limit() per minute (fixed window)
+// limit is read on every request so reloaded config takes effect immediately; limit() <= 0 disables the check.
+// Requests over the limit get HTTP 429 with a Retry-After header.
+func RateLimitMiddleware(limit func() int) gin.HandlerFunc {
+	var (
+		mu        sync.Mutex
+		windows   = make(map[string]*ipWindow)
+		lastSweep = time.Now()
+	)
+
+	return func(c *gin.Context) {
+		max := limit()
+		if max <= 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		// Drop windows of IPs that have been idle for a whole window
+		if now.Sub(lastSweep) >= rateLimitWindow {
+			for key, w := range windows {
+				if now.Sub(w.start) >= rateLimitWindow {
+					delete(windows, key)
+				}
+			}
+			lastSweep = now
+		}
+
+		w, ok := windows[ip]
+		if !ok || now.Sub(w.start) >= rateLimitWindow {
+			w = &ipWindow{start: now}
+			windows[ip] = w
+		}
+		w.count++
+		exceeded := w.count > max
+		retryAfter := w.start.Add(rateLimitWindow).Sub(now)
+		mu.Unlock()
+
+		if exceeded {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			TooManyRequests(c, "too many requests, please retry later")
+			return
+		}
+
+		c.Next()
+	}
+}
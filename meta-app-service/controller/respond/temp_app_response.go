@@ -1,10 +1,12 @@
 package respond
 
 import (
+	"strings"
 	"time"
 
 	"meta-app-service/conf"
 	model "meta-app-service/models"
+	"meta-app-service/service/temp_deploy_service"
 )
 
 // TempAppDeploymentDetails 临时应用部署详情
@@ -17,22 +19,23 @@ type TempAppDeploymentDetails struct {
 // TempAppDeployResponse 临时应用部署响应结构
 type TempAppDeployResponse struct {
 	ID                string                    `json:"id"`                 // TokenID
-	URL               string                    `json:"url"`                // 相对路径 URL
+	URL               string                    `json:"url"`                // 相对路径 URL（包含路径前缀）
 	PreviewURL        string                    `json:"preview_url"`        // 预览 URL（完整 URL）
 	ExpiresAt         time.Time                 `json:"expires_at"`         // 过期时间
 	DeploymentDetails *TempAppDeploymentDetails `json:"deployment_details"` // 部署详情
 }
 
 // ToTempAppDeployResponse 转换 TempAppDeploy 为响应结构
-func ToTempAppDeployResponse(deploy *model.TempAppDeploy) TempAppDeployResponse {
+// pathPrefix: 对外的路径前缀（indexer.path_prefix 或反向代理的 X-Forwarded-Prefix），拼接在 URL 前
+func ToTempAppDeployResponse(deploy *model.TempAppDeploy, pathPrefix string) TempAppDeployResponse {
 	// 构建 URL
-	url := "/temp/" + deploy.TokenID
+	url := strings.TrimRight(pathPrefix, "/") + "/temp/" + deploy.TokenID
 
 	// 构建预览 URL
 	previewURL := url
-	if conf.Cfg != nil && conf.Cfg.Indexer.SwaggerBaseUrl != "" {
+	if conf.Cfg() != nil && conf.Cfg().Indexer.SwaggerBaseUrl != "" {
 		// 如果配置了基础 URL，使用 https 协议
-		previewURL = "https://" + conf.Cfg.Indexer.SwaggerBaseUrl + url
+		previewURL = "https://" + conf.Cfg().Indexer.SwaggerBaseUrl + url
 	}
 
 	return TempAppDeployResponse{
@@ -103,3 +106,44 @@ func ToTempAppChunkUploadResponse(upload *model.TempAppChunkUpload) TempAppChunk
 		Progress:       progress,
 	}
 }
+
+// TempAppValidateFile 校验结果中的文件条目
+type TempAppValidateFile struct {
+	Path string `json:"path"` // 相对路径
+	Size int64  `json:"size"` // 文件大小（字节）
+}
+
+// TempAppValidateResponse 临时应用压缩包校验响应结构
+type TempAppValidateResponse struct {
+	Valid          bool                  `json:"valid"`           // 是否可以作为临时应用部署
+	IndexFile      string                `json:"index_file"`      // 检查的入口文件
+	HasIndex       bool                  `json:"has_index"`       // 根目录是否存在入口文件
+	TotalSize      int64                 `json:"total_size"`      // 解压后总大小（字节）
+	FileCount      int                   `json:"file_count"`      // 解压后文件数
+	Files          []TempAppValidateFile `json:"files"`           // 文件列表（按路径排序）
+	FilesTruncated bool                  `json:"files_truncated"` // 文件列表是否被截断
+	Errors         []string              `json:"errors"`          // 校验失败原因
+}
+
+// ToTempAppValidateResponse 转换 TempAppValidation 为响应结构
+func ToTempAppValidateResponse(validation *temp_deploy_service.TempAppValidation) TempAppValidateResponse {
+	files := make([]TempAppValidateFile, 0, len(validation.Files))
+	for _, file := range validation.Files {
+		files = append(files, TempAppValidateFile{Path: file.Path, Size: file.Size})
+	}
+	errs := validation.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+
+	return TempAppValidateResponse{
+		Valid:          validation.Valid,
+		IndexFile:      validation.IndexFile,
+		HasIndex:       validation.HasIndex,
+		TotalSize:      validation.TotalSize,
+		FileCount:      validation.FileCount,
+		Files:          files,
+		FilesTruncated: validation.FilesTruncated,
+		Errors:         errs,
+	}
+}
@@ -14,4 +14,10 @@ var (
 
 	// ErrDatabaseNotInitialized database is not initialized
 	ErrDatabaseNotInitialized = errors.New("database not initialized")
+
+	// ErrInvalidCursor malformed list cursor
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrAlreadyQueued the PIN already has a deploy queue item
+	ErrAlreadyQueued = errors.New("already in deploy queue")
 )
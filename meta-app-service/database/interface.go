@@ -1,6 +1,8 @@
 package database
 
 import (
+	"time"
+
 	model "meta-app-service/models"
 )
 
@@ -10,39 +12,78 @@ type Database interface {
 	CreateMetaApp(app *model.MetaApp) error
 	GetMetaAppByPinID(pinID string) (*model.MetaApp, error)
 	UpdateMetaApp(app *model.MetaApp) error
-	GetMetaAppsByCreatorMetaIDWithCursor(metaID string, cursor int64, size int) ([]*model.MetaApp, int64, error)
-	ListMetaAppsWithCursor(cursor int64, size int) ([]*model.MetaApp, int64, error)
+	// MetaApp lists use a key cursor ("{timestamp}_{pin_id}" of the previous page's last app, empty for the first page);
+	// the returned next cursor is empty when there are no more apps
+	GetMetaAppsByCreatorMetaIDWithCursor(metaID string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error)
+	GetMetaAppsByOwnerMetaIDWithCursor(metaID string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error)
+	// GetMetaAppsByAppNameWithCursor returns every app whose latest version has exactly this AppName, whatever its creator
+	GetMetaAppsByAppNameWithCursor(appName string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error)
+	ListMetaAppsWithCursor(cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error)
+	// GetMetaAppsByHeightRange returns every confirmed version in the height range, newest block first;
+	// its cursor is "{block_height}_{pin_id}" of the previous page's last app
+	GetMetaAppsByHeightRange(fromHeight, toHeight int64, cursor string, size int) ([]*model.MetaApp, string, error)
 	CountMetaApps() (int64, error)
+	CountMetaAppsByCreatorMetaID(metaID string) (int64, error)
 	GetLatestMetaAppByFirstPinID(firstPinID string) (*model.MetaApp, error)
+	// GetMetaAppHistoryByFirstPinID returns the most recent versions kept inline (see database.max_history_per_app);
+	// GetMetaAppHistoryPage pages through the full history including archived versions
 	GetMetaAppHistoryByFirstPinID(firstPinID string) ([]*model.MetaApp, error)
+	GetMetaAppHistoryPage(firstPinID string, cursor string, size int) ([]*model.MetaApp, string, error)
+
+	// MetaApp raw content operations (PIN content bytes as inscribed)
+	SaveMetaAppRawContent(content *model.MetaAppRawContent) error
+	GetMetaAppRawContent(pinID string) (*model.MetaAppRawContent, error)
+
+	// MetaApp pending modify operations (modifies whose referenced PIN is not indexed yet)
+	AddPendingModify(pending *model.MetaAppPendingModify) error
+	TakePendingModifies(targetPinID string) ([]*model.MetaAppPendingModify, error)
+	PurgePendingModifies(before time.Time) (int, error)
+
+	// MetaApp blacklist operations
+	CreateMetaAppBlacklist(entry *model.MetaAppBlacklist) error
+	GetMetaAppBlacklist(firstPinID string) (*model.MetaAppBlacklist, error)
+	DeleteMetaAppBlacklist(firstPinID string) error
 
 	// IndexerSyncStatus operations
 	CreateOrUpdateIndexerSyncStatus(status *model.IndexerSyncStatus) error
 	GetIndexerSyncStatusByChainName(chainName string) (*model.IndexerSyncStatus, error)
 	UpdateIndexerSyncStatusHeight(chainName string, height int64) error
 	GetAllIndexerSyncStatus() ([]*model.IndexerSyncStatus, error)
+	// Resume checkpoints are stored apart from the sync status; GetMaxMetaAppBlockHeight scans the PinID primary records
+	SaveIndexerSyncCheckpoint(checkpoint *model.IndexerSyncCheckpoint) error
+	GetIndexerSyncCheckpoint(chainName string) (*model.IndexerSyncCheckpoint, error)
+	GetMaxMetaAppBlockHeight(chainName string) (int64, error)
 
 	// MetaApp deploy operations
-	AddToDeployQueue(queue *model.MetaAppDeployQueue) error
+	AddToDeployQueue(queue *model.MetaAppDeployQueue) error // ErrAlreadyQueued when the PIN is already queued
 	GetDeployQueueItem(pinID string) (*model.MetaAppDeployQueue, error)
 	UpdateDeployQueueItem(queue *model.MetaAppDeployQueue) error
 	RemoveFromDeployQueue(pinID string) error
-	GetNextDeployQueueItem() (*model.MetaAppDeployQueue, error)
+	GetNextDeployQueueItem(leaseDuration time.Duration) (*model.MetaAppDeployQueue, error)
 	ListDeployQueueWithCursor(cursor int64, size int) ([]*model.MetaAppDeployQueue, int64, error)
 	CreateOrUpdateDeployFileContent(content *model.MetaAppDeployFileContent) error
 	GetDeployFileContent(pinID string) (*model.MetaAppDeployFileContent, error)
+	GetDeployFileContentHistory(pinID string) ([]*model.MetaAppDeployFileContent, error)
+	ListRecentDeploysWithCursor(cursor string, size int) ([]*model.MetaAppDeployFileContent, string, error)
+	GetLatestDeployByFirstPinID(firstPinID string) (*model.MetaAppDeployFileContent, error)
 
 	// TempApp deploy operations
 	CreateTempAppDeploy(deploy *model.TempAppDeploy) error
 	GetTempAppDeployByTokenID(tokenID string) (*model.TempAppDeploy, error)
+	GetTempAppDeployByContentHash(contentHash string) (*model.TempAppDeploy, error)
 	DeleteTempAppDeploy(tokenID string) error
 	ListExpiredTempAppDeploys() ([]*model.TempAppDeploy, error)
+	CountActiveTempAppDeploys() (int, error)
 
 	// TempApp chunk upload operations
 	CreateTempAppChunkUpload(upload *model.TempAppChunkUpload) error
 	GetTempAppChunkUploadByUploadID(uploadID string) (*model.TempAppChunkUpload, error)
 	UpdateTempAppChunkUpload(upload *model.TempAppChunkUpload) error
 	DeleteTempAppChunkUpload(uploadID string) error
+	ListStaleChunkUploads(before time.Time) ([]*model.TempAppChunkUpload, error)
+
+	// Maintenance operations
+	CheckIntegrity(repair bool) (*IntegrityReport, error)
 
 	// General operations
 	Close() error
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -19,34 +20,57 @@ import (
 
 // PebbleDatabase PebbleDB database implementation with multiple collections
 type PebbleDatabase struct {
-	collections map[string]*pebble.DB // Map of collection name to PebbleDB instance
+	collections map[string]*collection // Map of collection name to its key space
+	instances   map[string]*pebble.DB  // Open PebbleDB instances by directory name (closed once each)
 
 	statusIDCounter atomic.Int64
+
+	// MetaApp 的主记录、历史和索引共享一个 PebbleDB 实例，一次写入在一个 Batch 中原子提交；其他 collection 是独立实例。
+	// 读-改-写（历史记录、最新版本、旧索引 key 的删除）仍需要串行执行，以下锁保护这些操作
+	metaAppMu sync.RWMutex // MetaApp 写入（写锁）与跨 collection 读取（历史 + 归档、完整性检查）
+	queueMu   sync.Mutex   // 部署队列项的写入、领取（claim/lease）、更新和删除
+	deployMu  sync.Mutex   // 部署记录、最近部署索引和部署历史的读-改-写
+	pendingMu sync.Mutex   // 暂存 modify 的写入、取出和清理（避免同一 modify 被取出两次）
+
+	maxHistoryPerApp int // 历史记录中保留的版本数，更早的版本移到归档（0 表示不限制）
 }
 
 // PebbleConfig PebbleDB configuration
 type PebbleConfig struct {
-	DataDir string
+	DataDir          string
+	MaxHistoryPerApp int // Versions kept in an app's inline history, older versions move to the archive (0 = unlimited)
 }
 
 // Collection names and their key-value formats
 const (
 	// MetaApp collections
-	collectionMetaAppPinID           = "metaapp_pin"            // key: {pin_id}, value: JSON(MetaApp) - PinID 到 MetaApp 的映射
-	collectionMetaAppPinIDLastest    = "metaapp_pin_latest"     // key: {first_pin_id}, value: JSON(MetaApp) - 最新 MetaApp
-	collectionMetaAppPinIDHistory    = "metaapp_pin_history"    // key: {first_pin_id}, value:  JSON(MetaApp) list - 历史 MetaApp
-	collectionMetaAppMetaIDTimestamp = "metaapp_meta_timestamp" // key: {meta_id}:{timestamp}:{first_pin_id}, value: JSON(MetaApp) - 按 MetaID 和时间戳索引
-	collectionMetaAppTimestamp       = "metaapp_timestamp"      // key: {timestamp}:{first_pin_id}, value: JSON(MetaApp) - 按时间戳索引（用于全局列表）
+	collectionMetaAppPinID           = "metaapp_pin"             // key: {pin_id}, value: JSON(MetaApp) - PinID 到 MetaApp 的映射
+	collectionMetaAppPinIDLastest    = "metaapp_pin_latest"      // key: {first_pin_id}, value: JSON(MetaApp) - 最新 MetaApp
+	collectionMetaAppPinIDHistory    = "metaapp_pin_history"     // key: {first_pin_id}, value:  JSON(MetaApp) list - 历史 MetaApp（最近的版本，有上限）
+	collectionMetaAppHistoryArchive  = "metaapp_history_archive" // key: {first_pin_id}:{reverse_timestamp}:{pin_id}, value: JSON(MetaApp) - 超出历史上限的旧版本
+	collectionMetaAppMetaIDTimestamp = "metaapp_meta_timestamp"  // key: {meta_id}:{timestamp}:{first_pin_id}, value: JSON(MetaApp) - 按 MetaID 和时间戳索引
+	collectionMetaAppTimestamp       = "metaapp_timestamp"       // key: {timestamp}:{first_pin_id}, value: JSON(MetaApp) - 按时间戳索引（用于全局列表）
+	collectionMetaAppOwnerTimestamp  = "metaapp_owner_timestamp" // key: {owner_meta_id}:{timestamp}:{first_pin_id}, value: JSON(MetaApp) - 按当前拥有者 MetaID 和时间戳索引
+	collectionMetaAppName            = "metaapp_name"            // key: {app_name}\x00{timestamp}:{first_pin_id}, value: JSON(MetaApp) - 按应用名称（精确匹配）和时间戳索引
+	collectionMetaAppBlacklist       = "metaapp_blacklist"       // key: {first_pin_id}, value: JSON(MetaAppBlacklist) - 被禁用的 MetaApp
+	collectionMetaAppBlockHeight     = "metaapp_block_height"    // key: {reverse_block_height}:{pin_id}, value: JSON(MetaApp) - 按区块高度索引（每个已确认版本一条）
+	collectionMetaAppPendingModify   = "metaapp_pending_modify"  // key: {target_pin_id}:{pin_id}, value: JSON(MetaAppPendingModify) - 等待被引用 PIN 索引的 modify
+	collectionMetaAppRawContent      = "metaapp_raw_content"     // key: {pin_id}, value: JSON(MetaAppRawContent) - PIN 原始内容
 
 	collectionMetaAppDeployFileContent = "metaapp_deploy_file_content" // key: {pin_id}, value: JSON(MetaAppDeployFileContent) - 部署文件内容
 	collectionMetaAppDeployQueue       = "metaapp_deploy_queue"        // key: {reverse_timestamp}:{pin_id}, value: JSON(MetaAppDeployQueue) - 部署队列（按时间戳倒序）
+	collectionMetaAppDeployHistory     = "metaapp_deploy_history"      // key: {pin_id}, value: JSON(MetaAppDeployFileContent) list - 部署记录历史（最新的在前，有上限）
+	collectionMetaAppDeployRecent      = "metaapp_deploy_recent"       // key: {reverse_updated_at}:{first_pin_id}, value: JSON(MetaAppDeployFileContent) - 按最近一次部署成功时间倒序
+	collectionMetaAppDeployRecentKey   = "metaapp_deploy_recent_key"   // key: {first_pin_id}, value: 该应用在 metaapp_deploy_recent 中的 key
 
 	collectionTempAppDeploy      = "temp_app_deploy"       // key: {token_id}, value: JSON(TempAppDeploy) - 临时应用部署
 	collectionTempAppChunkUpload = "temp_app_chunk_upload" // key: {upload_id}, value: JSON(TempAppChunkUpload) - 临时应用分片上传
+	collectionTempAppHash        = "temp_app_hash"         // key: {content_hash}, value: {token_id} - 按压缩包内容哈希索引临时应用
 
 	// System collections
-	collectionSyncStatus = "sync_status" // key: {chain_name}, value: JSON(IndexerSyncStatus) - 同步状态
-	collectionCounters   = "counters"    // key: status, value: {max_id} - ID 计数器
+	collectionSyncStatus     = "sync_status"     // key: {chain_name}, value: JSON(IndexerSyncStatus) - 同步状态
+	collectionSyncCheckpoint = "sync_checkpoint" // key: {chain_name}, value: JSON(IndexerSyncCheckpoint) - 冗余的扫描恢复点（同步状态丢失或损坏时使用）
+	collectionCounters       = "counters"        // key: status, value: {max_id} - ID 计数器
 )
 
 // Counter keys
@@ -54,6 +78,9 @@ const (
 	keyStatusCounter = "status"
 )
 
+// maxDeployHistoryPerPin 每个 PinID 保留的部署记录历史条数
+const maxDeployHistoryPerPin = 20
+
 // NewPebbleDatabase create PebbleDB database instance with multiple collections
 func NewPebbleDatabase(config interface{}) (Database, error) {
 	cfg, ok := config.(*PebbleConfig)
@@ -68,24 +95,33 @@ func NewPebbleDatabase(config interface{}) (Database, error) {
 
 	log.Printf("PebbleDB data directory: %s", cfg.DataDir)
 
-	// List of all collections
+	// Collections with their own PebbleDB instance (the MetaApp collections in metaAppSharedCollections share one)
 	collectionNames := []string{
-		collectionMetaAppPinID,
-		collectionMetaAppPinIDLastest,
-		collectionMetaAppPinIDHistory,
-		collectionMetaAppMetaIDTimestamp,
-		collectionMetaAppTimestamp,
+		collectionMetaAppBlacklist,
+		collectionMetaAppPendingModify,
+		collectionMetaAppRawContent,
 		collectionMetaAppDeployFileContent,
 		collectionMetaAppDeployQueue,
+		collectionMetaAppDeployHistory,
+		collectionMetaAppDeployRecent,
+		collectionMetaAppDeployRecentKey,
 		collectionTempAppDeploy,
 		collectionTempAppChunkUpload,
+		collectionTempAppHash,
 		collectionSyncStatus,
+		collectionSyncCheckpoint,
 		collectionCounters,
 	}
 
 	// Open PebbleDB for each collection
-	collections := make(map[string]*pebble.DB)
-	for _, name := range collectionNames {
+	collections := make(map[string]*collection)
+	instances := make(map[string]*pebble.DB)
+	closeInstances := func() {
+		for _, openedDB := range instances {
+			openedDB.Close()
+		}
+	}
+	for _, name := range append(collectionNames, metaAppDBName) {
 		// Create collection path: dataDir/collectionName
 		collectionPath := filepath.Join(cfg.DataDir, "indexer_db", name)
 
@@ -96,17 +132,30 @@ func NewPebbleDatabase(config interface{}) (Database, error) {
 		db, err := pebble.Open(collectionPath, &pebble.Options{})
 		if err != nil {
 			// Close previously opened databases
-			for _, openedDB := range collections {
-				openedDB.Close()
-			}
+			closeInstances()
 			return nil, fmt.Errorf("failed to open collection %s at %s: %w", name, collectionPath, err)
 		}
-		collections[name] = db
+		instances[name] = db
+		if name != metaAppDBName {
+			collections[name] = &collection{db: db}
+		}
 		log.Printf("Collection %s opened successfully", name)
 	}
 
+	// MetaApp collections: key prefix "{collection}/" in the shared instance
+	// 旧版本中每个 collection 是独立目录，首次启动时复制到共享实例后删除
+	for _, name := range metaAppSharedCollections {
+		collections[name] = &collection{db: instances[metaAppDBName], prefix: []byte(name + "/")}
+		if err := migrateLegacyCollection(filepath.Join(cfg.DataDir, "indexer_db", name), collections[name]); err != nil {
+			closeInstances()
+			return nil, fmt.Errorf("failed to migrate collection %s: %w", name, err)
+		}
+	}
+
 	pdb := &PebbleDatabase{
-		collections: collections,
+		collections:      collections,
+		instances:        instances,
+		maxHistoryPerApp: cfg.MaxHistoryPerApp,
 	}
 
 	// Load counters
@@ -114,6 +163,26 @@ func NewPebbleDatabase(config interface{}) (Database, error) {
 		return nil, fmt.Errorf("failed to load counters: %w", err)
 	}
 
+	// 旧版本数据没有应用名称索引，首次启动时根据最新版本生成
+	if err := pdb.backfillMetaAppName(); err != nil {
+		return nil, fmt.Errorf("failed to build app name index: %w", err)
+	}
+
+	// 旧版本数据没有拥有者索引，首次启动时根据最新版本生成
+	if err := pdb.backfillMetaAppOwner(); err != nil {
+		return nil, fmt.Errorf("failed to build owner index: %w", err)
+	}
+
+	// 旧版本数据没有区块高度索引，首次启动时根据已确认的版本生成
+	if err := pdb.backfillMetaAppBlockHeight(); err != nil {
+		return nil, fmt.Errorf("failed to build block height index: %w", err)
+	}
+
+	// 旧版本数据没有最近部署索引，首次启动时根据部署记录生成
+	if err := pdb.backfillDeployRecent(); err != nil {
+		return nil, fmt.Errorf("failed to build recent deploy index: %w", err)
+	}
+
 	log.Printf("PebbleDB database connected successfully with %d collections", len(collections))
 	return pdb, nil
 }
@@ -134,39 +203,127 @@ func (p *PebbleDatabase) loadCounters() error {
 
 // MetaApp operations
 
-// paginateMetaAppsByTimestampDesc sorts MetaApps by timestamp desc (fallback PinID) then slices by cursor+size.
-func paginateMetaAppsByTimestampDesc(apps []*model.MetaApp, cursor int64, size int) ([]*model.MetaApp, int64) {
-	if len(apps) == 0 || size <= 0 {
-		return nil, cursor
+// paginateMetaAppsByTimestampDesc sorts MetaApps by timestamp desc (fallback PinID desc) and returns the page after cursor.
+// The cursor is the sort key of the last item of the previous page (see encodeMetaAppCursor), so apps inserted
+// between page requests do not shift later pages: newer apps sort before the cursor and are not returned again.
+// nextCursor is empty when there are no more apps.
+func paginateMetaAppsByTimestampDesc(apps []*model.MetaApp, cursor string, size int) ([]*model.MetaApp, string, error) {
+	afterTimestamp, afterPinID, hasCursor, err := parseMetaAppCursor(cursor)
+	if err != nil {
+		return nil, "", err
 	}
-
-	if cursor < 0 {
-		cursor = 0
+	if len(apps) == 0 || size <= 0 {
+		return nil, "", nil
 	}
 
 	sort.Slice(apps, func(i, j int) bool {
-		if apps[i].Timestamp == apps[j].Timestamp {
-			return apps[i].PinID > apps[j].PinID
-		}
-		return apps[i].Timestamp > apps[j].Timestamp
+		return metaAppSortsBefore(apps[i].Timestamp, apps[i].PinID, apps[j].Timestamp, apps[j].PinID)
 	})
 
-	start := int(cursor)
+	start := 0
+	if hasCursor {
+		start = sort.Search(len(apps), func(i int) bool {
+			return metaAppSortsBefore(afterTimestamp, afterPinID, apps[i].Timestamp, apps[i].PinID)
+		})
+	}
 	if start >= len(apps) {
-		return nil, cursor
+		return nil, "", nil
 	}
 
 	end := start + size
-	if end > len(apps) {
-		end = len(apps)
+	if end >= len(apps) {
+		return apps[start:], "", nil
 	}
-
 	paged := apps[start:end]
-	nextCursor := cursor + int64(len(paged))
-	return paged, nextCursor
+	return paged, encodeMetaAppCursor(paged[len(paged)-1]), nil
+}
+
+// metaAppSortsBefore 列表排序：时间戳倒序，时间戳相同时按 PinID 倒序
+func metaAppSortsBefore(timestamp int64, pinID string, otherTimestamp int64, otherPinID string) bool {
+	if timestamp == otherTimestamp {
+		return pinID > otherPinID
+	}
+	return timestamp > otherTimestamp
 }
 
+// encodeMetaAppCursor 列表游标：{timestamp}_{pin_id}（上一页最后一条记录的排序 key）
+func encodeMetaAppCursor(app *model.MetaApp) string {
+	return strconv.FormatInt(app.Timestamp, 10) + "_" + app.PinID
+}
+
+// parseMetaAppCursor 解析列表游标，空字符串和 "0" 表示第一页
+func parseMetaAppCursor(cursor string) (int64, string, bool, error) {
+	if cursor == "" || cursor == "0" {
+		return 0, "", false, nil
+	}
+	timestampPart, pinID, ok := strings.Cut(cursor, "_")
+	if !ok || pinID == "" {
+		return 0, "", false, ErrInvalidCursor
+	}
+	timestamp, err := strconv.ParseInt(timestampPart, 10, 64)
+	if err != nil {
+		return 0, "", false, ErrInvalidCursor
+	}
+	return timestamp, pinID, true, nil
+}
+
+// collectionBatches 一次写操作涉及的各 collection 的 Batch
+// 同一 PebbleDB 实例上的 collection 共用一个 pebble.Batch，MetaApp 的所有 collection 因此在一次 fsync 的提交中原子生效
+type collectionBatches struct {
+	p       *PebbleDatabase
+	shared  map[*pebble.DB]*pebble.Batch
+	batches map[string]*collectionBatch
+}
+
+func (p *PebbleDatabase) newCollectionBatches() *collectionBatches {
+	return &collectionBatches{p: p, shared: make(map[*pebble.DB]*pebble.Batch), batches: make(map[string]*collectionBatch)}
+}
+
+// get 获取 collection 对应的 Batch（不存在则创建，同一实例上的 collection 共用底层 Batch）
+func (b *collectionBatches) get(name string) *collectionBatch {
+	batch, ok := b.batches[name]
+	if !ok {
+		c := b.p.collections[name]
+		shared, ok := b.shared[c.db]
+		if !ok {
+			shared = c.db.NewBatch()
+			b.shared[c.db] = shared
+		}
+		batch = &collectionBatch{batch: shared, c: c}
+		b.batches[name] = batch
+	}
+	return batch
+}
+
+// commit 提交所有 Batch，每个 PebbleDB 实例一次 fsync 的提交（MetaApp collection 只有一次）
+func (b *collectionBatches) commit() error {
+	for db, batch := range b.shared {
+		if batch.Empty() {
+			continue
+		}
+		if err := batch.Commit(pebble.Sync); err != nil {
+			return fmt.Errorf("failed to commit batch: %w", err)
+		}
+		delete(b.shared, db)
+		batch.Close()
+	}
+	return nil
+}
+
+// close 释放所有 Batch
+func (b *collectionBatches) close() {
+	for _, batch := range b.shared {
+		batch.Close()
+	}
+}
+
+// CreateMetaApp 写入 MetaApp 及其所有索引
+// 主记录、历史、最新版本和各索引的删除与写入放在同一个 Batch 中，一次 fsync 原子提交，不会只写入一部分
+// 持有 MetaApp 写锁：多条链的扫描、ZMQ 内存池交易和管理接口可能并发写入同一个应用
 func (p *PebbleDatabase) CreateMetaApp(app *model.MetaApp) error {
+	p.metaAppMu.Lock()
+	defer p.metaAppMu.Unlock()
+
 	// Serialize MetaApp
 	data, err := json.Marshal(app)
 	if err != nil {
@@ -185,85 +342,147 @@ func (p *PebbleDatabase) CreateMetaApp(app *model.MetaApp) error {
 		}
 	}
 
+	batches := p.newCollectionBatches()
+	defer batches.close()
+
+	// Store in block height index (every confirmed version, not only the latest)
+	// key: reverse_block_height:pin_id, value: JSON(MetaApp)
+	// 同一 PinID 的高度变化（重组后被重新打包）时删除旧 key
+	if existingData, closer, err := p.collections[collectionMetaAppPinID].Get([]byte(app.PinID)); err == nil {
+		var existing model.MetaApp
+		unmarshalErr := json.Unmarshal(existingData, &existing)
+		closer.Close()
+		if unmarshalErr == nil && existing.BlockHeight > 0 && existing.BlockHeight != app.BlockHeight {
+			batches.get(collectionMetaAppBlockHeight).Delete([]byte(metaAppHeightKey(existing.BlockHeight, app.PinID)), nil)
+		}
+	}
+	if app.BlockHeight > 0 {
+		if err := batches.get(collectionMetaAppBlockHeight).Set([]byte(metaAppHeightKey(app.BlockHeight, app.PinID)), data, nil); err != nil {
+			return err
+		}
+	}
+
 	// Store in PinID collection (primary index)
 	// key: pin_id, value: JSON(MetaApp)
-	if err := p.collections[collectionMetaAppPinID].Set([]byte(app.PinID), data, pebble.Sync); err != nil {
+	if err := batches.get(collectionMetaAppPinID).Set([]byte(app.PinID), data, nil); err != nil {
+		return err
+	}
+
+	// Store in History collection
+	// key: first_pin_id, value: JSON array of MetaApp - 历史列表
+	if err := p.addToHistory(batches, firstPinID, app); err != nil {
 		return err
 	}
 
+	// 被版本策略拒绝的 modify 只记录在 PinID 和历史记录中，不成为最新版本
+	if app.State == model.MetaAppStateRejected {
+		return batches.commit()
+	}
+
+	// 如果已有更新的版本（例如重新扫描旧区块），只更新 PinID 和历史记录，不覆盖最新版本及其索引
+	// 当前最新版本同时决定了需要删除的旧索引 key（索引中每个 first_pin_id 只有最新版本的一条记录）
+	var previous *model.MetaApp
+	if latestData, closer, err := p.collections[collectionMetaAppPinIDLastest].Get([]byte(firstPinID)); err == nil {
+		var latest model.MetaApp
+		unmarshalErr := json.Unmarshal(latestData, &latest)
+		closer.Close()
+		if unmarshalErr == nil {
+			if latest.PinID != app.PinID && latest.Timestamp > app.Timestamp {
+				return batches.commit()
+			}
+			previous = &latest
+		}
+	}
+
 	// Store in Latest collection
 	// key: first_pin_id, value: JSON(MetaApp) - 最新的 MetaApp
-	if err := p.collections[collectionMetaAppPinIDLastest].Set([]byte(firstPinID), data, pebble.Sync); err != nil {
+	if err := batches.get(collectionMetaAppPinIDLastest).Set([]byte(firstPinID), data, nil); err != nil {
 		return err
 	}
 
-	// Store in History collection
-	// key: first_pin_id, value: JSON array of MetaApp - 历史列表
-	if err := p.addToHistory(firstPinID, app); err != nil {
-		return err
+	// 删除上一个最新版本的索引（直接根据其时间戳和 MetaID 计算 key，无需遍历）
+	// 新 key 与旧 key 相同时，后续的 Set 会覆盖删除
+	if previous != nil {
+		previousTimestampKey := reverseTimestampKey(previous.Timestamp)
+		batches.get(collectionMetaAppMetaIDTimestamp).Delete([]byte(previous.CreatorMetaId+":"+previousTimestampKey+":"+firstPinID), nil)
+		batches.get(collectionMetaAppTimestamp).Delete([]byte(previousTimestampKey+":"+firstPinID), nil)
+		if previous.OwnerMetaId != "" {
+			batches.get(collectionMetaAppOwnerTimestamp).Delete([]byte(previous.OwnerMetaId+":"+previousTimestampKey+":"+firstPinID), nil)
+		}
+		if previous.AppName != "" {
+			batches.get(collectionMetaAppName).Delete([]byte(metaAppNameKey(previous.AppName, previous.Timestamp, firstPinID)), nil)
+		}
 	}
 
 	// Store in MetaID+Timestamp index collection
 	// key: meta_id:reverse_timestamp:first_pin_id, value: JSON(MetaApp)
 	// Format: {meta_id}:{reverse_timestamp}:{first_pin_id} for sorting by timestamp desc
 	// Use reverse timestamp (max_int64 - timestamp) for descending order
-	// 注意：这里需要删除旧的索引（如果有的话），因为 first_pin_id 可能相同但 timestamp 不同
-	reverseTimestamp := int64(^uint64(0)>>1) - app.Timestamp
-	reverseTimestampKey := strconv.FormatInt(reverseTimestamp, 10)
-	metaIDTimestampKey := app.CreatorMetaId + ":" + reverseTimestampKey + ":" + firstPinID
-
-	// 删除旧的索引（如果有相同 first_pin_id 但不同 timestamp 的旧记录）
-	// 通过遍历找到旧的索引并删除
-	prefix := app.CreatorMetaId + ":"
-	iter, err := p.collections[collectionMetaAppMetaIDTimestamp].NewIter(&pebble.IterOptions{
-		LowerBound: []byte(prefix),
-		UpperBound: []byte(prefix + "~"),
-	})
-	if err == nil {
-		for iter.First(); iter.Valid(); iter.Next() {
-			key := string(iter.Key())
-			// 检查是否是同一个 first_pin_id 的旧记录
-			if strings.HasSuffix(key, ":"+firstPinID) && key != metaIDTimestampKey {
-				// 删除旧的索引
-				p.collections[collectionMetaAppMetaIDTimestamp].Delete(iter.Key(), pebble.Sync)
-			}
-		}
-		iter.Close()
-	}
-
-	if err := p.collections[collectionMetaAppMetaIDTimestamp].Set([]byte(metaIDTimestampKey), data, pebble.Sync); err != nil {
+	timestampKey := reverseTimestampKey(app.Timestamp)
+	metaIDTimestampKey := app.CreatorMetaId + ":" + timestampKey + ":" + firstPinID
+	if err := batches.get(collectionMetaAppMetaIDTimestamp).Set([]byte(metaIDTimestampKey), data, nil); err != nil {
 		return err
 	}
 
 	// Store in Timestamp index collection (for global list)
 	// key: reverse_timestamp:first_pin_id, value: JSON(MetaApp)
 	// Use reverse timestamp for descending order
-	// 同样需要删除旧的索引
-	timestampIndexKey := reverseTimestampKey + ":" + firstPinID
-
-	// 删除旧的全局索引
-	globalIter, err := p.collections[collectionMetaAppTimestamp].NewIter(nil)
-	if err == nil {
-		for globalIter.First(); globalIter.Valid(); globalIter.Next() {
-			key := string(globalIter.Key())
-			// 检查是否是同一个 first_pin_id 的旧记录
-			if strings.HasSuffix(key, ":"+firstPinID) && key != timestampIndexKey {
-				// 删除旧的索引
-				p.collections[collectionMetaAppTimestamp].Delete(globalIter.Key(), pebble.Sync)
-			}
+	timestampIndexKey := timestampKey + ":" + firstPinID
+	if err := batches.get(collectionMetaAppTimestamp).Set([]byte(timestampIndexKey), data, nil); err != nil {
+		return err
+	}
+
+	// Store in Owner+Timestamp index collection
+	// key: owner_meta_id:reverse_timestamp:first_pin_id, value: JSON(MetaApp)
+	// 拥有者可能通过 modify 转移，旧拥有者下的索引已在上面删除
+	if app.OwnerMetaId != "" {
+		ownerTimestampKey := app.OwnerMetaId + ":" + timestampKey + ":" + firstPinID
+		if err := batches.get(collectionMetaAppOwnerTimestamp).Set([]byte(ownerTimestampKey), data, nil); err != nil {
+			return err
 		}
-		globalIter.Close()
 	}
 
-	if err := p.collections[collectionMetaAppTimestamp].Set([]byte(timestampIndexKey), data, pebble.Sync); err != nil {
-		return err
+	// Store in AppName index collection
+	// key: app_name\x00reverse_timestamp:first_pin_id, value: JSON(MetaApp)
+	// 名称可能通过 modify 修改，旧名称下的索引已在上面删除
+	if app.AppName != "" {
+		if err := batches.get(collectionMetaAppName).Set([]byte(metaAppNameKey(app.AppName, app.Timestamp, firstPinID)), data, nil); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return batches.commit()
+}
+
+// reverseTimestampKey 倒序时间戳 key（max_int64 - timestamp），用于按时间倒序遍历
+func reverseTimestampKey(timestamp int64) string {
+	return strconv.FormatInt(int64(^uint64(0)>>1)-timestamp, 10)
+}
+
+// metaAppNameKey 应用名称索引 key：{app_name}\x00{reverse_timestamp}:{first_pin_id}
+// 应用名称可能包含 ":"，用 \x00 分隔，按名称前缀遍历时不会匹配到以该名称开头的其他名称
+func metaAppNameKey(appName string, timestamp int64, firstPinID string) string {
+	return appName + "\x00" + reverseTimestampKey(timestamp) + ":" + firstPinID
+}
+
+// metaAppHeightKey 区块高度索引 key：{reverse_block_height}:{pin_id}（高度倒序，同一高度按 PinID 排序）
+func metaAppHeightKey(blockHeight int64, pinID string) string {
+	return reverseTimestampKey(blockHeight) + ":" + pinID
+}
+
+// historyArchiveKey 历史归档 key：{first_pin_id}:{reverse_timestamp}:{pin_id}（时间倒序，同一时间按 PinID 排序）
+func historyArchiveKey(firstPinID string, app *model.MetaApp) string {
+	return firstPinID + ":" + historyOrderKey(app)
 }
 
-// addToHistory 添加 MetaApp 到历史记录
-func (p *PebbleDatabase) addToHistory(firstPinID string, app *model.MetaApp) error {
+// historyOrderKey 历史分页的排序 key：{reverse_timestamp}:{pin_id}
+func historyOrderKey(app *model.MetaApp) string {
+	return reverseTimestampKey(app.Timestamp) + ":" + app.PinID
+}
+
+// addToHistory 在 batch 中添加 MetaApp 到历史记录
+// 历史记录只保留最近的 maxHistoryPerApp 个版本，更早的版本移到归档 collection（每个版本一条记录，不再随每次 modify 重写）
+func (p *PebbleDatabase) addToHistory(batches *collectionBatches, firstPinID string, app *model.MetaApp) error {
 	historyDB := p.collections[collectionMetaAppPinIDHistory]
 
 	// 获取现有历史记录
@@ -275,14 +494,44 @@ func (p *PebbleDatabase) addToHistory(firstPinID string, app *model.MetaApp) err
 		closer.Close()
 	}
 
-	// 添加新记录到历史
-	history = append(history, app)
+	// 添加新记录到历史（相同 PinID 的记录直接覆盖，避免重新扫描时重复）
+	replaced := false
+	for i, item := range history {
+		if item.PinID == app.PinID {
+			history[i] = app
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		history = append(history, app)
+	}
 
 	// 按时间戳排序（最新的在前）
 	sort.Slice(history, func(i, j int) bool {
 		return history[i].Timestamp > history[j].Timestamp
 	})
 
+	// 该版本可能已在归档中（重新扫描旧区块，或上限调大后），先删除，仍超出上限时下面会重新写入
+	archiveBatch := batches.get(collectionMetaAppHistoryArchive)
+	if err := archiveBatch.Delete([]byte(historyArchiveKey(firstPinID, app)), nil); err != nil {
+		return err
+	}
+
+	// 超出上限的旧版本移到归档
+	if p.maxHistoryPerApp > 0 && len(history) > p.maxHistoryPerApp {
+		for _, archived := range history[p.maxHistoryPerApp:] {
+			archivedData, err := json.Marshal(archived)
+			if err != nil {
+				return err
+			}
+			if err := archiveBatch.Set([]byte(historyArchiveKey(firstPinID, archived)), archivedData, nil); err != nil {
+				return err
+			}
+		}
+		history = history[:p.maxHistoryPerApp]
+	}
+
 	// 序列化历史记录
 	historyData, err := json.Marshal(history)
 	if err != nil {
@@ -290,7 +539,7 @@ func (p *PebbleDatabase) addToHistory(firstPinID string, app *model.MetaApp) err
 	}
 
 	// 保存历史记录
-	return historyDB.Set([]byte(firstPinID), historyData, pebble.Sync)
+	return batches.get(collectionMetaAppPinIDHistory).Set([]byte(firstPinID), historyData, nil)
 }
 
 func (p *PebbleDatabase) GetMetaAppByPinID(pinID string) (*model.MetaApp, error) {
@@ -317,7 +566,7 @@ func (p *PebbleDatabase) UpdateMetaApp(app *model.MetaApp) error {
 	return p.CreateMetaApp(app)
 }
 
-func (p *PebbleDatabase) GetMetaAppsByCreatorMetaIDWithCursor(metaID string, cursor int64, size int) ([]*model.MetaApp, int64, error) {
+func (p *PebbleDatabase) GetMetaAppsByCreatorMetaIDWithCursor(metaID string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error) {
 	metaIDTimestampDB := p.collections[collectionMetaAppMetaIDTimestamp]
 	prefix := metaID + ":"
 
@@ -328,7 +577,7 @@ func (p *PebbleDatabase) GetMetaAppsByCreatorMetaIDWithCursor(metaID string, cur
 		UpperBound: []byte(prefix + "~"),
 	})
 	if err != nil {
-		return nil, 0, err
+		return nil, "", err
 	}
 	defer iter.Close()
 
@@ -352,29 +601,36 @@ func (p *PebbleDatabase) GetMetaAppsByCreatorMetaIDWithCursor(metaID string, cur
 		}
 	}
 
-	// 转换为列表并排序
+	// 转换为列表（按过滤条件筛选最新版本）并排序
 	apps := make([]*model.MetaApp, 0, len(firstPinIDMap))
 	for _, app := range firstPinIDMap {
+		if !filter.Match(app) {
+			continue
+		}
 		apps = append(apps, app)
 	}
 
 	// Apps are already sorted by reverse timestamp (descending), but we need to sort by actual timestamp desc
-	sorted, nextCursor := paginateMetaAppsByTimestampDesc(apps, cursor, size)
-	return sorted, nextCursor, nil
+	return paginateMetaAppsByTimestampDesc(apps, cursor, size)
 }
 
-func (p *PebbleDatabase) ListMetaAppsWithCursor(cursor int64, size int) ([]*model.MetaApp, int64, error) {
-	timestampDB := p.collections[collectionMetaAppTimestamp]
+// GetMetaAppsByOwnerMetaIDWithCursor 根据当前拥有者 MetaID 获取 MetaApp 列表（每个 first_pin_id 的最新版本，按时间倒序，支持过滤和分页）
+func (p *PebbleDatabase) GetMetaAppsByOwnerMetaIDWithCursor(metaID string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error) {
+	ownerTimestampDB := p.collections[collectionMetaAppOwnerTimestamp]
+	prefix := metaID + ":"
 
-	// Create iterator for timestamp collection
-	// key format: reverse_timestamp:first_pin_id
-	iter, err := timestampDB.NewIter(nil)
+	// Create iterator with prefix
+	// key format: owner_meta_id:reverse_timestamp:first_pin_id
+	iter, err := ownerTimestampDB.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix),
+		UpperBound: []byte(prefix + "~"),
+	})
 	if err != nil {
-		return nil, 0, err
+		return nil, "", err
 	}
 	defer iter.Close()
 
-	// 使用 map 去重，确保每个 first_pin_id 只保留最新的（由于索引已按 reverse_timestamp 排序，第一个就是最新的）
+	// 使用 map 去重，确保每个 first_pin_id 只保留最新的
 	firstPinIDMap := make(map[string]*model.MetaApp)
 	for iter.First(); iter.Valid(); iter.Next() {
 		var app model.MetaApp
@@ -388,339 +644,1154 @@ func (p *PebbleDatabase) ListMetaAppsWithCursor(cursor int64, size int) ([]*mode
 			firstPinID = app.PinID
 		}
 
-		// 如果这个 first_pin_id 还没有记录，或者当前记录的时间戳更新，则更新
 		if existing, exists := firstPinIDMap[firstPinID]; !exists || app.Timestamp > existing.Timestamp {
 			firstPinIDMap[firstPinID] = &app
 		}
 	}
 
-	// 转换为列表并排序
+	// 转换为列表（按过滤条件筛选最新版本）并排序
 	apps := make([]*model.MetaApp, 0, len(firstPinIDMap))
 	for _, app := range firstPinIDMap {
+		if !filter.Match(app) {
+			continue
+		}
 		apps = append(apps, app)
 	}
 
-	// Apps are already sorted by reverse timestamp (descending), but we need to sort by actual timestamp desc
-	sorted, nextCursor := paginateMetaAppsByTimestampDesc(apps, cursor, size)
-	return sorted, nextCursor, nil
+	return paginateMetaAppsByTimestampDesc(apps, cursor, size)
 }
 
-func (p *PebbleDatabase) CountMetaApps() (int64, error) {
-	// 统计唯一的 first_pin_id 数量（从 latest collection）
-	latestDB := p.collections[collectionMetaAppPinIDLastest]
+// GetMetaAppsByAppNameWithCursor 获取应用名称（精确匹配）为 appName 的 MetaApp 列表（每个 first_pin_id 的最新版本，按时间倒序，支持过滤和分页）
+// 同名应用可能来自不同创建者，全部返回，便于发现名称冲突
+func (p *PebbleDatabase) GetMetaAppsByAppNameWithCursor(appName string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error) {
+	prefix := appName + "\x00"
 
-	// Create iterator to count all unique first_pin_id
-	iter, err := latestDB.NewIter(nil)
+	// key format: app_name\x00reverse_timestamp:first_pin_id
+	iter, err := p.collections[collectionMetaAppName].NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix),
+		UpperBound: []byte(appName + "\x01"),
+	})
 	if err != nil {
-		return 0, err
+		return nil, "", err
 	}
 	defer iter.Close()
 
-	var count int64
+	// 索引中每个 first_pin_id 只有最新版本的一条记录
+	apps := make([]*model.MetaApp, 0)
 	for iter.First(); iter.Valid(); iter.Next() {
-		count++
+		var app model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &app); err != nil || app.AppName != appName {
+			continue
+		}
+		if !filter.Match(&app) {
+			continue
+		}
+		apps = append(apps, &app)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", err
 	}
 
-	return count, nil
+	return paginateMetaAppsByTimestampDesc(apps, cursor, size)
 }
 
-// GetLatestMetaAppByFirstPinID 根据 first_pin_id 获取最新的 MetaApp
-func (p *PebbleDatabase) GetLatestMetaAppByFirstPinID(firstPinID string) (*model.MetaApp, error) {
-	latestDB := p.collections[collectionMetaAppPinIDLastest]
+// backfillMetaAppName 应用名称索引为空时，根据每个应用的最新版本生成索引
+func (p *PebbleDatabase) backfillMetaAppName() error {
+	p.metaAppMu.Lock()
+	defer p.metaAppMu.Unlock()
 
-	data, closer, err := latestDB.Get([]byte(firstPinID))
+	nameIter, err := p.collections[collectionMetaAppName].NewIter(nil)
 	if err != nil {
-		if err == pebble.ErrNotFound {
-			return nil, ErrNotFound
-		}
-		return nil, err
+		return err
 	}
-	defer closer.Close()
-
-	var app model.MetaApp
-	if err := json.Unmarshal(data, &app); err != nil {
-		return nil, err
+	hasIndex := nameIter.First()
+	nameIter.Close()
+	if hasIndex {
+		return nil
 	}
 
-	return &app, nil
-}
-
-// GetMetaAppHistoryByFirstPinID 根据 first_pin_id 获取历史记录
-func (p *PebbleDatabase) GetMetaAppHistoryByFirstPinID(firstPinID string) ([]*model.MetaApp, error) {
-	historyDB := p.collections[collectionMetaAppPinIDHistory]
-
-	data, closer, err := historyDB.Get([]byte(firstPinID))
+	iter, err := p.collections[collectionMetaAppPinIDLastest].NewIter(nil)
 	if err != nil {
-		if err == pebble.ErrNotFound {
-			return []*model.MetaApp{}, nil // 返回空列表而不是错误
-		}
-		return nil, err
+		return err
 	}
-	defer closer.Close()
+	defer iter.Close()
 
-	var history []*model.MetaApp
-	if err := json.Unmarshal(data, &history); err != nil {
-		return nil, err
+	batch := p.collections[collectionMetaAppName].NewBatch()
+	defer batch.Close()
+	for iter.First(); iter.Valid(); iter.Next() {
+		var app model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &app); err != nil || app.AppName == "" {
+			continue
+		}
+		if err := batch.Set([]byte(metaAppNameKey(app.AppName, app.Timestamp, string(iter.Key()))), iter.Value(), nil); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if batch.Empty() {
+		return nil
 	}
 
-	return history, nil
+	log.Printf("Built app name index for %d MetaApp(s)", batch.Count())
+	return batch.Commit(pebble.Sync)
 }
 
-// IndexerSyncStatus operations
+// backfillMetaAppOwner 拥有者索引为空时，根据每个应用的最新版本生成索引
+func (p *PebbleDatabase) backfillMetaAppOwner() error {
+	p.metaAppMu.Lock()
+	defer p.metaAppMu.Unlock()
 
-func (p *PebbleDatabase) CreateOrUpdateIndexerSyncStatus(status *model.IndexerSyncStatus) error {
-	if status.ID == 0 {
-		status.ID = p.statusIDCounter.Add(1)
-		// Save counter
-		p.collections[collectionCounters].Set(
-			[]byte(keyStatusCounter),
-			[]byte(strconv.FormatInt(status.ID, 10)),
-			pebble.Sync,
-		)
+	ownerIter, err := p.collections[collectionMetaAppOwnerTimestamp].NewIter(nil)
+	if err != nil {
+		return err
+	}
+	hasIndex := ownerIter.First()
+	ownerIter.Close()
+	if hasIndex {
+		return nil
 	}
 
-	data, err := json.Marshal(status)
+	iter, err := p.collections[collectionMetaAppPinIDLastest].NewIter(nil)
 	if err != nil {
 		return err
 	}
+	defer iter.Close()
 
-	syncStatusDB := p.collections[collectionSyncStatus]
+	batch := p.collections[collectionMetaAppOwnerTimestamp].NewBatch()
+	defer batch.Close()
+	for iter.First(); iter.Valid(); iter.Next() {
+		var app model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &app); err != nil || app.OwnerMetaId == "" {
+			continue
+		}
+		ownerTimestampKey := app.OwnerMetaId + ":" + reverseTimestampKey(app.Timestamp) + ":" + string(iter.Key())
+		if err := batch.Set([]byte(ownerTimestampKey), iter.Value(), nil); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if batch.Empty() {
+		return nil
+	}
 
-	// Store by chain name (primary key for sync status)
-	return syncStatusDB.Set([]byte(status.ChainName), data, pebble.Sync)
+	log.Printf("Built owner index for %d MetaApp(s)", batch.Count())
+	return batch.Commit(pebble.Sync)
 }
 
-func (p *PebbleDatabase) GetIndexerSyncStatusByChainName(chainName string) (*model.IndexerSyncStatus, error) {
-	syncStatusDB := p.collections[collectionSyncStatus]
+// backfillMetaAppBlockHeight 区块高度索引为空时，根据 PinID 主记录中已确认的版本生成索引
+func (p *PebbleDatabase) backfillMetaAppBlockHeight() error {
+	p.metaAppMu.Lock()
+	defer p.metaAppMu.Unlock()
 
-	data, closer, err := syncStatusDB.Get([]byte(chainName))
+	heightIter, err := p.collections[collectionMetaAppBlockHeight].NewIter(nil)
 	if err != nil {
-		if err == pebble.ErrNotFound {
-			return nil, ErrNotFound
-		}
-		return nil, err
+		return err
 	}
-	defer closer.Close()
-
-	var status model.IndexerSyncStatus
-	if err := json.Unmarshal(data, &status); err != nil {
-		return nil, err
+	hasIndex := heightIter.First()
+	heightIter.Close()
+	if hasIndex {
+		return nil
 	}
 
-	return &status, nil
-}
-
-func (p *PebbleDatabase) UpdateIndexerSyncStatusHeight(chainName string, height int64) error {
-	status, err := p.GetIndexerSyncStatusByChainName(chainName)
+	iter, err := p.collections[collectionMetaAppPinID].NewIter(nil)
 	if err != nil {
 		return err
 	}
+	defer iter.Close()
 
-	status.CurrentSyncHeight = height
-	return p.CreateOrUpdateIndexerSyncStatus(status)
-}
-
-func (p *PebbleDatabase) GetAllIndexerSyncStatus() ([]*model.IndexerSyncStatus, error) {
+	batch := p.collections[collectionMetaAppBlockHeight].NewBatch()
+	defer batch.Close()
+	for iter.First(); iter.Valid(); iter.Next() {
+		var app model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &app); err != nil || app.BlockHeight <= 0 {
+			continue
+		}
+		if err := batch.Set([]byte(metaAppHeightKey(app.BlockHeight, string(iter.Key()))), iter.Value(), nil); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if batch.Empty() {
+		return nil
+	}
+
+	log.Printf("Built block height index for %d MetaApp version(s)", batch.Count())
+	return batch.Commit(pebble.Sync)
+}
+
+// ListMetaAppsWithCursor 获取所有 MetaApp 列表（每个 first_pin_id 的最新版本，按时间倒序，支持过滤和分页）
+func (p *PebbleDatabase) ListMetaAppsWithCursor(cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error) {
+	timestampDB := p.collections[collectionMetaAppTimestamp]
+
+	// Create iterator for timestamp collection
+	// key format: reverse_timestamp:first_pin_id
+	iter, err := timestampDB.NewIter(nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer iter.Close()
+
+	// 使用 map 去重，确保每个 first_pin_id 只保留最新的（由于索引已按 reverse_timestamp 排序，第一个就是最新的）
+	firstPinIDMap := make(map[string]*model.MetaApp)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var app model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &app); err != nil {
+			continue
+		}
+
+		// 确保 FirstPinId 已设置
+		firstPinID := app.FirstPinId
+		if firstPinID == "" {
+			firstPinID = app.PinID
+		}
+
+		// 如果这个 first_pin_id 还没有记录，或者当前记录的时间戳更新，则更新
+		if existing, exists := firstPinIDMap[firstPinID]; !exists || app.Timestamp > existing.Timestamp {
+			firstPinIDMap[firstPinID] = &app
+		}
+	}
+
+	// 转换为列表（按过滤条件筛选最新版本）并排序
+	apps := make([]*model.MetaApp, 0, len(firstPinIDMap))
+	for _, app := range firstPinIDMap {
+		if !filter.Match(app) {
+			continue
+		}
+		apps = append(apps, app)
+	}
+
+	// Apps are already sorted by reverse timestamp (descending), but we need to sort by actual timestamp desc
+	return paginateMetaAppsByTimestampDesc(apps, cursor, size)
+}
+
+// GetMetaAppsByHeightRange 获取区块高度在 [fromHeight, toHeight] 内的 MetaApp（每个已确认版本一条，高度倒序，同一高度按 PinID 排序）
+// cursor 为上一页最后一条记录的 "{block_height}_{pin_id}"，第一页为空；没有更多记录时返回的游标为空
+func (p *PebbleDatabase) GetMetaAppsByHeightRange(fromHeight, toHeight int64, cursor string, size int) ([]*model.MetaApp, string, error) {
+	afterHeight, afterPinID, hasCursor, err := parseMetaAppCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 倒序 key 中较大的高度在前：下界为 toHeight，上界为 fromHeight 的所有 key（':' 之后的下一个字符是 ';'）
+	iter, err := p.collections[collectionMetaAppBlockHeight].NewIter(&pebble.IterOptions{
+		LowerBound: []byte(reverseTimestampKey(toHeight) + ":"),
+		UpperBound: []byte(reverseTimestampKey(fromHeight) + ";"),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer iter.Close()
+
+	valid := iter.First()
+	if hasCursor {
+		cursorKey := metaAppHeightKey(afterHeight, afterPinID)
+		valid = iter.SeekGE([]byte(cursorKey))
+		if valid && string(iter.Key()) == cursorKey {
+			valid = iter.Next()
+		}
+	}
+
+	apps := make([]*model.MetaApp, 0, size)
+	for ; valid && len(apps) < size; valid = iter.Next() {
+		var app model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &app); err != nil {
+			continue
+		}
+		apps = append(apps, &app)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", err
+	}
+
+	// 还有剩余记录时返回最后一条的排序 key 作为游标
+	if !valid || len(apps) == 0 {
+		return apps, "", nil
+	}
+	last := apps[len(apps)-1]
+	return apps, strconv.FormatInt(last.BlockHeight, 10) + "_" + last.PinID, nil
+}
+
+func (p *PebbleDatabase) CountMetaApps() (int64, error) {
+	// 统计唯一的 first_pin_id 数量（从 latest collection）
+	latestDB := p.collections[collectionMetaAppPinIDLastest]
+
+	// Create iterator to count all unique first_pin_id
+	iter, err := latestDB.NewIter(nil)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	var count int64
+	for iter.First(); iter.Valid(); iter.Next() {
+		count++
+	}
+
+	return count, nil
+}
+
+// CountMetaAppsByCreatorMetaID 统计创建者名下唯一的 first_pin_id 数量
+func (p *PebbleDatabase) CountMetaAppsByCreatorMetaID(metaID string) (int64, error) {
+	prefix := metaID + ":"
+
+	// key format: meta_id:reverse_timestamp:first_pin_id，只需遍历 key
+	iter, err := p.collections[collectionMetaAppMetaIDTimestamp].NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix),
+		UpperBound: []byte(prefix + "~"),
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	firstPinIDs := make(map[string]struct{})
+	for iter.First(); iter.Valid(); iter.Next() {
+		key := string(iter.Key())
+		firstPinIDs[key[strings.LastIndex(key, ":")+1:]] = struct{}{}
+	}
+
+	return int64(len(firstPinIDs)), nil
+}
+
+// GetLatestMetaAppByFirstPinID 根据 first_pin_id 获取最新的 MetaApp
+func (p *PebbleDatabase) GetLatestMetaAppByFirstPinID(firstPinID string) (*model.MetaApp, error) {
+	latestDB := p.collections[collectionMetaAppPinIDLastest]
+
+	data, closer, err := latestDB.Get([]byte(firstPinID))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer closer.Close()
+
+	var app model.MetaApp
+	if err := json.Unmarshal(data, &app); err != nil {
+		return nil, err
+	}
+
+	return &app, nil
+}
+
+// GetMetaAppHistoryByFirstPinID 根据 first_pin_id 获取历史记录（最近的 maxHistoryPerApp 个版本，不包括归档）
+func (p *PebbleDatabase) GetMetaAppHistoryByFirstPinID(firstPinID string) ([]*model.MetaApp, error) {
+	historyDB := p.collections[collectionMetaAppPinIDHistory]
+
+	data, closer, err := historyDB.Get([]byte(firstPinID))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return []*model.MetaApp{}, nil // 返回空列表而不是错误
+		}
+		return nil, err
+	}
+	defer closer.Close()
+
+	var history []*model.MetaApp
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// GetMetaAppHistoryPage 分页获取 first_pin_id 的完整历史（历史记录和归档合并，时间倒序，同一时间按 PinID 排序）
+// cursor 为上一页最后一条记录的 "{timestamp}_{pin_id}"，第一页为空；没有更多记录时返回的游标为空
+// 持有 MetaApp 读锁，避免读取历史记录和归档之间有版本被移到归档（重复或遗漏）
+func (p *PebbleDatabase) GetMetaAppHistoryPage(firstPinID string, cursor string, size int) ([]*model.MetaApp, string, error) {
+	p.metaAppMu.RLock()
+	defer p.metaAppMu.RUnlock()
+
+	afterTimestamp, afterPinID, hasCursor, err := parseMetaAppCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	afterKey := ""
+	if hasCursor {
+		afterKey = historyOrderKey(&model.MetaApp{Timestamp: afterTimestamp, PinID: afterPinID})
+	}
+
+	// 历史记录（有上限，直接全部读取后按排序 key 过滤）
+	inline, err := p.GetMetaAppHistoryByFirstPinID(firstPinID)
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Slice(inline, func(i, j int) bool {
+		return historyOrderKey(inline[i]) < historyOrderKey(inline[j])
+	})
+	seen := make(map[string]bool, len(inline))
+	for _, app := range inline {
+		seen[app.PinID] = true
+	}
+	for len(inline) > 0 && historyOrderKey(inline[0]) <= afterKey {
+		inline = inline[1:]
+	}
+
+	// 归档（从游标之后开始遍历）
+	prefix := firstPinID + ":"
+	iter, err := p.collections[collectionMetaAppHistoryArchive].NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix),
+		UpperBound: []byte(prefix + "~"),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer iter.Close()
+
+	valid := iter.First()
+	if hasCursor {
+		valid = iter.SeekGE([]byte(prefix + afterKey))
+		if valid && string(iter.Key()) == prefix+afterKey {
+			valid = iter.Next()
+		}
+	}
+	nextArchived := func() *model.MetaApp {
+		for ; valid; valid = iter.Next() {
+			var app model.MetaApp
+			if err := json.Unmarshal(iter.Value(), &app); err != nil || seen[app.PinID] {
+				continue
+			}
+			valid = iter.Next()
+			return &app
+		}
+		return nil
+	}
+
+	// 按排序 key 归并两部分，多取一条判断是否还有下一页
+	apps := make([]*model.MetaApp, 0, size)
+	archived := nextArchived()
+	for len(apps) <= size {
+		var app *model.MetaApp
+		switch {
+		case len(inline) > 0 && (archived == nil || historyOrderKey(inline[0]) < historyOrderKey(archived)):
+			app, inline = inline[0], inline[1:]
+		case archived != nil:
+			app, archived = archived, nextArchived()
+		}
+		if app == nil {
+			break
+		}
+		apps = append(apps, app)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", err
+	}
+
+	if len(apps) <= size {
+		return apps, "", nil
+	}
+	apps = apps[:size]
+	return apps, encodeMetaAppCursor(apps[len(apps)-1]), nil
+}
+
+// IndexerSyncStatus operations
+
+func (p *PebbleDatabase) CreateOrUpdateIndexerSyncStatus(status *model.IndexerSyncStatus) error {
+	if status.ID == 0 {
+		status.ID = p.statusIDCounter.Add(1)
+		// Save counter
+		p.collections[collectionCounters].Set(
+			[]byte(keyStatusCounter),
+			[]byte(strconv.FormatInt(status.ID, 10)),
+			pebble.Sync,
+		)
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	syncStatusDB := p.collections[collectionSyncStatus]
+
+	// Store by chain name (primary key for sync status)
+	return syncStatusDB.Set([]byte(status.ChainName), data, pebble.Sync)
+}
+
+func (p *PebbleDatabase) GetIndexerSyncStatusByChainName(chainName string) (*model.IndexerSyncStatus, error) {
+	syncStatusDB := p.collections[collectionSyncStatus]
+
+	data, closer, err := syncStatusDB.Get([]byte(chainName))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer closer.Close()
+
+	var status model.IndexerSyncStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+func (p *PebbleDatabase) UpdateIndexerSyncStatusHeight(chainName string, height int64) error {
+	status, err := p.GetIndexerSyncStatusByChainName(chainName)
+	if err != nil {
+		return err
+	}
+
+	status.CurrentSyncHeight = height
+	return p.CreateOrUpdateIndexerSyncStatus(status)
+}
+
+func (p *PebbleDatabase) GetAllIndexerSyncStatus() ([]*model.IndexerSyncStatus, error) {
 	var statuses []*model.IndexerSyncStatus
 
-	syncStatusDB := p.collections[collectionSyncStatus]
+	syncStatusDB := p.collections[collectionSyncStatus]
+
+	iter, err := syncStatusDB.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var status model.IndexerSyncStatus
+		if err := json.Unmarshal(iter.Value(), &status); err != nil {
+			continue
+		}
+		statuses = append(statuses, &status)
+	}
+
+	return statuses, nil
+}
+
+// SaveIndexerSyncCheckpoint 写入链的扫描恢复点（与同步状态分开存储）
+func (p *PebbleDatabase) SaveIndexerSyncCheckpoint(checkpoint *model.IndexerSyncCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return p.collections[collectionSyncCheckpoint].Set([]byte(checkpoint.ChainName), data, pebble.Sync)
+}
+
+// GetIndexerSyncCheckpoint 获取链的扫描恢复点
+func (p *PebbleDatabase) GetIndexerSyncCheckpoint(chainName string) (*model.IndexerSyncCheckpoint, error) {
+	data, closer, err := p.collections[collectionSyncCheckpoint].Get([]byte(chainName))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer closer.Close()
+
+	var checkpoint model.IndexerSyncCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// GetMaxMetaAppBlockHeight 获取链上已索引的 MetaApp 版本中最高的区块高度（未确认记录不计入）
+// 区块高度索引按高度倒序，从第一条开始找到该链的第一条记录即为最高高度，不再遍历全部主记录
+func (p *PebbleDatabase) GetMaxMetaAppBlockHeight(chainName string) (int64, error) {
+	iter, err := p.collections[collectionMetaAppBlockHeight].NewIter(nil)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var app model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &app); err != nil {
+			continue
+		}
+		if app.ChainName == chainName && app.BlockHeight > 0 {
+			return app.BlockHeight, nil
+		}
+	}
+	return 0, iter.Error()
+}
+
+// MetaApp deploy operations
+
+// AddToDeployQueue 添加 MetaApp 到部署队列
+func (p *PebbleDatabase) AddToDeployQueue(queue *model.MetaAppDeployQueue) error {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	// 检查和写入在同一把锁内，并发入队同一个 PinID（重新扫描、ZMQ、管理接口）时只有一个成功
+	// 队列 key 包含时间戳，同一 PinID 的时间戳可能不同（内存池时间和区块时间），按 PinID 检查
+	if _, err := p.GetDeployQueueItem(queue.PinID); err == nil {
+		return ErrAlreadyQueued
+	}
+
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+
+	// key: reverse_timestamp:pin_id (用于按时间倒序排列)
+	reverseTimestamp := int64(^uint64(0)>>1) - queue.Timestamp
+	reverseTimestampKey := strconv.FormatInt(reverseTimestamp, 10)
+	queueKey := reverseTimestampKey + ":" + queue.PinID
+
+	return p.collections[collectionMetaAppDeployQueue].Set([]byte(queueKey), data, pebble.Sync)
+}
+
+// GetDeployQueueItem 获取部署队列项
+func (p *PebbleDatabase) GetDeployQueueItem(pinID string) (*model.MetaAppDeployQueue, error) {
+	queueDB := p.collections[collectionMetaAppDeployQueue]
+
+	// 遍历查找匹配的 pinID
+	iter, err := queueDB.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var queue model.MetaAppDeployQueue
+		if err := json.Unmarshal(iter.Value(), &queue); err != nil {
+			continue
+		}
+		if queue.PinID == pinID {
+			return &queue, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// UpdateDeployQueueItem 更新部署队列项
+// 与领取和删除串行执行，避免把刚被删除的队列项重新写回
+func (p *PebbleDatabase) UpdateDeployQueueItem(queue *model.MetaAppDeployQueue) error {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	queueDB := p.collections[collectionMetaAppDeployQueue]
+
+	// 遍历查找匹配的 pinID
+	iter, err := queueDB.NewIter(nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var existingQueue model.MetaAppDeployQueue
+		if err := json.Unmarshal(iter.Value(), &existingQueue); err != nil {
+			continue
+		}
+		if existingQueue.PinID == queue.PinID {
+			// 找到匹配的项，更新它
+			data, err := json.Marshal(queue)
+			if err != nil {
+				return err
+			}
+			return queueDB.Set(iter.Key(), data, pebble.Sync)
+		}
+	}
+
+	return ErrNotFound
+}
+
+// RemoveFromDeployQueue 从部署队列中移除
+func (p *PebbleDatabase) RemoveFromDeployQueue(pinID string) error {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	queueDB := p.collections[collectionMetaAppDeployQueue]
+
+	// 遍历查找并删除匹配的 pinID
+	iter, err := queueDB.NewIter(nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var queue model.MetaAppDeployQueue
+		if err := json.Unmarshal(iter.Value(), &queue); err != nil {
+			continue
+		}
+		if queue.PinID == pinID {
+			return queueDB.Delete(iter.Key(), pebble.Sync)
+		}
+	}
+
+	return ErrNotFound
+}
+
+// GetNextDeployQueueItem 领取下一个待处理的部署队列项（按时间戳倒序，最早的优先）
+// 领取时为队列项设置租约，租约到期前其他 worker 不会再领取该项；处理异常退出时租约到期后可被重新领取
+func (p *PebbleDatabase) GetNextDeployQueueItem(leaseDuration time.Duration) (*model.MetaAppDeployQueue, error) {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	queueDB := p.collections[collectionMetaAppDeployQueue]
+
+	// 创建迭代器（按 reverse_timestamp 排序，所以第一个是最早的）
+	iter, err := queueDB.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	now := time.Now()
+	for iter.First(); iter.Valid(); iter.Next() {
+		var queue model.MetaAppDeployQueue
+		if err := json.Unmarshal(iter.Value(), &queue); err != nil {
+			continue
+		}
+
+		// 跳过租约未到期的项（正在被其他 worker 处理）
+		if queue.LeaseUntil.After(now) {
+			continue
+		}
+
+		// 跳过还未到重试时间的项（失败退避中）
+		if queue.NextRetryAt.After(now) {
+			continue
+		}
+
+		// 设置租约并保存
+		queue.LeaseUntil = now.Add(leaseDuration)
+		data, err := json.Marshal(&queue)
+		if err != nil {
+			return nil, err
+		}
+		if err := queueDB.Set(append([]byte{}, iter.Key()...), data, pebble.Sync); err != nil {
+			return nil, err
+		}
+
+		return &queue, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// ListDeployQueueWithCursor 获取部署队列列表（支持游标分页，按时间戳倒序）
+func (p *PebbleDatabase) ListDeployQueueWithCursor(cursor int64, size int) ([]*model.MetaAppDeployQueue, int64, error) {
+	queueDB := p.collections[collectionMetaAppDeployQueue]
+
+	// 创建迭代器（按 reverse_timestamp 排序，所以第一个是最早的）
+	// key format: reverse_timestamp:pin_id
+	iter, err := queueDB.NewIter(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer iter.Close()
+
+	// 收集所有队列项
+	queues := make([]*model.MetaAppDeployQueue, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var queue model.MetaAppDeployQueue
+		if err := json.Unmarshal(iter.Value(), &queue); err != nil {
+			continue
+		}
+		queues = append(queues, &queue)
+	}
+
+	// 按时间戳倒序排序（最新的在前）
+	sort.Slice(queues, func(i, j int) bool {
+		if queues[i].Timestamp == queues[j].Timestamp {
+			return queues[i].PinID > queues[j].PinID
+		}
+		return queues[i].Timestamp > queues[j].Timestamp
+	})
+
+	// 分页处理
+	if cursor < 0 {
+		cursor = 0
+	}
+	if size <= 0 {
+		size = 20
+	}
+
+	start := int(cursor)
+	if start >= len(queues) {
+		return []*model.MetaAppDeployQueue{}, cursor, nil
+	}
+
+	end := start + size
+	if end > len(queues) {
+		end = len(queues)
+	}
+
+	paged := queues[start:end]
+	nextCursor := cursor + int64(len(paged))
+	return paged, nextCursor, nil
+}
+
+// CreateOrUpdateDeployFileContent 创建或更新部署文件内容
+func (p *PebbleDatabase) CreateOrUpdateDeployFileContent(content *model.MetaAppDeployFileContent) error {
+	p.deployMu.Lock()
+	defer p.deployMu.Unlock()
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+
+	// key: pin_id
+	if err := p.collections[collectionMetaAppDeployFileContent].Set([]byte(content.PinID), data, pebble.Sync); err != nil {
+		return err
+	}
+
+	// 部署成功时更新最近部署索引
+	if content.DeployStatus == "completed" {
+		if err := p.setDeployRecent(content, data); err != nil {
+			return err
+		}
+	}
+
+	// 同时追加到部署记录历史（主记录始终是最新的一条）
+	return p.addToDeployHistory(content)
+}
+
+// setDeployRecent 将应用移动到最近部署索引的最前面（每个 first_pin_id 只保留最近一次成功部署）
+func (p *PebbleDatabase) setDeployRecent(content *model.MetaAppDeployFileContent, data []byte) error {
+	firstPinID := content.FirstPinId
+	if firstPinID == "" {
+		firstPinID = content.PinID
+	}
+	recentDB := p.collections[collectionMetaAppDeployRecent]
+	recentKeyDB := p.collections[collectionMetaAppDeployRecentKey]
+
+	key := reverseTimestampKey(content.UpdatedAt.UnixMilli()) + ":" + firstPinID
+	if previousKey, closer, err := recentKeyDB.Get([]byte(firstPinID)); err == nil {
+		previous := string(previousKey)
+		closer.Close()
+		if previous != key {
+			if err := recentDB.Delete([]byte(previous), pebble.Sync); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := recentDB.Set([]byte(key), data, pebble.Sync); err != nil {
+		return err
+	}
+	return recentKeyDB.Set([]byte(firstPinID), []byte(key), pebble.Sync)
+}
+
+// backfillDeployRecent 最近部署索引为空时，根据已完成的部署记录生成索引
+func (p *PebbleDatabase) backfillDeployRecent() error {
+	p.deployMu.Lock()
+	defer p.deployMu.Unlock()
+
+	recentIter, err := p.collections[collectionMetaAppDeployRecentKey].NewIter(nil)
+	if err != nil {
+		return err
+	}
+	hasIndex := recentIter.First()
+	recentIter.Close()
+	if hasIndex {
+		return nil
+	}
+
+	iter, err := p.collections[collectionMetaAppDeployFileContent].NewIter(nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	// 同一应用的多个版本只保留最近一次成功部署
+	latest := make(map[string]*model.MetaAppDeployFileContent)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var content model.MetaAppDeployFileContent
+		if err := json.Unmarshal(iter.Value(), &content); err != nil || content.DeployStatus != "completed" {
+			continue
+		}
+		firstPinID := content.FirstPinId
+		if firstPinID == "" {
+			firstPinID = content.PinID
+		}
+		if existing, ok := latest[firstPinID]; !ok || content.UpdatedAt.After(existing.UpdatedAt) {
+			latest[firstPinID] = &content
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	for _, content := range latest {
+		data, err := json.Marshal(content)
+		if err != nil {
+			return err
+		}
+		if err := p.setDeployRecent(content, data); err != nil {
+			return err
+		}
+	}
+	if len(latest) > 0 {
+		log.Printf("Built recent deploy index for %d apps", len(latest))
+	}
+	return nil
+}
+
+// GetLatestDeployByFirstPinID 获取 first_pin_id 最近一次部署成功的记录（即部署目录中当前的版本）
+func (p *PebbleDatabase) GetLatestDeployByFirstPinID(firstPinID string) (*model.MetaAppDeployFileContent, error) {
+	key, closer, err := p.collections[collectionMetaAppDeployRecentKey].Get([]byte(firstPinID))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	recentKey := append([]byte(nil), key...)
+	closer.Close()
 
-	iter, err := syncStatusDB.NewIter(nil)
+	data, closer, err := p.collections[collectionMetaAppDeployRecent].Get(recentKey)
 	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer closer.Close()
+
+	var content model.MetaAppDeployFileContent
+	if err := json.Unmarshal(data, &content); err != nil {
 		return nil, err
 	}
+	return &content, nil
+}
+
+// ListRecentDeploysWithCursor 按最近一次部署成功时间倒序获取部署记录（每个 first_pin_id 一条）
+// cursor 为上一页返回的 nextCursor（第一页为空），没有更多记录时 nextCursor 为空
+func (p *PebbleDatabase) ListRecentDeploysWithCursor(cursor string, size int) ([]*model.MetaAppDeployFileContent, string, error) {
+	iter, err := p.collections[collectionMetaAppDeployRecent].NewIter(nil)
+	if err != nil {
+		return nil, "", err
+	}
 	defer iter.Close()
 
-	for iter.First(); iter.Valid(); iter.Next() {
-		var status model.IndexerSyncStatus
-		if err := json.Unmarshal(iter.Value(), &status); err != nil {
+	valid := iter.First()
+	if cursor != "" {
+		valid = iter.SeekGE([]byte(cursor))
+		if valid && string(iter.Key()) == cursor {
+			valid = iter.Next()
+		}
+	}
+
+	contents := make([]*model.MetaAppDeployFileContent, 0, size)
+	lastKey := ""
+	for ; valid && len(contents) < size; valid = iter.Next() {
+		var content model.MetaAppDeployFileContent
+		if err := json.Unmarshal(iter.Value(), &content); err != nil {
 			continue
 		}
-		statuses = append(statuses, &status)
+		contents = append(contents, &content)
+		lastKey = string(iter.Key())
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", err
 	}
 
-	return statuses, nil
+	// 还有剩余记录时返回最后一条的 key 作为游标
+	if !valid {
+		return contents, "", nil
+	}
+	return contents, lastKey, nil
 }
 
-// MetaApp deploy operations
+// addToDeployHistory 追加部署记录到历史（最新的在前，超过上限时丢弃最旧的记录）
+func (p *PebbleDatabase) addToDeployHistory(content *model.MetaAppDeployFileContent) error {
+	historyDB := p.collections[collectionMetaAppDeployHistory]
 
-// AddToDeployQueue 添加 MetaApp 到部署队列
-func (p *PebbleDatabase) AddToDeployQueue(queue *model.MetaAppDeployQueue) error {
-	data, err := json.Marshal(queue)
+	history, err := p.GetDeployFileContentHistory(content.PinID)
 	if err != nil {
 		return err
 	}
 
-	// key: reverse_timestamp:pin_id (用于按时间倒序排列)
-	reverseTimestamp := int64(^uint64(0)>>1) - queue.Timestamp
-	reverseTimestampKey := strconv.FormatInt(reverseTimestamp, 10)
-	queueKey := reverseTimestampKey + ":" + queue.PinID
+	history = append([]*model.MetaAppDeployFileContent{content}, history...)
+	if len(history) > maxDeployHistoryPerPin {
+		history = history[:maxDeployHistoryPerPin]
+	}
 
-	return p.collections[collectionMetaAppDeployQueue].Set([]byte(queueKey), data, pebble.Sync)
+	historyData, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return historyDB.Set([]byte(content.PinID), historyData, pebble.Sync)
 }
 
-// GetDeployQueueItem 获取部署队列项
-func (p *PebbleDatabase) GetDeployQueueItem(pinID string) (*model.MetaAppDeployQueue, error) {
-	queueDB := p.collections[collectionMetaAppDeployQueue]
+// GetDeployFileContentHistory 获取部署记录历史（最新的在前）
+func (p *PebbleDatabase) GetDeployFileContentHistory(pinID string) ([]*model.MetaAppDeployFileContent, error) {
+	historyDB := p.collections[collectionMetaAppDeployHistory]
 
-	// 遍历查找匹配的 pinID
-	iter, err := queueDB.NewIter(nil)
+	data, closer, err := historyDB.Get([]byte(pinID))
 	if err != nil {
+		if err == pebble.ErrNotFound {
+			return []*model.MetaAppDeployFileContent{}, nil // 返回空列表而不是错误
+		}
 		return nil, err
 	}
-	defer iter.Close()
+	defer closer.Close()
 
-	for iter.First(); iter.Valid(); iter.Next() {
-		var queue model.MetaAppDeployQueue
-		if err := json.Unmarshal(iter.Value(), &queue); err != nil {
-			continue
-		}
-		if queue.PinID == pinID {
-			return &queue, nil
-		}
+	var history []*model.MetaAppDeployFileContent
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
 	}
 
-	return nil, ErrNotFound
+	return history, nil
 }
 
-// UpdateDeployQueueItem 更新部署队列项
-func (p *PebbleDatabase) UpdateDeployQueueItem(queue *model.MetaAppDeployQueue) error {
-	queueDB := p.collections[collectionMetaAppDeployQueue]
-
-	// 遍历查找匹配的 pinID
-	iter, err := queueDB.NewIter(nil)
+// CreateMetaAppBlacklist 添加（或覆盖）MetaApp 黑名单记录
+func (p *PebbleDatabase) CreateMetaAppBlacklist(entry *model.MetaAppBlacklist) error {
+	data, err := json.Marshal(entry)
 	if err != nil {
 		return err
 	}
-	defer iter.Close()
 
-	for iter.First(); iter.Valid(); iter.Next() {
-		var existingQueue model.MetaAppDeployQueue
-		if err := json.Unmarshal(iter.Value(), &existingQueue); err != nil {
-			continue
-		}
-		if existingQueue.PinID == queue.PinID {
-			// 找到匹配的项，更新它
-			data, err := json.Marshal(queue)
-			if err != nil {
-				return err
-			}
-			return queueDB.Set(iter.Key(), data, pebble.Sync)
+	// key: first_pin_id
+	return p.collections[collectionMetaAppBlacklist].Set([]byte(entry.FirstPinId), data, pebble.Sync)
+}
+
+// GetMetaAppBlacklist 根据 FirstPinID 获取黑名单记录，未被禁用时返回 ErrNotFound
+func (p *PebbleDatabase) GetMetaAppBlacklist(firstPinID string) (*model.MetaAppBlacklist, error) {
+	data, closer, err := p.collections[collectionMetaAppBlacklist].Get([]byte(firstPinID))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFound
 		}
+		return nil, err
 	}
+	defer closer.Close()
 
-	return ErrNotFound
+	var entry model.MetaAppBlacklist
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
 }
 
-// RemoveFromDeployQueue 从部署队列中移除
-func (p *PebbleDatabase) RemoveFromDeployQueue(pinID string) error {
-	queueDB := p.collections[collectionMetaAppDeployQueue]
+// DeleteMetaAppBlacklist 删除黑名单记录（重新启用）
+func (p *PebbleDatabase) DeleteMetaAppBlacklist(firstPinID string) error {
+	return p.collections[collectionMetaAppBlacklist].Delete([]byte(firstPinID), pebble.Sync)
+}
 
-	// 遍历查找并删除匹配的 pinID
-	iter, err := queueDB.NewIter(nil)
+// MetaApp raw content operations
+
+// SaveMetaAppRawContent 保存 PIN 原始内容（重新扫描时覆盖）
+func (p *PebbleDatabase) SaveMetaAppRawContent(content *model.MetaAppRawContent) error {
+	data, err := json.Marshal(content)
 	if err != nil {
 		return err
 	}
-	defer iter.Close()
-
-	for iter.First(); iter.Valid(); iter.Next() {
-		var queue model.MetaAppDeployQueue
-		if err := json.Unmarshal(iter.Value(), &queue); err != nil {
-			continue
-		}
-		if queue.PinID == pinID {
-			return queueDB.Delete(iter.Key(), pebble.Sync)
-		}
-	}
 
-	return ErrNotFound
+	// key: pin_id
+	return p.collections[collectionMetaAppRawContent].Set([]byte(content.PinID), data, pebble.Sync)
 }
 
-// GetNextDeployQueueItem 获取下一个待处理的部署队列项（按时间戳倒序，最早的优先）
-func (p *PebbleDatabase) GetNextDeployQueueItem() (*model.MetaAppDeployQueue, error) {
-	queueDB := p.collections[collectionMetaAppDeployQueue]
-
-	// 创建迭代器（按 reverse_timestamp 排序，所以第一个是最早的）
-	iter, err := queueDB.NewIter(nil)
+// GetMetaAppRawContent 获取 PIN 原始内容，没有记录时返回 ErrNotFound
+func (p *PebbleDatabase) GetMetaAppRawContent(pinID string) (*model.MetaAppRawContent, error) {
+	data, closer, err := p.collections[collectionMetaAppRawContent].Get([]byte(pinID))
 	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
-	defer iter.Close()
+	defer closer.Close()
 
-	if !iter.First() {
-		return nil, ErrNotFound
+	var content model.MetaAppRawContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
 	}
 
-	var queue model.MetaAppDeployQueue
-	if err := json.Unmarshal(iter.Value(), &queue); err != nil {
-		return nil, err
+	return &content, nil
+}
+
+// MetaApp pending modify operations
+
+// AddPendingModify 暂存 modify 操作（同一 modify 重复暂存时覆盖）
+func (p *PebbleDatabase) AddPendingModify(pending *model.MetaAppPendingModify) error {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return err
 	}
 
-	return &queue, nil
+	// key: target_pin_id:pin_id
+	return p.collections[collectionMetaAppPendingModify].Set([]byte(pending.TargetPinID+":"+pending.PinID), data, pebble.Sync)
 }
 
-// ListDeployQueueWithCursor 获取部署队列列表（支持游标分页，按时间戳倒序）
-func (p *PebbleDatabase) ListDeployQueueWithCursor(cursor int64, size int) ([]*model.MetaAppDeployQueue, int64, error) {
-	queueDB := p.collections[collectionMetaAppDeployQueue]
+// TakePendingModifies 取出并删除引用 targetPinID 的所有暂存 modify 操作
+func (p *PebbleDatabase) TakePendingModifies(targetPinID string) ([]*model.MetaAppPendingModify, error) {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
 
-	// 创建迭代器（按 reverse_timestamp 排序，所以第一个是最早的）
-	// key format: reverse_timestamp:pin_id
-	iter, err := queueDB.NewIter(nil)
+	pendingDB := p.collections[collectionMetaAppPendingModify]
+	prefix := targetPinID + ":"
+
+	iter, err := pendingDB.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix),
+		UpperBound: []byte(prefix + "~"),
+	})
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer iter.Close()
 
-	// 收集所有队列项
-	queues := make([]*model.MetaAppDeployQueue, 0)
-	for iter.First(); iter.Valid(); iter.Next() {
-		var queue model.MetaAppDeployQueue
-		if err := json.Unmarshal(iter.Value(), &queue); err != nil {
-			continue
-		}
-		queues = append(queues, &queue)
-	}
+	batch := pendingDB.NewBatch()
+	defer batch.Close()
 
-	// 按时间戳倒序排序（最新的在前）
-	sort.Slice(queues, func(i, j int) bool {
-		if queues[i].Timestamp == queues[j].Timestamp {
-			return queues[i].PinID > queues[j].PinID
+	pending := make([]*model.MetaAppPendingModify, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var item model.MetaAppPendingModify
+		if err := json.Unmarshal(iter.Value(), &item); err == nil {
+			pending = append(pending, &item)
 		}
-		return queues[i].Timestamp > queues[j].Timestamp
-	})
-
-	// 分页处理
-	if cursor < 0 {
-		cursor = 0
+		batch.Delete(iter.Key(), nil)
 	}
-	if size <= 0 {
-		size = 20
+	if err := iter.Error(); err != nil {
+		return nil, err
 	}
-
-	start := int(cursor)
-	if start >= len(queues) {
-		return []*model.MetaAppDeployQueue{}, cursor, nil
+	if batch.Empty() {
+		return pending, nil
 	}
+	return pending, batch.Commit(pebble.Sync)
+}
 
-	end := start + size
-	if end > len(queues) {
-		end = len(queues)
-	}
+// PurgePendingModifies 删除暂存时间早于 before 的 modify 操作，返回删除数量
+func (p *PebbleDatabase) PurgePendingModifies(before time.Time) (int, error) {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
 
-	paged := queues[start:end]
-	nextCursor := cursor + int64(len(paged))
-	return paged, nextCursor, nil
-}
+	pendingDB := p.collections[collectionMetaAppPendingModify]
 
-// CreateOrUpdateDeployFileContent 创建或更新部署文件内容
-func (p *PebbleDatabase) CreateOrUpdateDeployFileContent(content *model.MetaAppDeployFileContent) error {
-	data, err := json.Marshal(content)
+	iter, err := pendingDB.NewIter(nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	defer iter.Close()
 
-	// key: pin_id
-	return p.collections[collectionMetaAppDeployFileContent].Set([]byte(content.PinID), data, pebble.Sync)
+	batch := pendingDB.NewBatch()
+	defer batch.Close()
+
+	purged := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		var item model.MetaAppPendingModify
+		if err := json.Unmarshal(iter.Value(), &item); err == nil && !item.CreatedAt.Before(before) {
+			continue
+		}
+		batch.Delete(iter.Key(), nil)
+		purged++
+	}
+	if err := iter.Error(); err != nil {
+		return 0, err
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+	return purged, batch.Commit(pebble.Sync)
 }
 
 // GetDeployFileContent 获取部署文件内容
@@ -754,7 +1825,15 @@ func (p *PebbleDatabase) CreateTempAppDeploy(deploy *model.TempAppDeploy) error
 	}
 
 	// key: token_id
-	return p.collections[collectionTempAppDeploy].Set([]byte(deploy.TokenID), data, pebble.Sync)
+	if err := p.collections[collectionTempAppDeploy].Set([]byte(deploy.TokenID), data, pebble.Sync); err != nil {
+		return err
+	}
+
+	// 内容哈希索引（同一内容指向最新的记录）
+	if deploy.ContentHash != "" {
+		return p.collections[collectionTempAppHash].Set([]byte(deploy.ContentHash), []byte(deploy.TokenID), pebble.Sync)
+	}
+	return nil
 }
 
 // GetTempAppDeployByTokenID 根据 TokenID 获取临时应用部署记录
@@ -778,9 +1857,40 @@ func (p *PebbleDatabase) GetTempAppDeployByTokenID(tokenID string) (*model.TempA
 	return &deploy, nil
 }
 
+// GetTempAppDeployByContentHash 根据压缩包内容哈希获取临时应用部署记录
+func (p *PebbleDatabase) GetTempAppDeployByContentHash(contentHash string) (*model.TempAppDeploy, error) {
+	data, closer, err := p.collections[collectionTempAppHash].Get([]byte(contentHash))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	tokenID := string(data)
+	closer.Close()
+
+	return p.GetTempAppDeployByTokenID(tokenID)
+}
+
 // DeleteTempAppDeploy 删除临时应用部署记录
+// 内容哈希索引仍指向该记录时一并删除
 func (p *PebbleDatabase) DeleteTempAppDeploy(tokenID string) error {
 	deployDB := p.collections[collectionTempAppDeploy]
+
+	if deploy, err := p.GetTempAppDeployByTokenID(tokenID); err == nil && deploy.ContentHash != "" {
+		hashDB := p.collections[collectionTempAppHash]
+		data, closer, err := hashDB.Get([]byte(deploy.ContentHash))
+		if err == nil {
+			indexed := string(data)
+			closer.Close()
+			if indexed == tokenID {
+				if err := hashDB.Delete([]byte(deploy.ContentHash), pebble.Sync); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	return deployDB.Delete([]byte(tokenID), pebble.Sync)
 }
 
@@ -812,6 +1922,29 @@ func (p *PebbleDatabase) ListExpiredTempAppDeploys() ([]*model.TempAppDeploy, er
 	return expired, nil
 }
 
+// CountActiveTempAppDeploys 统计未过期的临时应用部署记录数
+func (p *PebbleDatabase) CountActiveTempAppDeploys() (int, error) {
+	iter, err := p.collections[collectionTempAppDeploy].NewIter(nil)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	now := time.Now()
+	count := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		var deploy model.TempAppDeploy
+		if err := json.Unmarshal(iter.Value(), &deploy); err != nil {
+			continue
+		}
+		if deploy.ExpiresAt.After(now) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
 // TempApp chunk upload operations
 
 // CreateTempAppChunkUpload 创建临时应用分片上传记录
@@ -863,10 +1996,50 @@ func (p *PebbleDatabase) DeleteTempAppChunkUpload(uploadID string) error {
 	return uploadDB.Delete([]byte(uploadID), pebble.Sync)
 }
 
+// ListStaleChunkUploads 获取最后活动时间早于 before 的分片上传记录（未合并的废弃上传）
+func (p *PebbleDatabase) ListStaleChunkUploads(before time.Time) ([]*model.TempAppChunkUpload, error) {
+	uploadDB := p.collections[collectionTempAppChunkUpload]
+
+	iter, err := uploadDB.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	stale := make([]*model.TempAppChunkUpload, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var upload model.TempAppChunkUpload
+		if err := json.Unmarshal(iter.Value(), &upload); err != nil {
+			continue
+		}
+
+		// 最后活动时间：优先使用 UpdatedAt，没有则使用 CreatedAt
+		lastActive := upload.UpdatedAt
+		if lastActive.IsZero() {
+			lastActive = upload.CreatedAt
+		}
+		if lastActive.Before(before) {
+			stale = append(stale, &upload)
+		}
+	}
+
+	return stale, nil
+}
+
 // Close close all database connections
+// Waits for in-flight writes so shutdown never leaves an index half-updated
 func (p *PebbleDatabase) Close() error {
+	p.metaAppMu.Lock()
+	defer p.metaAppMu.Unlock()
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+	p.deployMu.Lock()
+	defer p.deployMu.Unlock()
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
 	var lastErr error
-	for name, db := range p.collections {
+	for name, db := range p.instances {
 		if err := db.Close(); err != nil {
 			log.Printf("Failed to close collection %s: %v", name, err)
 			lastErr = err
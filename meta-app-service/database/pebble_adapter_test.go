@@ -0,0 +1,378 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	model "meta-app-service/models"
+
+	"github.com/cockroachdb/pebble"
+)
+
+func newTestPebbleDatabase(t *testing.T) *PebbleDatabase {
+	t.Helper()
+	db, err := NewPebbleDatabase(&PebbleConfig{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.(*PebbleDatabase)
+}
+
+func createTestMetaApp(t *testing.T, db *PebbleDatabase, pinID string, timestamp int64) {
+	t.Helper()
+	app := &model.MetaApp{
+		PinID:         pinID,
+		FirstPinId:    pinID,
+		CreatorMetaId: "creator",
+		OwnerMetaId:   "creator",
+		Timestamp:     timestamp,
+	}
+	if err := db.CreateMetaApp(app); err != nil {
+		t.Fatalf("failed to create MetaApp %s: %v", pinID, err)
+	}
+}
+
+// TestListMetaAppsCursorStableUnderInserts inserts apps between page fetches and checks that every app
+// present before the first fetch is returned exactly once
+func TestListMetaAppsCursorStableUnderInserts(t *testing.T) {
+	db := newTestPebbleDatabase(t)
+
+	// 25 apps, two of them share a timestamp to cover the PinID tie-break
+	want := make(map[string]bool)
+	for i := 0; i < 25; i++ {
+		pinID := fmt.Sprintf("app%02di0", i)
+		timestamp := int64(1000 + i*10)
+		if i == 11 {
+			timestamp = 1000 + 10*10
+		}
+		createTestMetaApp(t, db, pinID, timestamp)
+		want[pinID] = true
+	}
+
+	seen := make(map[string]int)
+	cursor := ""
+	for page := 0; ; page++ {
+		apps, nextCursor, err := db.ListMetaAppsWithCursor(cursor, 7, model.MetaAppListFilter{})
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		for _, app := range apps {
+			seen[app.PinID]++
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+
+		// Newer apps arrive between requests (they sort before the cursor), plus an older one (after it)
+		createTestMetaApp(t, db, fmt.Sprintf("new%02di0", page), int64(5000+page))
+		if page == 0 {
+			createTestMetaApp(t, db, "oldi0", 1)
+		}
+		if page > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+	}
+
+	for pinID := range want {
+		if seen[pinID] != 1 {
+			t.Errorf("app %s returned %d times, want 1", pinID, seen[pinID])
+		}
+	}
+	for pinID, count := range seen {
+		if count > 1 {
+			t.Errorf("app %s duplicated (%d times)", pinID, count)
+		}
+	}
+	if seen["oldi0"] != 1 {
+		t.Errorf("app inserted after the cursor position was not returned")
+	}
+}
+
+func TestListMetaAppsInvalidCursor(t *testing.T) {
+	db := newTestPebbleDatabase(t)
+	createTestMetaApp(t, db, "appi0", 1000)
+
+	for _, cursor := range []string{"abc", "100", "x_appi0"} {
+		if _, _, err := db.ListMetaAppsWithCursor(cursor, 10, model.MetaAppListFilter{}); err != ErrInvalidCursor {
+			t.Errorf("cursor %q: got error %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+
+	// "0" is accepted as the first page for compatibility with offset cursors
+	apps, nextCursor, err := db.ListMetaAppsWithCursor("0", 10, model.MetaAppListFilter{})
+	if err != nil || len(apps) != 1 || nextCursor != "" {
+		t.Errorf("first page: got %d apps, next cursor %q, error %v", len(apps), nextCursor, err)
+	}
+}
+
+// TestConcurrentMetaAppModifies indexes modifies of the same app from several goroutines while reading
+// history and list pages, and checks that no version is lost and the indexes stay consistent
+func TestConcurrentMetaAppModifies(t *testing.T) {
+	db := newTestPebbleDatabase(t)
+	createTestMetaApp(t, db, "appi0", 1000)
+
+	const writers, modifies = 4, 10
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < modifies; i++ {
+				app := &model.MetaApp{
+					PinID:         fmt.Sprintf("mod%d%02di0", w, i),
+					FirstPinId:    "appi0",
+					CreatorMetaId: "creator",
+					OwnerMetaId:   "creator",
+					Timestamp:     int64(2000 + w*100 + i),
+				}
+				if err := db.CreateMetaApp(app); err != nil {
+					t.Errorf("failed to index modify %s: %v", app.PinID, err)
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < modifies; i++ {
+				if _, _, err := db.GetMetaAppHistoryPage("appi0", "", 100); err != nil {
+					t.Errorf("failed to read history: %v", err)
+				}
+				if _, _, err := db.ListMetaAppsWithCursor("", 10, model.MetaAppListFilter{}); err != nil {
+					t.Errorf("failed to list apps: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	history, _, err := db.GetMetaAppHistoryPage("appi0", "", 100)
+	if err != nil || len(history) != writers*modifies+1 {
+		t.Fatalf("history has %d versions (err %v), want %d", len(history), err, writers*modifies+1)
+	}
+	apps, _, err := db.ListMetaAppsWithCursor("", 10, model.MetaAppListFilter{})
+	if err != nil || len(apps) != 1 || apps[0].PinID != "mod309i0" {
+		t.Fatalf("list returned %d apps (err %v), want only the latest version mod309i0", len(apps), err)
+	}
+	report, err := db.CheckIntegrity(false)
+	if err != nil || report.Problems() != 0 {
+		t.Fatalf("integrity check found %+v (err %v), want no problems", report, err)
+	}
+}
+
+func TestGetMetaAppsByAppName(t *testing.T) {
+	db := newTestPebbleDatabase(t)
+	create := func(pinID, firstPinID, creator, appName string, timestamp int64) {
+		t.Helper()
+		app := &model.MetaApp{PinID: pinID, FirstPinId: firstPinID, CreatorMetaId: creator, AppName: appName, Timestamp: timestamp}
+		if err := db.CreateMetaApp(app); err != nil {
+			t.Fatalf("failed to create MetaApp %s: %v", pinID, err)
+		}
+	}
+	byName := func(appName string) []string {
+		t.Helper()
+		apps, _, err := db.GetMetaAppsByAppNameWithCursor(appName, "", 10, model.MetaAppListFilter{})
+		if err != nil {
+			t.Fatalf("failed to query %q: %v", appName, err)
+		}
+		pinIDs := make([]string, 0, len(apps))
+		for _, app := range apps {
+			pinIDs = append(pinIDs, app.PinID)
+		}
+		return pinIDs
+	}
+
+	// Two creators claim the same name; names sharing a prefix are not matched
+	create("a1i0", "a1i0", "alice", "wallet", 1000)
+	create("b1i0", "b1i0", "bob", "wallet", 2000)
+	create("c1i0", "c1i0", "carol", "wallet:pro", 3000)
+	create("d1i0", "d1i0", "dave", "Wallet", 4000)
+	if got := fmt.Sprint(byName("wallet")); got != "[b1i0 a1i0]" {
+		t.Fatalf("apps named wallet: %s, want [b1i0 a1i0]", got)
+	}
+
+	// Renaming through a modify moves the app to the new name
+	create("a2i0", "a1i0", "alice", "alice-wallet", 5000)
+	if got := fmt.Sprint(byName("wallet")); got != "[b1i0]" {
+		t.Fatalf("apps named wallet after rename: %s, want [b1i0]", got)
+	}
+	if got := fmt.Sprint(byName("alice-wallet")); got != "[a2i0]" {
+		t.Fatalf("apps named alice-wallet: %s, want [a2i0]", got)
+	}
+
+	// The index is rebuilt from the latest versions when it is empty (database from an older version)
+	nameDB := db.collections[collectionMetaAppName]
+	if err := nameDB.DeleteRange([]byte{0}, []byte{0xff}, nil); err != nil {
+		t.Fatalf("failed to clear app name index: %v", err)
+	}
+	if err := db.backfillMetaAppName(); err != nil {
+		t.Fatalf("failed to backfill app name index: %v", err)
+	}
+	if got := fmt.Sprint(byName("wallet"), byName("alice-wallet"), byName("wallet:pro")); got != "[b1i0] [a2i0] [c1i0]" {
+		t.Fatalf("apps after backfill: %s", got)
+	}
+	if report, err := db.CheckIntegrity(false); err != nil || report.Problems() != 0 {
+		t.Fatalf("integrity check found %+v (err %v), want no problems", report, err)
+	}
+}
+
+// TestGetLatestDeployByFirstPinID returns the last completed deploy of an app, ignoring later failed deploys
+func TestGetLatestDeployByFirstPinID(t *testing.T) {
+	db := newTestPebbleDatabase(t)
+	if _, err := db.GetLatestDeployByFirstPinID("appi0"); err != ErrNotFound {
+		t.Fatalf("got err %v before any deploy, want ErrNotFound", err)
+	}
+
+	deploys := []*model.MetaAppDeployFileContent{
+		{FirstPinId: "appi0", PinID: "appi0", DeployStatus: "completed", UpdatedAt: time.Unix(1700000000, 0)},
+		{FirstPinId: "appi0", PinID: "mod1i0", DeployStatus: "completed", UpdatedAt: time.Unix(1700000100, 0)},
+		{FirstPinId: "appi0", PinID: "mod2i0", DeployStatus: "failed", UpdatedAt: time.Unix(1700000200, 0)},
+	}
+	for _, deploy := range deploys {
+		if err := db.CreateOrUpdateDeployFileContent(deploy); err != nil {
+			t.Fatalf("failed to record deploy %s: %v", deploy.PinID, err)
+		}
+	}
+
+	deploy, err := db.GetLatestDeployByFirstPinID("appi0")
+	if err != nil || deploy.PinID != "mod1i0" {
+		t.Fatalf("got %+v (err %v), want the completed deploy mod1i0", deploy, err)
+	}
+}
+
+// TestGetMaxMetaAppBlockHeight reads the highest confirmed height per chain from the block height index,
+// which is backfilled from the primary records of older databases
+func TestGetMaxMetaAppBlockHeight(t *testing.T) {
+	db := newTestPebbleDatabase(t)
+	apps := []*model.MetaApp{
+		{PinID: "btc1i0", ChainName: "btc", BlockHeight: 900000, Timestamp: 1},
+		{PinID: "mvc1i0", ChainName: "mvc", BlockHeight: 120, Timestamp: 2},
+		{PinID: "mvc2i0", ChainName: "mvc", BlockHeight: 150, Timestamp: 3},
+		{PinID: "mvc3i0", ChainName: "mvc", Timestamp: 4}, // unconfirmed
+	}
+	for _, app := range apps {
+		if err := db.CreateMetaApp(app); err != nil {
+			t.Fatalf("failed to create MetaApp %s: %v", app.PinID, err)
+		}
+	}
+
+	check := func() {
+		t.Helper()
+		for chainName, want := range map[string]int64{"btc": 900000, "mvc": 150, "doge": 0} {
+			if got, err := db.GetMaxMetaAppBlockHeight(chainName); err != nil || got != want {
+				t.Fatalf("max height of %s is %d (err %v), want %d", chainName, got, err, want)
+			}
+		}
+	}
+	check()
+
+	// Drop the index as in a database created before it existed
+	heightDB := db.collections[collectionMetaAppBlockHeight]
+	if err := heightDB.DeleteRange([]byte{0x00}, []byte{0xff}, nil); err != nil {
+		t.Fatalf("failed to clear block height index: %v", err)
+	}
+	if got, _ := db.GetMaxMetaAppBlockHeight("mvc"); got != 0 {
+		t.Fatalf("max height %d with an empty index, want 0", got)
+	}
+	if err := db.backfillMetaAppBlockHeight(); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+	check()
+}
+
+// TestBackfillMetaAppOwner rebuilds the owner index of a database created before it existed
+func TestBackfillMetaAppOwner(t *testing.T) {
+	db := newTestPebbleDatabase(t)
+	createTestMetaApp(t, db, "app1i0", 1000)
+	createTestMetaApp(t, db, "app2i0", 2000)
+
+	ownerDB := db.collections[collectionMetaAppOwnerTimestamp]
+	if err := ownerDB.DeleteRange([]byte{0x00}, []byte{0xff}, nil); err != nil {
+		t.Fatalf("failed to clear owner index: %v", err)
+	}
+	if apps, _, _ := db.GetMetaAppsByOwnerMetaIDWithCursor("creator", "", 10, model.MetaAppListFilter{}); len(apps) != 0 {
+		t.Fatalf("got %d apps with an empty owner index, want 0", len(apps))
+	}
+
+	if err := db.backfillMetaAppOwner(); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+	apps, _, err := db.GetMetaAppsByOwnerMetaIDWithCursor("creator", "", 10, model.MetaAppListFilter{})
+	if err != nil || len(apps) != 2 || apps[0].PinID != "app2i0" || apps[1].PinID != "app1i0" {
+		t.Fatalf("got %d apps (err %v), want app2i0 and app1i0 newest first", len(apps), err)
+	}
+	report, err := db.CheckIntegrity(false)
+	if err != nil || report.Problems() != 0 {
+		t.Fatalf("integrity check found %+v (err %v), want no problems", report, err)
+	}
+}
+
+// TestMetaAppCollectionsShareOneInstance writes all MetaApp collections in one batch on the shared instance
+// and migrates the per-collection directories of older databases into it
+func TestMetaAppCollectionsShareOneInstance(t *testing.T) {
+	dataDir := t.TempDir()
+
+	// A database from an older version: the primary record in its own directory
+	legacyDB, err := pebble.Open(filepath.Join(dataDir, "indexer_db", collectionMetaAppPinID), &pebble.Options{})
+	if err != nil {
+		t.Fatalf("failed to open legacy collection: %v", err)
+	}
+	if err := legacyDB.Set([]byte("oldi0"), []byte(`{"pin_id":"oldi0"}`), pebble.Sync); err != nil {
+		t.Fatalf("failed to write legacy record: %v", err)
+	}
+	legacyDB.Close()
+
+	opened, err := NewPebbleDatabase(&PebbleConfig{DataDir: dataDir})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db := opened.(*PebbleDatabase)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := os.Stat(filepath.Join(dataDir, "indexer_db", collectionMetaAppPinID)); !os.IsNotExist(err) {
+		t.Fatalf("legacy collection directory still exists (stat err %v)", err)
+	}
+	if _, err := db.GetMetaAppByPinID("oldi0"); err != nil {
+		t.Fatalf("migrated record not found: %v", err)
+	}
+
+	for _, name := range metaAppSharedCollections {
+		if db.collections[name].db != db.instances[metaAppDBName] {
+			t.Fatalf("collection %s is not in the shared instance", name)
+		}
+	}
+
+	// One CreateMetaApp touches several collections but builds a single batch
+	batches := db.newCollectionBatches()
+	batches.get(collectionMetaAppPinID).Set([]byte("newi0"), []byte("{}"), nil)
+	batches.get(collectionMetaAppTimestamp).Set([]byte("1:newi0"), []byte("{}"), nil)
+	if len(batches.shared) != 1 {
+		t.Fatalf("got %d underlying batches for two MetaApp collections, want 1", len(batches.shared))
+	}
+	batches.close()
+
+	createTestMetaApp(t, db, "app1i0", 1000)
+	// Keys stay within their collection's prefix
+	iter, err := db.collections[collectionMetaAppPinID].NewIter(nil)
+	if err != nil {
+		t.Fatalf("failed to iterate: %v", err)
+	}
+	var keys []string
+	for iter.First(); iter.Valid(); iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	iter.Close()
+	if got := fmt.Sprint(keys); got != "[app1i0 oldi0]" {
+		t.Fatalf("primary records %s, want [app1i0 oldi0]", got)
+	}
+	if report, err := db.CheckIntegrity(false); err != nil || report.Apps != 1 {
+		t.Fatalf("integrity check found %+v (err %v), want 1 app", report, err)
+	}
+}
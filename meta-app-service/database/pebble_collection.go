@@ -0,0 +1,202 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// metaAppDBName MetaApp 共享 PebbleDB 实例的目录名（indexer_db/metaapp）
+const metaAppDBName = "metaapp"
+
+// metaAppSharedCollections 共享同一个 PebbleDB 实例的 MetaApp collection（key 加 "{collection}/" 前缀区分）
+// CreateMetaApp 和 CheckIntegrity 写入的都是这些 collection，放在同一实例中才能用一个 Batch 原子提交
+var metaAppSharedCollections = []string{
+	collectionMetaAppPinID,
+	collectionMetaAppPinIDLastest,
+	collectionMetaAppPinIDHistory,
+	collectionMetaAppHistoryArchive,
+	collectionMetaAppMetaIDTimestamp,
+	collectionMetaAppTimestamp,
+	collectionMetaAppOwnerTimestamp,
+	collectionMetaAppName,
+	collectionMetaAppBlockHeight,
+}
+
+// migrateBatchSize 迁移旧 collection 目录时每个 Batch 写入的 key 数
+const migrateBatchSize = 1000
+
+// collection 一个 collection 的 key 空间：独立的 PebbleDB 实例（prefix 为空），或共享实例中带前缀的一段 key
+// 方法与 pebble.DB 对应，key 和迭代边界自动加上前缀，迭代器返回的 key 去掉前缀
+type collection struct {
+	db     *pebble.DB
+	prefix []byte
+}
+
+// key 加上 collection 前缀
+func (c *collection) key(key []byte) []byte {
+	if len(c.prefix) == 0 {
+		return key
+	}
+	prefixed := make([]byte, 0, len(c.prefix)+len(key))
+	return append(append(prefixed, c.prefix...), key...)
+}
+
+func (c *collection) Get(key []byte) ([]byte, io.Closer, error) {
+	return c.db.Get(c.key(key))
+}
+
+func (c *collection) Set(key, value []byte, opts *pebble.WriteOptions) error {
+	return c.db.Set(c.key(key), value, opts)
+}
+
+func (c *collection) Delete(key []byte, opts *pebble.WriteOptions) error {
+	return c.db.Delete(c.key(key), opts)
+}
+
+func (c *collection) DeleteRange(start, end []byte, opts *pebble.WriteOptions) error {
+	return c.db.DeleteRange(c.key(start), c.key(end), opts)
+}
+
+// NewIter 创建迭代器，边界限制在 collection 前缀内（未指定的边界取前缀的上下界）
+func (c *collection) NewIter(opts *pebble.IterOptions) (*collectionIter, error) {
+	if len(c.prefix) > 0 {
+		prefixed := pebble.IterOptions{}
+		if opts != nil {
+			prefixed = *opts
+		}
+		prefixed.LowerBound = c.prefix
+		if opts != nil && opts.LowerBound != nil {
+			prefixed.LowerBound = c.key(opts.LowerBound)
+		}
+		prefixed.UpperBound = prefixUpperBound(c.prefix)
+		if opts != nil && opts.UpperBound != nil {
+			prefixed.UpperBound = c.key(opts.UpperBound)
+		}
+		opts = &prefixed
+	}
+	iter, err := c.db.NewIter(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &collectionIter{iter: iter, c: c}, nil
+}
+
+// NewBatch 创建只包含本 collection 写入的 Batch
+func (c *collection) NewBatch() *collectionBatch {
+	return &collectionBatch{batch: c.db.NewBatch(), c: c}
+}
+
+// prefixUpperBound 以 prefix 开头的 key 的（不包含）上界
+func prefixUpperBound(prefix []byte) []byte {
+	upper := append([]byte(nil), prefix...)
+	for i := len(upper) - 1; i >= 0; i-- {
+		upper[i]++
+		if upper[i] != 0 {
+			return upper[:i+1]
+		}
+	}
+	return nil
+}
+
+// collectionIter collection 迭代器，Key 返回去掉前缀的 key
+type collectionIter struct {
+	iter *pebble.Iterator
+	c    *collection
+}
+
+func (it *collectionIter) First() bool            { return it.iter.First() }
+func (it *collectionIter) Next() bool             { return it.iter.Next() }
+func (it *collectionIter) Valid() bool            { return it.iter.Valid() }
+func (it *collectionIter) SeekGE(key []byte) bool { return it.iter.SeekGE(it.c.key(key)) }
+func (it *collectionIter) Key() []byte            { return it.iter.Key()[len(it.c.prefix):] }
+func (it *collectionIter) Value() []byte          { return it.iter.Value() }
+func (it *collectionIter) Error() error           { return it.iter.Error() }
+func (it *collectionIter) Close() error           { return it.iter.Close() }
+
+// collectionBatch collection 上的 Batch，写入的 key 自动加上前缀
+// 同一实例上的多个 collectionBatch 可以共享一个 pebble.Batch（见 collectionBatches）
+type collectionBatch struct {
+	batch *pebble.Batch
+	c     *collection
+}
+
+func (b *collectionBatch) Set(key, value []byte, opts *pebble.WriteOptions) error {
+	return b.batch.Set(b.c.key(key), value, opts)
+}
+
+func (b *collectionBatch) Delete(key []byte, opts *pebble.WriteOptions) error {
+	return b.batch.Delete(b.c.key(key), opts)
+}
+
+func (b *collectionBatch) Commit(opts *pebble.WriteOptions) error { return b.batch.Commit(opts) }
+func (b *collectionBatch) Empty() bool                            { return b.batch.Empty() }
+func (b *collectionBatch) Count() uint32                          { return b.batch.Count() }
+func (b *collectionBatch) Close() error                           { return b.batch.Close() }
+
+// migrateLegacyCollection 把旧版本中独立目录的 collection（indexer_db/{name}）复制到共享实例的前缀下，完成后删除旧目录
+// 复制是幂等的：中途崩溃时旧目录仍在，下次启动重新复制
+func migrateLegacyCollection(legacyPath string, c *collection) error {
+	if _, err := os.Stat(legacyPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	legacyDB, err := pebble.Open(legacyPath, &pebble.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to open legacy collection at %s: %w", legacyPath, err)
+	}
+	copied, err := copyCollection(legacyDB, c)
+	if closeErr := legacyDB.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(legacyPath); err != nil {
+		return fmt.Errorf("failed to remove legacy collection at %s: %w", legacyPath, err)
+	}
+	log.Printf("Migrated %d keys from %s into the shared %s database", copied, legacyPath, metaAppDBName)
+	return nil
+}
+
+// copyCollection 复制 src 的全部 key 到 collection
+func copyCollection(src *pebble.DB, c *collection) (int, error) {
+	iter, err := src.NewIter(nil)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	batch := c.NewBatch()
+	defer func() { batch.Close() }()
+	copied := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := batch.Set(iter.Key(), iter.Value(), nil); err != nil {
+			return copied, err
+		}
+		copied++
+		if batch.Count() >= migrateBatchSize {
+			if err := batch.Commit(pebble.Sync); err != nil {
+				return copied, err
+			}
+			batch.Close()
+			batch = c.NewBatch()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return copied, err
+	}
+	if !batch.Empty() {
+		if err := batch.Commit(pebble.Sync); err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
@@ -0,0 +1,286 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	model "meta-app-service/models"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// IntegrityReport 索引一致性检查结果
+type IntegrityReport struct {
+	Apps                  int  // 有效的应用数（first_pin_id）
+	HistoryEntriesDropped int  // 历史记录中主记录已缺失的版本数
+	LatestRepaired        int  // 缺失或与历史不一致的最新版本记录数
+	LatestDropped         int  // 没有任何有效版本的最新版本记录数
+	DanglingIndexEntries  int  // 时间戳索引中指向缺失主记录或非最新版本的条目数
+	IndexEntriesRewritten int  // 最新版本缺失或内容过期的时间戳索引条目数
+	Repaired              bool // 是否已写入修复
+}
+
+// Problems 发现的不一致数量
+func (r *IntegrityReport) Problems() int {
+	return r.HistoryEntriesDropped + r.LatestRepaired + r.LatestDropped + r.DanglingIndexEntries + r.IndexEntriesRewritten
+}
+
+// metaAppIndexCollections 按时间戳排序的二级索引（每个 first_pin_id 只有最新版本的一条记录）
+var metaAppIndexCollections = []string{
+	collectionMetaAppMetaIDTimestamp,
+	collectionMetaAppTimestamp,
+	collectionMetaAppOwnerTimestamp,
+	collectionMetaAppName,
+}
+
+// metaAppIndexKeys 计算最新版本在各二级索引中的 key（与 CreateMetaApp 写入的 key 一致）
+func metaAppIndexKeys(app *model.MetaApp, firstPinID string) map[string]string {
+	timestampKey := reverseTimestampKey(app.Timestamp)
+	keys := map[string]string{
+		collectionMetaAppMetaIDTimestamp: app.CreatorMetaId + ":" + timestampKey + ":" + firstPinID,
+		collectionMetaAppTimestamp:       timestampKey + ":" + firstPinID,
+	}
+	if app.OwnerMetaId != "" {
+		keys[collectionMetaAppOwnerTimestamp] = app.OwnerMetaId + ":" + timestampKey + ":" + firstPinID
+	}
+	if app.AppName != "" {
+		keys[collectionMetaAppName] = metaAppNameKey(app.AppName, app.Timestamp, firstPinID)
+	}
+	return keys
+}
+
+// CheckIntegrity 检查 MetaApp 各 collection 之间的一致性，repair 为 true 时写入修复
+// 以 PinID 主记录为准：
+//  1. 删除历史记录中主记录已缺失的版本，历史为空时删除该应用的历史和最新版本
+//  2. 用历史中时间戳最新的版本重建最新版本 collection
+//  3. 删除时间戳索引中不属于最新版本的条目，补齐最新版本缺失的索引条目
+//  4. 按主记录重建区块高度索引（每个已确认版本一条，旧数据库升级后用 --repair 补齐）
+//
+// 检查期间持有 MetaApp 写锁，并发的索引写入会等待检查结束
+func (p *PebbleDatabase) CheckIntegrity(repair bool) (*IntegrityReport, error) {
+	p.metaAppMu.Lock()
+	defer p.metaAppMu.Unlock()
+
+	report := &IntegrityReport{}
+	batches := p.newCollectionBatches()
+	defer batches.close()
+
+	// 1. 历史记录 -> 每个应用的最新版本（使用主记录的内容）
+	latest, err := p.checkMetaAppHistory(batches, report)
+	if err != nil {
+		return nil, err
+	}
+	report.Apps = len(latest)
+
+	// 2. 最新版本 collection
+	if err := p.checkMetaAppLatest(batches, latest, report); err != nil {
+		return nil, err
+	}
+
+	// 3. 时间戳索引
+	expected := make(map[string]map[string][]byte, len(metaAppIndexCollections))
+	for _, collection := range metaAppIndexCollections {
+		expected[collection] = make(map[string][]byte)
+	}
+	for firstPinID, data := range latest {
+		var app model.MetaApp
+		if err := json.Unmarshal(data, &app); err != nil {
+			return nil, fmt.Errorf("failed to decode MetaApp %s: %w", firstPinID, err)
+		}
+		for collection, key := range metaAppIndexKeys(&app, firstPinID) {
+			expected[collection][key] = data
+		}
+	}
+	for _, collection := range metaAppIndexCollections {
+		if err := p.checkMetaAppIndex(batches, collection, expected[collection], report); err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. 区块高度索引
+	heightExpected, err := p.expectedMetaAppHeightIndex()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkMetaAppIndex(batches, collectionMetaAppBlockHeight, heightExpected, report); err != nil {
+		return nil, err
+	}
+
+	if !repair || report.Problems() == 0 {
+		return report, nil
+	}
+	if err := batches.commit(); err != nil {
+		return nil, err
+	}
+	report.Repaired = true
+	return report, nil
+}
+
+// expectedMetaAppHeightIndex 根据 PinID 主记录计算区块高度索引应有的条目（key -> 主记录 JSON）
+func (p *PebbleDatabase) expectedMetaAppHeightIndex() (map[string][]byte, error) {
+	iter, err := p.collections[collectionMetaAppPinID].NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	expected := make(map[string][]byte)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var app model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &app); err != nil || app.BlockHeight <= 0 {
+			continue
+		}
+		expected[metaAppHeightKey(app.BlockHeight, app.PinID)] = bytes.Clone(iter.Value())
+	}
+	return expected, iter.Error()
+}
+
+// checkMetaAppHistory 检查历史记录，返回 first_pin_id -> 最新版本主记录 JSON
+func (p *PebbleDatabase) checkMetaAppHistory(batches *collectionBatches, report *IntegrityReport) (map[string][]byte, error) {
+	pinDB := p.collections[collectionMetaAppPinID]
+	iter, err := p.collections[collectionMetaAppPinIDHistory].NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	latest := make(map[string][]byte)
+	for iter.First(); iter.Valid(); iter.Next() {
+		firstPinID := string(iter.Key())
+		var history []*model.MetaApp
+		if err := json.Unmarshal(iter.Value(), &history); err != nil {
+			log.Printf("Integrity check: dropping undecodable history of %s: %v", firstPinID, err)
+			report.HistoryEntriesDropped++
+			batches.get(collectionMetaAppPinIDHistory).Delete([]byte(firstPinID), nil)
+			continue
+		}
+
+		// 时间戳相同的版本以当前最新版本记录为准（CreateMetaApp 中后写入的生效）
+		currentLatestPinID := ""
+		if data, closer, err := p.collections[collectionMetaAppPinIDLastest].Get([]byte(firstPinID)); err == nil {
+			var current model.MetaApp
+			if json.Unmarshal(data, &current) == nil {
+				currentLatestPinID = current.PinID
+			}
+			closer.Close()
+		}
+
+		kept := make([]*model.MetaApp, 0, len(history))
+		var newest *model.MetaApp
+		var newestData []byte
+		for _, item := range history {
+			data, closer, err := pinDB.Get([]byte(item.PinID))
+			if err == pebble.ErrNotFound {
+				report.HistoryEntriesDropped++
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			data = bytes.Clone(data)
+			closer.Close()
+
+			var app model.MetaApp
+			if err := json.Unmarshal(data, &app); err != nil {
+				report.HistoryEntriesDropped++
+				continue
+			}
+			kept = append(kept, &app)
+			if newest == nil || app.Timestamp > newest.Timestamp || (app.Timestamp == newest.Timestamp && app.PinID == currentLatestPinID) {
+				newest, newestData = &app, data
+			}
+		}
+
+		if len(kept) == 0 {
+			batches.get(collectionMetaAppPinIDHistory).Delete([]byte(firstPinID), nil)
+			continue
+		}
+		if len(kept) != len(history) {
+			sort.Slice(kept, func(i, j int) bool {
+				return kept[i].Timestamp > kept[j].Timestamp
+			})
+			historyData, err := json.Marshal(kept)
+			if err != nil {
+				return nil, err
+			}
+			batches.get(collectionMetaAppPinIDHistory).Set([]byte(firstPinID), historyData, nil)
+		}
+		latest[firstPinID] = newestData
+	}
+	return latest, iter.Error()
+}
+
+// checkMetaAppLatest 检查最新版本 collection 与历史是否一致
+func (p *PebbleDatabase) checkMetaAppLatest(batches *collectionBatches, latest map[string][]byte, report *IntegrityReport) error {
+	iter, err := p.collections[collectionMetaAppPinIDLastest].NewIter(nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	seen := make(map[string]bool, len(latest))
+	for iter.First(); iter.Valid(); iter.Next() {
+		firstPinID := string(iter.Key())
+		data, ok := latest[firstPinID]
+		if !ok {
+			report.LatestDropped++
+			batches.get(collectionMetaAppPinIDLastest).Delete([]byte(firstPinID), nil)
+			continue
+		}
+		seen[firstPinID] = true
+		if !bytes.Equal(iter.Value(), data) {
+			report.LatestRepaired++
+			batches.get(collectionMetaAppPinIDLastest).Set([]byte(firstPinID), data, nil)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	for firstPinID, data := range latest {
+		if !seen[firstPinID] {
+			report.LatestRepaired++
+			batches.get(collectionMetaAppPinIDLastest).Set([]byte(firstPinID), data, nil)
+		}
+	}
+	return nil
+}
+
+// checkMetaAppIndex 检查一个时间戳索引：删除多余的条目，补齐缺失或内容过期的条目
+func (p *PebbleDatabase) checkMetaAppIndex(batches *collectionBatches, collection string, expected map[string][]byte, report *IntegrityReport) error {
+	iter, err := p.collections[collection].NewIter(nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	seen := make(map[string]bool, len(expected))
+	for iter.First(); iter.Valid(); iter.Next() {
+		key := string(iter.Key())
+		data, ok := expected[key]
+		if !ok {
+			report.DanglingIndexEntries++
+			batches.get(collection).Delete([]byte(key), nil)
+			continue
+		}
+		seen[key] = true
+		if !bytes.Equal(iter.Value(), data) {
+			report.IndexEntriesRewritten++
+			batches.get(collection).Set([]byte(key), data, nil)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	for key, data := range expected {
+		if !seen[key] {
+			report.IndexEntriesRewritten++
+			batches.get(collection).Set([]byte(key), data, nil)
+		}
+	}
+	return nil
+}
@@ -2,13 +2,22 @@ package indexer
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"meta-app-service/metrics"
 	"meta-app-service/tool"
 
 	"github.com/bitcoinsv/bsvd/wire"
@@ -22,36 +31,106 @@ type BlockScanner struct {
 	rpcUser     string
 	rpcPassword string
 	startHeight int64
-	interval    time.Duration
-	chainType   ChainType // Chain type: btc or mvc
+	interval    atomic.Int64 // Scan interval (time.Duration), updated at runtime by SetInterval
+	chainType   ChainType    // Chain type: btc or mvc
 	progressBar *progressbar.ProgressBar
 	zmqClient   *ZMQClient // ZMQ client for real-time transaction monitoring
 	zmqEnabled  bool       // Whether ZMQ is enabled
+
+	scanConcurrency int          // Number of blocks fetched concurrently ahead of the committed height
+	batchSize       int          // Number of handled blocks per sync status commit (onBlockComplete call)
+	txCache         *rawTxCache  // LRU cache for GetRawTransaction results
+	rpcBatchEnabled bool         // Fetch blocks with batched JSON-RPC calls
+	confirmations   int64        // Number of most recent blocks left unscanned until they are deep enough
+	httpClient      *http.Client // HTTP client for RPC calls (carries the RPC timeout)
+	rpcMaxRetries   int          // Max retries of an RPC call on transient errors (network errors, 5xx)
+	restURL         string       // Base URL of the node's REST interface, blocks are fetched as raw binary when set
+	node            NodeClient   // Node calls (JSON-RPC by default, replaceable with SetNodeClient)
+
+	paused        atomic.Bool   // Whether scanning is paused by the operator
+	holds         atomic.Int32  // Internal jobs (e.g. a manual rescan) holding the scan loop, independent of the operator pause
+	currentHeight atomic.Int64  // Next block height to scan
+	zmqActive     atomic.Bool   // Whether ZMQ real-time monitoring is running
+	scanRate      atomic.Uint64 // Blocks per second while catching up (float64 bits, exponential moving average)
+
+	nodeProbed    atomic.Bool  // Whether any RPC call has completed (reachability is unknown before)
+	nodeReachable atomic.Bool  // Whether the last RPC call reached the node
+	nodeFailures  atomic.Int64 // Consecutive RPC calls that could not reach the node
+	nodeLastError atomic.Value // Last error (string) of an RPC call that could not reach the node
+	scanHalted    atomic.Value // Reason (string) scanning stopped for good, e.g. the node pruned the next block
+
+	newBlock chan struct{} // Signaled by ZMQ hashblock notifications to wake the scan loop early
+}
+
+// defaultRawTxCacheSize default number of raw transactions kept in the LRU cache
+const defaultRawTxCacheSize = 10000
+
+const (
+	// defaultRPCTimeout default HTTP timeout of a single RPC call
+	defaultRPCTimeout = 30 * time.Second
+	// defaultRPCMaxRetries default number of retries of an RPC call on transient errors
+	defaultRPCMaxRetries = 3
+	// maxRPCRetryBackoff upper bound of the backoff between RPC retries
+	maxRPCRetryBackoff = 10 * time.Second
+	// maxScanErrorBackoff upper bound of the scan loop's backoff while the node keeps failing
+	maxScanErrorBackoff = 5 * time.Minute
+	// scanRateSmoothing weight of the latest batch in the scan rate moving average
+	scanRateSmoothing = 0.2
+)
+
+// ErrBlockPruned the node no longer has the requested block (pruned node, height below its prune horizon)
+var ErrBlockPruned = errors.New("block not available (pruned)")
+
+// NodeHealth reachability of the chain node as seen by the scanner
+type NodeHealth struct {
+	Probed              bool   `json:"probed"` // Whether any RPC call has completed yet, Reachable is meaningless before
+	Reachable           bool   `json:"reachable"`
+	ConsecutiveFailures int64  `json:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty"`
+	ScanHalted          string `json:"scan_halted,omitempty"` // Why scanning stopped and cannot resume without a config change
 }
 
 // NewBlockScanner create block scanner (default MVC)
 func NewBlockScanner(rpcURL, rpcUser, rpcPassword string, startHeight int64, interval int) *BlockScanner {
-	return &BlockScanner{
+	s := &BlockScanner{
 		rpcURL:      rpcURL,
 		rpcUser:     rpcUser,
 		rpcPassword: rpcPassword,
 		startHeight: startHeight,
-		interval:    time.Duration(interval) * time.Second,
 		chainType:   ChainTypeMVC,
+
+		scanConcurrency: 1,
+		batchSize:       1,
+		txCache:         newRawTxCache(defaultRawTxCacheSize),
+		httpClient:      tool.NewHTTPClient(defaultRPCTimeout),
+		rpcMaxRetries:   defaultRPCMaxRetries,
+		newBlock:        make(chan struct{}, 1),
 	}
+	s.node = &rpcNodeClient{s: s}
+	s.SetInterval(interval)
+	return s
 }
 
 // NewBlockScannerWithChain create block scanner with specified chain type
 func NewBlockScannerWithChain(rpcURL, rpcUser, rpcPassword string, startHeight int64, interval int, chainType ChainType) *BlockScanner {
-	return &BlockScanner{
+	s := &BlockScanner{
 		rpcURL:      rpcURL,
 		rpcUser:     rpcUser,
 		rpcPassword: rpcPassword,
 		startHeight: startHeight,
-		interval:    time.Duration(interval) * time.Second,
 		chainType:   chainType,
 		zmqEnabled:  false,
+
+		scanConcurrency: 1,
+		batchSize:       1,
+		txCache:         newRawTxCache(defaultRawTxCacheSize),
+		httpClient:      tool.NewHTTPClient(defaultRPCTimeout),
+		rpcMaxRetries:   defaultRPCMaxRetries,
+		newBlock:        make(chan struct{}, 1),
 	}
+	s.node = &rpcNodeClient{s: s}
+	s.SetInterval(interval)
+	return s
 }
 
 // EnableZMQ enable ZMQ real-time transaction monitoring
@@ -61,6 +140,173 @@ func (s *BlockScanner) EnableZMQ(zmqAddress string) {
 	log.Printf("ZMQ enabled for %s chain: %s", s.chainType, zmqAddress)
 }
 
+// SetInterval set scan interval in seconds, safe to call while the scanner is running (takes effect on the next wait)
+func (s *BlockScanner) SetInterval(seconds int) {
+	s.interval.Store(int64(time.Duration(seconds) * time.Second))
+}
+
+// scanInterval get current scan interval
+func (s *BlockScanner) scanInterval() time.Duration {
+	return time.Duration(s.interval.Load())
+}
+
+// SetScanConcurrency set number of blocks fetched concurrently during catch-up
+// Blocks are still handled and committed in strict height order
+func (s *BlockScanner) SetScanConcurrency(concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	s.scanConcurrency = concurrency
+}
+
+// SetBatchSize set number of blocks handled between sync status commits during catch-up
+// onBlockComplete is called once per batch with the last handled height (and before pausing, backing off
+// on a fetch error or waiting at the tip), so a restart may re-handle up to batchSize-1 blocks
+func (s *BlockScanner) SetBatchSize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	s.batchSize = size
+}
+
+// EnableRPCBatch fetch blocks with batched JSON-RPC calls during catch-up
+// Only enable it for nodes that support JSON-RPC batch requests
+func (s *BlockScanner) EnableRPCBatch() {
+	s.rpcBatchEnabled = true
+}
+
+// SetRawTxCacheSize set capacity of the raw transaction LRU cache
+func (s *BlockScanner) SetRawTxCacheSize(size int) {
+	if size < 1 {
+		size = defaultRawTxCacheSize
+	}
+	s.txCache = newRawTxCache(size)
+}
+
+// SetConfirmations set confirmation depth, the scanner only indexes blocks up to latestHeight - confirmations
+// Blocks closer to the tip are reorg-prone and are picked up once they are deep enough
+func (s *BlockScanner) SetConfirmations(confirmations int64) {
+	if confirmations < 0 {
+		confirmations = 0
+	}
+	s.confirmations = confirmations
+}
+
+// Confirmations get confirmation depth
+func (s *BlockScanner) Confirmations() int64 {
+	return s.confirmations
+}
+
+// SetRPCTimeout set HTTP timeout of a single RPC call (0 keeps the default)
+func (s *BlockScanner) SetRPCTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		s.httpClient = tool.NewHTTPClient(timeout)
+	}
+}
+
+// SetRPCMaxRetries set max retries of an RPC call on transient errors (0 disables retrying)
+func (s *BlockScanner) SetRPCMaxRetries(retries int) {
+	if retries < 0 {
+		retries = 0
+	}
+	s.rpcMaxRetries = retries
+}
+
+// SetRESTURL set base URL of the node's REST interface (e.g. bitcoind started with -rest)
+// Blocks are then fetched as raw binary from {url}/rest/block/{hash}.bin, halving the transfer compared to
+// JSON-RPC hex; an empty URL keeps the JSON-RPC path
+func (s *BlockScanner) SetRESTURL(url string) {
+	s.restURL = strings.TrimRight(url, "/")
+}
+
+// SetStartHeight set the height scanning starts from (only effective before Start)
+func (s *BlockScanner) SetStartHeight(height int64) {
+	s.startHeight = height
+}
+
+// SetNodeClient replace the node calls of the scanner (JSON-RPC to rpcURL by default)
+// Batched JSON-RPC and REST block fetching talk to the node directly and are skipped with a custom client
+func (s *BlockScanner) SetNodeClient(client NodeClient) {
+	if client == nil {
+		client = &rpcNodeClient{s: s}
+	}
+	s.node = client
+}
+
+// usesRPCNode check whether node calls go to the configured JSON-RPC node
+func (s *BlockScanner) usesRPCNode() bool {
+	_, ok := s.node.(*rpcNodeClient)
+	return ok
+}
+
+// ChainType get the chain this scanner is scanning
+func (s *BlockScanner) ChainType() ChainType {
+	return s.chainType
+}
+
+// Pause pause block scanning, the scanner stops advancing height until resumed
+func (s *BlockScanner) Pause() {
+	if !s.paused.Swap(true) {
+		log.Printf("Block scanner paused (chain: %s)", s.chainType)
+	}
+}
+
+// Resume resume block scanning
+func (s *BlockScanner) Resume() {
+	if s.paused.Swap(false) {
+		log.Printf("Block scanner resumed (chain: %s)", s.chainType)
+	}
+}
+
+// IsPaused check whether block scanning is paused by the operator
+func (s *BlockScanner) IsPaused() bool {
+	return s.paused.Load()
+}
+
+// Hold stop the scan loop for an internal job (e.g. a manual rescan) until Release
+// Holds do not touch the operator pause: releasing never resumes a scanner paused with Pause
+func (s *BlockScanner) Hold() {
+	s.holds.Add(1)
+}
+
+// Release release a Hold
+func (s *BlockScanner) Release() {
+	s.holds.Add(-1)
+}
+
+// isSuspended check whether the scan loop must not advance (paused or held)
+func (s *BlockScanner) isSuspended() bool {
+	return s.paused.Load() || s.holds.Load() > 0
+}
+
+// CurrentHeight get next block height to scan
+func (s *BlockScanner) CurrentHeight() int64 {
+	return s.currentHeight.Load()
+}
+
+// ScanRate get the scan rate in blocks per second (exponential moving average over recent batches)
+// Returns 0 until the first batch has been scanned; the last rate is kept while the scanner is idle at the tip
+func (s *BlockScanner) ScanRate() float64 {
+	return math.Float64frombits(s.scanRate.Load())
+}
+
+// recordScanRate fold a batch of blocks committed in elapsed into the scan rate moving average
+func (s *BlockScanner) recordScanRate(blocks int, elapsed time.Duration) {
+	if blocks <= 0 || elapsed <= 0 {
+		return
+	}
+	rate := float64(blocks) / elapsed.Seconds()
+	if prev := s.ScanRate(); prev > 0 {
+		rate = prev + scanRateSmoothing*(rate-prev)
+	}
+	s.scanRate.Store(math.Float64bits(rate))
+}
+
+// IsZMQActive check whether ZMQ real-time monitoring is running
+func (s *BlockScanner) IsZMQActive() bool {
+	return s.zmqActive.Load()
+}
+
 // SetZMQTransactionHandler set handler for ZMQ transactions
 func (s *BlockScanner) SetZMQTransactionHandler(handler func(tx interface{}, metaDataTx *MetaIDDataTx) error) {
 	if s.zmqClient != nil {
@@ -89,110 +335,276 @@ type RPCError struct {
 	Message string `json:"message"`
 }
 
-// GetBlockCount get current block height
-func (s *BlockScanner) GetBlockCount() (int64, error) {
-	request := RPCRequest{
-		Jsonrpc: "1.0",
-		ID:      "getblockcount",
-		Method:  "getblockcount",
-		Params:  []interface{}{},
+// NodeHealth get node reachability based on the most recent RPC calls
+func (s *BlockScanner) NodeHealth() NodeHealth {
+	health := NodeHealth{
+		Probed:              s.nodeProbed.Load(),
+		Reachable:           s.nodeReachable.Load(),
+		ConsecutiveFailures: s.nodeFailures.Load(),
+	}
+	if !health.Reachable {
+		health.LastError, _ = s.nodeLastError.Load().(string)
 	}
+	health.ScanHalted, _ = s.scanHalted.Load().(string)
+	return health
+}
 
-	response, err := s.rpcCall(request)
-	if err != nil {
-		return 0, err
+// blockRPCError build the error of a failed getblock call, wrapping ErrBlockPruned when the node pruned the block
+func blockRPCError(height int64, message string) error {
+	if strings.Contains(strings.ToLower(message), "pruned") {
+		if height >= 0 {
+			return fmt.Errorf("%w: block %d: %s", ErrBlockPruned, height, message)
+		}
+		return fmt.Errorf("%w: %s", ErrBlockPruned, message)
+	}
+	if height >= 0 {
+		return fmt.Errorf("rpc error for block %d: %s", height, message)
 	}
+	return fmt.Errorf("rpc error: %s", message)
+}
 
-	if response.Error != nil {
-		return 0, fmt.Errorf("rpc error: %s", response.Error.Message)
+// haltOnPrunedBlock stop scanning for good when the node has pruned the next block
+// Retrying cannot succeed, the start height has to be raised above the node's prune horizon
+func (s *BlockScanner) haltOnPrunedBlock(height int64, err error) {
+	reason := fmt.Sprintf("block %d is not available on the %s node (pruned): set indexer.%s_init_block_height "+
+		"above the node's prune height (see getblockchaininfo pruneheight) or use an unpruned node, then restart", height, s.chainType, s.chainType)
+	s.scanHalted.Store(reason)
+	log.Printf("\n❌ Block scanning stopped (chain: %s): %s (%v)", s.chainType, reason, err)
+}
+
+// recordNodeResult update node reachability after an RPC call
+// RPC error responses still count as reachable, only transient errors (connection, timeout, 5xx) mark the node down
+func (s *BlockScanner) recordNodeResult(err error) {
+	s.nodeProbed.Store(true)
+	if err == nil || !errors.Is(err, errTransientRPC) {
+		s.nodeReachable.Store(true)
+		s.nodeFailures.Store(0)
+		return
 	}
+	s.nodeReachable.Store(false)
+	s.nodeFailures.Add(1)
+	s.nodeLastError.Store(err.Error())
+}
 
-	height, ok := response.Result.(float64)
-	if !ok {
-		return 0, errors.New("invalid block height response")
+// WaitForNode check the node is reachable, retrying up to attempts times with exponential backoff
+// Returns the last error when the node is still unreachable after all attempts
+func (s *BlockScanner) WaitForNode(attempts int) (int64, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		height, err := s.GetBlockCount()
+		if err == nil {
+			return height, nil
+		}
+		if attempt >= attempts {
+			return 0, fmt.Errorf("node unreachable after %d attempts (chain: %s): %w", attempts, s.chainType, err)
+		}
+		log.Printf("Node not reachable (attempt %d/%d, chain: %s): %v, retrying in %s", attempt, attempts, s.chainType, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxRPCRetryBackoff {
+			backoff = maxRPCRetryBackoff
+		}
 	}
+}
 
-	return int64(height), nil
+// GetBlockCount get current block height (scan loop call, retried on transient errors)
+func (s *BlockScanner) GetBlockCount() (int64, error) {
+	return s.node.GetBlockCount(scanContext)
+}
+
+// GetBlockCountContext get current block height with a single attempt bounded by ctx (for request handlers)
+func (s *BlockScanner) GetBlockCountContext(ctx context.Context) (int64, error) {
+	return s.node.GetBlockCount(ctx)
 }
 
-// GetBlockHash get block hash
+// GetBlockhash get block hash (scan loop call, retried on transient errors)
 func (s *BlockScanner) GetBlockhash(height int64) (string, error) {
+	return s.node.GetBlockhash(scanContext, height)
+}
+
+// BlockHeader lightweight block header info returned by getblockheader
+type BlockHeader struct {
+	Hash          string // Block hash
+	PrevHash      string // Previous block hash (empty for genesis)
+	Height        int64  // Block height
+	Timestamp     int64  // Block time (unix seconds)
+	TxCount       int    // Number of transactions in the block
+	Confirmations int64  // Number of confirmations (-1 if the block is not on the main chain)
+}
+
+// GetBlockHeader get block header info by height
+// Uses getblockheader (verbose) so callers that only need hash/prevhash/time/tx count
+// do not have to download and deserialize the full block body
+func (s *BlockScanner) GetBlockHeader(height int64) (*BlockHeader, error) {
+	return s.GetBlockHeaderContext(scanContext, height)
+}
+
+// GetBlockHeaderContext get block header info by height, bounded by ctx (a single attempt unless ctx is the scan loop's)
+func (s *BlockScanner) GetBlockHeaderContext(ctx context.Context, height int64) (*BlockHeader, error) {
+	blockhash, err := s.node.GetBlockhash(ctx, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block hash: %w", err)
+	}
+
 	request := RPCRequest{
 		Jsonrpc: "1.0",
-		ID:      "getblockhash",
-		Method:  "getblockhash",
-		Params:  []interface{}{height},
+		ID:      "getblockheader",
+		Method:  "getblockheader",
+		Params:  []interface{}{blockhash, true}, // verbose=true return JSON object
 	}
 
-	response, err := s.rpcCall(request)
+	response, err := s.rpcCall(ctx, request)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if response.Error != nil {
-		return "", fmt.Errorf("rpc error: %s", response.Error.Message)
+		return nil, fmt.Errorf("rpc error: %s", response.Error.Message)
 	}
 
-	hash, ok := response.Result.(string)
+	result, ok := response.Result.(map[string]interface{})
 	if !ok {
-		return "", errors.New("invalid block hash response")
+		return nil, errors.New("invalid block header response")
+	}
+
+	header := &BlockHeader{Hash: blockhash, Height: height}
+	if prevHash, ok := result["previousblockhash"].(string); ok {
+		header.PrevHash = prevHash
+	}
+	if timestamp, ok := result["time"].(float64); ok {
+		header.Timestamp = int64(timestamp)
+	}
+	if confirmations, ok := result["confirmations"].(float64); ok {
+		header.Confirmations = int64(confirmations)
+	}
+	// Bitcoin Core reports the tx count as "nTx", BSV-based nodes (MVC) as "num_tx"
+	if txCount, ok := result["nTx"].(float64); ok {
+		header.TxCount = int(txCount)
+	} else if txCount, ok := result["num_tx"].(float64); ok {
+		header.TxCount = int(txCount)
 	}
 
-	return hash, nil
+	return header, nil
 }
 
-// GetBlockHex get block hex data
-// verbosity=0 returns raw block hex
+// GetBlockHex get raw block hex data
 func (s *BlockScanner) GetBlockHex(blockhash string) (string, error) {
-	request := RPCRequest{
-		Jsonrpc: "1.0",
-		ID:      "getblock",
-		Method:  "getblock",
-		Params:  []interface{}{blockhash, 0}, // verbosity=0 return raw hex
+	return s.node.GetBlockHex(scanContext, blockhash)
+}
+
+// GetBlockBytesREST get raw block bytes from the node's REST interface ({restURL}/rest/block/{hash}.bin)
+// gzip-compressed responses (Content-Encoding: gzip, or a gzip body from a compressing proxy) are decompressed;
+// transient failures are retried like RPC calls
+func (s *BlockScanner) GetBlockBytesREST(blockhash string) ([]byte, error) {
+	if s.restURL == "" {
+		return nil, errors.New("rest url not configured")
 	}
+	url := s.restURL + "/rest/block/" + blockhash + ".bin"
+
+	start := time.Now()
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		blockBytes, err := s.restGetOnce(url)
+		if err == nil || !errors.Is(err, errTransientRPC) || attempt >= s.rpcMaxRetries {
+			result := "success"
+			if err != nil {
+				result = "error"
+			}
+			metrics.RPCDuration.WithLabelValues(string(s.chainType), "rest_block", result).Observe(time.Since(start).Seconds())
+			return blockBytes, err
+		}
 
-	response, err := s.rpcCall(request)
+		log.Printf("REST block %s failed (attempt %d/%d, chain: %s): %v, retrying in %s", blockhash, attempt+1, s.rpcMaxRetries+1, s.chainType, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxRPCRetryBackoff {
+			backoff = maxRPCRetryBackoff
+		}
+	}
+}
+
+// restGetOnce send one REST GET request and return the (decompressed) response body
+func (s *BlockScanner) restGetOnce(url string) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	// Setting Accept-Encoding explicitly disables the transport's transparent decompression, handled below
+	request.Header.Set("Accept-Encoding", "gzip")
 
-	if response.Error != nil {
-		return "", fmt.Errorf("rpc error: %s", response.Error.Message)
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errTransientRPC, err)
 	}
+	defer response.Body.Close()
 
-	blockHex, ok := response.Result.(string)
-	if !ok {
-		return "", errors.New("invalid block hex response")
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read response: %v", errTransientRPC, err)
+	}
+	if response.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: http status %d", errTransientRPC, response.StatusCode)
+	}
+	if response.StatusCode != http.StatusOK {
+		// 404 when the block is unknown or pruned, 403/404 when the node runs without -rest
+		return nil, fmt.Errorf("http status %d: %s", response.StatusCode, strings.TrimSpace(string(respBody)))
 	}
 
-	return blockHex, nil
+	// A serialized block starts with its version, never with the gzip magic bytes
+	if strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") || bytes.HasPrefix(respBody, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(respBody))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress block: %w", err)
+		}
+		defer reader.Close()
+		if respBody, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("failed to decompress block: %w", err)
+		}
+	}
+	if len(respBody) == 0 {
+		return nil, errors.New("empty block response")
+	}
+	return respBody, nil
 }
 
-// GetRawTransaction get raw transaction by txid
-// verbosity=0 returns raw transaction hex
+// gzipMagic leading bytes of a gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// GetRawTransaction get raw transaction hex by txid
+// Results are kept in an LRU cache since blocks often reference the same funding transaction
 func (s *BlockScanner) GetRawTransaction(txid string) (string, error) {
-	request := RPCRequest{
-		Jsonrpc: "1.0",
-		ID:      "getrawtransaction",
-		Method:  "getrawtransaction",
-		Params:  []interface{}{txid, 0}, // verbosity=0 return raw hex
+	return s.GetRawTransactionContext(scanContext, txid)
+}
+
+// GetRawTransactionContext get raw transaction hex by txid, bounded by ctx (a single attempt unless ctx is the scan loop's)
+func (s *BlockScanner) GetRawTransactionContext(ctx context.Context, txid string) (string, error) {
+	if txHex, ok := s.txCache.Get(txid); ok {
+		metrics.RawTxCacheRequests.WithLabelValues("hit").Inc()
+		s.logRawTxCacheStats()
+		return txHex, nil
 	}
+	metrics.RawTxCacheRequests.WithLabelValues("miss").Inc()
+	s.logRawTxCacheStats()
 
-	response, err := s.rpcCall(request)
+	txHex, err := s.node.GetRawTransaction(ctx, txid)
 	if err != nil {
 		return "", err
 	}
 
-	if response.Error != nil {
-		return "", fmt.Errorf("rpc error: %s", response.Error.Message)
-	}
+	s.txCache.Add(txid, txHex)
+	return txHex, nil
+}
 
-	txHex, ok := response.Result.(string)
-	if !ok {
-		return "", errors.New("invalid transaction hex response")
+// logRawTxCacheStats log raw transaction cache hit ratio every 1000 lookups
+func (s *BlockScanner) logRawTxCacheStats() {
+	hits, misses := s.txCache.Stats()
+	total := hits + misses
+	if total%1000 == 0 {
+		log.Printf("Raw tx cache: %d lookups, hit ratio %.2f%% (chain: %s)", total, float64(hits)/float64(total)*100, s.chainType)
 	}
-
-	return txHex, nil
 }
 
 // GetBlockMsg get block message (MsgBlock) with all transactions
@@ -204,18 +616,39 @@ func (s *BlockScanner) GetBlockMsg(height int64) (interface{}, int, error) {
 		return nil, 0, fmt.Errorf("failed to get block hash: %w", err)
 	}
 
+	// Prefer raw binary over REST, falling back to JSON-RPC hex (REST disabled on the node, unreachable...)
+	if s.restURL != "" && s.usesRPCNode() {
+		blockBytes, err := s.GetBlockBytesREST(blockhash)
+		if err == nil {
+			return s.decodeBlockBytes(blockBytes)
+		}
+		log.Printf("REST block fetch failed at height %d (chain: %s), falling back to JSON-RPC: %v", height, s.chainType, err)
+	}
+
 	// Get block hex
 	blockHex, err := s.GetBlockHex(blockhash)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get block hex: %w", err)
 	}
 
+	return s.decodeBlockHex(blockHex)
+}
+
+// decodeBlockHex decode raw block hex into block message
+// Returns interface{} which can be *wire.MsgBlock (MVC) or *btcwire.MsgBlock (BTC)
+func (s *BlockScanner) decodeBlockHex(blockHex string) (interface{}, int, error) {
 	// Decode hex to bytes
 	blockBytes, err := hex.DecodeString(blockHex)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to decode block hex: %w", err)
 	}
 
+	return s.decodeBlockBytes(blockBytes)
+}
+
+// decodeBlockBytes deserialize raw block bytes into block message
+// Returns interface{} which can be *wire.MsgBlock (MVC) or *btcwire.MsgBlock (BTC)
+func (s *BlockScanner) decodeBlockBytes(blockBytes []byte) (interface{}, int, error) {
 	// Deserialize based on chain type
 	if s.chainType == ChainTypeBTC {
 		// Parse as BTC block
@@ -236,110 +669,249 @@ func (s *BlockScanner) GetBlockMsg(height int64) (interface{}, int, error) {
 	}
 }
 
+// scannedTx MetaID transaction parsed from a block
+type scannedTx struct {
+	tx         interface{}
+	metaDataTx *MetaIDDataTx
+}
+
+// scannedBlock block fetched and parsed, ready to be handled in height order
+type scannedBlock struct {
+	height    int64
+	timestamp int64
+	txCount   int
+	pinCount  int
+	txs       []scannedTx
+}
+
 // ScanBlock scan specified block
 // handler accepts interface{} for tx to support both BTC and MVC
 // Returns the number of processed MetaID transactions
 func (s *BlockScanner) ScanBlock(height int64, handler func(tx interface{}, metaDataTx *MetaIDDataTx, height, timestamp int64) error) (int, error) {
+	block, err := s.fetchBlock(height)
+	if err != nil {
+		return 0, err
+	}
+	return s.handleBlock(block, handler), nil
+}
+
+// fetchBlock fetch block from node and parse MetaID transactions
+// It has no side effects, so multiple blocks can be fetched concurrently
+func (s *BlockScanner) fetchBlock(height int64) (*scannedBlock, error) {
 	// Get block message with all transactions
 	msgBlockInterface, txCount, err := s.GetBlockMsg(height)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get block message: %w", err)
+		return nil, fmt.Errorf("failed to get block message: %w", err)
 	}
 
-	// log.Printf("Scanning block at height %d, transaction count: %d (chain: %s)", height, txCount, s.chainType)
+	return s.parseBlock(height, msgBlockInterface, txCount)
+}
 
-	processedCount := 0
-	metaidPinCount := 0
+// parseBlock parse MetaID transactions of a block message
+func (s *BlockScanner) parseBlock(height int64, msgBlockInterface interface{}, txCount int) (*scannedBlock, error) {
+	block := &scannedBlock{
+		height:  height,
+		txCount: txCount,
+	}
 
 	// Create parser
 	parser := NewMetaIDParser("")
 
-	// Process transactions based on chain type
+	// Collect transactions based on chain type
+	var txs []interface{}
 	if s.chainType == ChainTypeBTC {
 		// BTC block
 		btcBlock, ok := msgBlockInterface.(*btcwire.MsgBlock)
 		if !ok {
-			return 0, errors.New("invalid BTC block type")
+			return nil, errors.New("invalid BTC block type")
 		}
-		timestamp := btcBlock.Header.Timestamp.UnixMilli()
-
-		// Traverse transactions
+		block.timestamp = btcBlock.Header.Timestamp.UnixMilli()
 		for _, tx := range btcBlock.Transactions {
-			// Parse MetaID data
-			metaDataTx, err := parser.ParseAllPINs(tx, ChainTypeBTC)
-			if err != nil {
-				// not MetaID transaction, skip
-				continue
-			}
-			if metaDataTx == nil {
-				// not MetaID transaction, skip
-				continue
-			}
-			metaidPinCount += len(metaDataTx.MetaIDData)
-
-			// Call handler
-			if err := handler(tx, metaDataTx, height, timestamp); err != nil {
-				log.Printf("Failed to handle BTC transaction %s: %v", metaDataTx.TxID, err)
-			} else {
-				processedCount++
-			}
+			txs = append(txs, tx)
 		}
 	} else {
 		// MVC block
 		mvcBlock, ok := msgBlockInterface.(*wire.MsgBlock)
 		if !ok {
-			return 0, errors.New("invalid MVC block type")
+			return nil, errors.New("invalid MVC block type")
 		}
-		timestamp := mvcBlock.Header.Timestamp.UnixMilli()
-
-		// Traverse transactions
+		block.timestamp = mvcBlock.Header.Timestamp.UnixMilli()
 		for _, tx := range mvcBlock.Transactions {
-			// Parse MetaID data
-			metaDataTx, err := parser.ParseAllPINs(tx, ChainTypeMVC)
+			txs = append(txs, tx)
+		}
+	}
+
+	// Traverse transactions
+	for _, tx := range txs {
+		// Parse MetaID data
+		metaDataTx, err := parser.ParseAllPINs(tx, s.chainType)
+		if err != nil || metaDataTx == nil {
+			// not MetaID transaction, skip
+			continue
+		}
+		block.pinCount += len(metaDataTx.MetaIDData)
+		block.txs = append(block.txs, scannedTx{tx: tx, metaDataTx: metaDataTx})
+	}
+
+	return block, nil
+}
+
+// handleBlock call handler for every MetaID transaction of a fetched block
+// Returns the number of processed MetaID transactions
+func (s *BlockScanner) handleBlock(block *scannedBlock, handler func(tx interface{}, metaDataTx *MetaIDDataTx, height, timestamp int64) error) int {
+	processedCount := 0
+	for _, stx := range block.txs {
+		// Call handler
+		if err := handler(stx.tx, stx.metaDataTx, block.height, block.timestamp); err != nil {
+			log.Printf("Failed to handle %s transaction %s: %v", strings.ToUpper(string(s.chainType)), stx.metaDataTx.TxID, err)
+		} else {
+			processedCount++
+		}
+	}
+	log.Printf("Scanned block at height %d, transaction count: %d (chain: %s), MetaID PIN count: %d", block.height, block.txCount, s.chainType, block.pinCount)
+	metrics.BlockPins.WithLabelValues(string(s.chainType)).Observe(float64(block.pinCount))
+
+	return processedCount
+}
+
+// fetchBatch fetch blocks [from, to], using batched JSON-RPC when enabled
+// A configured REST URL takes precedence: binary blocks fetched concurrently beat batched hex
+func (s *BlockScanner) fetchBatch(from, to int64) ([]*scannedBlock, error) {
+	if s.rpcBatchEnabled && s.restURL == "" && s.usesRPCNode() {
+		return s.fetchBlocksBatch(from, to)
+	}
+	return s.fetchBlocks(from, to)
+}
+
+// fetchBlocksBatch fetch blocks [from, to] with two batched RPC calls (getblockhash, getblock),
+// then decode and parse them concurrently
+func (s *BlockScanner) fetchBlocksBatch(from, to int64) ([]*scannedBlock, error) {
+	blockHexes, err := s.GetBlockHexBatch(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	count := len(blockHexes)
+	blocks := make([]*scannedBlock, count)
+	errs := make([]error, count)
+
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			height := from + int64(i)
+			msgBlock, txCount, err := s.decodeBlockHex(blockHexes[i])
 			if err != nil {
-				// not MetaID transaction, skip
-				continue
-			}
-			if metaDataTx == nil {
-				// not MetaID transaction, skip
-				continue
+				errs[i] = err
+				return
 			}
+			blocks[i], errs[i] = s.parseBlock(height, msgBlock, txCount)
+		}(i)
+	}
+	wg.Wait()
 
-			// Call handler
-			if err := handler(tx, metaDataTx, height, timestamp); err != nil {
-				log.Printf("Failed to handle MVC transaction %s: %v", metaDataTx.TxID, err)
-			} else {
-				processedCount++
-			}
-			metaidPinCount += len(metaDataTx.MetaIDData)
+	for i := 0; i < count; i++ {
+		if errs[i] != nil {
+			return blocks[:i], fmt.Errorf("block %d: %w", from+int64(i), errs[i])
 		}
 	}
-	log.Printf("Scanned block at height %d, transaction count: %d (chain: %s), MetaID PIN count: %d", height, txCount, s.chainType, metaidPinCount)
+	return blocks, nil
+}
+
+// prefetchResult result of an asynchronous batch fetch
+type prefetchResult struct {
+	blocks []*scannedBlock
+	err    error
+}
+
+// prefetchBatch fetch blocks [from, to] in background
+func (s *BlockScanner) prefetchBatch(from, to int64) <-chan prefetchResult {
+	ch := make(chan prefetchResult, 1)
+	go func() {
+		blocks, err := s.fetchBatch(from, to)
+		ch <- prefetchResult{blocks: blocks, err: err}
+	}()
+	return ch
+}
 
-	return processedCount, nil
+// fetchBlocks fetch blocks [from, to] concurrently
+// Returns the consecutive fetched blocks starting at from, stopping before the first failed height,
+// together with the error of that failed height (nil if all succeeded)
+func (s *BlockScanner) fetchBlocks(from, to int64) ([]*scannedBlock, error) {
+	count := int(to - from + 1)
+	blocks := make([]*scannedBlock, count)
+	errs := make([]error, count)
+
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			blocks[i], errs[i] = s.fetchBlock(from + int64(i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < count; i++ {
+		if errs[i] != nil {
+			return blocks[:i], fmt.Errorf("block %d: %w", from+int64(i), errs[i])
+		}
+	}
+	return blocks, nil
 }
 
 // Start start scanner
 // handler accepts interface{} for tx to support both BTC and MVC
-// onBlockComplete is called after each block is successfully scanned
+// onBlockComplete is called with the last handled height once per batch of batchSize blocks (see SetBatchSize)
 func (s *BlockScanner) Start(
 	handler func(tx interface{}, metaDataTx *MetaIDDataTx, height, timestamp int64) error,
 	onBlockComplete func(height int64) error,
 ) {
 	currentHeight := s.startHeight
+	s.currentHeight.Store(currentHeight)
 	log.Printf("Block scanner started from height %d (chain: %s)", currentHeight, s.chainType)
 
 	zmqStarted := false // Track if ZMQ has been started
 
+	// Skip ZMQ mempool transactions while paused, they are indexed from the block once resumed
+	zmqHandler := func(tx interface{}, metaDataTx *MetaIDDataTx) error {
+		if s.isSuspended() {
+			return nil
+		}
+		// Call the same handler but with height = 0 (mempool transaction)
+		return handler(tx, metaDataTx, 0, time.Now().UnixMilli())
+	}
+	// Back off while the node keeps failing instead of hammering it every interval
+	errorBackoff := s.scanInterval()
+	backOffOnError := func() {
+		log.Printf("Retrying in %s (chain: %s)", errorBackoff, s.chainType)
+		time.Sleep(errorBackoff)
+		errorBackoff = nextScanErrorBackoff(errorBackoff)
+	}
+	// Scan right away when the node announces a new block instead of sleeping out the interval
+	zmqBlockHandler := func(blockHash string) {
+		s.NotifyNewBlock()
+	}
+
 	for {
+		// Do not advance height while paused
+		if s.isSuspended() {
+			time.Sleep(s.scanInterval())
+			continue
+		}
+
 		// get latest block height
 		latestHeight, err := s.GetBlockCount()
 		if err != nil {
 			log.Printf("Failed to get block count: %v", err)
-			time.Sleep(s.interval)
+			backOffOnError()
 			continue
 		}
 
+		// Only scan blocks with enough confirmations
+		latestHeight -= s.confirmations
+
 		// if new blocks exist, start scan
 		if currentHeight <= latestHeight {
 			blocksToScan := latestHeight - currentHeight + 1
@@ -361,25 +933,92 @@ func (s *BlockScanner) Start(
 
 			// log.Printf("Starting to scan %d blocks (from %d to %d)", blocksToScan, currentHeight, latestHeight)
 
+			// Commit the sync height once per batchSize handled blocks instead of after every block
+			var uncommitted int
+			commit := func() {
+				if uncommitted == 0 || onBlockComplete == nil {
+					uncommitted = 0
+					return
+				}
+				if err := onBlockComplete(currentHeight - 1); err != nil {
+					log.Printf("Failed to update sync status for block %d: %v", currentHeight-1, err)
+				}
+				uncommitted = 0
+			}
+
+			var prefetch <-chan prefetchResult
+			batchStart := time.Now() // Start of the current batch, for the scan rate
+		scanLoop:
 			for currentHeight <= latestHeight {
-				_, err := s.ScanBlock(currentHeight, handler)
-				if err != nil {
-					log.Printf("\nFailed to scan block %d: %v", currentHeight, err)
-					time.Sleep(s.interval)
-					continue
+				// Stop between batches when paused (a pending prefetch is discarded)
+				if s.isSuspended() {
+					log.Printf("\nBlock scanning paused at height %d", currentHeight)
+					break
+				}
+
+				// Fetch up to scanConcurrency blocks ahead concurrently (or take the prefetched batch)
+				var blocks []*scannedBlock
+				var fetchErr error
+				if prefetch != nil {
+					result := <-prefetch
+					blocks, fetchErr = result.blocks, result.err
+					prefetch = nil
+				} else {
+					blocks, fetchErr = s.fetchBatch(currentHeight, s.batchEnd(currentHeight, latestHeight))
+				}
+
+				// Prefetch the next batch while the current one is being handled
+				nextHeight := currentHeight + int64(len(blocks))
+				if fetchErr == nil && nextHeight <= latestHeight {
+					prefetch = s.prefetchBatch(nextHeight, s.batchEnd(nextHeight, latestHeight))
 				}
 
-				// Call onBlockComplete callback to update sync status
-				if onBlockComplete != nil {
-					if err := onBlockComplete(currentHeight); err != nil {
-						log.Printf("Failed to update sync status for block %d: %v", currentHeight, err)
+				// Handle and commit fetched blocks in strict height order
+				for _, block := range blocks {
+					// Stop before the next block when paused mid-batch (the rest of the batch is fetched again on resume)
+					if s.isSuspended() {
+						log.Printf("\nBlock scanning paused at height %d", currentHeight)
+						break scanLoop
+					}
+					s.handleBlock(block, handler)
+
+					metrics.BlocksScanned.WithLabelValues(string(s.chainType)).Inc()
+					metrics.SyncHeight.WithLabelValues(string(s.chainType)).Set(float64(block.height))
+
+					// Update progress bar
+					s.progressBar.Add(1)
+					currentHeight++
+					s.currentHeight.Store(currentHeight)
+
+					// Update sync status once the batch is complete
+					uncommitted++
+					if uncommitted >= s.batchSize {
+						commit()
 					}
 				}
 
-				// Update progress bar
-				s.progressBar.Add(1)
-				currentHeight++
+				s.recordScanRate(len(blocks), time.Since(batchStart))
+
+				// A pruned block will never become available, stop instead of retrying forever
+				if errors.Is(fetchErr, ErrBlockPruned) {
+					commit()
+					s.progressBar.Finish()
+					s.haltOnPrunedBlock(currentHeight, fetchErr)
+					return
+				}
+
+				// Retry from the first failed block, never committing past it (blocks handled before it are committed)
+				if fetchErr != nil {
+					log.Printf("\nFailed to scan block %d: %v", currentHeight, fetchErr)
+					commit()
+					backOffOnError()
+				} else {
+					errorBackoff = s.scanInterval()
+				}
+				batchStart = time.Now()
 			}
+			// Commit the partial batch left when caught up or paused
+			commit()
 
 			// Finish progress bar
 			s.progressBar.Finish()
@@ -390,20 +1029,21 @@ func (s *BlockScanner) Start(
 				log.Printf("✅ Caught up to latest block, starting ZMQ real-time monitoring...")
 
 				// Set ZMQ transaction handler (without height parameter for mempool txs)
-				s.zmqClient.SetTransactionHandler(func(tx interface{}, metaDataTx *MetaIDDataTx) error {
-					// Call the same handler but with height = 0 (mempool transaction)
-					return handler(tx, metaDataTx, 0, time.Now().UnixMilli())
-				})
+				s.zmqClient.SetTransactionHandler(zmqHandler)
+				s.zmqClient.SetBlockHandler(zmqBlockHandler)
 
 				// Start ZMQ client
-				if err := s.zmqClient.StartWithRawTx(); err != nil {
+				if err := s.zmqClient.StartWithRawTxAndHashBlock(); err != nil {
 					log.Printf("Failed to start ZMQ client: %v", err)
 				} else {
 					zmqStarted = true
+					s.zmqActive.Store(true)
 					log.Printf("✅ ZMQ real-time monitoring started successfully")
 				}
 			}
 		} else {
+			errorBackoff = s.scanInterval()
+
 			// Already at latest block
 			if !zmqStarted {
 				log.Printf("Already at latest block %d", currentHeight-1)
@@ -413,15 +1053,15 @@ func (s *BlockScanner) Start(
 					log.Printf("✅ At latest block, starting ZMQ real-time monitoring...")
 
 					// Set ZMQ transaction handler
-					s.zmqClient.SetTransactionHandler(func(tx interface{}, metaDataTx *MetaIDDataTx) error {
-						return handler(tx, metaDataTx, 0, time.Now().UnixMilli())
-					})
+					s.zmqClient.SetTransactionHandler(zmqHandler)
+					s.zmqClient.SetBlockHandler(zmqBlockHandler)
 
 					// Start ZMQ client
-					if err := s.zmqClient.StartWithRawTx(); err != nil {
+					if err := s.zmqClient.StartWithRawTxAndHashBlock(); err != nil {
 						log.Printf("Failed to start ZMQ client: %v", err)
 					} else {
 						zmqStarted = true
+						s.zmqActive.Store(true)
 						log.Printf("✅ ZMQ real-time monitoring started successfully")
 					}
 				}
@@ -430,9 +1070,49 @@ func (s *BlockScanner) Start(
 			}
 		}
 
-		// wait for next scan
-		time.Sleep(s.interval)
+		// wait for next scan (or a new block notification)
+		s.waitForNextScan()
+	}
+}
+
+// NotifyNewBlock wake the scan loop to check for new blocks immediately
+// Notifications arriving while a scan is running are coalesced into one extra check
+func (s *BlockScanner) NotifyNewBlock() {
+	select {
+	case s.newBlock <- struct{}{}:
+	default:
+	}
+}
+
+// waitForNextScan sleep for the scan interval, returning early on a new block notification
+func (s *BlockScanner) waitForNextScan() {
+	timer := time.NewTimer(s.scanInterval())
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-s.newBlock:
+	}
+}
+
+// nextScanErrorBackoff double the scan loop's error backoff, capped at maxScanErrorBackoff
+func nextScanErrorBackoff(current time.Duration) time.Duration {
+	if current < time.Second {
+		current = time.Second
 	}
+	next := current * 2
+	if next > maxScanErrorBackoff {
+		next = maxScanErrorBackoff
+	}
+	return next
+}
+
+// batchEnd get last height of the batch starting at from, capped at latestHeight
+func (s *BlockScanner) batchEnd(from, latestHeight int64) int64 {
+	end := from + int64(s.scanConcurrency) - 1
+	if end > latestHeight {
+		end = latestHeight
+	}
+	return end
 }
 
 // Stop stop scanner and ZMQ client
@@ -442,29 +1122,240 @@ func (s *BlockScanner) Stop() {
 	// Stop ZMQ client if running
 	if s.zmqClient != nil {
 		s.zmqClient.Stop()
+		s.zmqActive.Store(false)
 	}
 
 	log.Println("Block scanner stopped")
 }
 
 // rpcCall execute RPC call
-func (s *BlockScanner) rpcCall(request RPCRequest) (*RPCResponse, error) {
-	// set authentication header
-	headers := map[string]string{
-		"Authorization": "Basic " + tool.Base64Encode(s.rpcUser+":"+s.rpcPassword),
-	}
-
+func (s *BlockScanner) rpcCall(ctx context.Context, request RPCRequest) (*RPCResponse, error) {
 	// Send request
-	respStr, err := tool.PostUrl(s.rpcURL, request, headers)
+	start := time.Now()
+	respBody, err := s.rpcPost(ctx, request, request.Method)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.RPCDuration.WithLabelValues(string(s.chainType), request.Method, result).Observe(time.Since(start).Seconds())
 	if err != nil {
 		return nil, fmt.Errorf("rpc call failed: %w", err)
 	}
 
 	// Parse response
 	var response RPCResponse
-	if err := json.Unmarshal([]byte(respStr), &response); err != nil {
+	if err := json.Unmarshal(respBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse rpc response: %w", err)
 	}
 
 	return &response, nil
 }
+
+// errTransientRPC marks RPC failures worth retrying (network errors, HTTP 5xx without a JSON-RPC error body)
+var errTransientRPC = errors.New("transient rpc error")
+
+// rpcRetryKey context key marking node calls of the scan loop, the only calls retried on transient errors
+type rpcRetryKey struct{}
+
+// withRPCRetries mark ctx so transient RPC failures are retried with backoff
+// Request handlers pass their own context instead: one attempt within their deadline, never a backoff of several seconds
+func withRPCRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rpcRetryKey{}, true)
+}
+
+// scanContext context of the scan loop's node calls (retried, bounded by the RPC timeout of each attempt)
+var scanContext = withRPCRetries(context.Background())
+
+// rpcPost send a JSON-RPC payload and return the raw response body
+// Transient failures of scan loop calls (ctx from withRPCRetries) are retried with exponential backoff up to
+// rpcMaxRetries times, other calls make a single attempt;
+// genuine RPC errors (a JSON-RPC error object in the body) and other HTTP errors fail fast
+func (s *BlockScanner) rpcPost(ctx context.Context, payload interface{}, method string) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rpc request: %w", err)
+	}
+
+	maxRetries := 0
+	if ctx.Value(rpcRetryKey{}) != nil {
+		maxRetries = s.rpcMaxRetries
+	}
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		respBody, err := s.rpcPostOnce(ctx, body)
+		if err == nil || !errors.Is(err, errTransientRPC) || attempt >= maxRetries {
+			// A caller that went away (client disconnect) says nothing about the node
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				s.recordNodeResult(err)
+			}
+			return respBody, err
+		}
+
+		log.Printf("RPC %s failed (attempt %d/%d, chain: %s): %v, retrying in %s", method, attempt+1, maxRetries+1, s.chainType, err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxRPCRetryBackoff {
+			backoff = maxRPCRetryBackoff
+		}
+	}
+}
+
+// rpcPostOnce send one JSON-RPC HTTP request
+func (s *BlockScanner) rpcPostOnce(ctx context.Context, body []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json;charset=UTF-8")
+	request.Header.Set("Authorization", "Basic "+tool.Base64Encode(s.rpcUser+":"+s.rpcPassword))
+
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		// Connection refused, reset, timeout...
+		return nil, fmt.Errorf("%w: %v", errTransientRPC, err)
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read response: %v", errTransientRPC, err)
+	}
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		// bitcoind-style nodes answer RPC errors with HTTP 500 and a JSON-RPC error body, those are not retried
+		if isRPCErrorBody(respBody) {
+			return respBody, nil
+		}
+		return nil, fmt.Errorf("%w: http status %d", errTransientRPC, response.StatusCode)
+	}
+	if response.StatusCode != http.StatusOK && !isRPCErrorBody(respBody) {
+		return nil, fmt.Errorf("http status %d", response.StatusCode)
+	}
+
+	return respBody, nil
+}
+
+// isRPCErrorBody check whether a response body carries a JSON-RPC error object
+func isRPCErrorBody(body []byte) bool {
+	var response RPCResponse
+	if err := json.Unmarshal(body, &response); err == nil {
+		return response.Error != nil
+	}
+	var responses []RPCResponse
+	if err := json.Unmarshal(body, &responses); err == nil {
+		for _, item := range responses {
+			if item.Error != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rpcCallBatch execute batched JSON-RPC call
+// Responses are returned in the same order as requests (matched by request ID, which must be unique)
+func (s *BlockScanner) rpcCallBatch(ctx context.Context, requests []RPCRequest) ([]RPCResponse, error) {
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	// Send request
+	start := time.Now()
+	respBody, err := s.rpcPost(ctx, requests, requests[0].Method+"_batch")
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.RPCDuration.WithLabelValues(string(s.chainType), requests[0].Method+"_batch", result).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("rpc batch call failed: %w", err)
+	}
+
+	// Parse response
+	var responses []RPCResponse
+	if err := json.Unmarshal(respBody, &responses); err != nil {
+		return nil, fmt.Errorf("failed to parse rpc batch response: %w", err)
+	}
+
+	// Reorder responses to match requests
+	byID := make(map[string]RPCResponse, len(responses))
+	for _, response := range responses {
+		byID[response.ID] = response
+	}
+	ordered := make([]RPCResponse, len(requests))
+	for i, request := range requests {
+		response, ok := byID[request.ID]
+		if !ok {
+			return nil, fmt.Errorf("missing rpc batch response for request %s", request.ID)
+		}
+		ordered[i] = response
+	}
+
+	return ordered, nil
+}
+
+// GetBlockHexBatch get raw block hex for heights [from, to] using two batched RPC calls
+func (s *BlockScanner) GetBlockHexBatch(from, to int64) ([]string, error) {
+	count := int(to - from + 1)
+	if count <= 0 {
+		return nil, nil
+	}
+
+	// Batch getblockhash
+	hashRequests := make([]RPCRequest, count)
+	for i := 0; i < count; i++ {
+		height := from + int64(i)
+		hashRequests[i] = RPCRequest{
+			Jsonrpc: "1.0",
+			ID:      fmt.Sprintf("getblockhash-%d", height),
+			Method:  "getblockhash",
+			Params:  []interface{}{height},
+		}
+	}
+	hashResponses, err := s.rpcCallBatch(scanContext, hashRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	// Batch getblock
+	blockRequests := make([]RPCRequest, count)
+	for i, response := range hashResponses {
+		height := from + int64(i)
+		if response.Error != nil {
+			return nil, fmt.Errorf("rpc error for block hash %d: %s", height, response.Error.Message)
+		}
+		blockhash, ok := response.Result.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid block hash response for height %d", height)
+		}
+		blockRequests[i] = RPCRequest{
+			Jsonrpc: "1.0",
+			ID:      fmt.Sprintf("getblock-%d", height),
+			Method:  "getblock",
+			Params:  []interface{}{blockhash, 0}, // verbosity=0 return raw hex
+		}
+	}
+	blockResponses, err := s.rpcCallBatch(scanContext, blockRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHexes := make([]string, count)
+	for i, response := range blockResponses {
+		height := from + int64(i)
+		if response.Error != nil {
+			return nil, blockRPCError(height, response.Error.Message)
+		}
+		blockHex, ok := response.Result.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid block hex response for height %d", height)
+		}
+		blockHexes[i] = blockHex
+	}
+
+	return blockHexes, nil
+}
@@ -0,0 +1,392 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitcoinsv/bsvd/chaincfg/chainhash"
+	"github.com/bitcoinsv/bsvd/wire"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// mockNode NodeClient serving canned MVC blocks by height
+type mockNode struct {
+	mu         sync.Mutex
+	blocks     map[int64]string // height -> raw block hex
+	tip        int64
+	pruned     map[int64]bool  // heights whose block the node no longer has
+	failOnce   map[int64]bool  // heights whose first getblock fails with a transient error
+	hexCalls   map[int64]int   // getblock calls per height
+	rawTxCalls map[string]bool // txids requested with getrawtransaction
+}
+
+func newMockNode() *mockNode {
+	return &mockNode{
+		blocks:     make(map[int64]string),
+		pruned:     make(map[int64]bool),
+		failOnce:   make(map[int64]bool),
+		hexCalls:   make(map[int64]int),
+		rawTxCalls: make(map[string]bool),
+	}
+}
+
+func (m *mockNode) GetBlockCount(context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tip, nil
+}
+
+func (m *mockNode) GetBlockhash(_ context.Context, height int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if height < 0 || height > m.tip {
+		return "", fmt.Errorf("rpc error: Block height out of range")
+	}
+	return fmt.Sprintf("hash-%d", height), nil
+}
+
+func (m *mockNode) GetBlockHex(_ context.Context, blockhash string) (string, error) {
+	var height int64
+	if _, err := fmt.Sscanf(blockhash, "hash-%d", &height); err != nil {
+		return "", fmt.Errorf("rpc error: Block not found")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hexCalls[height]++
+	if m.pruned[height] {
+		return "", blockRPCError(-1, "Block not available (pruned data)")
+	}
+	if m.failOnce[height] && m.hexCalls[height] == 1 {
+		return "", fmt.Errorf("%w: connection refused", errTransientRPC)
+	}
+	return m.blocks[height], nil
+}
+
+func (m *mockNode) GetRawTransaction(_ context.Context, txid string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rawTxCalls[txid] = true
+	return "", fmt.Errorf("rpc error: No such mempool or blockchain transaction")
+}
+
+// addBlock add a block at the next height with one transaction per PIN path (empty path = non-MetaID transaction)
+func (m *mockNode) addBlock(t *testing.T, paths ...string) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tip++
+	height := m.tip
+
+	block := wire.MsgBlock{Header: wire.BlockHeader{Version: 1, Timestamp: time.Unix(1700000000+height*600, 0)}}
+	for i, path := range paths {
+		tx := wire.NewMsgTx(1)
+		prevHash := chainhash.DoubleHashH([]byte(fmt.Sprintf("funding-%d-%d", height, i)))
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0), nil))
+		if path == "" {
+			tx.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_TRUE}))
+		} else {
+			script, err := txscript.NewScriptBuilder().
+				AddOp(txscript.OP_FALSE).AddOp(txscript.OP_RETURN).
+				AddData([]byte("metaid")).AddData([]byte("create")).AddData([]byte(path)).
+				AddData([]byte("0")).AddData([]byte("1.0.0")).AddData([]byte("application/json")).
+				AddData([]byte(fmt.Sprintf(`{"height":%d}`, height))).
+				Script()
+			if err != nil {
+				t.Fatalf("failed to build PIN script: %v", err)
+			}
+			tx.AddTxOut(wire.NewTxOut(0, script))
+		}
+		block.AddTransaction(tx)
+	}
+
+	var buf bytes.Buffer
+	if err := block.Serialize(&buf); err != nil {
+		t.Fatalf("failed to serialize block: %v", err)
+	}
+	m.blocks[height] = hex.EncodeToString(buf.Bytes())
+}
+
+// handledPin PIN passed to the scan handler
+type handledPin struct {
+	height    int64
+	timestamp int64
+	path      string
+}
+
+func newMockScanner(node *mockNode) *BlockScanner {
+	scanner := NewBlockScannerWithChain("http://127.0.0.1:1", "", "", 1, 0, ChainTypeMVC)
+	scanner.SetNodeClient(node)
+	return scanner
+}
+
+func TestScanBlockWithMockNode(t *testing.T) {
+	node := newMockNode()
+	node.addBlock(t, "", "/protocols/metaapp", "")
+	scanner := newMockScanner(node)
+
+	var pins []handledPin
+	count, err := scanner.ScanBlock(1, func(tx interface{}, metaDataTx *MetaIDDataTx, height, timestamp int64) error {
+		for _, pin := range metaDataTx.MetaIDData {
+			pins = append(pins, handledPin{height: height, timestamp: timestamp, path: pin.Path})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanBlock failed: %v", err)
+	}
+	if count != 1 || len(pins) != 1 {
+		t.Fatalf("ScanBlock handled %d transactions / %d PINs, want 1 / 1", count, len(pins))
+	}
+	want := handledPin{height: 1, timestamp: (1700000000 + 600) * 1000, path: "/protocols/metaapp"}
+	if pins[0] != want {
+		t.Fatalf("handled PIN %+v, want %+v", pins[0], want)
+	}
+
+	if _, err := scanner.ScanBlock(2, func(interface{}, *MetaIDDataTx, int64, int64) error { return nil }); err == nil {
+		t.Fatal("ScanBlock beyond the tip succeeded, want error")
+	}
+}
+
+// TestScanLoopWithMockNode runs the scan loop over canned blocks until it halts on a pruned block
+func TestScanLoopWithMockNode(t *testing.T) {
+	node := newMockNode()
+	for height := 1; height <= 5; height++ {
+		node.addBlock(t, fmt.Sprintf("/protocols/metaapp/%d", height))
+	}
+	node.addBlock(t, "/protocols/metaapp/6")
+	node.pruned[6] = true
+	node.failOnce[3] = true
+
+	scanner := newMockScanner(node)
+	scanner.SetScanConcurrency(2)
+	scanner.SetBatchSize(2)
+
+	var pins []handledPin
+	var committed []int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner.Start(func(tx interface{}, metaDataTx *MetaIDDataTx, height, timestamp int64) error {
+			for _, pin := range metaDataTx.MetaIDData {
+				pins = append(pins, handledPin{height: height, timestamp: timestamp, path: pin.Path})
+			}
+			return nil
+		}, func(height int64) error {
+			committed = append(committed, height)
+			return nil
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("scan loop did not halt on the pruned block")
+	}
+
+	// Blocks are handled in height order exactly once, the transient failure of block 3 is retried
+	if len(pins) != 5 {
+		t.Fatalf("handled %d PINs, want 5: %+v", len(pins), pins)
+	}
+	for i, pin := range pins {
+		if want := fmt.Sprintf("/protocols/metaapp/%d", i+1); pin.height != int64(i+1) || pin.path != want {
+			t.Fatalf("PIN %d is %+v, want height %d path %s", i, pin, i+1, want)
+		}
+	}
+	if node.hexCalls[3] != 2 {
+		t.Fatalf("block 3 fetched %d times, want 2 (one transient failure)", node.hexCalls[3])
+	}
+
+	// Never commits past the pruned block, and reports why scanning stopped
+	if len(committed) == 0 || committed[len(committed)-1] != 5 {
+		t.Fatalf("committed heights %v, want last commit at 5", committed)
+	}
+	for i := 1; i < len(committed); i++ {
+		if committed[i] <= committed[i-1] {
+			t.Fatalf("committed heights %v are not increasing", committed)
+		}
+	}
+	if scanner.CurrentHeight() != 6 || scanner.NodeHealth().ScanHalted == "" {
+		t.Fatalf("scanner at height %d (halted %q), want halted at 6", scanner.CurrentHeight(), scanner.NodeHealth().ScanHalted)
+	}
+}
+
+// TestScanLoopPauseMidBatch pauses the scan loop from the handler and checks it stops before the next block of the batch,
+// and that releasing an internal hold does not resume an operator pause
+func TestScanLoopPauseMidBatch(t *testing.T) {
+	node := newMockNode()
+	for height := 1; height <= 5; height++ {
+		node.addBlock(t, fmt.Sprintf("/protocols/metaapp/%d", height))
+	}
+	node.pruned[5] = true
+
+	scanner := newMockScanner(node)
+	scanner.SetScanConcurrency(4)
+	scanner.SetBatchSize(10)
+
+	var mu sync.Mutex
+	var handled, committed []int64
+	snapshot := func() ([]int64, []int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int64(nil), handled...), append([]int64(nil), committed...)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner.Start(func(tx interface{}, metaDataTx *MetaIDDataTx, height, timestamp int64) error {
+			mu.Lock()
+			handled = append(handled, height)
+			mu.Unlock()
+			if height == 2 {
+				scanner.Pause()
+			}
+			return nil
+		}, func(height int64) error {
+			mu.Lock()
+			committed = append(committed, height)
+			mu.Unlock()
+			return nil
+		})
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, c := snapshot(); len(c) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("scan loop did not commit after the pause")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if h, c := snapshot(); len(h) != 2 || len(c) != 1 || c[0] != 2 {
+		t.Fatalf("handled %v, committed %v, want blocks 1-2 handled and committed at 2", h, c)
+	}
+
+	// A rescan style hold released while the operator pause is set keeps the scanner paused
+	scanner.Hold()
+	scanner.Release()
+	if !scanner.IsPaused() {
+		t.Fatal("releasing a hold resumed the operator pause")
+	}
+	scanner.Hold()
+	scanner.Resume()
+	time.Sleep(1500 * time.Millisecond)
+	if h, _ := snapshot(); len(h) != 2 {
+		t.Fatalf("handled %v while held, want no progress", h)
+	}
+	scanner.Release()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("scan loop did not halt on the pruned block")
+	}
+	if h, _ := snapshot(); len(h) != 4 || h[2] != 3 || h[3] != 4 {
+		t.Fatalf("handled %v, want 1-4 in order", h)
+	}
+}
+
+func TestRawTransactionCacheWithMockNode(t *testing.T) {
+	node := newMockNode()
+	scanner := newMockScanner(node)
+
+	scanner.txCache.Add("cached", "00")
+	if txHex, err := scanner.GetRawTransaction("cached"); err != nil || txHex != "00" {
+		t.Fatalf("GetRawTransaction(cached) = %q, %v", txHex, err)
+	}
+	if node.rawTxCalls["cached"] {
+		t.Fatal("cached transaction was requested from the node")
+	}
+
+	if _, err := scanner.GetRawTransaction("missing"); err == nil || !node.rawTxCalls["missing"] {
+		t.Fatalf("GetRawTransaction(missing) err %v, node called %v", err, node.rawTxCalls["missing"])
+	}
+}
+
+// TestGetBlockHeader reads hash, prevhash, time and tx count from getblockheader (MVC nodes report num_tx)
+// and checks node health is only reported once an RPC call has completed
+func TestGetBlockHeader(t *testing.T) {
+	node := newMockNode()
+	node.addBlock(t, "")
+	blockhash, _ := node.GetBlockhash(context.Background(), 1)
+
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Method != "getblockheader" || request.Params[0] != blockhash {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result": map[string]interface{}{"hash": blockhash, "previousblockhash": "00ab", "time": 1700000600, "num_tx": 3, "confirmations": 2},
+			"error":  nil,
+			"id":     request.ID,
+		})
+	}))
+	defer rpc.Close()
+
+	scanner := NewBlockScannerWithChain(rpc.URL, "", "", 1, 0, ChainTypeMVC)
+	scanner.SetNodeClient(node)
+	if scanner.NodeHealth().Probed {
+		t.Fatal("node reported as probed before any RPC call")
+	}
+	header, err := scanner.GetBlockHeader(1)
+	if err != nil {
+		t.Fatalf("GetBlockHeader failed: %v", err)
+	}
+	want := BlockHeader{Hash: blockhash, PrevHash: "00ab", Height: 1, Timestamp: 1700000600, TxCount: 3, Confirmations: 2}
+	if *header != want {
+		t.Fatalf("got %+v, want %+v", *header, want)
+	}
+	if health := scanner.NodeHealth(); !health.Probed || !health.Reachable {
+		t.Fatalf("node health %+v after a successful RPC call, want probed and reachable", health)
+	}
+}
+
+// TestRPCRetriesOnlyInScanLoop retries transient failures of scan loop calls, while calls made with a
+// request context get a single attempt
+func TestRPCRetriesOnlyInScanLoop(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer rpc.Close()
+	callCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := calls
+		calls = 0
+		return n
+	}
+
+	scanner := NewBlockScannerWithChain(rpc.URL, "", "", 1, 0, ChainTypeMVC)
+	scanner.SetRPCMaxRetries(1)
+
+	if _, err := scanner.GetBlockCountContext(context.Background()); err == nil {
+		t.Fatal("expected an error from a 503 response")
+	}
+	if n := callCount(); n != 1 {
+		t.Fatalf("request path made %d attempts, want 1", n)
+	}
+
+	if _, err := scanner.GetBlockCount(); err == nil {
+		t.Fatal("expected an error from a 503 response")
+	}
+	if n := callCount(); n != 2 {
+		t.Fatalf("scan loop made %d attempts, want 2", n)
+	}
+}
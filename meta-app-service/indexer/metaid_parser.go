@@ -2,16 +2,16 @@ package indexer
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 
 	"github.com/bitcoinsv/bsvd/wire"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	btcwire "github.com/btcsuite/btcd/wire"
-	"github.com/btcsuite/btcutil/base58"
 	"github.com/metaid-developers/metaid-script-decoder/decoder"
 	"github.com/metaid-developers/metaid-script-decoder/decoder/btc"
 	"github.com/metaid-developers/metaid-script-decoder/decoder/mvc"
@@ -180,7 +180,6 @@ func (p *MetaIDParser) ParseAllPINs(tx interface{}, chainType ChainType) (*MetaI
 	var txID string
 	var address string
 	var err error
-	_ = address
 
 	// Type assertion based on chainType
 	if chainType == ChainTypeBTC {
@@ -238,9 +237,14 @@ func (p *MetaIDParser) ParseAllPINs(tx interface{}, chainType ChainType) (*MetaI
 		return nil, nil
 	}
 
-	// Convert all PINs to MetaIDData (address already extracted above)
+	// Convert all PINs to MetaIDData; the decoder resolves the owner from the outputs,
+	// the first input address (extracted above) is the fallback creator
 	var results []*MetaIDData
 	for _, pin := range pins {
+		creatorAddress := pin.OwnerAddress
+		if creatorAddress == "" {
+			creatorAddress = address
+		}
 		data := &MetaIDData{
 			PinID:                pin.Id,
 			Operation:            pin.Operation,
@@ -254,7 +258,7 @@ func (p *MetaIDParser) ParseAllPINs(tx interface{}, chainType ChainType) (*MetaI
 			Content:              pin.ContentBody,
 			TxID:                 txID,
 			Vout:                 pin.Vout,
-			CreatorAddress:       pin.OwnerAddress,
+			CreatorAddress:       creatorAddress,
 			CreatorInputLocation: pin.CreatorInputLocation,
 			OwnerAddress:         pin.OwnerAddress,
 			ChainName:            chainName,
@@ -269,43 +273,98 @@ func (p *MetaIDParser) ParseAllPINs(tx interface{}, chainType ChainType) (*MetaI
 	}, nil
 }
 
-// extractBTCAddress extract address from BTC transaction first input
+// DecodeRawTransaction decode raw transaction hex into *btcwire.MsgTx (BTC) or *wire.MsgTx (MVC)
+// The result can be passed to ParseAllPINs with the same chain type
+func DecodeRawTransaction(txHex string, chainType ChainType) (interface{}, error) {
+	txBytes, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction hex: %w", err)
+	}
+
+	if chainType == ChainTypeBTC {
+		var btcTx btcwire.MsgTx
+		if err := btcTx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+			return nil, fmt.Errorf("failed to deserialize BTC transaction: %w", err)
+		}
+		return &btcTx, nil
+	}
+
+	var mvcTx wire.MsgTx
+	if err := mvcTx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, fmt.Errorf("failed to deserialize MVC transaction: %w", err)
+	}
+	return &mvcTx, nil
+}
+
+// extractBTCCreatorAddress extract the address spent by the first input from its unlocking data (no previous transaction lookup)
+// Supported: P2PKH (scriptSig <sig> <pubkey>), P2SH-P2WPKH (scriptSig <0014{hash}> + witness), P2WPKH (witness <sig> <pubkey>)
+// and P2TR script path spends (witness ... <script> <control block>); P2TR key path spends only carry a signature,
+// their address cannot be derived and "" is returned
 func extractBTCCreatorAddress(tx *btcwire.MsgTx) string {
-	// In Bitcoin, the address is typically extracted from the first input's previous output
-	// This is a simplified implementation - in production you may need to query the previous transaction
-	// to get the actual address
-	if len(tx.TxIn) > 0 {
-		// Return the previous transaction hash as a placeholder
-		// In a real implementation, you would need to:
-		// 1. Get the previous transaction using tx.TxIn[0].PreviousOutPoint.Hash
-		// 2. Extract the address from that transaction's output
-		return tx.TxIn[0].PreviousOutPoint.Hash.String()
-	}
-	return ""
+	if len(tx.TxIn) == 0 {
+		return ""
+	}
+	address, err := btcInputAddress(tx.TxIn[0], &chaincfg.MainNetParams)
+	if err != nil {
+		return ""
+	}
+	return address.EncodeAddress()
 }
 
-// extractMVCAddress extract address from MVC transaction first input
-func extractMVCCreatorAddress(tx *wire.MsgTx) string {
-	// In MVC, the address is typically extracted from the first input's previous output
-	// This is a simplified implementation - in production you may need to query the previous transaction
-	// to get the actual address
-	if len(tx.TxIn) > 0 {
-		// Return the previous transaction hash as a placeholder
-		// In a real implementation, you would need to:
-		// 1. Get the previous transaction using tx.TxIn[0].PreviousOutPoint.Hash
-		// 2. Extract the address from that transaction's output
-		return tx.TxIn[0].PreviousOutPoint.Hash.String()
-	}
-	return ""
+// btcInputAddress derive the address an input spends from its scriptSig and witness
+func btcInputAddress(txIn *btcwire.TxIn, params *chaincfg.Params) (btcutil.Address, error) {
+	pushes, err := txscript.PushedData(txIn.SignatureScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signature script: %w", err)
+	}
+	witness := txIn.Witness
+
+	switch {
+	case len(pushes) == 0 && len(witness) == 2 && len(witness[1]) == 33 && (witness[1][0] == 0x02 || witness[1][0] == 0x03):
+		// P2WPKH: witness <sig> <compressed pubkey>
+		return btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(witness[1]), params)
+	case len(pushes) == 1 && len(witness) > 0:
+		// P2SH wrapped segwit: scriptSig pushes the redeem script (witness program)
+		return btcutil.NewAddressScriptHash(pushes[0], params)
+	case len(pushes) == 2 && len(witness) == 0:
+		// P2PKH: scriptSig <sig> <pubkey>
+		return btcutil.NewAddressPubKeyHash(btcutil.Hash160(pushes[1]), params)
+	case len(pushes) == 0 && len(witness) >= 2:
+		return taprootScriptPathAddress(witness, params)
+	}
+	return nil, errors.New("unsupported input type")
+}
+
+// taprootScriptPathAddress derive the P2TR address of a script path spend from the leaf script and control block
+func taprootScriptPathAddress(witness btcwire.TxWitness, params *chaincfg.Params) (btcutil.Address, error) {
+	// An optional annex (first byte 0x50) follows the control block
+	if len(witness) >= 3 && len(witness[len(witness)-1]) > 0 && witness[len(witness)-1][0] == txscript.TaprootAnnexTag {
+		witness = witness[:len(witness)-1]
+	}
+	controlBlock, err := txscript.ParseControlBlock(witness[len(witness)-1])
+	if err != nil {
+		return nil, fmt.Errorf("not a taproot script path spend: %w", err)
+	}
+	rootHash := controlBlock.RootHash(witness[len(witness)-2])
+	outputKey := txscript.ComputeTaprootOutputKey(controlBlock.InternalKey, rootHash)
+	return btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), params)
 }
 
-// TxToHex convert MVC transaction to hexadecimal string (backward compatibility)
-func TxToHex(tx *wire.MsgTx) (string, error) {
-	var buf bytes.Buffer
-	if err := tx.Serialize(&buf); err != nil {
-		return "", err
+// extractMVCCreatorAddress extract the address spent by the first input from its P2PKH scriptSig (<sig> <pubkey>)
+// Returns "" for other unlocking scripts
+func extractMVCCreatorAddress(tx *wire.MsgTx) string {
+	if len(tx.TxIn) == 0 {
+		return ""
 	}
-	return hex.EncodeToString(buf.Bytes()), nil
+	pushes, err := txscript.PushedData(tx.TxIn[0].SignatureScript)
+	if err != nil || len(pushes) != 2 {
+		return ""
+	}
+	address, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pushes[1]), &chaincfg.MainNetParams)
+	if err != nil {
+		return ""
+	}
+	return address.EncodeAddress()
 }
 
 // FindCreatorAddressFromCreatorInputLocation find creator address from CreatorInputLocation
@@ -386,13 +445,19 @@ func extractAddressFromBTCInput(tx *btcwire.MsgTx, outputIndex int) (string, err
 
 	output := tx.TxOut[outputIndex]
 
-	// Extract address from scriptPubKey (P2PKH)
+	// Extract address from scriptPubKey (P2PKH/P2SH/P2WPKH/P2WSH/P2TR)
 	scriptPubKey := output.PkScript
 	if len(scriptPubKey) == 0 {
 		return "", errors.New("empty script pubkey")
 	}
 
-	_, addresses, _, err := txscript.ExtractPkScriptAddrs(scriptPubKey, &chaincfg.MainNetParams)
+	return btcScriptAddress(scriptPubKey, &chaincfg.MainNetParams)
+}
+
+// btcScriptAddress encode the address of a BTC output script
+// txscript classifies standard scripts: P2PKH/P2SH/P2PK as base58, segwit v0 (P2WPKH/P2WSH) as bech32 and taproot (P2TR) as bech32m
+func btcScriptAddress(script []byte, params *chaincfg.Params) (string, error) {
+	_, addresses, _, err := txscript.ExtractPkScriptAddrs(script, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to extract addresses from script pubkey: %w", err)
 	}
@@ -425,29 +490,3 @@ func extractAddressFromMVCInput(tx *wire.MsgTx, outputIndex int) (string, error)
 	}
 	return addresses[0].EncodeAddress(), nil
 }
-
-// pubKeyHashToAddress convert pubKeyHash to address
-func pubKeyHashToAddress(pubKeyHash []byte, chainType ChainType) string {
-	if len(pubKeyHash) != 20 {
-		return ""
-	}
-
-	// For both BTC and MVC, we use the same address format (P2PKH)
-	// 1. Add version byte (0x00 for mainnet)
-	// 2. Calculate checksum (double SHA256)
-	// 3. Base58 encode
-
-	// Step 1: Add version byte (0x00 for mainnet P2PKH)
-	versionedPayload := append([]byte{0x00}, pubKeyHash...)
-
-	// Step 2: Calculate checksum (first 4 bytes of double SHA256)
-	firstSHA := sha256.Sum256(versionedPayload)
-	secondSHA := sha256.Sum256(firstSHA[:])
-	checksum := secondSHA[:4]
-
-	// Step 3: Append checksum and Base58 encode
-	fullPayload := append(versionedPayload, checksum...)
-	address := base58.Encode(fullPayload)
-
-	return address
-}
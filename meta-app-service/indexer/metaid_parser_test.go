@@ -0,0 +1,141 @@
+package indexer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bitcoinsv/bsvd/wire"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+)
+
+// TestBTCScriptAddress encodes standard output scripts back to their mainnet address
+func TestBTCScriptAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{"P2PKH", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"},
+		{"P2SH", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"},
+		{"P2WPKH", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{"P2WSH", "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3"},
+		{"P2TR", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := btcutil.DecodeAddress(tt.address, &chaincfg.MainNetParams)
+			if err != nil {
+				t.Fatalf("failed to decode address: %v", err)
+			}
+			script, err := txscript.PayToAddrScript(addr)
+			if err != nil {
+				t.Fatalf("failed to build script: %v", err)
+			}
+			got, err := btcScriptAddress(script, &chaincfg.MainNetParams)
+			if err != nil {
+				t.Fatalf("btcScriptAddress failed: %v", err)
+			}
+			if got != tt.address {
+				t.Fatalf("got %s, want %s", got, tt.address)
+			}
+		})
+	}
+
+	if _, err := btcScriptAddress([]byte{txscript.OP_RETURN}, &chaincfg.MainNetParams); err == nil {
+		t.Fatal("expected an error for an OP_RETURN script")
+	}
+}
+
+// TestExtractBTCCreatorAddress derives the spent address from the first input's scriptSig and witness
+func TestExtractBTCCreatorAddress(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	privKey, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x01}, 32))
+	pubKey := privKey.PubKey().SerializeCompressed()
+	pubKeyHash := btcutil.Hash160(pubKey)
+	sig := bytes.Repeat([]byte{0x30}, 71) // only its position matters
+
+	p2pkh, _ := btcutil.NewAddressPubKeyHash(pubKeyHash, params)
+	p2wpkh, _ := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params)
+	redeemScript, _ := txscript.PayToAddrScript(p2wpkh)
+	p2shP2wpkh, _ := btcutil.NewAddressScriptHash(redeemScript, params)
+
+	// P2TR with a single <pubkey> OP_CHECKSIG leaf, spent through the script path
+	leafScript, _ := txscript.NewScriptBuilder().
+		AddData(schnorr.SerializePubKey(privKey.PubKey())).AddOp(txscript.OP_CHECKSIG).Script()
+	tree := txscript.AssembleTaprootScriptTree(txscript.NewBaseTapLeaf(leafScript))
+	controlBlock := tree.LeafMerkleProofs[0].ToControlBlock(privKey.PubKey())
+	controlBlockBytes, err := controlBlock.ToBytes()
+	if err != nil {
+		t.Fatalf("failed to serialize control block: %v", err)
+	}
+	rootHash := tree.RootNode.TapHash()
+	outputKey := txscript.ComputeTaprootOutputKey(privKey.PubKey(), rootHash[:])
+	p2tr, _ := btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), params)
+
+	pushScript := func(pushes ...[]byte) []byte {
+		builder := txscript.NewScriptBuilder()
+		for _, data := range pushes {
+			builder.AddData(data)
+		}
+		script, err := builder.Script()
+		if err != nil {
+			t.Fatalf("failed to build scriptSig: %v", err)
+		}
+		return script
+	}
+
+	tests := []struct {
+		name      string
+		sigScript []byte
+		witness   btcwire.TxWitness
+		want      string
+	}{
+		{"P2PKH", pushScript(sig, pubKey), nil, p2pkh.EncodeAddress()},
+		{"P2SH-P2WPKH", pushScript(redeemScript), btcwire.TxWitness{sig, pubKey}, p2shP2wpkh.EncodeAddress()},
+		{"P2WPKH", nil, btcwire.TxWitness{sig, pubKey}, p2wpkh.EncodeAddress()},
+		{"P2TR script path", nil, btcwire.TxWitness{sig[:64], leafScript, controlBlockBytes}, p2tr.EncodeAddress()},
+		{"P2TR script path with annex", nil, btcwire.TxWitness{sig[:64], leafScript, controlBlockBytes, {txscript.TaprootAnnexTag}}, p2tr.EncodeAddress()},
+		{"P2TR key path", nil, btcwire.TxWitness{sig[:64]}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := btcwire.NewMsgTx(2)
+			txIn := btcwire.NewTxIn(&btcwire.OutPoint{}, tt.sigScript, nil)
+			txIn.Witness = tt.witness
+			tx.AddTxIn(txIn)
+			if got := extractBTCCreatorAddress(tx); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := extractBTCCreatorAddress(btcwire.NewMsgTx(2)); got != "" {
+		t.Fatalf("got %q for a transaction without inputs, want empty", got)
+	}
+}
+
+// TestExtractMVCCreatorAddress derives the P2PKH address from the first input's scriptSig
+func TestExtractMVCCreatorAddress(t *testing.T) {
+	privKey, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x02}, 32))
+	pubKey := privKey.PubKey().SerializeCompressed()
+	want, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), &chaincfg.MainNetParams)
+
+	sigScript, err := txscript.NewScriptBuilder().AddData(bytes.Repeat([]byte{0x30}, 71)).AddData(pubKey).Script()
+	if err != nil {
+		t.Fatalf("failed to build scriptSig: %v", err)
+	}
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, sigScript))
+	if got := extractMVCCreatorAddress(tx); got != want.EncodeAddress() {
+		t.Fatalf("got %q, want %q", got, want.EncodeAddress())
+	}
+
+	tx.TxIn[0].SignatureScript = []byte{txscript.OP_TRUE}
+	if got := extractMVCCreatorAddress(tx); got != "" {
+		t.Fatalf("got %q for a non-P2PKH input, want empty", got)
+	}
+}
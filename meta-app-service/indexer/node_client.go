@@ -0,0 +1,133 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// NodeClient node calls the scanner depends on
+// The scanner talks JSON-RPC to the configured node by default (rpcNodeClient); SetNodeClient replaces it,
+// e.g. with canned blocks in tests, so the scan loop can run without a live node.
+// ctx bounds each call; only calls of the scan loop (see withRPCRetries) are retried on transient errors
+type NodeClient interface {
+	// GetBlockCount get current block height
+	GetBlockCount(ctx context.Context) (int64, error)
+	// GetBlockhash get block hash by height
+	GetBlockhash(ctx context.Context, height int64) (string, error)
+	// GetBlockHex get raw block hex by block hash
+	GetBlockHex(ctx context.Context, blockhash string) (string, error)
+	// GetRawTransaction get raw transaction hex by txid
+	GetRawTransaction(ctx context.Context, txid string) (string, error)
+}
+
+// rpcNodeClient NodeClient over the scanner's JSON-RPC connection (timeouts, retries, metrics, node health)
+type rpcNodeClient struct {
+	s *BlockScanner
+}
+
+// GetBlockCount get current block height
+func (c *rpcNodeClient) GetBlockCount(ctx context.Context) (int64, error) {
+	request := RPCRequest{
+		Jsonrpc: "1.0",
+		ID:      "getblockcount",
+		Method:  "getblockcount",
+		Params:  []interface{}{},
+	}
+
+	response, err := c.s.rpcCall(ctx, request)
+	if err != nil {
+		return 0, err
+	}
+
+	if response.Error != nil {
+		return 0, fmt.Errorf("rpc error: %s", response.Error.Message)
+	}
+
+	height, ok := response.Result.(float64)
+	if !ok {
+		return 0, errors.New("invalid block height response")
+	}
+
+	return int64(height), nil
+}
+
+// GetBlockhash get block hash
+func (c *rpcNodeClient) GetBlockhash(ctx context.Context, height int64) (string, error) {
+	request := RPCRequest{
+		Jsonrpc: "1.0",
+		ID:      "getblockhash",
+		Method:  "getblockhash",
+		Params:  []interface{}{height},
+	}
+
+	response, err := c.s.rpcCall(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	if response.Error != nil {
+		return "", fmt.Errorf("rpc error: %s", response.Error.Message)
+	}
+
+	hash, ok := response.Result.(string)
+	if !ok {
+		return "", errors.New("invalid block hash response")
+	}
+
+	return hash, nil
+}
+
+// GetBlockHex get block hex data
+// verbosity=0 returns raw block hex
+func (c *rpcNodeClient) GetBlockHex(ctx context.Context, blockhash string) (string, error) {
+	request := RPCRequest{
+		Jsonrpc: "1.0",
+		ID:      "getblock",
+		Method:  "getblock",
+		Params:  []interface{}{blockhash, 0}, // verbosity=0 return raw hex
+	}
+
+	response, err := c.s.rpcCall(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	if response.Error != nil {
+		return "", blockRPCError(-1, response.Error.Message)
+	}
+
+	blockHex, ok := response.Result.(string)
+	if !ok {
+		return "", errors.New("invalid block hex response")
+	}
+
+	return blockHex, nil
+}
+
+// GetRawTransaction get raw transaction by txid
+// verbosity=0 returns raw transaction hex
+func (c *rpcNodeClient) GetRawTransaction(ctx context.Context, txid string) (string, error) {
+	request := RPCRequest{
+		Jsonrpc: "1.0",
+		ID:      "getrawtransaction",
+		Method:  "getrawtransaction",
+		Params:  []interface{}{txid, 0}, // verbosity=0 return raw hex
+	}
+
+	response, err := c.s.rpcCall(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	if response.Error != nil {
+		return "", fmt.Errorf("rpc error: %s", response.Error.Message)
+	}
+
+	txHex, ok := response.Result.(string)
+	if !ok {
+		return "", errors.New("invalid transaction hex response")
+	}
+
+	return txHex, nil
+}
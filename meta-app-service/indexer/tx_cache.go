@@ -0,0 +1,73 @@
+package indexer
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// rawTxCache LRU cache of raw transaction hex keyed by txid
+// Raw transactions are immutable, so entries never need to be invalidated
+type rawTxCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// rawTxCacheEntry cache entry stored in the LRU list
+type rawTxCacheEntry struct {
+	txid  string
+	txHex string
+}
+
+// newRawTxCache create LRU cache with given capacity
+func newRawTxCache(capacity int) *rawTxCache {
+	return &rawTxCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get get raw transaction hex by txid
+func (c *rawTxCache) Get(txid string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[txid]
+	if !ok {
+		c.misses.Add(1)
+		return "", false
+	}
+	c.hits.Add(1)
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*rawTxCacheEntry).txHex, true
+}
+
+// Add add raw transaction hex, evicting the least recently used entry when full
+func (c *rawTxCache) Add(txid, txHex string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[txid]; ok {
+		c.ll.MoveToFront(elem)
+		elem.Value.(*rawTxCacheEntry).txHex = txHex
+		return
+	}
+
+	c.items[txid] = c.ll.PushFront(&rawTxCacheEntry{txid: txid, txHex: txHex})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*rawTxCacheEntry).txid)
+	}
+}
+
+// Stats get cache hit and miss counts
+func (c *rawTxCache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
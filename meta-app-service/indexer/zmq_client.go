@@ -42,6 +42,9 @@ type ZMQClient struct {
 
 	// Transaction handler
 	txHandler func(tx interface{}, metaDataTx *MetaIDDataTx) error
+
+	// New block handler (hashblock topic)
+	blockHandler func(blockHash string)
 }
 
 // MessageHandler is the function type for handling ZMQ messages
@@ -66,6 +69,11 @@ func (c *ZMQClient) SetTransactionHandler(handler func(tx interface{}, metaDataT
 	c.txHandler = handler
 }
 
+// SetBlockHandler set handler called with the block hash when a hashblock notification arrives
+func (c *ZMQClient) SetBlockHandler(handler func(blockHash string)) {
+	c.blockHandler = handler
+}
+
 // AddTopic adds a topic to listen to and its handler
 func (c *ZMQClient) AddTopic(topic string, handler MessageHandler) {
 	// Ensure topic is not duplicated
@@ -238,6 +246,16 @@ func (c *ZMQClient) handleHashTx(topic string, data []byte) error {
 	return nil
 }
 
+// handleHashBlock handles new block hash messages
+func (c *ZMQClient) handleHashBlock(topic string, data []byte) error {
+	blockHash := hex.EncodeToString(data)
+	log.Printf("Received new block from ZMQ: %s (chain: %s)", blockHash, c.chainType)
+	if c.blockHandler != nil {
+		c.blockHandler(blockHash)
+	}
+	return nil
+}
+
 // StartWithRawTx starts ZMQ client and listens to raw transaction topic
 func (c *ZMQClient) StartWithRawTx() error {
 	// Add rawtx topic with handler
@@ -252,6 +270,13 @@ func (c *ZMQClient) StartWithHashTx() error {
 	return c.Start()
 }
 
+// StartWithRawTxAndHashBlock starts ZMQ client and listens to raw transaction and new block topics
+func (c *ZMQClient) StartWithRawTxAndHashBlock() error {
+	c.AddTopic("rawtx", c.handleRawTx)
+	c.AddTopic("hashblock", c.handleHashBlock)
+	return c.Start()
+}
+
 // StartWithBothTopics starts ZMQ client and listens to both rawtx and hashtx topics
 func (c *ZMQClient) StartWithBothTopics() error {
 	// Add both topics
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"log"
+	"math"
+
+	"meta-app-service/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "metaapp"
+
+var (
+	// BlocksScanned total number of blocks scanned, use rate() for blocks per second
+	BlocksScanned = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "blocks_scanned_total",
+		Help:      "Total number of blocks scanned.",
+	}, []string{"chain"})
+
+	// SyncHeight last scanned block height
+	SyncHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "sync_height",
+		Help:      "Last scanned block height.",
+	}, []string{"chain"})
+
+	// BlockPins number of MetaID PINs found per block
+	BlockPins = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "block_pins",
+		Help:      "Number of MetaID PINs found per scanned block.",
+		Buckets:   []float64{0, 1, 5, 10, 50, 100, 500, 1000},
+	}, []string{"chain"})
+
+	// RawTxCacheRequests raw transaction cache lookups by result (hit/miss)
+	RawTxCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "raw_tx_cache_requests_total",
+		Help:      "Raw transaction cache lookups by result.",
+	}, []string{"result"})
+
+	// RPCDuration latency of node RPC calls
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "call_duration_seconds",
+		Help:      "Latency of node RPC calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"chain", "method", "result"})
+
+	// DeployResults deploy attempts by result (success/failure)
+	DeployResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "deploy",
+		Name:      "results_total",
+		Help:      "Total number of deploy attempts by result.",
+	}, []string{"result"})
+
+	// DeployQueueLength current deploy queue length, evaluated on scrape
+	DeployQueueLength = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "deploy",
+		Name:      "queue_length",
+		Help:      "Current number of items in the deploy queue.",
+	}, deployQueueLength)
+)
+
+func init() {
+	prometheus.MustRegister(
+		BlocksScanned,
+		SyncHeight,
+		BlockPins,
+		RawTxCacheRequests,
+		RPCDuration,
+		DeployResults,
+		DeployQueueLength,
+	)
+}
+
+// Handler gin handler exposing metrics in Prometheus text format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// deployQueueLength count deploy queue items
+func deployQueueLength() float64 {
+	if database.DB == nil {
+		return 0
+	}
+	queues, _, err := database.DB.ListDeployQueueWithCursor(0, math.MaxInt32)
+	if err != nil {
+		log.Printf("Failed to get deploy queue length: %v", err)
+		return 0
+	}
+	return float64(len(queues))
+}
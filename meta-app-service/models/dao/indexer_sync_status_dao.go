@@ -36,6 +36,25 @@ func (dao *IndexerSyncStatusDAO) UpdateCurrentSyncHeight(chainName string, heigh
 	return dao.db.UpdateIndexerSyncStatusHeight(chainName, height)
 }
 
+// SaveCheckpoint save the resume checkpoint of a chain
+func (dao *IndexerSyncStatusDAO) SaveCheckpoint(checkpoint *model.IndexerSyncCheckpoint) error {
+	return dao.db.SaveIndexerSyncCheckpoint(checkpoint)
+}
+
+// GetCheckpoint get the resume checkpoint of a chain (nil when none has been written)
+func (dao *IndexerSyncStatusDAO) GetCheckpoint(chainName string) (*model.IndexerSyncCheckpoint, error) {
+	checkpoint, err := dao.db.GetIndexerSyncCheckpoint(chainName)
+	if err == database.ErrNotFound {
+		return nil, nil
+	}
+	return checkpoint, err
+}
+
+// GetMaxMetaAppBlockHeight get the highest block height of the MetaApp versions indexed from a chain
+func (dao *IndexerSyncStatusDAO) GetMaxMetaAppBlockHeight(chainName string) (int64, error) {
+	return dao.db.GetMaxMetaAppBlockHeight(chainName)
+}
+
 // GetAll get all chain sync status
 func (dao *IndexerSyncStatusDAO) GetAll() ([]*model.IndexerSyncStatus, error) {
 	return dao.db.GetAllIndexerSyncStatus()
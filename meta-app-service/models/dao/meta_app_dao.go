@@ -2,6 +2,7 @@ package dao
 
 import (
 	"fmt"
+	"time"
 
 	"meta-app-service/database"
 	model "meta-app-service/models"
@@ -35,6 +36,54 @@ func (d *MetaAppDAO) GetByPinID(pinID string) (*model.MetaApp, error) {
 	return d.db.GetMetaAppByPinID(pinID)
 }
 
+// GetLatestByFirstPinID 根据 FirstPinID 获取最新版本
+func (d *MetaAppDAO) GetLatestByFirstPinID(firstPinID string) (*model.MetaApp, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return d.db.GetLatestMetaAppByFirstPinID(firstPinID)
+}
+
+// SaveRawContent 保存 PIN 原始内容
+func (d *MetaAppDAO) SaveRawContent(content *model.MetaAppRawContent) error {
+	if d.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	return d.db.SaveMetaAppRawContent(content)
+}
+
+// GetRawContent 获取 PIN 原始内容
+func (d *MetaAppDAO) GetRawContent(pinID string) (*model.MetaAppRawContent, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return d.db.GetMetaAppRawContent(pinID)
+}
+
+// AddPendingModify 暂存被引用的 PIN 尚未索引的 modify 操作
+func (d *MetaAppDAO) AddPendingModify(pending *model.MetaAppPendingModify) error {
+	if d.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	return d.db.AddPendingModify(pending)
+}
+
+// TakePendingModifies 取出并删除引用 targetPinID 的暂存 modify 操作
+func (d *MetaAppDAO) TakePendingModifies(targetPinID string) ([]*model.MetaAppPendingModify, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return d.db.TakePendingModifies(targetPinID)
+}
+
+// PurgePendingModifies 删除暂存时间早于 before 的 modify 操作
+func (d *MetaAppDAO) PurgePendingModifies(before time.Time) (int, error) {
+	if d.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	return d.db.PurgePendingModifies(before)
+}
+
 // Update 更新 MetaApp 记录
 func (d *MetaAppDAO) Update(app *model.MetaApp) error {
 	if d.db == nil {
@@ -43,18 +92,58 @@ func (d *MetaAppDAO) Update(app *model.MetaApp) error {
 	return d.db.UpdateMetaApp(app)
 }
 
-// GetByCreatorMetaIDWithCursor 根据创建者 MetaID 获取 MetaApp 列表（按时间倒序，支持分页）
-func (d *MetaAppDAO) GetByCreatorMetaIDWithCursor(metaID string, cursor int64, size int) ([]*model.MetaApp, int64, error) {
+// GetByCreatorMetaIDWithCursor 根据创建者 MetaID 获取 MetaApp 列表（按时间倒序，支持过滤和分页）
+func (d *MetaAppDAO) GetByCreatorMetaIDWithCursor(metaID string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error) {
+	if d.db == nil {
+		return nil, "", fmt.Errorf("database not initialized")
+	}
+	return d.db.GetMetaAppsByCreatorMetaIDWithCursor(metaID, cursor, size, filter)
+}
+
+// CountByCreatorMetaID 统计创建者名下的 MetaApp 数量（按 first_pin_id 去重）
+func (d *MetaAppDAO) CountByCreatorMetaID(metaID string) (int64, error) {
+	if d.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	return d.db.CountMetaAppsByCreatorMetaID(metaID)
+}
+
+// GetByOwnerMetaIDWithCursor 根据当前拥有者 MetaID 获取 MetaApp 列表（按时间倒序，支持过滤和分页）
+func (d *MetaAppDAO) GetByOwnerMetaIDWithCursor(metaID string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error) {
+	if d.db == nil {
+		return nil, "", fmt.Errorf("database not initialized")
+	}
+	return d.db.GetMetaAppsByOwnerMetaIDWithCursor(metaID, cursor, size, filter)
+}
+
+// GetByAppNameWithCursor 根据应用名称（精确匹配）获取 MetaApp 列表（按时间倒序，支持过滤和分页）
+func (d *MetaAppDAO) GetByAppNameWithCursor(appName string, cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error) {
+	if d.db == nil {
+		return nil, "", fmt.Errorf("database not initialized")
+	}
+	return d.db.GetMetaAppsByAppNameWithCursor(appName, cursor, size, filter)
+}
+
+// GetByHeightRange 获取区块高度在 [fromHeight, toHeight] 内的 MetaApp（高度倒序，支持分页）
+func (d *MetaAppDAO) GetByHeightRange(fromHeight, toHeight int64, cursor string, size int) ([]*model.MetaApp, string, error) {
+	if d.db == nil {
+		return nil, "", fmt.Errorf("database not initialized")
+	}
+	return d.db.GetMetaAppsByHeightRange(fromHeight, toHeight, cursor, size)
+}
+
+// GetHistoryPage 分页获取 first_pin_id 的完整历史版本（包括归档的旧版本，时间倒序）
+func (d *MetaAppDAO) GetHistoryPage(firstPinID string, cursor string, size int) ([]*model.MetaApp, string, error) {
 	if d.db == nil {
-		return nil, 0, fmt.Errorf("database not initialized")
+		return nil, "", fmt.Errorf("database not initialized")
 	}
-	return d.db.GetMetaAppsByCreatorMetaIDWithCursor(metaID, cursor, size)
+	return d.db.GetMetaAppHistoryPage(firstPinID, cursor, size)
 }
 
-// ListWithCursor 获取所有 MetaApp 列表（按时间倒序，支持分页）
-func (d *MetaAppDAO) ListWithCursor(cursor int64, size int) ([]*model.MetaApp, int64, error) {
+// ListWithCursor 获取所有 MetaApp 列表（按时间倒序，支持过滤和分页）
+func (d *MetaAppDAO) ListWithCursor(cursor string, size int, filter model.MetaAppListFilter) ([]*model.MetaApp, string, error) {
 	if d.db == nil {
-		return nil, 0, fmt.Errorf("database not initialized")
+		return nil, "", fmt.Errorf("database not initialized")
 	}
-	return d.db.ListMetaAppsWithCursor(cursor, size)
+	return d.db.ListMetaAppsWithCursor(cursor, size, filter)
 }
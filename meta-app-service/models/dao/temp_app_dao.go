@@ -2,6 +2,7 @@ package dao
 
 import (
 	"fmt"
+	"time"
 
 	"meta-app-service/database"
 	model "meta-app-service/models"
@@ -35,6 +36,14 @@ func (d *TempAppDAO) GetByTokenID(tokenID string) (*model.TempAppDeploy, error)
 	return d.db.GetTempAppDeployByTokenID(tokenID)
 }
 
+// GetByContentHash 根据压缩包内容哈希获取临时应用部署记录
+func (d *TempAppDAO) GetByContentHash(contentHash string) (*model.TempAppDeploy, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return d.db.GetTempAppDeployByContentHash(contentHash)
+}
+
 // Delete 删除临时应用部署记录
 func (d *TempAppDAO) Delete(tokenID string) error {
 	if d.db == nil {
@@ -51,6 +60,14 @@ func (d *TempAppDAO) ListExpired() ([]*model.TempAppDeploy, error) {
 	return d.db.ListExpiredTempAppDeploys()
 }
 
+// CountActive 统计未过期的临时应用部署记录数
+func (d *TempAppDAO) CountActive() (int, error) {
+	if d.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	return d.db.CountActiveTempAppDeploys()
+}
+
 // CreateChunkUpload 创建临时应用分片上传记录
 func (d *TempAppDAO) CreateChunkUpload(upload *model.TempAppChunkUpload) error {
 	if d.db == nil {
@@ -82,3 +99,11 @@ func (d *TempAppDAO) DeleteChunkUpload(uploadID string) error {
 	}
 	return d.db.DeleteTempAppChunkUpload(uploadID)
 }
+
+// ListStaleChunkUploads 获取最后活动时间早于 before 的分片上传记录
+func (d *TempAppDAO) ListStaleChunkUploads(before time.Time) ([]*model.TempAppChunkUpload, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return d.db.ListStaleChunkUploads(before)
+}
@@ -21,3 +21,12 @@ type IndexerSyncStatus struct {
 func (IndexerSyncStatus) TableName() string {
 	return "tb_indexer_sync_status"
 }
+
+// IndexerSyncCheckpoint redundant resume point of a chain, written every indexer.checkpoint_interval blocks
+// and stored apart from IndexerSyncStatus so a lost or corrupted sync status does not restart indexing from scratch
+type IndexerSyncCheckpoint struct {
+	ChainName string    `json:"chain_name"` // btc/mvc
+	Height    int64     `json:"height"`     // Last fully scanned block height
+	BlockHash string    `json:"block_hash"` // Hash of the block at Height, checked against the node on startup
+	UpdatedAt time.Time `json:"updated_at"` // Write time
+}
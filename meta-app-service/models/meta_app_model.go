@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 // MetaApp MetaApp 协议数据模型
 type MetaApp struct {
@@ -30,6 +34,7 @@ type MetaApp struct {
 	ContentHash string   `json:"content_hash"` // 内容哈希
 	Metadata    string   `json:"metadata"`     // 元数据 (JSON 字符串)
 	Disabled    bool     `json:"disabled"`     // 是否禁用
+	Decrypted   bool     `json:"decrypted"`    // PIN 内容是否经过解密
 
 	// 链信息
 	ChainName   string `json:"chain_name"`   // 链名称: btc, mvc
@@ -43,10 +48,95 @@ type MetaApp struct {
 	OwnerMetaId    string `json:"owner_meta_id"`   // 拥有者 MetaID
 
 	// 状态信息
-	Status int `json:"status"` // 状态: 0-失败, 1-成功
-	State  int `json:"state"`  // 状态码
+	Status      int    `json:"status"`                 // 状态: 0-失败, 1-成功
+	State       int    `json:"state"`                  // 状态码: 0-正常, 1-内容校验失败（不会部署）, 2-被版本策略拒绝（不会成为最新版本）
+	StateReason string `json:"state_reason,omitempty"` // 状态原因（例如内容校验失败、版本号未递增的原因）
 
 	// 时间戳
 	CreatedAt time.Time `json:"created_at"` // 创建时间
 	UpdatedAt time.Time `json:"updated_at"` // 更新时间
 }
+
+// MetaApp 状态码
+const (
+	MetaAppStateNormal   = 0 // 正常
+	MetaAppStateInvalid  = 1 // 内容校验失败，不加入部署队列
+	MetaAppStateRejected = 2 // modify 的版本号不大于最新版本，被版本策略拒绝：只记录在历史中，不成为最新版本也不部署
+)
+
+// MetaAppRuntimes 支持的运行环境
+var MetaAppRuntimes = []string{"browser", "android", "ios", "windows", "macOS", "Linux"}
+
+// MetaAppChainNames 支持的链名称
+var MetaAppChainNames = []string{"btc", "mvc"}
+
+// 未确认（mempool）MetaApp 过滤方式
+const (
+	UnconfirmedInclude = "include" // 包含未确认记录（默认）
+	UnconfirmedExclude = "exclude" // 排除未确认记录
+	UnconfirmedOnly    = "only"    // 只返回未确认记录
+)
+
+// MetaAppUnconfirmedModes 支持的未确认记录过滤方式
+var MetaAppUnconfirmedModes = []string{UnconfirmedInclude, UnconfirmedExclude, UnconfirmedOnly}
+
+// MetaAppListFilter MetaApp 列表过滤条件（字段为空表示不过滤）
+type MetaAppListFilter struct {
+	ChainName   string // 链名称: btc, mvc
+	Runtime     string // 运行环境: browser/android/ios/windows/macOS/Linux（不区分大小写）
+	Unconfirmed string // 未确认记录过滤方式: include/exclude/only
+}
+
+// Match 判断 MetaApp 是否满足过滤条件
+func (f MetaAppListFilter) Match(app *MetaApp) bool {
+	if f.ChainName != "" && !strings.EqualFold(app.ChainName, f.ChainName) {
+		return false
+	}
+	if f.Runtime != "" && !strings.EqualFold(app.Runtime, f.Runtime) {
+		return false
+	}
+	switch strings.ToLower(f.Unconfirmed) {
+	case UnconfirmedExclude:
+		return app.IsConfirmed()
+	case UnconfirmedOnly:
+		return !app.IsConfirmed()
+	}
+	return true
+}
+
+// IsConfirmed 判断 MetaApp 是否已上链确认（ZMQ mempool 交易索引的未确认记录区块高度为 0）
+func (a *MetaApp) IsConfirmed() bool {
+	return a.BlockHeight > 0
+}
+
+// IsServable 判断该版本是否可以部署和提供服务（未被创建者禁用、不是 revoke 操作、内容校验通过、未被版本策略拒绝）
+func (a *MetaApp) IsServable() bool {
+	return !a.Disabled && !strings.EqualFold(a.Operation, "revoke") && a.State != MetaAppStateInvalid && a.State != MetaAppStateRejected
+}
+
+// MetaAppBlacklist MetaApp 黑名单记录（被禁用的应用不再提供静态文件服务，也不会再被部署）
+type MetaAppBlacklist struct {
+	FirstPinId   string    `json:"first_pin_id"`  // 第一个 PIN ID
+	Reason       string    `json:"reason"`        // 禁用原因
+	FilesRemoved bool      `json:"files_removed"` // 是否已删除部署文件
+	CreatedAt    time.Time `json:"created_at"`    // 禁用时间
+}
+
+// MetaAppRawContent MetaApp PIN 的原始内容（链上写入的字节，未解析、未解密）
+type MetaAppRawContent struct {
+	PinID       string    `json:"pin_id"`       // MetaApp PinID
+	ContentType string    `json:"content_type"` // PIN 声明的内容类型
+	Encryption  string    `json:"encryption"`   // PIN 的加密方式（"" 或 "0" 表示未加密）
+	Content     []byte    `json:"content"`      // 原始内容
+	CreatedAt   time.Time `json:"created_at"`   // 记录时间
+}
+
+// MetaAppPendingModify 被引用的 PIN 尚未索引而暂存的 modify 操作，被引用的 PIN 索引后重新处理
+type MetaAppPendingModify struct {
+	TargetPinID string          `json:"target_pin_id"` // 尚未索引的被引用 PinID
+	PinID       string          `json:"pin_id"`        // modify 操作的 PinID
+	Height      int64           `json:"height"`        // 区块高度（mempool 为 0）
+	Timestamp   int64           `json:"timestamp"`     // 区块时间
+	Data        json.RawMessage `json:"data"`          // 原始 PIN 数据
+	CreatedAt   time.Time       `json:"created_at"`    // 暂存时间
+}
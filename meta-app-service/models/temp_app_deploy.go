@@ -9,6 +9,7 @@ type TempAppDeploy struct {
 	ExpiresAt      time.Time `json:"expires_at"`       // 过期时间
 	Status         string    `json:"status"`           // 状态: pending/processing/completed/failed
 	Message        string    `json:"message"`          // 错误信息等
+	ContentHash    string    `json:"content_hash"`     // 上传压缩包的 SHA-256（用于重复上传检测）
 	CreatedAt      time.Time `json:"created_at"`       // 创建时间
 	UpdatedAt      time.Time `json:"updated_at"`       // 更新时间
 }
@@ -21,6 +22,7 @@ type TempAppChunkUpload struct {
 	TotalChunks    int          `json:"total_chunks"`    // 总分片数
 	ChunkSize      int64        `json:"chunk_size"`      // 分片大小
 	UploadedChunks map[int]bool `json:"uploaded_chunks"` // 已上传的分片索引（key: chunkIndex, value: true）
+	ChunkMD5s      []string     `json:"chunk_md5s"`      // 初始化时声明的每个分片的 MD5（可选，按分片索引排列）
 	Status         string       `json:"status"`          // 状态: uploading/merging/completed/failed
 	Message        string       `json:"message"`         // 错误信息等
 	CreatedAt      time.Time    `json:"created_at"`      // 创建时间
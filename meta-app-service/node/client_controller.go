@@ -3,6 +3,7 @@ package node
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"meta-app-service/conf"
 
@@ -21,6 +22,8 @@ var (
 
 var (
 	MyClientController *ClientController
+
+	clientControllerMu sync.Mutex // guards MyClientController.ClientMap (API handlers call concurrently)
 )
 
 func getChainRpcParams(chain string) (string, string, string) {
@@ -28,6 +31,9 @@ func getChainRpcParams(chain string) (string, string, string) {
 }
 
 func NewClientController(chain string) *ClientController {
+	clientControllerMu.Lock()
+	defer clientControllerMu.Unlock()
+
 	if MyClientController != nil {
 		if _, ok := MyClientController.ClientMap[chain]; ok {
 			return MyClientController
@@ -49,13 +55,25 @@ func NewClientController(chain string) *ClientController {
 	return MyClientController
 }
 
+// call send an RPC request with the client of a chain, looked up under clientControllerMu
+// (NewClientController adds clients to ClientMap concurrently)
+func (c *ClientController) call(net, path string, request []interface{}) (*gjson.Result, error) {
+	clientControllerMu.Lock()
+	client, ok := c.ClientMap[net]
+	clientControllerMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no rpc client for chain %s", net)
+	}
+	return client.Call(path, request)
+}
+
 func (c *ClientController) BroadcastTx(net, txHexStr string) (string, error) {
 	request := []interface{}{
 		txHexStr,
 		false,
 	}
 
-	result, err := c.ClientMap[net].Call("sendrawtransaction", request)
+	result, err := c.call(net, "sendrawtransaction", request)
 	if err != nil {
 		return "", err
 	}
@@ -78,7 +96,7 @@ func (c *ClientController) BroadcastTxBatch(net string, txHexStrs ...string) (*S
 		txObjects,
 	}
 
-	result, err := c.ClientMap[net].Call("sendrawtransactions", request)
+	result, err := c.call(net, "sendrawtransactions", request)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +133,7 @@ func (c *ClientController) BroadcastTxBatchWithOptions(net string, options ...Tx
 		txObjects,
 	}
 
-	result, err := c.ClientMap[net].Call("sendrawtransactions", request)
+	result, err := c.call(net, "sendrawtransactions", request)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +147,7 @@ func (c *ClientController) GetBlockhash(net string, height uint64) (string, erro
 		height,
 	}
 
-	result, err := c.ClientMap[net].Call("getblockhash", request)
+	result, err := c.call(net, "getblockhash", request)
 	if err != nil {
 		return "", err
 	}
@@ -139,7 +157,7 @@ func (c *ClientController) GetBlockhash(net string, height uint64) (string, erro
 
 func (c *ClientController) GetBlockHeight(net string) (uint64, error) {
 
-	result, err := c.ClientMap[net].Call("getblockcount", nil)
+	result, err := c.call(net, "getblockcount", nil)
 	if err != nil {
 		return 0, err
 	}
@@ -157,7 +175,7 @@ func (c *ClientController) GetBlock(net string, hash string, format ...uint64) (
 		request = append(request, format[0])
 	}
 
-	result, err := c.ClientMap[net].Call("getblock", request)
+	result, err := c.call(net, "getblock", request)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +189,7 @@ func (c *ClientController) GetTxIDsInMemPool(net string) ([]string, error) {
 		txids = make([]string, 0)
 	)
 
-	result, err := c.ClientMap[net].Call("getrawmempool", nil)
+	result, err := c.call(net, "getrawmempool", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -199,7 +217,7 @@ func (c *ClientController) GetTransaction(net string, txid string) (*Transaction
 		true,
 	}
 
-	result, err = c.ClientMap[net].Call("getrawtransaction", request)
+	result, err = c.call(net, "getrawtransaction", request)
 	if err != nil {
 
 		request = []interface{}{
@@ -207,7 +225,7 @@ func (c *ClientController) GetTransaction(net string, txid string) (*Transaction
 			1,
 		}
 
-		result, err = c.ClientMap[net].Call("getrawtransaction", request)
+		result, err = c.call(net, "getrawtransaction", request)
 		if err != nil {
 			return nil, err
 		}
@@ -228,7 +246,7 @@ func (c *ClientController) GetTransactionHex(net string, txid string) (string, e
 		false,
 	}
 
-	result, err = c.ClientMap[net].Call("getrawtransaction", request)
+	result, err = c.call(net, "getrawtransaction", request)
 	if err != nil {
 
 		request = []interface{}{
@@ -236,7 +254,7 @@ func (c *ClientController) GetTransactionHex(net string, txid string) (string, e
 			0,
 		}
 
-		result, err = c.ClientMap[net].Call("getrawtransaction", request)
+		result, err = c.call(net, "getrawtransaction", request)
 		if err != nil {
 			return "", err
 		}
@@ -250,7 +268,7 @@ func (c *ClientController) GetMempool(net string) ([]string, error) {
 		txIds = make([]string, 0)
 	)
 
-	result, err := c.ClientMap[net].Call("getrawmempool", nil)
+	result, err := c.call(net, "getrawmempool", nil)
 	if err != nil {
 		return nil, err
 	}
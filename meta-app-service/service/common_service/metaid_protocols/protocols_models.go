@@ -53,8 +53,63 @@ const (
 	MonitorMetaApp = "metaapp"
 )
 
+// MetaAppProtocolPath path of the MetaApp protocol
+var MetaAppProtocolPath = fmt.Sprintf("/protocols/%s", strings.ToLower(MonitorMetaApp))
+
 var (
+	// ProtocolList protocol paths recognized by the indexer (indexer.protocols, MetaApp only by default)
 	ProtocolList = []string{
-		fmt.Sprintf("/protocols/%s", strings.ToLower(MonitorMetaApp)),
+		MetaAppProtocolPath,
 	}
 )
+
+// SetProtocolList replace the recognized protocol paths
+// Paths are lower-cased, given a leading slash and de-duplicated; an empty list keeps the current list
+func SetProtocolList(paths []string) {
+	list := make([]string, 0, len(paths))
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		path = strings.ToLower(strings.TrimSpace(path))
+		if path == "" {
+			continue
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		path = strings.TrimSuffix(path, "/")
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		list = append(list, path)
+	}
+	if len(list) > 0 {
+		ProtocolList = list
+	}
+}
+
+// MatchProtocol get the protocol path a PIN path belongs to, empty when none matches
+// The protocol must be the whole path or a leading path segment of it (after an optional "<host>:" prefix),
+// so /protocols/metaapp matches /protocols/metaapp and /protocols/metaapp/xxx but not /protocols/metaapp-config
+// or /protocols/comment?ref=/protocols/metaapp; when several protocols match, the longest wins
+func MatchProtocol(path string) string {
+	path = strings.ToLower(stripPathHost(path))
+	matched := ""
+	for _, protocolPath := range ProtocolList {
+		if path != protocolPath && !strings.HasPrefix(path, protocolPath+"/") {
+			continue
+		}
+		if len(protocolPath) > len(matched) {
+			matched = protocolPath
+		}
+	}
+	return matched
+}
+
+// stripPathHost remove the optional "<host>:" prefix of a MetaID path
+func stripPathHost(path string) string {
+	if i := strings.Index(path, ":/"); i > 0 && !strings.Contains(path[:i], "/") {
+		return path[i+1:]
+	}
+	return path
+}
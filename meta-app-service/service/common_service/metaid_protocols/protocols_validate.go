@@ -0,0 +1,117 @@
+package metaid_protocols
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	model "meta-app-service/models"
+)
+
+var (
+	// pinIDPattern 64 hex characters + 'i' + output index
+	pinIDPattern = regexp.MustCompile(`^[0-9a-f]{64}i\d+$`)
+	// metafileImagePattern metafile://<pinid>, optionally followed by a file extension
+	metafileImagePattern = regexp.MustCompile(`^metafile://[0-9a-f]{64}i\d+(\.[0-9A-Za-z]+)?$`)
+)
+
+// ValidationError MetaApp content validation failure with all failed checks
+type ValidationError struct {
+	Reasons []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid MetaApp content: " + strings.Join(e.Reasons, "; ")
+}
+
+// ValidateMetaApp check the deployable code, indexFile and runtime values and metafile:// formatting
+// Returns *ValidationError listing every failed check, nil when the content is deployable
+func ValidateMetaApp(app *MetaApp) error {
+	var reasons []string
+
+	// code (or content as fallback) is what gets downloaded and deployed
+	code := strings.TrimSpace(app.Code)
+	content := strings.TrimSpace(app.Content)
+	switch {
+	case code != "":
+		if !isMetafilePinID(code) {
+			reasons = append(reasons, fmt.Sprintf("code must be metafile://<pinid>, got %q", app.Code))
+		}
+	case content != "":
+		if !isMetafilePinID(content) && !pinIDPattern.MatchString(content) {
+			reasons = append(reasons, fmt.Sprintf("content must be <pinid> or metafile://<pinid>, got %q", app.Content))
+		}
+	default:
+		reasons = append(reasons, "code is required")
+	}
+
+	// indexFile and runtime are optional (index.html and browser are used when unset), but must be well-formed when set
+	if reason := validateIndexFile(app.IndexFile); reason != "" {
+		reasons = append(reasons, reason)
+	}
+
+	if reason := validateRuntime(app.Runtime); reason != "" {
+		reasons = append(reasons, reason)
+	}
+
+	// Image fields are optional, but must point to a metafile when set
+	if app.Icon != "" && !metafileImagePattern.MatchString(app.Icon) {
+		reasons = append(reasons, fmt.Sprintf("icon must be metafile://<pinid>, got %q", app.Icon))
+	}
+	if app.CoverImg != "" && !metafileImagePattern.MatchString(app.CoverImg) {
+		reasons = append(reasons, fmt.Sprintf("coverImg must be metafile://<pinid>, got %q", app.CoverImg))
+	}
+	for i, img := range app.IntroImgs {
+		if img != "" && !metafileImagePattern.MatchString(img) {
+			reasons = append(reasons, fmt.Sprintf("introImgs[%d] must be metafile://<pinid>, got %q", i, img))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &ValidationError{Reasons: reasons}
+	}
+	return nil
+}
+
+// validateIndexFile check indexFile, when set, is a relative path inside the deploy directory (a leading "/" is allowed)
+func validateIndexFile(indexFile string) string {
+	indexFile = strings.TrimSpace(indexFile)
+	if indexFile == "" {
+		return ""
+	}
+	cleaned := path.Clean(strings.TrimPrefix(indexFile, "/"))
+	if strings.Contains(indexFile, "://") || strings.Contains(indexFile, "\\") ||
+		cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Sprintf("indexFile must be a file path inside the app, got %q", indexFile)
+	}
+	return ""
+}
+
+// validateRuntime check every runtime value (separated by "/" or ",") is a known runtime, an empty runtime is accepted
+func validateRuntime(runtime string) string {
+	if strings.TrimSpace(runtime) == "" {
+		return ""
+	}
+
+	values := strings.FieldsFunc(runtime, func(r rune) bool { return r == '/' || r == ',' })
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		valid := false
+		for _, known := range model.MetaAppRuntimes {
+			if strings.EqualFold(value, known) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Sprintf("invalid runtime %q, accepted values: %s", value, strings.Join(model.MetaAppRuntimes, "/"))
+		}
+	}
+	return ""
+}
+
+// isMetafilePinID check value is metafile://<pinid>
+func isMetafilePinID(value string) bool {
+	return strings.HasPrefix(value, "metafile://") && pinIDPattern.MatchString(strings.TrimPrefix(value, "metafile://"))
+}
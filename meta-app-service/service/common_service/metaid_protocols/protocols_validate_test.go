@@ -0,0 +1,39 @@
+package metaid_protocols
+
+import (
+	"strings"
+	"testing"
+)
+
+const testCodePinID = "metafile://adbb39ae44c8ce3e1b5f2ba6a8a3b6d9f07c1a4e3f2d9b8c7a6e5d4c3b2a1f0ei0"
+
+func TestValidateMetaApp(t *testing.T) {
+	cases := []struct {
+		name    string
+		app     MetaApp
+		wantErr string // substring of the error, empty when valid
+	}{
+		{name: "code only", app: MetaApp{Code: testCodePinID}},
+		{name: "content pinid", app: MetaApp{Content: strings.TrimPrefix(testCodePinID, "metafile://")}},
+		{name: "index file and runtime", app: MetaApp{Code: testCodePinID, IndexFile: "/dist/index.html", Runtime: "browser/android"}},
+		{name: "missing code", app: MetaApp{IndexFile: "index.html", Runtime: "browser"}, wantErr: "code is required"},
+		{name: "malformed code", app: MetaApp{Code: "https://example.com/app.zip"}, wantErr: "code must be metafile://"},
+		{name: "index file outside app", app: MetaApp{Code: testCodePinID, IndexFile: "../index.html"}, wantErr: "indexFile"},
+		{name: "index file url", app: MetaApp{Code: testCodePinID, IndexFile: "https://example.com/index.html"}, wantErr: "indexFile"},
+		{name: "index file directory", app: MetaApp{Code: testCodePinID, IndexFile: "/"}, wantErr: "indexFile"},
+		{name: "unknown runtime", app: MetaApp{Code: testCodePinID, Runtime: "browser/tv"}, wantErr: `invalid runtime "tv"`},
+		{name: "malformed icon", app: MetaApp{Code: testCodePinID, Icon: "icon.png"}, wantErr: "icon must be metafile://"},
+	}
+	for _, c := range cases {
+		err := ValidateMetaApp(&c.app)
+		if c.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s: error %v, want %q", c.name, err, c.wantErr)
+		}
+	}
+}
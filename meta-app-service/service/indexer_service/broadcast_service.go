@@ -0,0 +1,113 @@
+package indexer_service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"meta-app-service/common"
+	"meta-app-service/indexer"
+	"meta-app-service/node"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+)
+
+// 广播交易的错误类型（由节点 RPC 错误信息归类而来）
+var (
+	ErrTxInvalid          = errors.New("invalid raw transaction")
+	ErrTxInsufficientFee  = errors.New("insufficient fee")
+	ErrTxMissingInputs    = errors.New("missing or spent inputs")
+	ErrTxAlreadyInMempool = errors.New("transaction already in mempool")
+	ErrTxAlreadyInChain   = errors.New("transaction already in block chain")
+	ErrTxRejected         = errors.New("transaction rejected by node")
+)
+
+// BroadcastTx 通过节点广播已签名的交易，返回 txid
+// 节点返回的错误会被归类为上面的错误类型（使用 errors.Is 判断），交易已在 mempool 或已上链时同样返回 txid
+func BroadcastTx(chain, rawTx string) (string, error) {
+	rawTx = strings.TrimSpace(rawTx)
+	txID, err := rawTxID(chain, rawTx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTxInvalid, err)
+	}
+
+	result, err := node.BroadcastTx(chain, rawTx)
+	if err != nil {
+		return txID, classifyBroadcastError(err)
+	}
+	if result != "" {
+		txID = result
+	}
+	return txID, nil
+}
+
+// rawTxID 解析原始交易并计算 txid（MVC 版本 10 及以上的交易 txid 算法与 BTC 不同）
+func rawTxID(chain, rawTx string) (string, error) {
+	decoded, err := indexer.DecodeRawTransaction(rawTx, indexer.ChainType(chain))
+	if err != nil {
+		return "", err
+	}
+	if tx, ok := decoded.(*btcwire.MsgTx); ok {
+		return tx.TxHash().String(), nil
+	}
+	txID := common.GetMvcTxhashFromRaw(rawTx)
+	if txID == "" {
+		return "", fmt.Errorf("failed to compute txid")
+	}
+	return txID, nil
+}
+
+// 节点 sendrawtransaction 的 RPC 错误码（Bitcoin Core / BSV 系节点一致）
+const (
+	rpcDeserializationError = -22 // RPC_DESERIALIZATION_ERROR：交易无法解析
+	rpcVerifyError          = -25 // RPC_VERIFY_ERROR：一般为输入不存在或已花费
+	rpcVerifyRejected       = -26 // RPC_VERIFY_REJECTED：被内存池策略拒绝，错误信息为拒绝原因
+	rpcVerifyAlreadyInChain = -27 // RPC_VERIFY_ALREADY_IN_CHAIN：交易已上链（部分节点已在内存池时也返回）
+)
+
+// insufficientFeeReasons 节点因手续费不足拒绝交易的原因（不包括 absurdly-high-fee 等手续费过高的拒绝）
+var insufficientFeeReasons = []string{
+	"min relay fee not met",
+	"mempool min fee not met",
+	"insufficient fee",
+	"insufficient priority",
+}
+
+// rpcErrorPattern 节点 RPC 错误信息格式：[code]message（见 node.IsError）
+var rpcErrorPattern = regexp.MustCompile(`^\[(-?\d+)\](.*)$`)
+
+// classifyBroadcastError 根据节点 sendrawtransaction 的 RPC 错误码和拒绝原因归类错误
+// 非 RPC 错误（如网络错误）原样返回
+func classifyBroadcastError(err error) error {
+	match := rpcErrorPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	code, _ := strconv.Atoi(match[1])
+	reason := strings.ToLower(match[2])
+
+	switch {
+	case strings.Contains(reason, "txn-already-in-mempool") || strings.Contains(reason, "txn-already-known") ||
+		strings.Contains(reason, "already in mempool") || strings.Contains(reason, "already known"):
+		return fmt.Errorf("%w: %v", ErrTxAlreadyInMempool, err)
+	case code == rpcVerifyAlreadyInChain:
+		return fmt.Errorf("%w: %v", ErrTxAlreadyInChain, err)
+	case code == rpcDeserializationError:
+		return fmt.Errorf("%w: %v", ErrTxInvalid, err)
+	case code == rpcVerifyError && (strings.Contains(reason, "missing inputs") || strings.Contains(reason, "missingorspent")):
+		return fmt.Errorf("%w: %v", ErrTxMissingInputs, err)
+	case code == rpcVerifyRejected:
+		if strings.Contains(reason, "missingorspent") {
+			return fmt.Errorf("%w: %v", ErrTxMissingInputs, err)
+		}
+		for _, feeReason := range insufficientFeeReasons {
+			if strings.Contains(reason, feeReason) {
+				return fmt.Errorf("%w: %v", ErrTxInsufficientFee, err)
+			}
+		}
+	}
+	// 其他 RPC 错误，如脚本校验失败、双花冲突、手续费过高
+	return fmt.Errorf("%w: %v", ErrTxRejected, err)
+}
@@ -0,0 +1,37 @@
+package indexer_service
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestClassifyBroadcastError classifies node errors by RPC code and reject reason
+func TestClassifyBroadcastError(t *testing.T) {
+	networkErr := errors.New("dial tcp 127.0.0.1:9882: connect: connection refused")
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"min relay fee", errors.New("[-26]66: min relay fee not met"), ErrTxInsufficientFee},
+		{"mempool min fee", errors.New("[-26]mempool min fee not met, 100 < 200"), ErrTxInsufficientFee},
+		{"insufficient priority", errors.New("[-26]66: insufficient priority"), ErrTxInsufficientFee},
+		{"absurdly high fee", errors.New("[-26]256: absurdly-high-fee"), ErrTxRejected},
+		{"fee in a script error", errors.New("[-26]16: mandatory-script-verify-flag-failed (feepayer signature)"), ErrTxRejected},
+		{"missing inputs", errors.New("[-25]Missing inputs"), ErrTxMissingInputs},
+		{"inputs spent", errors.New("[-26]bad-txns-inputs-missingorspent"), ErrTxMissingInputs},
+		{"already in mempool", errors.New("[-26]txn-already-in-mempool"), ErrTxAlreadyInMempool},
+		{"already known", errors.New("[-27]txn-already-known"), ErrTxAlreadyInMempool},
+		{"already in chain", errors.New("[-27]Transaction already in block chain"), ErrTxAlreadyInChain},
+		{"decode failed", errors.New("[-22]TX decode failed"), ErrTxInvalid},
+		{"double spend", errors.New("[-26]txn-mempool-conflict"), ErrTxRejected},
+		{"network error", networkErr, networkErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyBroadcastError(tt.err); !errors.Is(got, tt.want) {
+				t.Fatalf("classified %q as %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package indexer_service
+
+import (
+	"log"
+	"strings"
+
+	"meta-app-service/conf"
+	"meta-app-service/indexer"
+)
+
+// creatorAddress 获取 PIN 的真实创建者地址（creator input 所花费输出的地址），查询失败时使用解析器给出的地址
+func (s *IndexerService) creatorAddress(metaData *indexer.MetaIDData) string {
+	if metaData.CreatorInputLocation == "" {
+		return metaData.CreatorAddress
+	}
+	realAddress, err := s.parser.FindCreatorAddressFromCreatorInputLocation(metaData.CreatorInputLocation, s.chainType)
+	if err != nil {
+		log.Printf("Failed to get creator address from location %s: %v, using fallback address",
+			metaData.CreatorInputLocation, err)
+		return metaData.CreatorAddress
+	}
+	log.Printf("Found real creator address for PIN %s: %s (from location: %s)", metaData.PinID, realAddress, metaData.CreatorInputLocation)
+	return realAddress
+}
+
+// creatorAllowed 按 indexer.creator_allowlist / creator_denylist 判断是否索引该 PIN，不索引时返回原因
+// 名单项可以是创建者地址或 MetaID（地址的 sha256）；未配置名单时不查询创建者地址
+func (s *IndexerService) creatorAllowed(metaData *indexer.MetaIDData) (bool, string) {
+	allowlist, denylist := conf.Cfg().Indexer.CreatorAllowlist, conf.Cfg().Indexer.CreatorDenylist
+	if len(allowlist) == 0 && len(denylist) == 0 {
+		return true, ""
+	}
+
+	// 查询到的真实地址写回 metaData，后续处理不再重复查询
+	address := s.creatorAddress(metaData)
+	metaData.CreatorAddress = address
+	metaData.CreatorInputLocation = ""
+
+	metaID := calculateMetaID(address)
+	if len(allowlist) > 0 && !creatorListed(allowlist, address, metaID) {
+		return false, "creator " + address + " is not in creator_allowlist"
+	}
+	if creatorListed(denylist, address, metaID) {
+		return false, "creator " + address + " is in creator_denylist"
+	}
+	return true, ""
+}
+
+// creatorListed 判断地址或其 MetaID 是否在名单中（MetaID 不区分大小写）
+func creatorListed(list []string, address, metaID string) bool {
+	if address == "" {
+		return false
+	}
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry == address || strings.EqualFold(entry, metaID) {
+			return true
+		}
+	}
+	return false
+}
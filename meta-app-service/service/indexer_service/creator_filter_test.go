@@ -0,0 +1,53 @@
+package indexer_service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+	"meta-app-service/indexer"
+	"meta-app-service/models/dao"
+)
+
+// TestHandleTransactionCreatorFilter checks PINs of filtered creators are neither stored nor queued for deploy
+func TestHandleTransactionCreatorFilter(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{metaAppDAO: dao.NewMetaAppDAO()}
+	s.registerProtocolProcessors()
+
+	const trusted, unknown, banned = "1TrustedCreatorAddress", "1UnknownCreatorAddress", "1BannedCreatorAddress"
+	conf.Cfg().Indexer.CreatorAllowlist = []string{trusted, calculateMetaID(banned)}
+	conf.Cfg().Indexer.CreatorDenylist = []string{banned}
+
+	content, _ := json.Marshal(map[string]interface{}{
+		"appName":   "app",
+		"runtime":   "browser",
+		"indexFile": "index.html",
+		"content":   testCodePinID,
+	})
+	pin := func(pinID, creator string) *indexer.MetaIDData {
+		return &indexer.MetaIDData{PinID: pinID, Operation: "create", Path: "/protocols/metaapp", CreatorAddress: creator, Content: content}
+	}
+	err := s.handleTransaction(nil, &indexer.MetaIDDataTx{MetaIDData: []*indexer.MetaIDData{
+		pin("trustedi0", trusted),
+		pin("unknowni0", unknown),
+		pin("bannedi0", banned), // allowed by MetaID, then denied by address
+		pin("anonymousi0", ""),
+	}}, 100, 1700000000)
+	if err != nil {
+		t.Fatalf("handleTransaction failed: %v", err)
+	}
+
+	if _, err := database.DB.GetMetaAppByPinID("trustedi0"); err != nil {
+		t.Fatalf("allowed creator's PIN not indexed: %v", err)
+	}
+	for _, pinID := range []string{"unknowni0", "bannedi0", "anonymousi0"} {
+		if _, err := database.DB.GetMetaAppByPinID(pinID); err == nil {
+			t.Errorf("filtered PIN %s was indexed", pinID)
+		}
+	}
+	if got := countDeployQueue(t); got != 1 {
+		t.Fatalf("deploy queue has %d items, want 1", got)
+	}
+}
@@ -0,0 +1,98 @@
+package indexer_service
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"testing"
+
+	"meta-app-service/conf"
+	"meta-app-service/indexer"
+	"meta-app-service/tool"
+)
+
+// encryptPinContent AES-256-GCM encrypt plaintext with sha256(secret), laid out as nonce + ciphertext + tag
+func encryptPinContent(t *testing.T, secret string, plaintext []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(tool.DeriveAESKey(secret))
+	if err != nil {
+		t.Fatalf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("failed to create GCM: %v", err)
+	}
+	nonce := bytes.Repeat([]byte{0x07}, gcm.NonceSize())
+	return gcm.Seal(nonce, nonce, plaintext, nil)
+}
+
+// setDecryptionSecret set meta_app.decryption_secret for the test
+func setDecryptionSecret(t *testing.T, secret string) {
+	t.Helper()
+	prev := conf.Cfg()
+	conf.SetCfg(&conf.Config{MetaApp: conf.MetaAppConfig{DecryptionSecret: secret}})
+	t.Cleanup(func() { conf.SetCfg(prev) })
+}
+
+// TestDecryptPinContentRoundTrip decrypts raw and base64 content for every algorithm alias,
+// with the key from the configured secret and from the owner address
+func TestDecryptPinContentRoundTrip(t *testing.T) {
+	const owner = "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"
+	plaintext := []byte(`{"appName":"secret"}`)
+	keySources := []struct {
+		name   string
+		secret string // meta_app.decryption_secret
+		key    string // secret the content is encrypted with
+	}{
+		{"configured secret", "s3cret", "s3cret"},
+		{"owner address", "", owner},
+	}
+	for _, source := range keySources {
+		for _, alias := range []string{"aes", "aes256", "aes-256", "aes-256-gcm"} {
+			t.Run(source.name+"/"+alias, func(t *testing.T) {
+				setDecryptionSecret(t, source.secret)
+				sealed := encryptPinContent(t, source.key, plaintext)
+				for _, content := range [][]byte{sealed, []byte(base64.StdEncoding.EncodeToString(sealed))} {
+					got, decrypted, err := decryptPinContent(&indexer.MetaIDData{Encryption: alias, OwnerAddress: owner, Content: content})
+					if err != nil || !decrypted || !bytes.Equal(got, plaintext) {
+						t.Fatalf("got %q (decrypted %v, err %v), want %q", got, decrypted, err, plaintext)
+					}
+				}
+			})
+		}
+	}
+
+	got, decrypted, err := decryptPinContent(&indexer.MetaIDData{Encryption: "0", Content: plaintext})
+	if err != nil || decrypted || !bytes.Equal(got, plaintext) {
+		t.Fatalf("unencrypted content changed: %q (decrypted %v, err %v)", got, decrypted, err)
+	}
+}
+
+// TestDecryptPinContentErrors returns errors, never panics, on a wrong key, a truncated nonce,
+// content that is neither ciphertext nor base64 and an unknown algorithm
+func TestDecryptPinContentErrors(t *testing.T) {
+	setDecryptionSecret(t, "s3cret")
+	sealed := encryptPinContent(t, "s3cret", []byte("hello"))
+
+	tests := []struct {
+		name       string
+		encryption string
+		content    []byte
+	}{
+		{"wrong key", "aes", encryptPinContent(t, "other", []byte("hello"))},
+		{"truncated nonce", "aes", sealed[:5]},
+		{"truncated base64 nonce", "aes", []byte(base64.StdEncoding.EncodeToString(sealed[:5]))},
+		{"not base64", "aes", []byte("not base64 content!")},
+		{"empty content", "aes-256-gcm", nil},
+		{"unsupported algorithm", "chacha20", sealed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, decrypted, err := decryptPinContent(&indexer.MetaIDData{Encryption: tt.encryption, Content: tt.content})
+			if err == nil || decrypted || got != nil {
+				t.Fatalf("got %q (decrypted %v, err %v), want an error", got, decrypted, err)
+			}
+		})
+	}
+}
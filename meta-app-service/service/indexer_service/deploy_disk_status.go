@@ -0,0 +1,144 @@
+package indexer_service
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+	model "meta-app-service/models"
+)
+
+// 部署目录核对发现的问题
+const (
+	DeployDiskProblemOrphaned = "orphaned" // 目录存在但没有部署记录
+	DeployDiskProblemMissing  = "missing"  // 部署记录为已完成但目录不存在或为空
+)
+
+// DeployDiskEntry 一个应用的部署目录与部署记录的核对结果
+type DeployDiskEntry struct {
+	FirstPinID   string     `json:"first_pin_id"`
+	PinID        string     `json:"pin_id,omitempty"`        // 部署记录对应的版本 PinID（没有部署记录时为空）
+	DeployStatus string     `json:"deploy_status,omitempty"` // 部署记录状态（没有部署记录时为空）
+	DeployedAt   *time.Time `json:"deployed_at,omitempty"`   // 部署记录更新时间
+	OnDisk       bool       `json:"on_disk"`                 // 部署目录存在且不为空
+	SizeBytes    int64      `json:"size_bytes"`              // 部署目录大小
+	Evicted      bool       `json:"evicted"`                 // 因超出部署目录配额被清理（再次访问时重新部署，不算缺失）
+	Problem      string     `json:"problem,omitempty"`       // orphaned / missing，没有问题时为空
+}
+
+// DeployDiskStatus 部署目录核对结果
+type DeployDiskStatus struct {
+	BaseDir  string             `json:"base_dir"`
+	Total    int                `json:"total"`    // 核对的应用数（目录和部署记录的并集）
+	Orphaned int                `json:"orphaned"` // 没有部署记录的目录数
+	Missing  int                `json:"missing"`  // 缺少文件的已完成部署数
+	Entries  []*DeployDiskEntry `json:"entries"`  // 按 first_pin_id 排序
+}
+
+// GetDeployDiskStatus 核对部署目录与部署记录
+// 列出部署目录下的应用目录（目录名为 first_pin_id），与部署记录对照，标记没有部署记录的目录（orphaned）
+// 和记录为已完成但没有文件的部署（missing），供清理工具使用
+func (s *IndexerAppService) GetDeployDiskStatus() (*DeployDiskStatus, error) {
+	if database.DB == nil {
+		return nil, database.ErrDatabaseNotInitialized
+	}
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		return nil, ErrDeployDisabled
+	}
+
+	baseDir := metaAppDeployBaseDir()
+	dirEntries, err := os.ReadDir(baseDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	entries := make(map[string]*DeployDiskEntry)
+	evicted := make(map[string]bool)
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		// 跳过 .staging、.releases、.images 等隐藏目录
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		// 应用目录是指向 .releases 中版本目录的符号链接，os.Stat 跟随链接
+		if info, err := os.Stat(filepath.Join(baseDir, name)); err != nil || !info.IsDir() {
+			if strings.HasSuffix(name, evictedMarkerSuffix) {
+				evicted[strings.TrimSuffix(name, evictedMarkerSuffix)] = true
+			}
+			continue
+		}
+		appDir := filepath.Join(baseDir, name)
+		files, err := os.ReadDir(appDir)
+		if err != nil {
+			return nil, err
+		}
+		entries[name] = &DeployDiskEntry{
+			FirstPinID: name,
+			OnDisk:     len(files) > 0,
+			SizeBytes:  deployDirSize(appDir),
+		}
+	}
+
+	// 最近部署索引中每个 first_pin_id 有一条最近一次成功部署的记录
+	cursor := ""
+	for {
+		deploys, nextCursor, err := database.DB.ListRecentDeploysWithCursor(cursor, 500)
+		if err != nil {
+			return nil, err
+		}
+		for _, deploy := range deploys {
+			firstPinID := deploy.FirstPinId
+			if firstPinID == "" {
+				firstPinID = deploy.PinID
+			}
+			entry, ok := entries[firstPinID]
+			if !ok {
+				entry = &DeployDiskEntry{FirstPinID: firstPinID}
+				entries[firstPinID] = entry
+			}
+			entry.setDeploy(deploy)
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	status := &DeployDiskStatus{BaseDir: baseDir, Entries: make([]*DeployDiskEntry, 0, len(entries))}
+	for firstPinID, entry := range entries {
+		// 不在最近部署索引中的目录（没有成功部署过，或部署记录早于索引），按 first_pin_id 查询部署记录
+		if entry.DeployStatus == "" {
+			if deploy, err := database.DB.GetDeployFileContent(firstPinID); err == nil {
+				entry.setDeploy(deploy)
+			}
+		}
+		entry.Evicted = evicted[firstPinID] && !entry.OnDisk
+
+		switch {
+		case entry.DeployStatus == "":
+			entry.Problem = DeployDiskProblemOrphaned
+			status.Orphaned++
+		case entry.DeployStatus == "completed" && !entry.OnDisk && !entry.Evicted:
+			entry.Problem = DeployDiskProblemMissing
+			status.Missing++
+		}
+		status.Entries = append(status.Entries, entry)
+	}
+	sort.Slice(status.Entries, func(i, j int) bool {
+		return status.Entries[i].FirstPinID < status.Entries[j].FirstPinID
+	})
+	status.Total = len(status.Entries)
+	return status, nil
+}
+
+// setDeploy 记录核对使用的部署记录
+func (e *DeployDiskEntry) setDeploy(deploy *model.MetaAppDeployFileContent) {
+	e.PinID = deploy.PinID
+	e.DeployStatus = deploy.DeployStatus
+	deployedAt := deploy.UpdatedAt
+	e.DeployedAt = &deployedAt
+}
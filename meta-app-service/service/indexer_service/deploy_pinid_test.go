@@ -0,0 +1,122 @@
+package indexer_service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"meta-app-service/database"
+	"meta-app-service/indexer"
+	model "meta-app-service/models"
+	"meta-app-service/models/dao"
+)
+
+const testCodePinID = "adbb39ae2b8c1129e09815d131a510268f4ba496a3d79021a8c4dc78f4dbb875i0"
+
+func TestDeployPinIDNormalization(t *testing.T) {
+	cases := []struct {
+		name          string
+		code, content string
+		want          string
+	}{
+		{"code", "metafile://" + testCodePinID, "", "metafile://" + testCodePinID},
+		{"code wins over content", "metafile://" + testCodePinID, "other", "metafile://" + testCodePinID},
+		{"bare content", "", testCodePinID, "metafile://" + testCodePinID},
+		{"prefixed content", "", "metafile://" + testCodePinID, "metafile://" + testCodePinID},
+		{"content with whitespace", "", "  " + testCodePinID + "\n", "metafile://" + testCodePinID},
+		{"blank code falls back to content", "   ", testCodePinID, "metafile://" + testCodePinID},
+		{"nothing", "", " ", ""},
+	}
+	for _, tc := range cases {
+		if got := deployPinID(tc.code, tc.content); got != tc.want {
+			t.Errorf("%s: deployPinID(%q, %q) = %q, want %q", tc.name, tc.code, tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestValidateDeployPinIDRejectsMalformedContent(t *testing.T) {
+	valid := []string{
+		testCodePinID,
+		"metafile://" + testCodePinID,
+		" metafile://" + testCodePinID + " ",
+		testCodePinID[:64] + "i12",
+	}
+	for _, content := range valid {
+		if err := validateDeployPinID("", content); err != nil {
+			t.Errorf("content %q: unexpected error %v", content, err)
+		}
+	}
+
+	malformed := []string{
+		"",
+		"   ",
+		"metafile://",
+		"not-a-pin",
+		testCodePinID[:63] + "i0",       // hex part too short
+		testCodePinID[:64] + "0" + "i0", // hex part too long
+		strings.ToUpper(testCodePinID[:64]) + "i0",    // uppercase hex
+		testCodePinID[:64],                            // missing output index
+		testCodePinID[:64] + "i",                      // empty output index
+		testCodePinID[:64] + "ix",                     // non-numeric output index
+		"metafile://metafile://" + testCodePinID,      // doubled prefix
+		"https://" + testCodePinID,                    // wrong scheme
+		"METAFILE://" + testCodePinID,                 // scheme is case sensitive
+		"metafile://" + testCodePinID + "/index.html", // trailing path
+		testCodePinID + " " + testCodePinID,           // two pins
+	}
+	for _, content := range malformed {
+		if err := validateDeployPinID("", content); err == nil {
+			t.Errorf("content %q: expected validation error", content)
+		}
+	}
+
+	// A malformed Code is not rescued by a valid Content
+	if err := validateDeployPinID("metafile://bad", testCodePinID); err == nil {
+		t.Error("malformed code with valid content: expected validation error")
+	}
+}
+
+// TestProcessMetaAppContentSkipsMalformedContent indexes a MetaApp whose Content is not a pinId and checks it is
+// recorded as invalid instead of entering the deploy queue
+func TestProcessMetaAppContentSkipsMalformedContent(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{metaAppDAO: dao.NewMetaAppDAO()}
+
+	index := func(pinID, content string) *model.MetaApp {
+		t.Helper()
+		data, _ := json.Marshal(map[string]interface{}{
+			"appName":   "app",
+			"runtime":   "browser",
+			"indexFile": "index.html",
+			"content":   content,
+		})
+		if err := s.processMetaAppContent(&indexer.MetaIDData{PinID: pinID, Operation: "create", Content: data}, 100, 1700000000); err != nil {
+			t.Fatalf("failed to index %s: %v", pinID, err)
+		}
+		app, err := database.DB.GetMetaAppByPinID(pinID)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", pinID, err)
+		}
+		return app
+	}
+
+	app := index("bad1i0", testCodePinID[:64]+"ix")
+	if app.State != model.MetaAppStateInvalid || app.StateReason == "" {
+		t.Fatalf("malformed content indexed with state %d (%q), want invalid", app.State, app.StateReason)
+	}
+	if got := countDeployQueue(t); got != 0 {
+		t.Fatalf("deploy queue has %d items after malformed content, want 0", got)
+	}
+
+	app = index("good1i0", " "+testCodePinID)
+	if app.State != model.MetaAppStateNormal {
+		t.Fatalf("valid content indexed with state %d (%q), want normal", app.State, app.StateReason)
+	}
+	items, _, err := database.DB.ListDeployQueueWithCursor(0, 100)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("deploy queue has %d items (err %v), want 1", len(items), err)
+	}
+	if items[0].Code != "metafile://"+testCodePinID {
+		t.Fatalf("queued code %q, want normalized metafile://%s", items[0].Code, testCodePinID)
+	}
+}
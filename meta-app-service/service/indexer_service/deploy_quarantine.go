@@ -0,0 +1,99 @@
+package indexer_service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"meta-app-service/conf"
+	model "meta-app-service/models"
+)
+
+// deployFailedDirName 失败部署的隔离目录名（位于部署目录下，meta_app.keep_failed_deploys 开启时保留失败部署的文件供排查）
+const deployFailedDirName = ".failed"
+
+// quarantineFailedDeploy 将失败部署的临时目录移到隔离目录，并在旁边写入 <目录名>.error 记录失败原因
+// 临时目录已不存在时（已替换到正式目录）不处理；移动失败时临时目录仍由调用方清理
+func quarantineFailedDeploy(deployBaseDir, stagingDir string, queueItem *model.MetaAppDeployQueue, deployErr error) {
+	if _, err := os.Stat(stagingDir); err != nil {
+		return
+	}
+
+	failedBaseDir := filepath.Join(deployBaseDir, deployFailedDirName)
+	if err := os.MkdirAll(failedBaseDir, 0755); err != nil {
+		log.Printf("Failed to create failed deploy directory: %v", err)
+		return
+	}
+	failedDir := filepath.Join(failedBaseDir, filepath.Base(stagingDir))
+	if err := os.Rename(stagingDir, failedDir); err != nil {
+		log.Printf("Failed to keep failed deploy %s: %v", stagingDir, err)
+		return
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "time: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&report, "pin_id: %s\n", queueItem.PinID)
+	fmt.Fprintf(&report, "first_pin_id: %s\n", queueItem.FirstPinId)
+	fmt.Fprintf(&report, "code: %s\n", queueItem.Code)
+	fmt.Fprintf(&report, "content: %s\n", queueItem.Content)
+	fmt.Fprintf(&report, "version: %s\n", queueItem.Version)
+	fmt.Fprintf(&report, "attempt: %d\n", queueItem.TryCount+1)
+	fmt.Fprintf(&report, "error: %v\n", deployErr)
+	if err := os.WriteFile(failedDir+".error", []byte(report.String()), 0644); err != nil {
+		log.Printf("Failed to write failed deploy error for %s: %v", failedDir, err)
+	}
+	log.Printf("Kept failed deploy of %s in %s", queueItem.PinID, failedDir)
+
+	pruneFailedDeploys(failedBaseDir, queueItem.FirstPinId, conf.Cfg().MetaApp.MaxFailedDeploys)
+}
+
+// pruneFailedDeploys 只保留 first_pin_id 最近的 keep 个失败部署（目录名为 <firstPinId>-<unixnano>），删除更早的目录及其 .error 文件
+// keep <= 0 时不清理
+func pruneFailedDeploys(failedBaseDir, firstPinID string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(failedBaseDir)
+	if err != nil {
+		log.Printf("Failed to read failed deploy directory %s: %v", failedBaseDir, err)
+		return
+	}
+
+	type failedDeploy struct {
+		name      string
+		attemptAt int64
+	}
+	var deploys []failedDeploy
+	prefix := firstPinID + "-"
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		attemptAt, err := strconv.ParseInt(strings.TrimPrefix(entry.Name(), prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		deploys = append(deploys, failedDeploy{name: entry.Name(), attemptAt: attemptAt})
+	}
+	if len(deploys) <= keep {
+		return
+	}
+
+	// 最新的在前
+	sort.Slice(deploys, func(i, j int) bool {
+		return deploys[i].attemptAt > deploys[j].attemptAt
+	})
+	for _, deploy := range deploys[keep:] {
+		dir := filepath.Join(failedBaseDir, deploy.name)
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("Failed to remove old failed deploy %s: %v", dir, err)
+			continue
+		}
+		os.Remove(dir + ".error")
+	}
+}
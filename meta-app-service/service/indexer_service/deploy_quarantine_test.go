@@ -0,0 +1,272 @@
+package indexer_service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+	model "meta-app-service/models"
+	"meta-app-service/models/dao"
+)
+
+// TestDeployMetaAppKeepsFailedDeploy fails a deploy on a content hash mismatch and checks the downloaded
+// file is kept in the quarantine directory with the error only when meta_app.keep_failed_deploys is set
+func TestDeployMetaAppKeepsFailedDeploy(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{metaAppDAO: dao.NewMetaAppDAO()}
+
+	metafs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info/" + testCodePinID:
+			w.Write([]byte(`{"code":0,"data":{"file_name":"app.js","file_size":7}}`))
+		case "/content/" + testCodePinID:
+			w.Write([]byte("alert()"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer metafs.Close()
+	deployDir := t.TempDir()
+	conf.Cfg().MetaApp.DeployFilePath = deployDir
+	conf.Cfg().Metafs = conf.MetafsConfig{Domain: metafs.URL, FileInfoPath: "/info", AccelerateContentPath: "/content"}
+
+	app := &model.MetaApp{PinID: "app1i0", FirstPinId: "app1i0", Code: testCodePinID, Version: "1.0.0", ContentHash: "sha256:" + strings.Repeat("0", 64), Timestamp: 1700000000}
+	if err := database.DB.CreateMetaApp(app); err != nil {
+		t.Fatalf("failed to create MetaApp: %v", err)
+	}
+	queueItem := &model.MetaAppDeployQueue{FirstPinId: app.FirstPinId, PinID: app.PinID, Code: app.Code, Version: app.Version, TryCount: 1}
+	failedDir := filepath.Join(deployDir, deployFailedDirName)
+
+	// Disabled: the staging directory is removed
+	if err := s.deployMetaApp(context.Background(), queueItem); err == nil {
+		t.Fatal("deploy with mismatched content hash succeeded, want error")
+	}
+	if _, err := os.Stat(failedDir); !os.IsNotExist(err) {
+		t.Fatalf("failed deploy kept with keep_failed_deploys disabled (stat err %v)", err)
+	}
+
+	conf.Cfg().MetaApp.KeepFailedDeploys = true
+	deployErr := s.deployMetaApp(context.Background(), queueItem)
+	if deployErr == nil {
+		t.Fatal("deploy with mismatched content hash succeeded, want error")
+	}
+	entries, err := os.ReadDir(failedDir)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("failed deploy directory has %d entries (err %v), want directory and error file", len(entries), err)
+	}
+	var keptDir string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			keptDir = filepath.Join(failedDir, entry.Name())
+		}
+	}
+	if keptDir == "" || !strings.HasPrefix(filepath.Base(keptDir), app.FirstPinId+"-") {
+		t.Fatalf("no failed deploy directory for %s in %v", app.FirstPinId, entries)
+	}
+	if content, err := os.ReadFile(filepath.Join(keptDir, "app.js")); err != nil || string(content) != "alert()" {
+		t.Fatalf("downloaded file not kept: %q, %v", content, err)
+	}
+	report, err := os.ReadFile(keptDir + ".error")
+	if err != nil || !strings.Contains(string(report), deployErr.Error()) || !strings.Contains(string(report), "attempt: 2") {
+		t.Fatalf("error file %q (err %v) does not record the failure", report, err)
+	}
+
+	// Nothing is left in staging and the app is not deployed
+	if entries, _ := os.ReadDir(filepath.Join(deployDir, deployStagingDirName)); len(entries) != 0 {
+		t.Fatalf("staging directory not cleaned up: %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(deployDir, app.FirstPinId)); !os.IsNotExist(err) {
+		t.Fatalf("failed deploy swapped into place (stat err %v)", err)
+	}
+}
+
+// TestDeployMetaAppFailsOnExtractError fails the deploy when the downloaded archive cannot be extracted
+// and leaves the current deploy in place
+func TestDeployMetaAppFailsOnExtractError(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{metaAppDAO: dao.NewMetaAppDAO()}
+
+	metafs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info/" + testCodePinID:
+			w.Write([]byte(`{"code":0,"data":{"file_name":"app.zip","file_size":12}}`))
+		case "/content/" + testCodePinID:
+			w.Write([]byte("PK\x03\x04broken"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer metafs.Close()
+	deployDir := t.TempDir()
+	conf.Cfg().MetaApp.DeployFilePath = deployDir
+	conf.Cfg().Metafs = conf.MetafsConfig{Domain: metafs.URL, FileInfoPath: "/info", AccelerateContentPath: "/content"}
+
+	app := &model.MetaApp{PinID: "app1i0", FirstPinId: "app1i0", Code: testCodePinID, Version: "1.0.1", Timestamp: 1700000000}
+	if err := database.DB.CreateMetaApp(app); err != nil {
+		t.Fatalf("failed to create MetaApp: %v", err)
+	}
+	liveDir := filepath.Join(deployDir, app.FirstPinId)
+	if err := os.MkdirAll(liveDir, 0755); err != nil {
+		t.Fatalf("failed to create live deploy: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(liveDir, "index.html"), []byte("v1"), 0644); err != nil {
+		t.Fatalf("failed to write live deploy: %v", err)
+	}
+
+	queueItem := &model.MetaAppDeployQueue{FirstPinId: app.FirstPinId, PinID: app.PinID, Code: app.Code, Version: app.Version}
+	if err := s.deployMetaApp(context.Background(), queueItem); err == nil {
+		t.Fatal("deploy of a broken archive succeeded, want error")
+	}
+	if content, err := os.ReadFile(filepath.Join(liveDir, "index.html")); err != nil || string(content) != "v1" {
+		t.Fatalf("live deploy changed: %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(liveDir, "app.zip")); !os.IsNotExist(err) {
+		t.Fatalf("broken archive swapped into the live deploy (stat err %v)", err)
+	}
+	if record, err := database.DB.GetDeployFileContent(app.PinID); err != nil || record.DeployStatus != "failed" {
+		t.Fatalf("deploy record %+v (err %v), want failed", record, err)
+	}
+}
+
+// TestSwapDeployDir replaces the live deploy and restores it when the new deploy cannot be moved into place
+func TestSwapDeployDir(t *testing.T) {
+	root := t.TempDir()
+	liveDir := filepath.Join(root, "app")
+	stagingDir := filepath.Join(root, ".staging-app")
+	for dir, content := range map[string]string{liveDir: "v1", stagingDir: "v2"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", dir, err)
+		}
+	}
+
+	if err := swapDeployDir(stagingDir, liveDir); err != nil {
+		t.Fatalf("swapDeployDir failed: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(liveDir, "index.html")); err != nil || string(content) != "v2" {
+		t.Fatalf("live deploy %q (err %v), want v2", content, err)
+	}
+	if info, err := os.Lstat(liveDir); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("live deploy is not a symlink (err %v)", err)
+	}
+	if releases, _ := os.ReadDir(filepath.Join(root, deployReleasesDirName)); len(releases) != 2 {
+		t.Fatalf("got %d release directories, want the live one and the previous (legacy) one", len(releases))
+	}
+
+	// The staging directory is gone now: the failed swap restores the live deploy
+	if err := swapDeployDir(stagingDir, liveDir); err == nil {
+		t.Fatal("swap of a missing staging directory succeeded, want error")
+	}
+	if content, err := os.ReadFile(filepath.Join(liveDir, "index.html")); err != nil || string(content) != "v2" {
+		t.Fatalf("live deploy %q (err %v) not restored, want v2", content, err)
+	}
+}
+
+// TestSwapDeployDirNeverMissing polls the live deploy while it is replaced repeatedly: the path must always resolve
+func TestSwapDeployDirNeverMissing(t *testing.T) {
+	root := t.TempDir()
+	liveDir := filepath.Join(root, "app")
+	newStaging := func(i int) string {
+		t.Helper()
+		stagingDir := filepath.Join(root, deployStagingDirName, fmt.Sprintf("app-%d", i))
+		if err := os.MkdirAll(stagingDir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", stagingDir, err)
+		}
+		if err := os.WriteFile(filepath.Join(stagingDir, "index.html"), []byte(fmt.Sprint(i)), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", stagingDir, err)
+		}
+		return stagingDir
+	}
+	if err := swapDeployDir(newStaging(0), liveDir); err != nil {
+		t.Fatalf("initial deploy failed: %v", err)
+	}
+
+	// A single reader polls the live path; each swap waits for a poll started after the previous swap,
+	// so a read in flight never spans two swaps (the previous release is only kept until the next swap)
+	stop := make(chan struct{})
+	missing := make(chan error, 1)
+	var polls atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := os.Stat(filepath.Join(liveDir, "index.html")); err != nil {
+				missing <- err
+				return
+			}
+			polls.Add(1)
+		}
+	}()
+
+	for i := 1; i <= 50; i++ {
+		if err := swapDeployDir(newStaging(i), liveDir); err != nil {
+			t.Fatalf("swap %d failed: %v", i, err)
+		}
+		for after := polls.Load() + 2; polls.Load() < after && len(missing) == 0; {
+			time.Sleep(10 * time.Microsecond)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	select {
+	case err := <-missing:
+		t.Fatalf("live deploy was missing during a swap: %v", err)
+	default:
+	}
+
+	if content, err := os.ReadFile(filepath.Join(liveDir, "index.html")); err != nil || string(content) != "50" {
+		t.Fatalf("live deploy %q (err %v), want 50", content, err)
+	}
+	if releases, _ := os.ReadDir(filepath.Join(root, deployReleasesDirName)); len(releases) != 2 {
+		t.Fatalf("got %d release directories, want the live one and the previous one", len(releases))
+	}
+	if err := removeDeployDir(liveDir); err != nil {
+		t.Fatalf("removeDeployDir failed: %v", err)
+	}
+	if releases, _ := os.ReadDir(filepath.Join(root, deployReleasesDirName)); len(releases) != 0 {
+		t.Fatalf("got %d release directories after removal, want 0", len(releases))
+	}
+}
+
+// TestPruneFailedDeploys keeps only the newest failed deploys of an app, together with their error files
+func TestPruneFailedDeploys(t *testing.T) {
+	failedBaseDir := t.TempDir()
+	names := []string{"app1i0-100", "app1i0-300", "app1i0-200", "app1i0-400", "app1i01-50"}
+	for _, name := range names {
+		if err := os.MkdirAll(filepath.Join(failedBaseDir, name), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(failedBaseDir, name+".error"), []byte("error"), 0644); err != nil {
+			t.Fatalf("failed to write %s.error: %v", name, err)
+		}
+	}
+
+	pruneFailedDeploys(failedBaseDir, "app1i0", 2)
+
+	for _, name := range names {
+		_, dirErr := os.Stat(filepath.Join(failedBaseDir, name))
+		_, errorFileErr := os.Stat(filepath.Join(failedBaseDir, name+".error"))
+		wantKept := name != "app1i0-100" && name != "app1i0-200"
+		if kept := dirErr == nil && errorFileErr == nil; kept != wantKept {
+			t.Fatalf("%s kept = %v, want %v", name, kept, wantKept)
+		}
+	}
+}
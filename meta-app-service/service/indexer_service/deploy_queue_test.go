@@ -0,0 +1,195 @@
+package indexer_service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+	model "meta-app-service/models"
+)
+
+// setupDeployQueueTest opens a temporary database with deploys enabled
+func setupDeployQueueTest(t *testing.T) {
+	t.Helper()
+	db, err := database.NewPebbleDatabase(&database.PebbleConfig{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	prevDB, prevCfg := database.DB, conf.Cfg()
+	database.DB = db
+	conf.SetCfg(&conf.Config{MetaApp: conf.MetaAppConfig{DeployEnabled: true}})
+	t.Cleanup(func() {
+		db.Close()
+		database.DB = prevDB
+		conf.SetCfg(prevCfg)
+	})
+}
+
+func newTestDeployMetaApp(pinID string, timestamp int64) *model.MetaApp {
+	return &model.MetaApp{
+		PinID:      pinID,
+		FirstPinId: pinID,
+		Code:       "metafile://code" + pinID,
+		Version:    "1.0.0",
+		Timestamp:  timestamp,
+	}
+}
+
+func countDeployQueue(t *testing.T) int {
+	t.Helper()
+	items, _, err := database.DB.ListDeployQueueWithCursor(0, 100)
+	if err != nil {
+		t.Fatalf("failed to list deploy queue: %v", err)
+	}
+	return len(items)
+}
+
+// TestAddToDeployQueueSkipsRescannedPin enqueues the same PIN again, as a block rescan does, with a different
+// timestamp (mempool time vs block time) so the queue keys differ, and checks only one item is queued
+func TestAddToDeployQueueSkipsRescannedPin(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{}
+
+	if err := s.addToDeployQueue(newTestDeployMetaApp("pin1i0", 1700000000000)); err != nil {
+		t.Fatalf("first enqueue failed: %v", err)
+	}
+	if err := s.addToDeployQueue(newTestDeployMetaApp("pin1i0", 1700000000000)); err != nil {
+		t.Fatalf("rescan enqueue failed: %v", err)
+	}
+	if err := s.addToDeployQueue(newTestDeployMetaApp("pin1i0", 1700000060000)); err != nil {
+		t.Fatalf("rescan enqueue with new timestamp failed: %v", err)
+	}
+	if got := countDeployQueue(t); got != 1 {
+		t.Fatalf("deploy queue has %d items after re-enqueue, want 1", got)
+	}
+
+	// A different PIN with the same timestamp is still queued
+	if err := s.addToDeployQueue(newTestDeployMetaApp("pin2i0", 1700000000000)); err != nil {
+		t.Fatalf("enqueue of second pin failed: %v", err)
+	}
+	if got := countDeployQueue(t); got != 2 {
+		t.Fatalf("deploy queue has %d items, want 2", got)
+	}
+}
+
+// TestAddToDeployQueueConcurrent enqueues the same PIN from several goroutines (block scan, ZMQ and admin redeploy)
+// and checks the duplicate check inside AddToDeployQueue lets only one through
+func TestAddToDeployQueueConcurrent(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := s.addToDeployQueue(newTestDeployMetaApp("pin1i0", 1700000000000+int64(i))); err != nil {
+				t.Errorf("enqueue %d failed: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := countDeployQueue(t); got != 1 {
+		t.Fatalf("deploy queue has %d items after concurrent enqueue, want 1", got)
+	}
+
+	queue, err := newDeployQueueItem(newTestDeployMetaApp("pin1i0", 1700000099000))
+	if err != nil {
+		t.Fatalf("failed to build queue item: %v", err)
+	}
+	if err := database.DB.AddToDeployQueue(queue); !errors.Is(err, database.ErrAlreadyQueued) {
+		t.Fatalf("got err %v, want ErrAlreadyQueued", err)
+	}
+}
+
+// TestAddToDeployQueueSkipsDeployedPin rescans a PIN whose deploy already completed and left the queue
+func TestAddToDeployQueueSkipsDeployedPin(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{}
+
+	app := newTestDeployMetaApp("pin1i0", 1700000000000)
+	if err := database.DB.CreateOrUpdateDeployFileContent(&model.MetaAppDeployFileContent{
+		FirstPinId:   app.FirstPinId,
+		PinID:        app.PinID,
+		Code:         app.Code,
+		Version:      app.Version,
+		DeployStatus: "completed",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}); err != nil {
+		t.Fatalf("failed to record deploy: %v", err)
+	}
+
+	if err := s.addToDeployQueue(app); err != nil {
+		t.Fatalf("rescan enqueue failed: %v", err)
+	}
+	if got := countDeployQueue(t); got != 0 {
+		t.Fatalf("deploy queue has %d items after rescan of deployed pin, want 0", got)
+	}
+}
+
+// TestProcessNextDeployItemDropsSupersededVersion removes the queue item of an older version once a newer
+// servable version of the same app is indexed, so it cannot overwrite the newer deploy
+func TestProcessNextDeployItemDropsSupersededVersion(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{}
+
+	v1 := newTestDeployMetaApp("pin1i0", 1700000000000)
+	v2 := newTestDeployMetaApp("pin2i0", 1700000060000)
+	v2.FirstPinId = v1.PinID
+	for _, app := range []*model.MetaApp{v1, v2} {
+		if err := database.DB.CreateMetaApp(app); err != nil {
+			t.Fatalf("failed to create MetaApp %s: %v", app.PinID, err)
+		}
+	}
+	if err := s.addToDeployQueue(v1); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	processed, err := s.processNextDeployItem()
+	if !processed || err != nil {
+		t.Fatalf("processNextDeployItem = %v, %v, want processed without error", processed, err)
+	}
+	if got := countDeployQueue(t); got != 0 {
+		t.Fatalf("deploy queue has %d items, want the superseded item removed", got)
+	}
+	if _, err := database.DB.GetDeployFileContent(v1.PinID); err != database.ErrNotFound {
+		t.Fatalf("superseded version was deployed (err %v)", err)
+	}
+}
+
+// TestGetLatestServableMetaAppFallsBackToArchive finds the servable version in the history archive when every
+// version in the capped inline history is revoked or disabled
+func TestGetLatestServableMetaAppFallsBackToArchive(t *testing.T) {
+	db, err := database.NewPebbleDatabase(&database.PebbleConfig{DataDir: t.TempDir(), MaxHistoryPerApp: 2})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	prevDB := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		db.Close()
+		database.DB = prevDB
+	})
+
+	versions := []*model.MetaApp{
+		{PinID: "app1i0", FirstPinId: "app1i0", Operation: "create", Timestamp: 1700000000},
+		{PinID: "mod1i0", FirstPinId: "app1i0", Operation: "modify", Timestamp: 1700000100},
+		{PinID: "mod2i0", FirstPinId: "app1i0", Operation: "modify", Timestamp: 1700000200, Disabled: true},
+		{PinID: "mod3i0", FirstPinId: "app1i0", Operation: "revoke", Timestamp: 1700000300},
+	}
+	for _, app := range versions {
+		if err := database.DB.CreateMetaApp(app); err != nil {
+			t.Fatalf("failed to index %s: %v", app.PinID, err)
+		}
+	}
+
+	servable, err := GetLatestServableMetaApp("app1i0")
+	if err != nil || servable.PinID != "mod1i0" {
+		t.Fatalf("got %+v (err %v), want archived version mod1i0", servable, err)
+	}
+}
@@ -0,0 +1,180 @@
+package indexer_service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+)
+
+// evictedMarkerSuffix 应用目录被配额清理后在部署目录下留下的标记文件后缀（<first_pin_id>.evicted），
+// 再次访问时据此重新部署，避免对从未部署成功的应用反复加入部署队列
+const evictedMarkerSuffix = ".evicted"
+
+// accessTouchInterval 同一应用访问时间的最小更新间隔（访问时间记录为部署目录的修改时间）
+const accessTouchInterval = time.Minute
+
+var (
+	// lastAccessTouch 应用部署目录 -> 上次更新访问时间的时间
+	lastAccessTouch sync.Map
+	// deployQuotaMu 避免多个 worker 同时执行配额清理
+	deployQuotaMu sync.Mutex
+)
+
+// metaAppDeployBaseDir 获取部署基础目录
+func metaAppDeployBaseDir() string {
+	if conf.Cfg().MetaApp.DeployFilePath == "" {
+		return "./meta_app_deploy_data"
+	}
+	return conf.Cfg().MetaApp.DeployFilePath
+}
+
+// RecordMetaAppAccess 记录应用被访问（更新部署目录的修改时间，作为配额清理的 LRU 依据，重启后仍然有效）
+// 未配置 meta_app.max_deploy_dir_size_mb 时不记录
+func RecordMetaAppAccess(appDeployDir string) {
+	if conf.Cfg().MetaApp.MaxDeployDirSizeMB <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if last, ok := lastAccessTouch.Load(appDeployDir); ok && now.Sub(last.(time.Time)) < accessTouchInterval {
+		return
+	}
+	lastAccessTouch.Store(appDeployDir, now)
+
+	if err := os.Chtimes(appDeployDir, now, now); err != nil {
+		log.Printf("Failed to record access time of %s: %v", appDeployDir, err)
+	}
+}
+
+// RestoreEvictedMetaApp 应用目录因超出配额被清理后再次被访问时，重新加入部署队列
+// 返回 true 表示该应用曾被清理（已加入或已在部署队列中），调用方可提示稍后重试
+func RestoreEvictedMetaApp(firstPinID string) bool {
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		return false
+	}
+	markerPath := filepath.Join(metaAppDeployBaseDir(), firstPinID+evictedMarkerSuffix)
+	if _, err := os.Stat(markerPath); err != nil {
+		return false
+	}
+
+	if err := NewIndexerAppService().RedeployMetaApp(firstPinID); err != nil && !errors.Is(err, database.ErrAlreadyQueued) {
+		log.Printf("Failed to queue redeploy of evicted MetaApp %s: %v", firstPinID, err)
+		return false
+	}
+	return true
+}
+
+// clearEvictedMarker 部署成功后删除清理标记
+func clearEvictedMarker(firstPinID string) {
+	markerPath := filepath.Join(metaAppDeployBaseDir(), firstPinID+evictedMarkerSuffix)
+	if err := os.Remove(markerPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove evicted marker %s: %v", markerPath, err)
+	}
+}
+
+// deployDirEntry 部署目录下的一个应用目录
+type deployDirEntry struct {
+	firstPinID string
+	size       int64
+	lastAccess time.Time
+}
+
+// enforceDeployQuota 部署目录总大小超出 meta_app.max_deploy_dir_size_mb 时，按最近访问时间从旧到新删除应用目录，直到不超出配额
+// keepFirstPinID: 不清理的应用（刚部署完成的应用）
+func (s *IndexerService) enforceDeployQuota(keepFirstPinID string) {
+	maxSize := int64(conf.Cfg().MetaApp.MaxDeployDirSizeMB) * 1024 * 1024
+	if maxSize <= 0 {
+		return
+	}
+	deployQuotaMu.Lock()
+	defer deployQuotaMu.Unlock()
+
+	baseDir := metaAppDeployBaseDir()
+	dirEntries, err := os.ReadDir(baseDir)
+	if err != nil {
+		log.Printf("Failed to read deploy directory %s: %v", baseDir, err)
+		return
+	}
+
+	var apps []deployDirEntry
+	var totalSize int64
+	for _, dirEntry := range dirEntries {
+		// 跳过 .staging、.releases 等隐藏目录；应用目录是指向 .releases 中版本目录的符号链接，os.Stat 跟随链接
+		if strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(baseDir, dirEntry.Name()))
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		size := deployDirSize(filepath.Join(baseDir, dirEntry.Name()))
+		totalSize += size
+		apps = append(apps, deployDirEntry{firstPinID: dirEntry.Name(), size: size, lastAccess: info.ModTime()})
+	}
+	if totalSize <= maxSize {
+		return
+	}
+
+	// 最久未访问的在前
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].lastAccess.Before(apps[j].lastAccess)
+	})
+
+	for _, app := range apps {
+		if totalSize <= maxSize {
+			break
+		}
+		if app.firstPinID == keepFirstPinID {
+			continue
+		}
+		if s.evictDeployDir(baseDir, app.firstPinID) {
+			totalSize -= app.size
+			log.Printf("Evicted MetaApp %s (%d bytes, last access %s) to keep deploy directory under quota", app.firstPinID, app.size, app.lastAccess.Format(time.RFC3339))
+		}
+	}
+
+	if totalSize > maxSize {
+		log.Printf("Deploy directory is still over quota after eviction: %d > %d bytes", totalSize, maxSize)
+	}
+}
+
+// evictDeployDir 删除应用目录并留下清理标记（持有该应用的部署锁，避免与部署并发）
+func (s *IndexerService) evictDeployDir(baseDir, firstPinID string) bool {
+	unlock := lockDeploy(firstPinID)
+	defer unlock()
+
+	markerPath := filepath.Join(baseDir, firstPinID+evictedMarkerSuffix)
+	if err := os.WriteFile(markerPath, nil, 0644); err != nil {
+		log.Printf("Failed to write evicted marker %s: %v", markerPath, err)
+		return false
+	}
+	if err := removeDeployDir(filepath.Join(baseDir, firstPinID)); err != nil {
+		log.Printf("Failed to evict MetaApp %s: %v", firstPinID, err)
+		return false
+	}
+	lastAccessTouch.Delete(filepath.Join(baseDir, firstPinID))
+	return true
+}
+
+// deployDirSize 计算目录占用的字节数，无法访问的文件忽略（path 是符号链接时统计其指向的目录）
+func deployDirSize(path string) int64 {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
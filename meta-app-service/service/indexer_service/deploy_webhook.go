@@ -0,0 +1,124 @@
+package indexer_service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"meta-app-service/conf"
+	model "meta-app-service/models"
+	"meta-app-service/tool"
+)
+
+// 部署 webhook 事件状态
+const (
+	DeployWebhookStatusCompleted = "completed"
+	DeployWebhookStatusFailed    = "failed"
+	DeployWebhookStatusSkipped   = "skipped"
+)
+
+// DeployWebhookSignatureHeader webhook 签名头：sha256=hex(HMAC-SHA256(secret, body))
+const DeployWebhookSignatureHeader = "X-MetaApp-Signature"
+
+// deployWebhookTimeout 单次 webhook 请求超时
+const deployWebhookTimeout = 10 * time.Second
+
+var deployWebhookClient = tool.NewHTTPClient(deployWebhookTimeout)
+
+// DeployWebhookPayload 部署结果通知内容
+type DeployWebhookPayload struct {
+	PinID      string `json:"pin_id"`
+	FirstPinID string `json:"first_pin_id"`
+	Status     string `json:"status"` // completed / failed / skipped
+	Message    string `json:"message"`
+	DeployURL  string `json:"deploy_url"`
+	Timestamp  int64  `json:"timestamp"` // 通知时间（毫秒）
+}
+
+// notifyDeployWebhook 异步发送部署结果通知（未配置 meta_app.deploy_webhook_url 时不发送）
+func notifyDeployWebhook(queueItem *model.MetaAppDeployQueue, status, message string) {
+	webhookURL := conf.Cfg().MetaApp.DeployWebhookURL
+	if webhookURL == "" {
+		return
+	}
+
+	firstPinID := queueItem.FirstPinId
+	if firstPinID == "" {
+		firstPinID = queueItem.PinID
+	}
+	payload := DeployWebhookPayload{
+		PinID:      queueItem.PinID,
+		FirstPinID: firstPinID,
+		Status:     status,
+		Message:    message,
+		DeployURL:  strings.TrimSuffix(conf.Cfg().MetaApp.PublicURL, "/") + "/" + firstPinID + "/",
+		Timestamp:  time.Now().UnixMilli(),
+	}
+
+	go func() {
+		if err := sendDeployWebhook(webhookURL, conf.Cfg().MetaApp.DeployWebhookSecret, conf.Cfg().MetaApp.DeployWebhookMaxRetries, payload); err != nil {
+			log.Printf("Failed to send deploy webhook for %s: %v", payload.PinID, err)
+		}
+	}()
+}
+
+// sendDeployWebhook 发送 webhook，失败时按 1s、2s、4s... 退避重试
+func sendDeployWebhook(webhookURL, secret string, maxRetries int, payload DeployWebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err = postDeployWebhook(webhookURL, secret, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxRetries {
+			return err
+		}
+		log.Printf("Deploy webhook for %s failed (%v), retrying in %s (attempt %d/%d)", payload.PinID, err, backoff, attempt+1, maxRetries)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postDeployWebhook 发送一次 webhook 请求，非 2xx 视为失败
+func postDeployWebhook(webhookURL, secret string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(DeployWebhookSignatureHeader, signDeployWebhook(secret, body))
+	}
+
+	resp, err := deployWebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signDeployWebhook 计算 webhook 签名：sha256=hex(HMAC-SHA256(secret, body))
+func signDeployWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,191 @@
+package indexer_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"meta-app-service/conf"
+	"meta-app-service/indexer"
+	"meta-app-service/tool"
+)
+
+const (
+	// maxDescriptorSize 从 metafs 获取的 MetaApp 描述 JSON 大小上限
+	maxDescriptorSize = 1 << 20
+	// descriptorFetchTimeout 从 metafs 获取 MetaApp 描述 JSON 的超时时间（所有网关合计）
+	descriptorFetchTimeout = 30 * time.Second
+	// descriptorFetchBackoff 获取失败后在此时间内不再访问 metafs，直接暂存，避免每个 PIN 都阻塞扫描直到超时
+	descriptorFetchBackoff = time.Minute
+	// descriptorRetryInterval 重新处理描述 JSON 获取失败而暂存的 PIN 的最小间隔
+	descriptorRetryInterval = 5 * time.Minute
+	// descriptorRetryTarget 描述 JSON 获取失败的 PIN 在暂存集合中的键（不是 PinID，不会被 processPendingModifies 取出）
+	descriptorRetryTarget = "descriptor-retry"
+)
+
+// descriptorFetchFailedAt 上次从 metafs 获取描述 JSON 失败的时间（Unix 纳秒，0 表示最近一次成功）
+var descriptorFetchFailedAt atomic.Int64
+
+// errDescriptorFetchBackoff 最近获取描述 JSON 失败，暂不访问 metafs
+var errDescriptorFetchBackoff = errors.New("metafs recently unavailable, retrying later")
+
+// descriptorFetchError 从 metafs 获取描述 JSON 失败（网络或网关的暂时性错误），PIN 会被暂存稍后重试而不是丢弃
+type descriptorFetchError struct {
+	refPinID string
+	err      error
+}
+
+func (e *descriptorFetchError) Error() string {
+	return fmt.Sprintf("failed to fetch MetaApp descriptor %s: %v", e.refPinID, e.err)
+}
+
+func (e *descriptorFetchError) Unwrap() error {
+	return e.err
+}
+
+// descriptorReference 判断 PIN 内容是否为 metafile://<pinid> 引用（描述 JSON 存放在另一个 PIN 中），返回被引用的 pinId
+func descriptorReference(content []byte) (string, bool) {
+	value := string(bytes.TrimSpace(content))
+	if !strings.HasPrefix(value, "metafile://") {
+		return "", false
+	}
+	pinID := strings.TrimPrefix(value, "metafile://")
+	if !pinIDPattern.MatchString(pinID) {
+		return "", false
+	}
+	return pinID, true
+}
+
+// resolveMetaAppDescriptor 获取 MetaApp 描述 JSON
+// 内容为 metafile://<pinid> 引用时从 metafs 获取被引用 PIN 的内容（只解析一层引用），否则原样返回
+// 获取失败时返回 *descriptorFetchError，最近 descriptorFetchBackoff 内失败过时不再访问 metafs 直接返回该错误
+func resolveMetaAppDescriptor(pinID string, content []byte) ([]byte, error) {
+	refPinID, ok := descriptorReference(content)
+	if !ok {
+		return content, nil
+	}
+
+	if failedAt := descriptorFetchFailedAt.Load(); failedAt != 0 && time.Since(time.Unix(0, failedAt)) < descriptorFetchBackoff {
+		return nil, &descriptorFetchError{refPinID: refPinID, err: errDescriptorFetchBackoff}
+	}
+
+	log.Printf("MetaApp %s stores its descriptor in %s, fetching from metafs", pinID, refPinID)
+	descriptor, err := fetchMetafsContent(refPinID)
+	if err != nil {
+		descriptorFetchFailedAt.Store(time.Now().UnixNano())
+		return nil, &descriptorFetchError{refPinID: refPinID, err: err}
+	}
+	descriptorFetchFailedAt.Store(0)
+	if _, nested := descriptorReference(descriptor); nested {
+		return nil, fmt.Errorf("MetaApp descriptor %s is itself a metafile reference", refPinID)
+	}
+	return descriptor, nil
+}
+
+// fetchMetafsContent 从 metafs 获取 PIN 内容（小文件，读入内存）
+// 依次尝试各网关，每个网关先走加速地址，失败时回退到普通内容地址
+func fetchMetafsContent(pinID string) ([]byte, error) {
+	gateways := conf.Cfg().Metafs.GatewayList()
+	if len(gateways) == 0 {
+		return nil, fmt.Errorf("metafs domain not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), descriptorFetchTimeout)
+	defer cancel()
+
+	var lastErr error
+	for _, gateway := range gateways {
+		urls := []string{metafsURL(gateway, conf.Cfg().Metafs.AccelerateContentPath, pinID)}
+		if conf.Cfg().Metafs.ContentPath != "" {
+			urls = append(urls, metafsURL(gateway, conf.Cfg().Metafs.ContentPath, pinID))
+		}
+		for _, contentURL := range urls {
+			content, err := fetchSmallContent(ctx, contentURL, maxDescriptorSize)
+			if err == nil {
+				return content, nil
+			}
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			log.Printf("Failed to fetch %s from metafs: %v", contentURL, err)
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// fetchSmallContent GET 获取内容，超过 maxSize 时报错
+func fetchSmallContent(ctx context.Context, url string, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := tool.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, fmt.Errorf("content exceeds %d bytes", maxSize)
+	}
+	return content, nil
+}
+
+// parkDescriptorPin 暂存描述 JSON 获取失败的 MetaApp PIN，返回是否已暂存（由 retryParkedDescriptors 稍后重新处理）
+// 重试时再次失败的 PIN 保留最初的暂存时间，超过 indexer.pending_modify_expire_hours 后清理
+func (s *IndexerService) parkDescriptorPin(metaData *indexer.MetaIDData, height, timestamp int64, fetchErr error) bool {
+	parkedAt := time.Now()
+	if value, ok := s.descriptorParkedAt.Load(metaData.PinID); ok {
+		parkedAt = value.(time.Time)
+	}
+	if !s.parkPin(metaData, descriptorRetryTarget, height, timestamp, parkedAt) {
+		return false
+	}
+	log.Printf("MetaApp %s parked for retry: %v", metaData.PinID, fetchErr)
+	return true
+}
+
+// retryParkedDescriptors 重新处理描述 JSON 获取失败而暂存的 PIN（最多每 descriptorRetryInterval 执行一次）
+// 在扫描协程中同步执行，与区块处理串行；再次失败的 PIN 会重新暂存
+func (s *IndexerService) retryParkedDescriptors() {
+	now := time.Now()
+	last := s.lastDescriptorRetry.Load()
+	if now.Sub(time.Unix(last, 0)) < descriptorRetryInterval || !s.lastDescriptorRetry.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+
+	pending, err := s.metaAppDAO.TakePendingModifies(descriptorRetryTarget)
+	if err != nil {
+		log.Printf("Failed to load MetaApp PINs parked for descriptor retry: %v", err)
+		return
+	}
+	for _, item := range pending {
+		var metaData indexer.MetaIDData
+		if err := json.Unmarshal(item.Data, &metaData); err != nil {
+			log.Printf("Failed to decode parked MetaApp PIN %s: %v", item.PinID, err)
+			continue
+		}
+		log.Printf("Retrying MetaApp %s whose descriptor could not be fetched", item.PinID)
+		s.descriptorParkedAt.Store(item.PinID, item.CreatedAt)
+		if err := s.processMetaAppPin(&metaData, item.Height, item.Timestamp); err != nil {
+			log.Printf("Failed to process parked MetaApp PIN %s: %v", item.PinID, err)
+		}
+		s.descriptorParkedAt.Delete(item.PinID)
+	}
+}
@@ -0,0 +1,143 @@
+package indexer_service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+	"meta-app-service/indexer"
+	"meta-app-service/models/dao"
+)
+
+const testDescriptorPinID = "1f0c4ad0a3b4f4ac4b9f2b0b2ff62c4f0d8e1a6ab0c9ce1f2e8e7a5b3c4d5e6fi0"
+
+func TestDescriptorReference(t *testing.T) {
+	cases := []struct {
+		content string
+		want    string
+		ok      bool
+	}{
+		{"metafile://" + testDescriptorPinID, testDescriptorPinID, true},
+		{" metafile://" + testDescriptorPinID + "\n", testDescriptorPinID, true},
+		{`{"appName":"app"}`, "", false},
+		{testDescriptorPinID, "", false},
+		{"metafile://not-a-pin", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := descriptorReference([]byte(tc.content))
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("descriptorReference(%q) = %q, %v; want %q, %v", tc.content, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// TestProcessMetaAppContentResolvesDescriptorReference indexes a MetaApp whose PIN content points at its descriptor
+func TestProcessMetaAppContentResolvesDescriptorReference(t *testing.T) {
+	setupDeployQueueTest(t)
+	t.Cleanup(func() { descriptorFetchFailedAt.Store(0) })
+	s := &IndexerService{metaAppDAO: dao.NewMetaAppDAO()}
+
+	var requested []string
+	metafs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/content/" + testDescriptorPinID:
+			w.Write([]byte(`{"appName":"external","runtime":"browser","indexFile":"index.html","version":"1.2.0","content":"` + testCodePinID + `"}`))
+		case "/content/" + testCodePinID:
+			w.Write([]byte("metafile://" + testDescriptorPinID))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer metafs.Close()
+	conf.Cfg().Metafs = conf.MetafsConfig{Domain: metafs.URL, AccelerateContentPath: "/accelerate", ContentPath: "/content"}
+
+	metaData := &indexer.MetaIDData{PinID: "ref1i0", Operation: "create", Content: []byte("metafile://" + testDescriptorPinID)}
+	if err := s.processMetaAppContent(metaData, 100, 1700000000); err != nil {
+		t.Fatalf("failed to index MetaApp with descriptor reference: %v", err)
+	}
+	app, err := database.DB.GetMetaAppByPinID("ref1i0")
+	if err != nil {
+		t.Fatalf("failed to load MetaApp: %v", err)
+	}
+	if app.AppName != "external" || app.Version != "1.2.0" || app.Content != testCodePinID {
+		t.Fatalf("indexed %+v, want fields from the external descriptor", app)
+	}
+	// The accelerated path is tried first and falls back to the plain content path
+	if len(requested) != 2 || !strings.HasPrefix(requested[0], "/accelerate/") {
+		t.Fatalf("requested %v, want accelerated path then content path", requested)
+	}
+
+	// A reference to another reference is not followed
+	metaData = &indexer.MetaIDData{PinID: "ref2i0", Operation: "create", Content: []byte("metafile://" + testCodePinID)}
+	if err := s.processMetaAppContent(metaData, 100, 1700000100); err == nil {
+		t.Fatal("nested descriptor reference indexed, want error")
+	}
+
+	// A missing descriptor fails instead of indexing an empty app
+	metaData = &indexer.MetaIDData{PinID: "ref3i0", Operation: "create", Content: []byte("metafile://" + strings.Repeat("0", 64) + "i0")}
+	if err := s.processMetaAppContent(metaData, 100, 1700000200); err == nil {
+		t.Fatal("missing descriptor indexed, want error")
+	}
+}
+
+// TestProcessMetaAppPinParksUnfetchedDescriptor parks a MetaApp whose descriptor cannot be fetched instead of
+// dropping it, and indexes it on the next retry once metafs serves the descriptor
+func TestProcessMetaAppPinParksUnfetchedDescriptor(t *testing.T) {
+	setupDeployQueueTest(t)
+	conf.Cfg().Indexer.PendingModifyExpireHours = 24
+	t.Cleanup(func() { descriptorFetchFailedAt.Store(0) })
+	s := &IndexerService{metaAppDAO: dao.NewMetaAppDAO()}
+
+	available := false
+	requests := 0
+	metafs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !available {
+			http.Error(w, "gateway timeout", http.StatusGatewayTimeout)
+			return
+		}
+		w.Write([]byte(`{"appName":"external","runtime":"browser","version":"1.0.0","content":"` + testCodePinID + `"}`))
+	}))
+	defer metafs.Close()
+	conf.Cfg().Metafs = conf.MetafsConfig{Domain: metafs.URL, AccelerateContentPath: "/content"}
+
+	for _, pinID := range []string{"park1i0", "park2i0"} {
+		metaData := &indexer.MetaIDData{PinID: pinID, Operation: "create", Content: []byte("metafile://" + testDescriptorPinID)}
+		if err := s.processMetaAppPin(metaData, 100, 1700000000); err != nil {
+			t.Fatalf("processMetaAppPin(%s) failed: %v, want parked", pinID, err)
+		}
+		if _, err := database.DB.GetMetaAppByPinID(pinID); err != database.ErrNotFound {
+			t.Fatalf("MetaApp %s indexed without its descriptor (err %v)", pinID, err)
+		}
+	}
+	// After a failure metafs is not called again for the next PIN
+	if requests != 1 {
+		t.Fatalf("metafs requested %d times, want 1 (backoff after the failure)", requests)
+	}
+
+	// Still unavailable: the retry parks the PINs again
+	descriptorFetchFailedAt.Store(0)
+	s.retryParkedDescriptors()
+	if _, err := database.DB.GetMetaAppByPinID("park1i0"); err != database.ErrNotFound {
+		t.Fatalf("MetaApp indexed while metafs is unavailable (err %v)", err)
+	}
+
+	available = true
+	descriptorFetchFailedAt.Store(0)
+	s.retryParkedDescriptors()
+	if _, err := database.DB.GetMetaAppByPinID("park1i0"); err != database.ErrNotFound {
+		t.Fatal("retried again within the retry interval")
+	}
+	s.lastDescriptorRetry.Store(0)
+	s.retryParkedDescriptors()
+	for _, pinID := range []string{"park1i0", "park2i0"} {
+		app, err := database.DB.GetMetaAppByPinID(pinID)
+		if err != nil || app.AppName != "external" || app.BlockHeight != 100 {
+			t.Fatalf("MetaApp %s after retry: %+v (err %v), want indexed at height 100", pinID, app, err)
+		}
+	}
+}
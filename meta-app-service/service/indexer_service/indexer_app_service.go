@@ -2,8 +2,11 @@ package indexer_service
 
 import (
 	"archive/zip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +14,7 @@ import (
 
 	"meta-app-service/conf"
 	"meta-app-service/database"
+	"meta-app-service/indexer"
 	model "meta-app-service/models"
 	"meta-app-service/models/dao"
 )
@@ -34,17 +38,18 @@ type MetaAppWithDeploy struct {
 }
 
 // ListMetaApps 获取 MetaApp 列表（时间倒序，可分页）
-// cursor: 游标（从 0 开始）
+// cursor: 游标（上一页返回的 nextCursor，第一页为空）
 // size: 每页大小
-func (s *IndexerAppService) ListMetaApps(cursor, size int64) ([]*MetaAppWithDeploy, int64, error) {
+// filter: 过滤条件（链名称、运行环境、未确认记录）
+func (s *IndexerAppService) ListMetaApps(cursor string, size int64, filter model.MetaAppListFilter) ([]*MetaAppWithDeploy, string, error) {
 	if s.metaAppDAO == nil {
-		return nil, 0, database.ErrDatabaseNotInitialized
+		return nil, "", database.ErrDatabaseNotInitialized
 	}
 
 	// 获取 MetaApp 列表（从 collectionMetaAppTimestamp，返回每个 first_pin_id 的最新版本）
-	apps, nextCursor, err := s.metaAppDAO.ListWithCursor(cursor, int(size))
+	apps, nextCursor, err := s.metaAppDAO.ListWithCursor(cursor, int(size), filter)
 	if err != nil {
-		return nil, 0, err
+		return nil, "", err
 	}
 
 	// 获取每个 MetaApp 的部署信息（使用 first_pin_id）
@@ -55,14 +60,7 @@ func (s *IndexerAppService) ListMetaApps(cursor, size int64) ([]*MetaAppWithDepl
 		}
 
 		// 获取部署信息
-		deployPinID := app.PinID
-		// if deployPinID == "" {
-		// 	deployPinID = app.PinID
-		// }
-		deployInfo, err := database.DB.GetDeployFileContent(deployPinID)
-		if err == nil && deployInfo != nil {
-			appWithDeploy.DeployInfo = deployInfo
-		}
+		appWithDeploy.DeployInfo = getDeployInfo(app)
 
 		result = append(result, appWithDeploy)
 	}
@@ -70,19 +68,44 @@ func (s *IndexerAppService) ListMetaApps(cursor, size int64) ([]*MetaAppWithDepl
 	return result, nextCursor, nil
 }
 
+// GetMetaAppsByHeightRange 获取区块高度在 [fromHeight, toHeight] 内的 MetaApp（每个已确认版本一条，包括部署情况，高度倒序，可分页）
+// cursor: 游标（上一页返回的 nextCursor，第一页为空）
+// size: 每页大小
+func (s *IndexerAppService) GetMetaAppsByHeightRange(fromHeight, toHeight int64, cursor string, size int64) ([]*MetaAppWithDeploy, string, error) {
+	if s.metaAppDAO == nil {
+		return nil, "", database.ErrDatabaseNotInitialized
+	}
+
+	apps, nextCursor, err := s.metaAppDAO.GetByHeightRange(fromHeight, toHeight, cursor, int(size))
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]*MetaAppWithDeploy, 0, len(apps))
+	for _, app := range apps {
+		result = append(result, &MetaAppWithDeploy{
+			MetaApp:    app,
+			DeployInfo: getDeployInfo(app),
+		})
+	}
+
+	return result, nextCursor, nil
+}
+
 // GetMetaAppsByCreatorMetaID 根据 MetaID 获取 MetaApp 列表（包括部署情况，时间倒序，可分页）
 // metaID: 创建者 MetaID
-// cursor: 游标（从 0 开始）
+// cursor: 游标（上一页返回的 nextCursor，第一页为空）
 // size: 每页大小
-func (s *IndexerAppService) GetMetaAppsByCreatorMetaID(metaID string, cursor, size int64) ([]*MetaAppWithDeploy, int64, error) {
+// filter: 过滤条件（链名称、运行环境、未确认记录）
+func (s *IndexerAppService) GetMetaAppsByCreatorMetaID(metaID string, cursor string, size int64, filter model.MetaAppListFilter) ([]*MetaAppWithDeploy, string, error) {
 	if s.metaAppDAO == nil {
-		return nil, 0, database.ErrDatabaseNotInitialized
+		return nil, "", database.ErrDatabaseNotInitialized
 	}
 
 	// 获取 MetaApp 列表（从 collectionMetaAppMetaIDTimestamp，返回每个 first_pin_id 的最新版本）
-	apps, nextCursor, err := s.metaAppDAO.GetByCreatorMetaIDWithCursor(metaID, cursor, int(size))
+	apps, nextCursor, err := s.metaAppDAO.GetByCreatorMetaIDWithCursor(metaID, cursor, int(size), filter)
 	if err != nil {
-		return nil, 0, err
+		return nil, "", err
 	}
 
 	// 获取每个 MetaApp 的部署信息（使用 first_pin_id）
@@ -93,14 +116,7 @@ func (s *IndexerAppService) GetMetaAppsByCreatorMetaID(metaID string, cursor, si
 		}
 
 		// 获取部署信息
-		deployPinID := app.PinID
-		// if deployPinID == "" {
-		// 	deployPinID = app.PinID
-		// }
-		deployInfo, err := database.DB.GetDeployFileContent(deployPinID)
-		if err == nil && deployInfo != nil {
-			appWithDeploy.DeployInfo = deployInfo
-		}
+		appWithDeploy.DeployInfo = getDeployInfo(app)
 
 		result = append(result, appWithDeploy)
 	}
@@ -108,6 +124,91 @@ func (s *IndexerAppService) GetMetaAppsByCreatorMetaID(metaID string, cursor, si
 	return result, nextCursor, nil
 }
 
+// GetMetaAppsByOwnerMetaID 根据当前拥有者 MetaID 获取 MetaApp 列表（包括部署情况，时间倒序，可分页）
+// metaID: 拥有者 MetaID
+// cursor: 游标（上一页返回的 nextCursor，第一页为空）
+// size: 每页大小
+// filter: 过滤条件（链名称、运行环境、未确认记录）
+func (s *IndexerAppService) GetMetaAppsByOwnerMetaID(metaID string, cursor string, size int64, filter model.MetaAppListFilter) ([]*MetaAppWithDeploy, string, error) {
+	if s.metaAppDAO == nil {
+		return nil, "", database.ErrDatabaseNotInitialized
+	}
+
+	// 获取 MetaApp 列表（从 collectionMetaAppOwnerTimestamp，返回每个 first_pin_id 的最新版本）
+	apps, nextCursor, err := s.metaAppDAO.GetByOwnerMetaIDWithCursor(metaID, cursor, int(size), filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 获取每个 MetaApp 的部署信息
+	result := make([]*MetaAppWithDeploy, 0, len(apps))
+	for _, app := range apps {
+		result = append(result, &MetaAppWithDeploy{
+			MetaApp:    app,
+			DeployInfo: getDeployInfo(app),
+		})
+	}
+
+	return result, nextCursor, nil
+}
+
+// GetMetaAppsByAppName 根据应用名称（精确匹配）获取 MetaApp 列表（包括部署情况，时间倒序，可分页）
+// 同名应用可能来自不同创建者，全部返回，便于发现名称冲突
+// appName: 应用名称
+// cursor: 游标（上一页返回的 nextCursor，第一页为空）
+// size: 每页大小
+// filter: 过滤条件（链名称、运行环境、未确认记录）
+func (s *IndexerAppService) GetMetaAppsByAppName(appName string, cursor string, size int64, filter model.MetaAppListFilter) ([]*MetaAppWithDeploy, string, error) {
+	if s.metaAppDAO == nil {
+		return nil, "", database.ErrDatabaseNotInitialized
+	}
+
+	// 获取 MetaApp 列表（从 collectionMetaAppName，返回每个 first_pin_id 的最新版本）
+	apps, nextCursor, err := s.metaAppDAO.GetByAppNameWithCursor(appName, cursor, int(size), filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 获取每个 MetaApp 的部署信息
+	result := make([]*MetaAppWithDeploy, 0, len(apps))
+	for _, app := range apps {
+		result = append(result, &MetaAppWithDeploy{
+			MetaApp:    app,
+			DeployInfo: getDeployInfo(app),
+		})
+	}
+
+	return result, nextCursor, nil
+}
+
+// ListRecentDeploys 按最近一次部署成功时间倒序获取 MetaApp 列表（与按链上时间排序的 ListMetaApps 不同，反映文件下载解压完成的顺序）
+// cursor: 游标（上一页返回的 nextCursor，第一页为空）
+// size: 每页大小
+func (s *IndexerAppService) ListRecentDeploys(cursor string, size int64) ([]*MetaAppWithDeploy, string, error) {
+	if database.DB == nil {
+		return nil, "", database.ErrDatabaseNotInitialized
+	}
+
+	deploys, nextCursor, err := database.DB.ListRecentDeploysWithCursor(cursor, int(size))
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]*MetaAppWithDeploy, 0, len(deploys))
+	for _, deploy := range deploys {
+		app, err := database.DB.GetMetaAppByPinID(deploy.PinID)
+		if err != nil {
+			continue
+		}
+		result = append(result, &MetaAppWithDeploy{
+			MetaApp:    app,
+			DeployInfo: deploy,
+		})
+	}
+
+	return result, nextCursor, nil
+}
+
 // GetMetaAppByPinID 根据 PinID 获取 MetaApp 详情（包括部署情况）
 // pinID: MetaApp PinID
 func (s *IndexerAppService) GetMetaAppByPinID(pinID string) (*MetaAppWithDeploy, error) {
@@ -125,22 +226,8 @@ func (s *IndexerAppService) GetMetaAppByPinID(pinID string) (*MetaAppWithDeploy,
 		MetaApp: app,
 	}
 
-	// 获取部署信息（使用 first_pin_id 对应的部署信息，如果有的话）
-	deployPinID := pinID
-	// if app.FirstPinId != "" {
-	// 	// 尝试使用 first_pin_id 获取部署信息（因为部署是基于 first_pin_id 的）
-	// 	deployInfo, err := database.DB.GetDeployFileContent(app.FirstPinId)
-	// 	if err == nil && deployInfo != nil {
-	// 		appWithDeploy.DeployInfo = deployInfo
-	// 		return appWithDeploy, nil
-	// 	}
-	// }
-
-	// 如果 first_pin_id 没有部署信息，尝试使用当前 pinID
-	deployInfo, err := database.DB.GetDeployFileContent(deployPinID)
-	if err == nil && deployInfo != nil {
-		appWithDeploy.DeployInfo = deployInfo
-	}
+	// 获取部署信息（当前版本未部署时回退到 first_pin_id 下最新已部署版本）
+	appWithDeploy.DeployInfo = getDeployInfo(app)
 
 	return appWithDeploy, nil
 }
@@ -163,27 +250,92 @@ func (s *IndexerAppService) GetMetaAppByFirstPinID(firstPinID string) (*MetaAppW
 	}
 
 	// 获取部署信息
-	deployInfo, err := database.DB.GetDeployFileContent(app.PinID)
-	if err == nil && deployInfo != nil {
-		appWithDeploy.DeployInfo = deployInfo
-	}
+	appWithDeploy.DeployInfo = getDeployInfo(app)
 
 	return appWithDeploy, nil
 }
 
-// GetMetaAppHistoryByFirstPinID 根据 FirstPinID 获取 MetaApp 历史版本列表
+// servableHistoryPageSize 在归档中查找可用版本时每页读取的版本数
+const servableHistoryPageSize = 100
+
+// GetLatestServableMetaApp 获取 first_pin_id 下最新的可提供服务的版本
+// 跳过被创建者禁用（Disabled）、revoke 以及内容校验失败的版本，回退到最近的可用版本；没有可用版本时返回 ErrNoServableVersion
+// 历史记录只保留最近的 max_history_per_app 个版本，其中没有可用版本时按时间倒序分页查找归档的旧版本
+func GetLatestServableMetaApp(firstPinID string) (*model.MetaApp, error) {
+	if database.DB == nil {
+		return nil, database.ErrDatabaseNotInitialized
+	}
+
+	history, err := database.DB.GetMetaAppHistoryByFirstPinID(firstPinID)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, database.ErrNotFound
+	}
+
+	var servable *model.MetaApp
+	for _, app := range history {
+		if app.IsServable() && (servable == nil || app.Timestamp > servable.Timestamp) {
+			servable = app
+		}
+	}
+	if servable != nil {
+		return servable, nil
+	}
+
+	// 完整历史按时间倒序返回，第一个可用版本即为最新的可用版本
+	cursor := ""
+	for {
+		page, nextCursor, err := database.DB.GetMetaAppHistoryPage(firstPinID, cursor, servableHistoryPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range page {
+			if app.IsServable() {
+				return app, nil
+			}
+		}
+		if nextCursor == "" {
+			return nil, ErrNoServableVersion
+		}
+		cursor = nextCursor
+	}
+}
+
+// GetLatestServableMetaAppByFirstPinID 根据 FirstPinID 获取最新的可提供服务的 MetaApp 详情（包括部署情况）
 // firstPinID: MetaApp FirstPinID
-func (s *IndexerAppService) GetMetaAppHistoryByFirstPinID(firstPinID string) ([]*MetaAppWithDeploy, error) {
+func (s *IndexerAppService) GetLatestServableMetaAppByFirstPinID(firstPinID string) (*MetaAppWithDeploy, error) {
 	if s.metaAppDAO == nil {
 		return nil, database.ErrDatabaseNotInitialized
 	}
 
-	// 获取历史记录
-	history, err := database.DB.GetMetaAppHistoryByFirstPinID(firstPinID)
+	app, err := GetLatestServableMetaApp(firstPinID)
 	if err != nil {
 		return nil, err
 	}
 
+	return &MetaAppWithDeploy{
+		MetaApp:    app,
+		DeployInfo: getDeployInfo(app),
+	}, nil
+}
+
+// GetMetaAppHistoryByFirstPinID 根据 FirstPinID 获取 MetaApp 历史版本列表（包括已归档的旧版本，时间倒序，可分页）
+// firstPinID: MetaApp FirstPinID
+// cursor: 游标（上一页返回的 nextCursor，第一页为空）
+// size: 每页大小
+func (s *IndexerAppService) GetMetaAppHistoryByFirstPinID(firstPinID string, cursor string, size int64) ([]*MetaAppWithDeploy, string, error) {
+	if s.metaAppDAO == nil {
+		return nil, "", database.ErrDatabaseNotInitialized
+	}
+
+	// 获取历史记录
+	history, nextCursor, err := s.metaAppDAO.GetHistoryPage(firstPinID, cursor, int(size))
+	if err != nil {
+		return nil, "", err
+	}
+
 	// 转换为带部署信息的列表
 	result := make([]*MetaAppWithDeploy, 0, len(history))
 	for _, app := range history {
@@ -192,17 +344,185 @@ func (s *IndexerAppService) GetMetaAppHistoryByFirstPinID(firstPinID string) ([]
 		}
 
 		// 获取部署信息（使用 first_pin_id）
-		deployInfo, err := database.DB.GetDeployFileContent(app.PinID)
-		if err == nil && deployInfo != nil {
-			appWithDeploy.DeployInfo = deployInfo
-		}
+		appWithDeploy.DeployInfo = getDeployInfo(app)
 
 		result = append(result, appWithDeploy)
 	}
 
+	return result, nextCursor, nil
+}
+
+// getDeployInfo 获取 MetaApp 的部署信息
+// 部署信息按部署时的 PinID 保存，而静态文件目录按 first_pin_id 共享，
+// 因此当前版本没有已完成的部署记录时，回退到同一 first_pin_id 下最新已部署版本的部署信息
+// 仅索引模式下不返回部署信息
+func getDeployInfo(app *model.MetaApp) *model.MetaAppDeployFileContent {
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		return nil
+	}
+
+	ownInfo, err := database.DB.GetDeployFileContent(app.PinID)
+	if err != nil {
+		ownInfo = nil
+	}
+	if ownInfo != nil && ownInfo.DeployStatus == "completed" {
+		return ownInfo
+	}
+
+	firstPinID := app.FirstPinId
+	if firstPinID == "" {
+		return ownInfo
+	}
+
+	// 历史记录按时间倒序，取最新的已完成部署
+	history, err := database.DB.GetMetaAppHistoryByFirstPinID(firstPinID)
+	if err != nil {
+		return ownInfo
+	}
+	for _, item := range history {
+		if item.PinID == app.PinID {
+			continue
+		}
+		deployInfo, err := database.DB.GetDeployFileContent(item.PinID)
+		if err == nil && deployInfo != nil && deployInfo.DeployStatus == "completed" {
+			return deployInfo
+		}
+	}
+
+	return ownInfo
+}
+
+// GetDeployHistory 获取 MetaApp 的部署记录历史（每次部署尝试的状态、消息和时间，最新的在前）
+// pinID: MetaApp PinID
+func (s *IndexerAppService) GetDeployHistory(pinID string) ([]*model.MetaAppDeployFileContent, error) {
+	if s.metaAppDAO == nil {
+		return nil, database.ErrDatabaseNotInitialized
+	}
+
+	// 确认 MetaApp 存在
+	if _, err := s.metaAppDAO.GetByPinID(pinID); err != nil {
+		return nil, err
+	}
+
+	return database.DB.GetDeployFileContentHistory(pinID)
+}
+
+// MetaAppRawTx MetaApp 源交易
+type MetaAppRawTx struct {
+	PinID     string             `json:"pin_id"`         // MetaApp PinID
+	TxID      string             `json:"tx_id"`          // 交易 ID
+	ChainName string             `json:"chain_name"`     // 链名称: btc, mvc
+	RawTx     string             `json:"raw_tx"`         // 原始交易 hex
+	Pins      []*MetaAppRawTxPin `json:"pins,omitempty"` // 从交易中解析出的 PIN 数据（decode=true 时返回）
+}
+
+// MetaAppRawTxPin 从源交易中解析出的 PIN 数据
+type MetaAppRawTxPin struct {
+	PinID        string `json:"pin_id"`
+	Operation    string `json:"operation"`
+	Path         string `json:"path"`
+	OriginalPath string `json:"original_path"`
+	Encryption   string `json:"encryption"`
+	Version      string `json:"version"`
+	ContentType  string `json:"content_type"`
+	Content      string `json:"content"` // PIN 内容原文
+}
+
+// GetMetaAppRawTx 从节点获取 MetaApp 的源交易，用于核对链上实际写入的内容与索引结果
+// app: MetaApp 记录
+// scanner: MetaApp 所在链的区块扫描器
+// decode: 是否同时解析交易中的 PIN 数据
+func (s *IndexerAppService) GetMetaAppRawTx(ctx context.Context, app *model.MetaApp, scanner *indexer.BlockScanner, decode bool) (*MetaAppRawTx, error) {
+	if scanner == nil {
+		return nil, fmt.Errorf("chain %s is not indexed by this service", app.ChainName)
+	}
+
+	txID := app.TxID
+	if txID == "" {
+		// 兼容没有 TxID 的旧记录：PinID = {txid}i{vout}
+		if idx := strings.LastIndex(app.PinID, "i"); idx > 0 {
+			txID = app.PinID[:idx]
+		}
+	}
+
+	// 接口请求中只访问节点一次（不重试），超时由 nodeRequestTimeout 限制
+	ctx, cancel := context.WithTimeout(ctx, nodeRequestTimeout)
+	defer cancel()
+	rawTx, err := scanner.GetRawTransactionContext(ctx, txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get raw transaction %s: %w", txID, err)
+	}
+
+	result := &MetaAppRawTx{
+		PinID:     app.PinID,
+		TxID:      txID,
+		ChainName: app.ChainName,
+		RawTx:     rawTx,
+	}
+
+	if !decode {
+		return result, nil
+	}
+
+	chainType := scanner.ChainType()
+	tx, err := indexer.DecodeRawTransaction(rawTx, chainType)
+	if err != nil {
+		return nil, err
+	}
+	metaDataTx, err := indexer.NewMetaIDParser("").ParseAllPINs(tx, chainType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PIN data: %w", err)
+	}
+	result.Pins = make([]*MetaAppRawTxPin, 0)
+	if metaDataTx != nil {
+		for _, pin := range metaDataTx.MetaIDData {
+			result.Pins = append(result.Pins, &MetaAppRawTxPin{
+				PinID:        pin.PinID,
+				Operation:    pin.Operation,
+				Path:         pin.Path,
+				OriginalPath: pin.OriginalPath,
+				Encryption:   pin.Encryption,
+				Version:      pin.Version,
+				ContentType:  pin.ContentType,
+				Content:      string(pin.Content),
+			})
+		}
+	}
+
 	return result, nil
 }
 
+// GetMetaAppRawContent 获取 MetaApp PIN 的原始内容（链上写入的字节，未解析、未解密）
+// 索引时保存原始内容；之前索引的记录没有保存时，从节点获取源交易解析出该 PIN 的内容并保存
+// app: MetaApp 记录
+// scanner: MetaApp 所在链的区块扫描器（为 nil 时不从源交易获取）
+func (s *IndexerAppService) GetMetaAppRawContent(ctx context.Context, app *model.MetaApp, scanner *indexer.BlockScanner) (*model.MetaAppRawContent, error) {
+	if s.metaAppDAO == nil {
+		return nil, database.ErrDatabaseNotInitialized
+	}
+
+	content, err := s.metaAppDAO.GetRawContent(app.PinID)
+	if err != database.ErrNotFound || scanner == nil {
+		return content, err
+	}
+
+	rawTx, err := s.GetMetaAppRawTx(ctx, app, scanner, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, pin := range rawTx.Pins {
+		if pin.PinID != app.PinID {
+			continue
+		}
+		content = newMetaAppRawContent(pin.PinID, pin.ContentType, pin.Encryption, []byte(pin.Content))
+		if err := s.metaAppDAO.SaveRawContent(content); err != nil {
+			log.Printf("Failed to save raw content of MetaApp %s: %v", app.PinID, err)
+		}
+		return content, nil
+	}
+	return nil, database.ErrNotFound
+}
+
 // GetStats 获取统计信息（当前已同步的 MetaApp 总数）
 func (s *IndexerAppService) GetStats() (int64, error) {
 	if s.metaAppDAO == nil {
@@ -218,12 +538,46 @@ func (s *IndexerAppService) GetStats() (int64, error) {
 	return count, nil
 }
 
+// CountMetaAppsByCreatorMetaID 统计创建者名下的 MetaApp 数量（按 first_pin_id 去重）
+func (s *IndexerAppService) CountMetaAppsByCreatorMetaID(metaID string) (int64, error) {
+	if s.metaAppDAO == nil {
+		return 0, database.ErrDatabaseNotInitialized
+	}
+	return s.metaAppDAO.CountByCreatorMetaID(metaID)
+}
+
+// GetDeployQueueItem 获取部署队列项（包括重试次数和最近一次失败的错误信息）
+// pinID: 队列项 PinID
+func (s *IndexerAppService) GetDeployQueueItem(pinID string) (*model.MetaAppDeployQueue, error) {
+	if database.DB == nil {
+		return nil, database.ErrDatabaseNotInitialized
+	}
+	return database.DB.GetDeployQueueItem(pinID)
+}
+
+// RemoveDeployQueueItem 手动将队列项从部署队列中移除（用于清理反复失败的队列项）
+// 正在部署中的队列项不会被中断，部署结束后也不会重新加入队列
+// pinID: 队列项 PinID
+func (s *IndexerAppService) RemoveDeployQueueItem(pinID string) error {
+	if database.DB == nil {
+		return database.ErrDatabaseNotInitialized
+	}
+	if err := database.DB.RemoveFromDeployQueue(pinID); err != nil {
+		return err
+	}
+	log.Printf("Removed %s from deploy queue", pinID)
+	return nil
+}
+
 // RedeployMetaApp 根据 PinID 重新将 MetaApp 加入部署队列
 // pinID: MetaApp PinID
 func (s *IndexerAppService) RedeployMetaApp(pinID string) error {
 	if s.metaAppDAO == nil {
 		return database.ErrDatabaseNotInitialized
 	}
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		return ErrDeployDisabled
+	}
 
 	// 1. 获取 MetaApp 信息
 	metaApp, err := s.metaAppDAO.GetByPinID(pinID)
@@ -231,73 +585,181 @@ func (s *IndexerAppService) RedeployMetaApp(pinID string) error {
 		return fmt.Errorf("failed to get MetaApp: %w", err)
 	}
 
-	fristMetaApp, err := database.DB.GetLatestMetaAppByFirstPinID(metaApp.FirstPinId)
+	// 部署最新的可提供服务的版本（跳过被禁用、revoke 和内容校验失败的版本）
+	fristMetaApp, err := GetLatestServableMetaApp(metaApp.FirstPinId)
 	if err != nil {
 		return err
 	}
 
+	// 被禁用的应用无法部署
+	if IsMetaAppDisabled(fristMetaApp.FirstPinId) {
+		return ErrMetaAppDisabled
+	}
+
+	// 非 Web 应用不部署
+	if reason := webBundleSkipReason(fristMetaApp); reason != "" {
+		return fmt.Errorf("%w: %s", ErrDeploySkipped, reason)
+	}
+
 	deployPinId := fristMetaApp.PinID
 
-	// 2. 检查是否已经在队列中，如果在则返回错误
-	existingQueueItem, err := database.DB.GetDeployQueueItem(deployPinId)
-	if err == nil && existingQueueItem != nil {
-		// 已经在队列中，返回错误
-		return fmt.Errorf("MetaApp %s is already in deploy queue", deployPinId)
+	// 2. 创建新的部署队列项（重置 TryCount 为 0）
+	queue, err := newDeployQueueItem(fristMetaApp)
+	if err != nil {
+		return err
 	}
 
-	// 3. 准备部署队列项
-	codePinID := fristMetaApp.Code
-	if codePinID == "" {
-		// 如果没有 Code，尝试使用 Content
-		if fristMetaApp.Content != "" {
-			if !strings.HasPrefix(fristMetaApp.Content, "metafile://") {
-				codePinID = "metafile://" + fristMetaApp.Content
-			} else {
-				codePinID = fristMetaApp.Content
-			}
+	// 3. 添加到部署队列（已经在队列中时返回 ErrAlreadyQueued）
+	if err := database.DB.AddToDeployQueue(queue); err != nil {
+		if errors.Is(err, database.ErrAlreadyQueued) {
+			return fmt.Errorf("MetaApp %s is %w", deployPinId, err)
 		}
+		return fmt.Errorf("failed to add to deploy queue: %w", err)
 	}
 
+	return nil
+}
+
+// newDeployQueueItem 根据 MetaApp 创建部署队列项（优先部署 Code，没有 Code 时使用 Content）
+func newDeployQueueItem(app *model.MetaApp) (*model.MetaAppDeployQueue, error) {
+	// 没有 Code 时使用 Content，统一为 metafile://<pinid>
+	codePinID := deployPinID(app.Code, app.Content)
 	if codePinID == "" {
-		return fmt.Errorf("no code or content pinId found for MetaApp %s", deployPinId)
+		return nil, fmt.Errorf("no code or content pinId found for MetaApp %s", app.PinID)
 	}
 
-	// 4. 创建新的部署队列项（重置 TryCount 为 0）
-	queue := &model.MetaAppDeployQueue{
-		PinID:       fristMetaApp.PinID,
-		Timestamp:   fristMetaApp.Timestamp,
-		Content:     fristMetaApp.Content,
+	return &model.MetaAppDeployQueue{
+		FirstPinId:  app.FirstPinId,
+		PinID:       app.PinID,
+		Timestamp:   app.Timestamp,
+		Content:     app.Content,
 		Code:        codePinID,
-		ContentType: fristMetaApp.ContentType,
-		Version:     fristMetaApp.Version,
-		TryCount:    0, // 重置重试次数
+		ContentType: app.ContentType,
+		Version:     app.Version,
+		TryCount:    0,
 		CreatedAt:   time.Now(),
+	}, nil
+}
+
+var (
+	// ErrMetaAppDisabled MetaApp 已被禁用
+	ErrMetaAppDisabled = errors.New("metaapp is disabled")
+	// ErrDeployDisabled 仅索引模式下不部署、不托管应用文件
+	ErrDeployDisabled = errors.New("metaapp hosting is disabled on this indexer")
+	// ErrDeploySkipped 应用不是在浏览器中运行的 Web 应用，不部署
+	ErrDeploySkipped = errors.New("metaapp is not a web bundle, deploy skipped")
+	// ErrZipTooLarge 应用大小超出 meta_app.max_zip_size_mb，不在线压缩
+	ErrZipTooLarge = errors.New("metaapp is too large to download as zip")
+	// ErrNoServableVersion 所有版本都被禁用、revoke 或内容校验失败
+	ErrNoServableVersion = errors.New("metaapp has no servable version")
+)
+
+// DeployStatusSkipped 部署记录状态：非 Web 应用，跳过部署
+const DeployStatusSkipped = "skipped"
+
+// DisableMetaApp 禁用 MetaApp（加入黑名单），禁用后静态文件服务返回 403，且不会再被部署
+// pinID: MetaApp 任意版本的 PinID（按其 first_pin_id 禁用整个应用）
+// reason: 禁用原因
+// removeFiles: 是否同时删除已部署的文件
+func (s *IndexerAppService) DisableMetaApp(pinID, reason string, removeFiles bool) (*model.MetaAppBlacklist, error) {
+	if s.metaAppDAO == nil {
+		return nil, database.ErrDatabaseNotInitialized
 	}
 
-	// 5. 添加到部署队列
-	if err := database.DB.AddToDeployQueue(queue); err != nil {
-		return fmt.Errorf("failed to add to deploy queue: %w", err)
+	metaApp, err := s.metaAppDAO.GetByPinID(pinID)
+	if err != nil {
+		return nil, err
+	}
+	firstPinID := metaApp.FirstPinId
+	if firstPinID == "" {
+		firstPinID = metaApp.PinID
 	}
 
-	return nil
+	entry := &model.MetaAppBlacklist{
+		FirstPinId: firstPinID,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+
+	// 删除文件时持有部署锁（与部署队列、修复共用），等待正在进行的部署完成，避免其在删除后重新写入部署目录
+	if removeFiles {
+		unlock := lockDeploy(firstPinID)
+		defer unlock()
+	}
+
+	// 先写入黑名单，确保删除文件期间不会再被部署或访问
+	if err := database.DB.CreateMetaAppBlacklist(entry); err != nil {
+		return nil, fmt.Errorf("failed to add to blacklist: %w", err)
+	}
+
+	if removeFiles {
+		deployBaseDir := conf.Cfg().MetaApp.DeployFilePath
+		if deployBaseDir == "" {
+			deployBaseDir = "./meta_app_deploy_data"
+		}
+		if err := removeDeployDir(filepath.Join(deployBaseDir, firstPinID)); err != nil {
+			return nil, fmt.Errorf("failed to remove deploy files: %w", err)
+		}
+		entry.FilesRemoved = true
+		if err := database.DB.CreateMetaAppBlacklist(entry); err != nil {
+			return nil, fmt.Errorf("failed to update blacklist: %w", err)
+		}
+	}
+
+	return entry, nil
 }
 
-// DownloadMetaAppAsZip 根据 FirstPinID 压缩对应的部署文件夹为 zip 文件
-// firstPinID: MetaApp FirstPinID
-// 返回 zip 文件路径和错误
-func (s *IndexerAppService) DownloadMetaAppAsZip(firstPinID string) (string, error) {
+// EnableMetaApp 重新启用被禁用的 MetaApp（从黑名单移除）
+// 如果禁用时删除了部署文件，需要调用 RedeployMetaApp 重新部署
+func (s *IndexerAppService) EnableMetaApp(pinID string) error {
+	if s.metaAppDAO == nil {
+		return database.ErrDatabaseNotInitialized
+	}
+
+	metaApp, err := s.metaAppDAO.GetByPinID(pinID)
+	if err != nil {
+		return err
+	}
+	firstPinID := metaApp.FirstPinId
+	if firstPinID == "" {
+		firstPinID = metaApp.PinID
+	}
+
+	if _, err := database.DB.GetMetaAppBlacklist(firstPinID); err != nil {
+		return err
+	}
+
+	return database.DB.DeleteMetaAppBlacklist(firstPinID)
+}
+
+// IsMetaAppDisabled 判断 first_pin_id 对应的 MetaApp 是否已被禁用
+func IsMetaAppDisabled(firstPinID string) bool {
+	if database.DB == nil {
+		return false
+	}
+	_, err := database.DB.GetMetaAppBlacklist(firstPinID)
+	return err == nil
+}
+
+// GetMetaAppZipDir 根据 FirstPinID 获取可打包下载的部署目录
+// 检查部署是否启用、应用是否被禁用、目录是否存在以及大小是否超出 meta_app.max_zip_size_mb，
+// 在写入响应之前调用，出错时调用方仍可返回 JSON 错误
+func (s *IndexerAppService) GetMetaAppZipDir(firstPinID string) (string, error) {
 	if firstPinID == "" {
 		return "", fmt.Errorf("firstPinID is required")
 	}
 
-	// 获取部署基础目录
-	deployBaseDir := conf.Cfg.MetaApp.DeployFilePath
-	if deployBaseDir == "" {
-		deployBaseDir = "./meta_app_deploy_data"
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		return "", ErrDeployDisabled
+	}
+
+	// 被禁用的应用不提供下载
+	if IsMetaAppDisabled(firstPinID) {
+		return "", ErrMetaAppDisabled
 	}
 
 	// 构建应用部署目录路径
-	appDeployDir := filepath.Join(deployBaseDir, firstPinID)
+	appDeployDir := filepath.Join(metaAppDeployBaseDir(), firstPinID)
 
 	// 检查目录是否存在
 	info, err := os.Stat(appDeployDir)
@@ -312,27 +774,34 @@ func (s *IndexerAppService) DownloadMetaAppAsZip(firstPinID string) (string, err
 		return "", fmt.Errorf("path is not a directory: %s", appDeployDir)
 	}
 
-	// 创建临时 zip 文件
-	tmpDir := os.TempDir()
-	zipFileName := fmt.Sprintf("%s.zip", firstPinID)
-	zipFilePath := filepath.Join(tmpDir, zipFileName)
+	// 检查应用大小，超出限制时不在线压缩
+	if maxSize := int64(conf.Cfg().MetaApp.MaxZipSizeMB) * 1024 * 1024; maxSize > 0 {
+		if size := deployDirSize(appDeployDir); size > maxSize {
+			return "", fmt.Errorf("%w: %d bytes exceeds limit of %d MB", ErrZipTooLarge, size, conf.Cfg().MetaApp.MaxZipSizeMB)
+		}
+	}
 
-	// 创建 zip 文件
-	zipFile, err := os.Create(zipFilePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create zip file: %w", err)
+	// 应用目录是符号链接，返回其指向的版本目录（filepath.Walk 不跟随根目录的符号链接）
+	if resolved, err := filepath.EvalSymlinks(appDeployDir); err == nil {
+		appDeployDir = resolved
 	}
-	defer zipFile.Close()
+	return appDeployDir, nil
+}
 
-	// 创建 zip writer
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
+// WriteMetaAppZip 将部署目录压缩为 zip 并直接写入 w（不生成临时文件）
+// ctx: 请求上下文，取消（如客户端断开）时中止压缩
+// appDeployDir: GetMetaAppZipDir 返回的部署目录
+func WriteMetaAppZip(ctx context.Context, appDeployDir string, w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
 
 	// 遍历目录并添加到 zip
-	err = filepath.Walk(appDeployDir, func(filePath string, info os.FileInfo, err error) error {
+	err := filepath.Walk(appDeployDir, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		// 获取相对路径（相对于 appDeployDir）
 		relPath, err := filepath.Rel(appDeployDir, filePath)
@@ -351,8 +820,8 @@ func (s *IndexerAppService) DownloadMetaAppAsZip(firstPinID string) (string, err
 			return err
 		}
 
-		// 设置文件名（使用相对路径，保持目录结构）
-		header.Name = relPath
+		// 设置文件名（使用相对路径，保持目录结构，zip 内统一使用 / 分隔）
+		header.Name = filepath.ToSlash(relPath)
 
 		// 如果是目录，设置目录标志
 		if info.IsDir() {
@@ -376,7 +845,7 @@ func (s *IndexerAppService) DownloadMetaAppAsZip(firstPinID string) (string, err
 			}
 			defer file.Close()
 
-			_, err = io.Copy(writer, file)
+			_, err = io.Copy(writer, &contextReader{ctx: ctx, r: file})
 			if err != nil {
 				return err
 			}
@@ -384,12 +853,23 @@ func (s *IndexerAppService) DownloadMetaAppAsZip(firstPinID string) (string, err
 
 		return nil
 	})
-
 	if err != nil {
-		// 清理临时文件
-		os.Remove(zipFilePath)
-		return "", fmt.Errorf("failed to create zip: %w", err)
+		return fmt.Errorf("failed to create zip: %w", err)
 	}
 
-	return zipFilePath, nil
+	// 写入 zip 目录区
+	return zipWriter.Close()
+}
+
+// contextReader 在每次读取前检查 ctx，ctx 取消后返回 ctx.Err()，用于中止大文件复制
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
 }
@@ -1,25 +1,35 @@
 package indexer_service
 
 import (
-	"archive/zip"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"meta-app-service/conf"
 	"meta-app-service/database"
 	"meta-app-service/indexer"
+	"meta-app-service/metrics"
 	model "meta-app-service/models"
 	"meta-app-service/models/dao"
 	"meta-app-service/service/common_service/metaid_protocols"
+	"meta-app-service/tool"
 	"regexp"
 )
 
@@ -30,6 +40,65 @@ type IndexerService struct {
 	metaAppDAO    *dao.MetaAppDAO
 	chainType     indexer.ChainType
 	parser        *indexer.MetaIDParser
+
+	deployWorkersMu   sync.Mutex      // 保护 deployWorkerStops
+	deployWorkerStops []chan struct{} // 运行中的部署 worker 的停止信号，数量即 worker 数量
+
+	protocolProcessors map[string]protocolProcessor // 协议路径 -> PIN 处理器
+
+	rescanMu     sync.Mutex // 保护重新扫描状态
+	rescanning   bool       // 是否正在重新扫描
+	rescanFrom   int64      // 重新扫描起始高度
+	rescanTo     int64      // 重新扫描结束高度
+	rescanHeight int64      // 重新扫描当前高度
+
+	lastPendingPurge atomic.Int64 // 上次清理过期暂存 modify 的时间（Unix 秒）
+	lastCheckpoint   atomic.Int64 // 最近一次写入的扫描恢复点高度
+
+	lastDescriptorRetry atomic.Int64 // 上次重新处理描述 JSON 获取失败的 PIN 的时间（Unix 秒）
+	descriptorParkedAt  sync.Map     // PinID -> 最初暂存时间（重试期间再次暂存时保留）
+
+	mempoolPins mempoolPinCache // ZMQ 推送的内存池 MetaApp PIN（/api/v1/mempool/metaapps）
+}
+
+var (
+	// ErrRescanRunning a rescan is already running
+	ErrRescanRunning = errors.New("rescan already running")
+	// ErrInvalidRescanRange invalid rescan height range
+	ErrInvalidRescanRange = errors.New("invalid rescan range")
+)
+
+// deployStagingDirName 部署临时目录名（位于部署目录下，重新部署时先写入这里再替换正式目录）
+const deployStagingDirName = ".staging"
+
+// deployReleasesDirName 部署版本目录名（位于部署目录下），应用目录 {first_pin_id} 是指向其中一个版本的符号链接
+const deployReleasesDirName = ".releases"
+
+// downloadResumeAttempts 下载中断后通过 HTTP Range 续传的最大次数
+const downloadResumeAttempts = 5
+
+// deployLeaseDuration 部署队列项的租约时长（worker 异常退出后，租约到期可被重新领取）
+const deployLeaseDuration = 10 * time.Minute
+
+// deployRetryBackoffs 部署失败后的重试间隔（指数退避，超出部分使用最后一个值）
+var deployRetryBackoffs = []time.Duration{
+	5 * time.Second,
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+}
+
+// deployRetryBackoff 根据已失败次数计算下次重试前的等待时间
+func deployRetryBackoff(tryCount int) time.Duration {
+	if tryCount <= 0 {
+		return 0
+	}
+	if tryCount > len(deployRetryBackoffs) {
+		return deployRetryBackoffs[len(deployRetryBackoffs)-1]
+	}
+	return deployRetryBackoffs[tryCount-1]
 }
 
 // NewIndexerService create indexer service instance
@@ -45,19 +114,24 @@ func NewIndexerServiceWithChain(chainType indexer.ChainType) (*IndexerService, e
 	// Get current sync height from database
 	var currentSyncHeight int64 = 0
 	syncStatus, err := syncStatusDAO.GetByChainName(chainName)
-	if err == nil && syncStatus != nil && syncStatus.CurrentSyncHeight > 0 {
+	if err != nil {
+		log.Printf("Failed to read sync status for %s chain, it will be rebuilt: %v", chainName, err)
+	} else if syncStatus != nil && syncStatus.CurrentSyncHeight > 0 {
 		currentSyncHeight = syncStatus.CurrentSyncHeight
 		log.Printf("Found existing sync status for %s chain, current sync height: %d", chainName, currentSyncHeight)
 	}
 
-	// Determine start height based on configuration
-	configStartHeight := conf.Cfg.Indexer.StartHeight
+	// Determine start height based on configuration (per-chain chains.<name>.start_height takes precedence)
+	configStartHeight := conf.Cfg().Indexer.StartHeight
+	if chainCfg, ok := conf.Cfg().Chains[chainName]; ok && chainCfg.StartHeight > 0 {
+		configStartHeight = chainCfg.StartHeight
+	}
 	if configStartHeight == 0 {
 		// Use chain-specific init height if not specified
 		if chainType == indexer.ChainTypeMVC {
-			configStartHeight = conf.Cfg.Indexer.MvcInitBlockHeight
+			configStartHeight = conf.Cfg().Indexer.MvcInitBlockHeight
 		} else if chainType == indexer.ChainTypeBTC {
-			configStartHeight = conf.Cfg.Indexer.BtcInitBlockHeight
+			configStartHeight = conf.Cfg().Indexer.BtcInitBlockHeight
 		}
 	}
 
@@ -74,22 +148,51 @@ func NewIndexerServiceWithChain(chainType indexer.ChainType) (*IndexerService, e
 		log.Printf("No start height configured, starting from: %d", startHeight)
 	}
 
-	log.Printf("Indexer service will start from block height: %d (chain: %s)", startHeight, chainType)
-
-	// Create block scanner with chain type
+	// Create block scanner with chain type (each chain uses its own node config)
+	chainCfg := conf.GetChainConfig(chainName)
 	scanner := indexer.NewBlockScannerWithChain(
-		conf.Cfg.Chain.RpcUrl,
-		conf.Cfg.Chain.RpcUser,
-		conf.Cfg.Chain.RpcPass,
+		chainCfg.RpcUrl,
+		chainCfg.RpcUser,
+		chainCfg.RpcPass,
 		startHeight,
-		conf.Cfg.Indexer.ScanInterval,
+		conf.Cfg().Indexer.ScanInterval,
 		chainType,
 	)
 
+	scanner.SetRPCTimeout(time.Duration(chainCfg.RpcTimeoutSeconds) * time.Second)
+	scanner.SetRPCMaxRetries(chainCfg.RpcMaxRetries)
+	scanner.SetScanConcurrency(conf.Cfg().Indexer.ScanConcurrency)
+	scanner.SetBatchSize(conf.Cfg().Indexer.BatchSize)
+	scanner.SetRawTxCacheSize(conf.Cfg().Indexer.RawTxCacheSize)
+	scanner.SetConfirmations(conf.Cfg().Indexer.Confirmations)
+	// 重新加载配置时更新扫描间隔
+	conf.OnReload(func(old, cur *conf.Config) {
+		if cur.Indexer.ScanInterval != old.Indexer.ScanInterval {
+			scanner.SetInterval(cur.Indexer.ScanInterval)
+		}
+	})
+	if conf.Cfg().Indexer.RpcBatchEnabled {
+		scanner.EnableRPCBatch()
+		log.Println("Batched JSON-RPC block fetching enabled")
+	}
+	if chainCfg.RestUrl != "" {
+		scanner.SetRESTURL(chainCfg.RestUrl)
+		log.Printf("REST block fetching enabled: %s (chain: %s)", chainCfg.RestUrl, chainName)
+	}
+
+	// Make sure the node is reachable before scanning, instead of retrying blindly forever
+	if attempts := chainCfg.StartupCheckAttempts; attempts > 0 {
+		nodeHeight, err := scanner.WaitForNode(attempts)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Node reachable (chain: %s), latest block height: %d", chainName, nodeHeight)
+	}
+
 	// Enable ZMQ if configured
-	if conf.Cfg.Indexer.ZmqEnabled && conf.Cfg.Indexer.ZmqAddress != "" {
-		scanner.EnableZMQ(conf.Cfg.Indexer.ZmqAddress)
-		log.Printf("ZMQ real-time monitoring enabled: %s", conf.Cfg.Indexer.ZmqAddress)
+	if conf.Cfg().Indexer.ZmqEnabled && chainCfg.ZmqAddress != "" {
+		scanner.EnableZMQ(chainCfg.ZmqAddress)
+		log.Printf("ZMQ real-time monitoring enabled: %s (chain: %s)", chainCfg.ZmqAddress, chainName)
 	} else {
 		log.Println("ZMQ real-time monitoring disabled")
 	}
@@ -105,6 +208,12 @@ func NewIndexerServiceWithChain(chainType indexer.ChainType) (*IndexerService, e
 		chainType:     chainType,
 		parser:        parser,
 	}
+	service.registerProtocolProcessors()
+
+	// Resume from the redundant checkpoint or the indexed MetaApps when the sync status is behind them (lost or corrupted)
+	startHeight = service.reconcileStartHeight(startHeight)
+	scanner.SetStartHeight(startHeight)
+	log.Printf("Indexer service will start from block height: %d (chain: %s)", startHeight, chainType)
 
 	// Initialize sync status in database
 	if err := service.initializeSyncStatus(startHeight); err != nil {
@@ -115,26 +224,30 @@ func NewIndexerServiceWithChain(chainType indexer.ChainType) (*IndexerService, e
 }
 
 // initializeSyncStatus initialize sync status in database
+// A missing or unreadable status is (re)created, a status behind the resolved start height is moved up to it
 func (s *IndexerService) initializeSyncStatus(startHeight int64) error {
 	chainName := string(s.chainType)
 
+	initialHeight := int64(0)
+	if startHeight > 0 {
+		initialHeight = startHeight - 1 // Will be updated when first block is scanned
+	}
+
 	// Try to get existing status
 	existingStatus, err := s.syncStatusDAO.GetByChainName(chainName)
-	if err == nil && existingStatus != nil {
+	if err == nil && existingStatus != nil && existingStatus.CurrentSyncHeight >= initialHeight {
 		log.Printf("Sync status already exists for %s chain, current sync height: %d", chainName, existingStatus.CurrentSyncHeight)
 		return nil
 	}
 
-	// Create initial status (only if not exists)
-	initialHeight := int64(0)
-	if startHeight > 0 {
-		initialHeight = startHeight - 1 // Will be updated when first block is scanned
-	}
-
 	status := &model.IndexerSyncStatus{
 		ChainName:         chainName,
 		CurrentSyncHeight: initialHeight,
 	}
+	if err == nil && existingStatus != nil {
+		status = existingStatus
+		status.CurrentSyncHeight = initialHeight
+	}
 
 	if err := s.syncStatusDAO.CreateOrUpdate(status); err != nil {
 		return fmt.Errorf("failed to create sync status: %w", err)
@@ -144,15 +257,27 @@ func (s *IndexerService) initializeSyncStatus(startHeight int64) error {
 	return nil
 }
 
-// Start start indexer service
+// Start start indexer service (deploy processor and block scanning)
 func (s *IndexerService) Start() {
 	log.Println("Indexer service starting...")
 	// Start deploy processor
 	s.StartDeployProcessor()
 
+	s.StartScanning()
+}
+
+// StartScanning start block scanning only (blocks until the scanner stops)
+// When several chains are indexed in one process, the shared deploy processor is started once
+// and every chain's service only runs its own scanner
+func (s *IndexerService) StartScanning() {
+	log.Printf("Block scanning starting (chain: %s)...", s.chainType)
 	// Start block scanning with block complete callback
 	s.scanner.Start(s.handleTransaction, s.onBlockComplete)
+}
 
+// ChainType get the chain this service is indexing
+func (s *IndexerService) ChainType() indexer.ChainType {
+	return s.chainType
 }
 
 // GetScanner get block scanner instance
@@ -160,6 +285,98 @@ func (s *IndexerService) GetScanner() *indexer.BlockScanner {
 	return s.scanner
 }
 
+// IndexerState indexer scanning state
+type IndexerState struct {
+	ChainName     string `json:"chain_name"`     // Chain name: btc, mvc
+	Paused        bool   `json:"paused"`         // Whether block scanning is paused
+	CurrentHeight int64  `json:"current_height"` // Next block height to scan
+	ZmqActive     bool   `json:"zmq_active"`     // Whether ZMQ real-time monitoring is running
+	Rescanning    bool   `json:"rescanning"`     // Whether a manual rescan is running
+	RescanFrom    int64  `json:"rescan_from"`    // Manual rescan start height
+	RescanTo      int64  `json:"rescan_to"`      // Manual rescan end height
+	RescanHeight  int64  `json:"rescan_height"`  // Manual rescan current height
+}
+
+// PauseScanning pause block scanning (HTTP server and deploy processor keep running)
+func (s *IndexerService) PauseScanning() {
+	s.scanner.Pause()
+}
+
+// ResumeScanning resume block scanning
+func (s *IndexerService) ResumeScanning() {
+	s.scanner.Resume()
+}
+
+// GetState get indexer scanning state
+func (s *IndexerService) GetState() *IndexerState {
+	s.rescanMu.Lock()
+	defer s.rescanMu.Unlock()
+
+	return &IndexerState{
+		ChainName:     string(s.chainType),
+		Paused:        s.scanner.IsPaused(),
+		CurrentHeight: s.scanner.CurrentHeight(),
+		ZmqActive:     s.scanner.IsZMQActive(),
+		Rescanning:    s.rescanning,
+		RescanFrom:    s.rescanFrom,
+		RescanTo:      s.rescanTo,
+		RescanHeight:  s.rescanHeight,
+	}
+}
+
+// Rescan re-scan blocks [from, to] in background with the normal transaction handler, overwriting records
+// The main scan loop is held while rescanning; an operator pause set before or during the rescan is kept afterwards
+func (s *IndexerService) Rescan(from, to int64) error {
+	if from < 0 || from > to {
+		return fmt.Errorf("%w: from=%d, to=%d", ErrInvalidRescanRange, from, to)
+	}
+
+	latestHeight, err := s.scanner.GetBlockCount()
+	if err != nil {
+		return fmt.Errorf("failed to get latest block height: %w", err)
+	}
+	if to > latestHeight {
+		return fmt.Errorf("%w: to=%d is above current tip %d", ErrInvalidRescanRange, to, latestHeight)
+	}
+
+	s.rescanMu.Lock()
+	defer s.rescanMu.Unlock()
+	if s.rescanning {
+		return ErrRescanRunning
+	}
+	s.rescanning = true
+	s.rescanFrom = from
+	s.rescanTo = to
+	s.rescanHeight = from
+
+	go s.runRescan(from, to)
+	return nil
+}
+
+// runRescan re-scan blocks [from, to], stopping at the first failed block
+func (s *IndexerService) runRescan(from, to int64) {
+	s.scanner.Hold()
+	defer func() {
+		s.scanner.Release()
+		s.rescanMu.Lock()
+		s.rescanning = false
+		s.rescanMu.Unlock()
+	}()
+
+	log.Printf("Rescan started: blocks %d - %d (chain: %s)", from, to, s.chainType)
+	for height := from; height <= to; height++ {
+		s.rescanMu.Lock()
+		s.rescanHeight = height
+		s.rescanMu.Unlock()
+
+		if _, err := s.scanner.ScanBlock(height, s.handleTransaction); err != nil {
+			log.Printf("Rescan stopped at block %d: %v", height, err)
+			return
+		}
+	}
+	log.Printf("Rescan completed: blocks %d - %d (chain: %s)", from, to, s.chainType)
+}
+
 // onBlockComplete called after each block is successfully scanned
 func (s *IndexerService) onBlockComplete(height int64) error {
 	chainName := string(s.chainType)
@@ -169,6 +386,12 @@ func (s *IndexerService) onBlockComplete(height int64) error {
 		return fmt.Errorf("failed to update sync height: %w", err)
 	}
 
+	// Redundant resume checkpoint every indexer.checkpoint_interval blocks
+	s.writeCheckpoint(height)
+
+	// Retry MetaApps parked because their descriptor could not be fetched from metafs
+	s.retryParkedDescriptors()
+
 	return nil
 }
 
@@ -186,121 +409,168 @@ func (s *IndexerService) handleTransaction(tx interface{}, metaDataTx *indexer.M
 	// log.Printf("Found MetaID pinId: %s,  transaction: %s at height %d (chain: %s), PIN count: %d",
 	// 	pinId, txID, height, chainNameFromTx, len(metaDataTx.MetaIDData))
 
-	// Process each PIN in the transaction
+	// Dispatch each PIN to the processor of the protocol its path matches
 	for _, metaData := range metaDataTx.MetaIDData {
-		// log.Printf("Processing PIN: %s (path: %s, operation: %s, originalPath: %s, content type: %s)",
-		// 	metaData.PinID, metaData.Path, metaData.Operation, metaData.OriginalPath, metaData.ContentType)
-
-		// Check if this is a MetaApp protocol PIN
-		isMetaApp, isPathPinID := isMetaAppPath(metaData.Path)
-		if isMetaApp {
-			log.Printf("Processing MetaApp PIN: %s (path: %s, operation: %s, originalPath: %s)",
-				metaData.PinID, metaData.Path, metaData.Operation, metaData.OriginalPath)
-
-			// Check if already exists (by PinID)
-			existingApp, err := s.metaAppDAO.GetByPinID(metaData.PinID)
-			if err == nil && existingApp != nil {
-				log.Printf("MetaApp PIN already indexed: %s", metaData.PinID)
-
-				// Update block height if needed
-				if existingApp.BlockHeight < height && height > 0 {
-					existingApp.BlockHeight = height
-					if err := s.metaAppDAO.Update(existingApp); err != nil {
-						log.Printf("Failed to update MetaApp block height: %v", err)
-					}
-				}
+		protocolPath := s.matchPinProtocol(metaData)
+		if protocolPath == "" {
+			continue
+		}
+		if protocolPath == metaid_protocols.MetaAppProtocolPath {
+			s.trackMempoolPin(metaData, height)
+		}
+		processor, ok := s.protocolProcessors[protocolPath]
+		if !ok {
+			continue
+		}
+		// Skip PINs of creators filtered out by indexer.creator_allowlist / creator_denylist (not stored, not queued)
+		if allowed, reason := s.creatorAllowed(metaData); !allowed {
+			log.Printf("Skipping %s PIN %s: %s", protocolPath, metaData.PinID, reason)
+			continue
+		}
+		// Continue processing other PINs even if one fails
+		if err := processor(metaData, height, timestamp); err != nil {
+			log.Printf("Failed to process %s PIN %s: %v", protocolPath, metaData.PinID, err)
+		}
+	}
 
-				continue
-			}
+	return nil
+}
 
-			if metaData.Operation == "modify" {
-				log.Printf("Processing MetaApp modify operation: %s (path: %s, operation: %s, originalPath: %s)",
-					metaData.PinID, metaData.Path, metaData.Operation, metaData.OriginalPath)
-			}
+// protocolProcessor 处理属于某个协议的 PIN
+type protocolProcessor func(metaData *indexer.MetaIDData, height, timestamp int64) error
 
-			// 处理 modify 操作
-			if metaData.Operation == "modify" && metaData.Path != "" {
-				// 提取 first_pin_id 从 Path (格式: @{pin_id})，需要递归查找
-				firstPinID, err := s.extractFirstPinIDFromOriginalPath(metaData.Path)
-				if err != nil {
-					log.Printf("Failed to extract first_pin_id from path %s: %v, skipping modify operation", metaData.Path, err)
-					continue
-				}
+// registerProtocolProcessors 注册各协议的 PIN 处理器（indexer.protocols 中没有处理器的协议只记录日志）
+func (s *IndexerService) registerProtocolProcessors() {
+	s.protocolProcessors = map[string]protocolProcessor{
+		metaid_protocols.MetaAppProtocolPath: s.processMetaAppPin,
+	}
+	for _, protocolPath := range metaid_protocols.ProtocolList {
+		if _, ok := s.protocolProcessors[protocolPath]; !ok {
+			log.Printf("No processor registered for protocol %s, its PINs are ignored", protocolPath)
+		}
+	}
+}
 
-				if firstPinID != "" {
-					log.Printf("Processing MetaApp modify operation: current PIN=%s, first PIN=%s",
-						metaData.PinID, firstPinID)
-
-					// 处理 modify 操作
-					if err := s.processMetaAppModify(metaData, firstPinID, height, timestamp); err != nil {
-						log.Printf("Failed to process MetaApp modify for PIN %s: %v", metaData.PinID, err)
-						// Continue processing other PINs even if one fails
-						continue
-					}
-					continue
-				}
-				continue
-			}
+// processMetaAppPin 处理 MetaApp 协议 PIN（create / modify）
+func (s *IndexerService) processMetaAppPin(metaData *indexer.MetaIDData, height, timestamp int64) error {
+	log.Printf("Processing MetaApp PIN: %s (path: %s, operation: %s, originalPath: %s)",
+		metaData.PinID, metaData.Path, metaData.Operation, metaData.OriginalPath)
 
-			if metaData.Operation == "create" && isPathPinID {
-				// log.Printf("Processing MetaApp create operation with path PIN: %s (path: %s, operation: %s, originalPath: %s)",
-				// 	metaData.PinID, metaData.Path, metaData.Operation, metaData.OriginalPath)
-				continue
-			}
+	// Check if already exists (by PinID)
+	existingApp, err := s.metaAppDAO.GetByPinID(metaData.PinID)
+	if err == nil && existingApp != nil {
+		log.Printf("MetaApp PIN already indexed: %s", metaData.PinID)
 
-			// Process MetaApp content (create operation)
-			if err := s.processMetaAppContent(metaData, height, timestamp); err != nil {
-				log.Printf("Failed to process MetaApp content for PIN %s: %v", metaData.PinID, err)
-				// Continue processing other PINs even if one fails
-				continue
+		// 之前通过 ZMQ 以未确认状态（高度 0）索引的记录，在区块中再次出现时更新为已确认
+		if existingApp.BlockHeight < height && height > 0 {
+			if !existingApp.IsConfirmed() {
+				log.Printf("MetaApp PIN confirmed in block %d: %s", height, metaData.PinID)
+			}
+			// 未确认记录的时间戳是收到内存池交易的时间，改用区块时间，
+			// Update 会按新的时间戳重建时间索引，保证排序与区块扫描索引的记录一致
+			if existingApp.BlockHeight == 0 && timestamp > 0 {
+				existingApp.Timestamp = ensureMillisecondTimestamp(timestamp)
+			}
+			existingApp.BlockHeight = height
+			existingApp.UpdatedAt = time.Now()
+			if err := s.metaAppDAO.Update(existingApp); err != nil {
+				log.Printf("Failed to update MetaApp block height: %v", err)
 			}
 		}
+
+		return nil
 	}
 
-	return nil
-}
+	if metaData.Operation == "modify" {
+		log.Printf("Processing MetaApp modify operation: %s (path: %s, operation: %s, originalPath: %s)",
+			metaData.PinID, metaData.Path, metaData.Operation, metaData.OriginalPath)
+	}
 
-// isMetaAppPath check if path is a MetaApp protocol path
-func isMetaAppPath(path string) (bool, isPinID bool) {
-	if path == "" {
-		return false, false
+	// 处理 modify 操作
+	if metaData.Operation == "modify" && metaData.Path != "" {
+		// 提取 first_pin_id 从 Path (格式: @{pin_id})，需要递归查找
+		firstPinID, err := s.extractFirstPinIDFromOriginalPath(metaData.Path)
+		if err != nil {
+			// 引用链上的 PIN 尚未索引时暂存，等该 PIN 索引后重新处理
+			var notIndexed *pinNotIndexedError
+			if errors.As(err, &notIndexed) && s.parkModify(metaData, notIndexed.pinID, height, timestamp) {
+				return nil
+			}
+			log.Printf("Failed to extract first_pin_id from path %s: %v, skipping modify operation", metaData.Path, err)
+			return nil
+		}
+
+		if firstPinID != "" {
+			log.Printf("Processing MetaApp modify operation: current PIN=%s, first PIN=%s",
+				metaData.PinID, firstPinID)
+
+			// 处理 modify 操作（描述 JSON 暂时无法获取时暂存，稍后重试）
+			if err := s.processMetaAppModify(metaData, firstPinID, height, timestamp); err != nil {
+				var fetchErr *descriptorFetchError
+				if errors.As(err, &fetchErr) && s.parkDescriptorPin(metaData, height, timestamp, err) {
+					return nil
+				}
+				return fmt.Errorf("failed to process MetaApp modify: %w", err)
+			}
+			s.processPendingModifies(metaData.PinID)
+		}
+		return nil
 	}
 
-	// 1. 检查是否匹配 MetaApp 协议路径（create 操作）
-	for _, protocolPath := range metaid_protocols.ProtocolList {
-		if strings.HasPrefix(path, protocolPath) || strings.Contains(path, protocolPath) {
-			return true, false
+	// Process MetaApp content (create operation), parked for a later retry when its descriptor cannot be fetched yet
+	if err := s.processMetaAppContent(metaData, height, timestamp); err != nil {
+		var fetchErr *descriptorFetchError
+		if errors.As(err, &fetchErr) && s.parkDescriptorPin(metaData, height, timestamp, err) {
+			return nil
 		}
+		return fmt.Errorf("failed to process MetaApp content: %w", err)
 	}
+	s.processPendingModifies(metaData.PinID)
 
-	// 2. 检查是否是 modify 操作的 path 格式
-	// modify 操作的 path 可能是：
-	//   - @{pinId} - 直接引用其他 pinId
-	//   - {host:@pinId} - 带 host 的格式
-	if strings.HasPrefix(path, "@") {
-		// 以 @ 开头，说明是 modify 操作引用其他 pinId
-		return true, true
-	}
-
-	// 检查是否包含 @ 符号（可能是 {host:@pinId} 格式）
-	if strings.Contains(path, "@") {
-		// 提取 @ 后面的部分，检查是否符合 pinId 格式
-		// 格式: 64 个十六进制字符 + 'i' + 数字
-		parts := strings.Split(path, "@")
-		if len(parts) > 1 {
-			// 获取 @ 后面的部分
-			afterAt := parts[len(parts)-1]
-			// 移除可能的 } 后缀（如果是 {host:@pinId} 格式）
-			afterAt = strings.TrimSuffix(afterAt, "}")
-			// 检查是否符合 pinId 格式
-			matched, err := regexp.MatchString(`^[0-9a-f]{64}i\d+$`, afterAt)
-			if err == nil && matched {
-				return true, true
+	return nil
+}
+
+// matchPinProtocol get the protocol a PIN belongs to, empty when it is not indexed
+// create PINs are matched by their path (indexer.protocols); modify PINs carry a reference to the modified PIN
+// (@{pinId}) instead, they belong to the MetaApp protocol only when the referenced PIN is an indexed MetaApp
+func (s *IndexerService) matchPinProtocol(metaData *indexer.MetaIDData) string {
+	if metaData.Operation == "modify" {
+		targetPinID := modifyTargetPinID(metaData.Path)
+		if targetPinID == "" {
+			return ""
+		}
+		if _, err := s.metaAppDAO.GetByPinID(targetPinID); err != nil {
+			// 被引用的 PIN 可能还未索引（create 在之后的区块或仍在处理中），内容是 MetaApp 时交给 MetaApp 处理器暂存
+			if conf.Cfg().Indexer.PendingModifyExpireHours > 0 && looksLikeMetaAppModify(metaData) {
+				return metaid_protocols.MetaAppProtocolPath
 			}
+			return ""
 		}
+		return metaid_protocols.MetaAppProtocolPath
 	}
+	return metaid_protocols.MatchProtocol(metaData.Path)
+}
+
+// pinIDPattern 64 个十六进制字符 + 'i' + 输出序号
+var pinIDPattern = regexp.MustCompile(`^[0-9a-f]{64}i\d+$`)
 
-	return false, false
+// modifyTargetPinID 获取 modify 操作的 path 引用的 PinID，path 不是引用格式时返回空字符串
+// modify 操作的 path 可能是：
+//   - @{pinId} - 直接引用其他 pinId
+//   - {host:@pinId} - 带 host 的格式
+func modifyTargetPinID(path string) string {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	if i := strings.Index(path, ":@"); i >= 0 {
+		path = path[i+1:]
+	}
+	if !strings.HasPrefix(path, "@") {
+		return ""
+	}
+	pinID := strings.TrimPrefix(path, "@")
+	if !pinIDPattern.MatchString(pinID) {
+		return ""
+	}
+	return pinID
 }
 
 // calculateMetaID calculate MetaID from address (SHA256 hash)
@@ -319,14 +589,8 @@ func (s *IndexerService) extractFirstPinIDFromOriginalPath(path string) (string,
 		return "", fmt.Errorf("path is empty")
 	}
 
-	// 移除 @ 前缀
-	var currentPinID string
-	if strings.HasPrefix(path, "@") {
-		currentPinID = strings.TrimPrefix(path, "@")
-	} else {
-		currentPinID = path
-	}
-
+	// 解析 @{pin_id} / {host:@pin_id}
+	currentPinID := modifyTargetPinID(path)
 	if currentPinID == "" {
 		return "", fmt.Errorf("invalid path format: %s", path)
 	}
@@ -348,9 +612,8 @@ func (s *IndexerService) findFirstPinIDRecursive(pinID string, visited map[strin
 	metaApp, err := s.metaAppDAO.GetByPinID(pinID)
 	if err != nil {
 		// 如果找不到，说明这个 pinID 就是 first_pin_id（可能是 create 操作还未索引）
-		log.Printf("MetaApp not found for pinID %s, assuming it's first_pin_id", pinID)
-		// return pinID, nil
-		return "", fmt.Errorf("MetaApp not found for pinID %s", pinID)
+		log.Printf("MetaApp not found for pinID %s, it may not be indexed yet", pinID)
+		return "", &pinNotIndexedError{pinID: pinID}
 	}
 
 	// 如果是 create 操作，这个 pinID 就是 first_pin_id
@@ -404,27 +667,32 @@ func ensureMillisecondTimestamp(timestamp int64) int64 {
 // processMetaAppContent 处理并保存 MetaApp 协议内容
 func (s *IndexerService) processMetaAppContent(metaData *indexer.MetaIDData, height, timestamp int64) error {
 	// 获取真实的创建者地址
-	creatorAddress := metaData.CreatorAddress
-	if metaData.CreatorInputLocation != "" {
-		realAddress, err := s.parser.FindCreatorAddressFromCreatorInputLocation(metaData.CreatorInputLocation, s.chainType)
-		if err != nil {
-			log.Printf("Failed to get creator address from location %s: %v, using fallback address",
-				metaData.CreatorInputLocation, err)
-		} else {
-			creatorAddress = realAddress
-			log.Printf("Found real creator address for MetaApp: %s (from location: %s)", realAddress, metaData.CreatorInputLocation)
-		}
+	creatorAddress := s.creatorAddress(metaData)
+
+	// 解密 PIN 内容（未加密的内容原样返回）
+	content, decrypted, err := decryptPinContent(metaData)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt MetaApp content: %w", err)
+	}
+
+	// 描述 JSON 存放在 metafile:// 引用的 PIN 中时，从 metafs 获取
+	content, err = resolveMetaAppDescriptor(metaData.PinID, content)
+	if err != nil {
+		return err
 	}
 
 	// 解析 MetaApp JSON 内容
 	var metaAppProto metaid_protocols.MetaApp
-	if err := json.Unmarshal(metaData.Content, &metaAppProto); err != nil {
+	if err := json.Unmarshal(content, &metaAppProto); err != nil {
 		return fmt.Errorf("failed to parse MetaApp JSON: %w", err)
 	}
 
 	log.Printf("Parsed MetaApp: title=%s, appName=%s, version=%s, contentType=%s",
 		metaAppProto.Title, metaAppProto.AppName, metaAppProto.Version, metaAppProto.ContentType)
 
+	// 校验 MetaApp 内容，校验失败的应用仍然索引，但记录原因且不加入部署队列
+	state, stateReason := validateMetaAppContent(metaData.PinID, &metaAppProto)
+
 	// 序列化 Metadata 为 JSON 字符串（如果已经是字符串则直接使用）
 	metadataJSON := metaAppProto.Metadata
 	if metadataJSON == "" {
@@ -465,6 +733,7 @@ func (s *IndexerService) processMetaAppContent(metaData *indexer.MetaIDData, hei
 		ContentHash:    metaAppProto.ContentHash,
 		Metadata:       metadataJSON,
 		Disabled:       metaAppProto.Disabled,
+		Decrypted:      decrypted,
 		ChainName:      metaData.ChainName,
 		BlockHeight:    height,
 		Timestamp:      millisecondTimestamp,
@@ -473,7 +742,8 @@ func (s *IndexerService) processMetaAppContent(metaData *indexer.MetaIDData, hei
 		OwnerAddress:   metaData.OwnerAddress,
 		OwnerMetaId:    calculateMetaID(metaData.OwnerAddress),
 		Status:         1, // 1 表示成功
-		State:          0,
+		State:          state,
+		StateReason:    stateReason,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -485,9 +755,12 @@ func (s *IndexerService) processMetaAppContent(metaData *indexer.MetaIDData, hei
 
 	log.Printf("MetaApp indexed successfully: PIN=%s, Title=%s, AppName=%s, Version=%s, Chain=%s",
 		metaData.PinID, metaAppProto.Title, metaAppProto.AppName, metaAppProto.Version, metaData.ChainName)
+	s.saveRawContent(metaData)
 
-	// 添加到部署队列
-	if err := s.addToDeployQueue(metaApp); err != nil {
+	// 添加到部署队列（内容校验失败的应用不部署）
+	if metaApp.State == model.MetaAppStateInvalid {
+		log.Printf("Skipping deploy for invalid MetaApp %s: %s", metaApp.PinID, metaApp.StateReason)
+	} else if err := s.addToDeployQueue(metaApp); err != nil {
 		log.Printf("Failed to add MetaApp to deploy queue: %v", err)
 		// 不返回错误，因为索引已经成功
 	}
@@ -495,30 +768,115 @@ func (s *IndexerService) processMetaAppContent(metaData *indexer.MetaIDData, hei
 	return nil
 }
 
+// saveRawContent 保存 PIN 原始内容（链上写入的字节），供核对索引结果使用；失败不影响索引
+func (s *IndexerService) saveRawContent(metaData *indexer.MetaIDData) {
+	content := newMetaAppRawContent(metaData.PinID, metaData.ContentType, metaData.Encryption, metaData.Content)
+	if err := s.metaAppDAO.SaveRawContent(content); err != nil {
+		log.Printf("Failed to save raw content of MetaApp %s: %v", metaData.PinID, err)
+	}
+}
+
+// newMetaAppRawContent 构建 PIN 原始内容记录
+func newMetaAppRawContent(pinID, contentType, encryption string, content []byte) *model.MetaAppRawContent {
+	return &model.MetaAppRawContent{
+		PinID:       pinID,
+		ContentType: contentType,
+		Encryption:  encryption,
+		Content:     content,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// validateMetaAppContent 校验 MetaApp 内容，返回记录的状态码和原因
+// 除协议字段校验外，还校验部署时下载的 pinId（Code，没有 Code 时为 Content），格式错误的应用不进入部署队列，
+// 避免在下载时才失败并耗尽重试次数
+func validateMetaAppContent(pinID string, metaAppProto *metaid_protocols.MetaApp) (int, string) {
+	if err := metaid_protocols.ValidateMetaApp(metaAppProto); err != nil {
+		log.Printf("MetaApp content validation failed for PIN %s: %v", pinID, err)
+		return model.MetaAppStateInvalid, err.Error()
+	}
+	if err := validateDeployPinID(metaAppProto.Code, metaAppProto.Content); err != nil {
+		log.Printf("MetaApp deploy pinId validation failed for PIN %s: %v", pinID, err)
+		return model.MetaAppStateInvalid, err.Error()
+	}
+	return model.MetaAppStateNormal, ""
+}
+
+// decryptPinContent 根据 Encryption 字段解密 PIN 内容
+// Encryption 为 "" 或 "0" 时表示未加密，内容原样返回
+// 支持 AES-256-GCM（"aes" / "aes256" / "aes-256" / "aes-256-gcm"），内容格式为 nonce + 密文，可以是原始字节或 base64 编码
+// 密钥为 sha256(meta_app.decryption_secret)，未配置时使用 sha256(owner address)
+// 注意：owner address 在链上公开，任何人都能推导出该密钥，这种方式只是混淆，不提供任何机密性
+func decryptPinContent(metaData *indexer.MetaIDData) ([]byte, bool, error) {
+	encryption := strings.ToLower(strings.TrimSpace(metaData.Encryption))
+	switch encryption {
+	case "", "0":
+		return metaData.Content, false, nil
+	case "aes", "aes256", "aes-256", "aes-256-gcm":
+	default:
+		return nil, false, fmt.Errorf("unsupported encryption: %s", metaData.Encryption)
+	}
+
+	secret := conf.Cfg().MetaApp.DecryptionSecret
+	if secret == "" {
+		secret = metaData.OwnerAddress
+	}
+	if secret == "" {
+		return nil, false, fmt.Errorf("no decryption secret configured and owner address is empty")
+	}
+	key := tool.DeriveAESKey(secret)
+
+	plaintext, err := tool.AESGCMDecrypt(key, metaData.Content)
+	if err != nil {
+		// 尝试 base64 编码的密文
+		ciphertext, decodeErr := base64.StdEncoding.DecodeString(strings.TrimSpace(string(metaData.Content)))
+		if decodeErr != nil {
+			return nil, false, err
+		}
+		plaintext, err = tool.AESGCMDecrypt(key, ciphertext)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return plaintext, true, nil
+}
+
 // processMetaAppModify 处理 MetaApp modify 操作
 func (s *IndexerService) processMetaAppModify(metaData *indexer.MetaIDData, firstPinID string, height, timestamp int64) error {
 	// 获取真实的创建者地址
-	creatorAddress := metaData.CreatorAddress
-	if metaData.CreatorInputLocation != "" {
-		realAddress, err := s.parser.FindCreatorAddressFromCreatorInputLocation(metaData.CreatorInputLocation, s.chainType)
-		if err != nil {
-			log.Printf("Failed to get creator address from location %s: %v, using fallback address",
-				metaData.CreatorInputLocation, err)
-		} else {
-			creatorAddress = realAddress
-			log.Printf("Found real creator address for MetaApp modify: %s (from location: %s)", realAddress, metaData.CreatorInputLocation)
-		}
+	creatorAddress := s.creatorAddress(metaData)
+
+	// 解密 PIN 内容（未加密的内容原样返回）
+	content, decrypted, err := decryptPinContent(metaData)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt MetaApp content: %w", err)
+	}
+
+	// 描述 JSON 存放在 metafile:// 引用的 PIN 中时，从 metafs 获取
+	content, err = resolveMetaAppDescriptor(metaData.PinID, content)
+	if err != nil {
+		return err
 	}
 
 	// 解析 MetaApp JSON 内容
 	var metaAppProto metaid_protocols.MetaApp
-	if err := json.Unmarshal(metaData.Content, &metaAppProto); err != nil {
+	if err := json.Unmarshal(content, &metaAppProto); err != nil {
 		return fmt.Errorf("failed to parse MetaApp JSON: %w", err)
 	}
 
 	log.Printf("Parsed MetaApp modify: title=%s, appName=%s, version=%s, contentType=%s, firstPinID=%s",
 		metaAppProto.Title, metaAppProto.AppName, metaAppProto.Version, metaAppProto.ContentType, firstPinID)
 
+	// 确保时间戳是 13 位（毫秒级）
+	millisecondTimestamp := ensureMillisecondTimestamp(timestamp)
+
+	// 校验 MetaApp 内容，校验失败的应用仍然索引，但记录原因且不加入部署队列
+	state, stateReason := validateMetaAppContent(metaData.PinID, &metaAppProto)
+	if state == model.MetaAppStateNormal {
+		state, stateReason = s.checkModifyVersion(metaData.PinID, firstPinID, metaAppProto.Version, millisecondTimestamp)
+	}
+
 	// 序列化 Metadata 为 JSON 字符串（如果已经是字符串则直接使用）
 	metadataJSON := metaAppProto.Metadata
 	if metadataJSON == "" {
@@ -528,9 +886,6 @@ func (s *IndexerService) processMetaAppModify(metaData *indexer.MetaIDData, firs
 	// 计算创建者 MetaID
 	creatorMetaID := calculateMetaID(creatorAddress)
 
-	// 确保时间戳是 13 位（毫秒级）
-	millisecondTimestamp := ensureMillisecondTimestamp(timestamp)
-
 	// 创建数据库记录（modify 操作）
 	metaApp := &model.MetaApp{
 		FirstPinId:     firstPinID,
@@ -556,6 +911,7 @@ func (s *IndexerService) processMetaAppModify(metaData *indexer.MetaIDData, firs
 		ContentHash:    metaAppProto.ContentHash,
 		Metadata:       metadataJSON,
 		Disabled:       metaAppProto.Disabled,
+		Decrypted:      decrypted,
 		ChainName:      metaData.ChainName,
 		BlockHeight:    height,
 		Timestamp:      millisecondTimestamp,
@@ -564,7 +920,8 @@ func (s *IndexerService) processMetaAppModify(metaData *indexer.MetaIDData, firs
 		OwnerAddress:   metaData.OwnerAddress,
 		OwnerMetaId:    calculateMetaID(metaData.OwnerAddress),
 		Status:         1, // 1 表示成功
-		State:          0,
+		State:          state,
+		StateReason:    stateReason,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -576,9 +933,12 @@ func (s *IndexerService) processMetaAppModify(metaData *indexer.MetaIDData, firs
 
 	log.Printf("MetaApp modify indexed successfully: PIN=%s, FirstPIN=%s, Title=%s, AppName=%s, Version=%s, Chain=%s",
 		metaData.PinID, firstPinID, metaAppProto.Title, metaAppProto.AppName, metaAppProto.Version, metaData.ChainName)
+	s.saveRawContent(metaData)
 
-	// 添加到部署队列
-	if err := s.addToDeployQueue(metaApp); err != nil {
+	// 添加到部署队列（内容校验失败、被版本策略拒绝的版本不部署）
+	if metaApp.State != model.MetaAppStateNormal {
+		log.Printf("Skipping deploy for MetaApp modify %s (state %d): %s", metaApp.PinID, metaApp.State, metaApp.StateReason)
+	} else if err := s.addToDeployQueue(metaApp); err != nil {
 		log.Printf("Failed to add MetaApp modify to deploy queue: %v", err)
 		// 不返回错误，因为索引已经成功
 	}
@@ -592,82 +952,261 @@ func (s *IndexerService) addToDeployQueue(metaApp *model.MetaApp) error {
 		return fmt.Errorf("database not initialized")
 	}
 
-	// 提取 Code pinId（保持 metafile:// 格式）
-	codePinID := metaApp.Code
-	if codePinID == "" {
-		// 如果没有 Code，尝试使用 Content（需要添加 metafile:// 前缀）
-		if metaApp.Content != "" {
-			if strings.HasPrefix(metaApp.Content, "metafile://") {
-				codePinID = metaApp.Content
-			} else {
-				codePinID = "metafile://" + metaApp.Content
-			}
+	// 仅索引模式下不部署
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		return nil
+	}
+
+	// 新版本被创建者禁用或 revoke 时，回退部署最近的可用版本（已部署完成则不需要重新部署）
+	if !metaApp.IsServable() {
+		servable, err := GetLatestServableMetaApp(metaApp.FirstPinId)
+		if err != nil {
+			log.Printf("MetaApp %s is not servable and has no servable version (%v), skipping deploy", metaApp.PinID, err)
+			return nil
+		}
+		if deployInfo, err := database.DB.GetDeployFileContent(servable.PinID); err == nil && deployInfo.DeployStatus == "completed" {
+			log.Printf("MetaApp %s is not servable, keeping deployed version %s", metaApp.PinID, servable.PinID)
+			return nil
 		}
+		log.Printf("MetaApp %s is not servable, deploying latest servable version %s", metaApp.PinID, servable.PinID)
+		metaApp = servable
 	}
 
-	if codePinID == "" {
+	// 幂等：重新扫描区块会再次处理同一交易，PinID 已部署完成或已在队列中（AddToDeployQueue 返回 ErrAlreadyQueued）时不重复入队
+	if deployInfo, err := database.DB.GetDeployFileContent(metaApp.PinID); err == nil && deployInfo.DeployStatus == "completed" {
+		log.Printf("MetaApp %s is already deployed, skipping duplicate enqueue", metaApp.PinID)
+		return nil
+	}
+
+	// 非 Web 应用不部署，记录跳过原因
+	if reason := webBundleSkipReason(metaApp); reason != "" {
+		log.Printf("MetaApp %s is not a web bundle (%s), skipping deploy", metaApp.PinID, reason)
+		recordDeploySkipped(metaApp, reason)
+		return nil
+	}
+
+	// 提取 Code pinId（保持 metafile:// 格式，没有 Code 时使用 Content）
+	queue, err := newDeployQueueItem(metaApp)
+	if err != nil {
 		log.Printf("No code or content pinId found for MetaApp %s, skipping deploy", metaApp.PinID)
 		return nil
 	}
 
-	queue := &model.MetaAppDeployQueue{
-		FirstPinId:  metaApp.FirstPinId,
-		PinID:       metaApp.PinID,
-		Timestamp:   metaApp.Timestamp,
-		Content:     metaApp.Content,
-		Code:        codePinID,
-		ContentType: metaApp.ContentType,
-		Version:     metaApp.Version,
-		TryCount:    0,
-		CreatedAt:   time.Now(),
+	if err := database.DB.AddToDeployQueue(queue); err != nil {
+		if errors.Is(err, database.ErrAlreadyQueued) {
+			log.Printf("MetaApp %s is already in deploy queue, skipping duplicate enqueue", metaApp.PinID)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// webBundleSkipReason 判断 MetaApp 是否是在浏览器中运行的 Web 应用，不是时返回跳过部署的原因
+// runtime 为空视为 browser；Code 是应用的 Web 包，没有 Code 时部署 Content，
+// 此时 contentType 为协议路径（如 /protocols/metatree）说明 Content 是协议数据而不是 Web 包
+func webBundleSkipReason(app *model.MetaApp) string {
+	if app.Runtime != "" && !strings.EqualFold(app.Runtime, "browser") {
+		return fmt.Sprintf("runtime %s is not browser", app.Runtime)
+	}
+	if app.Code == "" && strings.HasPrefix(app.ContentType, "/protocols/") {
+		return fmt.Sprintf("content type %s is not a web bundle", app.ContentType)
 	}
+	return ""
+}
 
-	return database.DB.AddToDeployQueue(queue)
+// recordDeploySkipped 记录跳过部署的状态和原因
+func recordDeploySkipped(app *model.MetaApp, reason string) {
+	deployContent := &model.MetaAppDeployFileContent{
+		FirstPinId:    app.FirstPinId,
+		PinID:         app.PinID,
+		Content:       app.Content,
+		Code:          app.Code,
+		ContentType:   app.ContentType,
+		Version:       app.Version,
+		DeployStatus:  DeployStatusSkipped,
+		DeployMessage: reason,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if err := database.DB.CreateOrUpdateDeployFileContent(deployContent); err != nil {
+		log.Printf("Failed to record skipped deploy for %s: %v", app.PinID, err)
+	}
 }
 
-// StartDeployProcessor 启动部署处理器（后台 goroutine）
+// StartDeployProcessor 启动部署处理器（后台 goroutine，worker 数量由 meta_app.deploy_workers 配置）
 func (s *IndexerService) StartDeployProcessor() {
-	go s.deployProcessor()
-	log.Println("MetaApp deploy processor started")
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		log.Println("MetaApp deploy disabled (meta_app.deploy_enabled=false), running in index-only mode")
+		return
+	}
+
+	// 清理上次异常退出遗留的部署临时目录
+	deployBaseDir := conf.Cfg().MetaApp.DeployFilePath
+	if deployBaseDir == "" {
+		deployBaseDir = "./meta_app_deploy_data"
+	}
+	if err := os.RemoveAll(filepath.Join(deployBaseDir, deployStagingDirName)); err != nil {
+		log.Printf("Failed to clean up deploy staging directory: %v", err)
+	}
+	cleanupDeployReleases(deployBaseDir)
+
+	// 启动时检查部署目录配额
+	go s.enforceDeployQuota("")
+
+	s.setDeployWorkers(conf.Cfg().MetaApp.DeployWorkers)
+	log.Printf("MetaApp deploy processor started with %d worker(s)", len(s.deployWorkerStops))
+
+	// 重新加载配置时调整 worker 数量
+	conf.OnReload(func(old, cur *conf.Config) {
+		if cur.MetaApp.DeployWorkers != old.MetaApp.DeployWorkers {
+			s.setDeployWorkers(cur.MetaApp.DeployWorkers)
+			log.Printf("MetaApp deploy workers changed to %d", cur.MetaApp.DeployWorkers)
+		}
+	})
+}
+
+// setDeployWorkers 调整部署 worker 数量：不足时启动新 worker，多余的 worker 处理完当前项后退出
+func (s *IndexerService) setDeployWorkers(workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+	s.deployWorkersMu.Lock()
+	defer s.deployWorkersMu.Unlock()
+
+	for len(s.deployWorkerStops) < workers {
+		stop := make(chan struct{})
+		go s.deployProcessor(len(s.deployWorkerStops), stop)
+		s.deployWorkerStops = append(s.deployWorkerStops, stop)
+	}
+	for len(s.deployWorkerStops) > workers {
+		last := len(s.deployWorkerStops) - 1
+		close(s.deployWorkerStops[last])
+		s.deployWorkerStops = s.deployWorkerStops[:last]
+	}
 }
 
-// deployProcessor 部署处理器（持续处理部署队列）
-func (s *IndexerService) deployProcessor() {
+// deployProcessor 部署处理器（持续处理部署队列，stop 关闭时退出）
+func (s *IndexerService) deployProcessor(workerID int, stop <-chan struct{}) {
 	ticker := time.NewTicker(5 * time.Second) // 每 5 秒检查一次
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if err := s.processNextDeployItem(); err != nil {
-			log.Printf("Failed to process deploy item: %v", err)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// 队列非空时连续处理，直到队列中没有可领取的项
+		for {
+			processed, err := s.processNextDeployItem()
+			if err != nil {
+				// 出错时等待下一个周期再重试，避免失败项被立即重复领取
+				log.Printf("[worker %d] Failed to process deploy item: %v", workerID, err)
+				break
+			}
+			if !processed {
+				break
+			}
+			select {
+			case <-stop:
+				return
+			default:
+			}
 		}
 	}
 }
 
-// processNextDeployItem 处理下一个部署队列项
-func (s *IndexerService) processNextDeployItem() error {
+var (
+	deployLocksMu sync.Mutex                     // 保护 deployLocks
+	deployLocks   = make(map[string]*sync.Mutex) // 按 first_pin_id 加锁，避免同一应用的多个版本并发部署
+)
+
+// lockDeploy 获取指定 first_pin_id 的部署锁，返回解锁函数
+// 锁在包级别共享：部署 worker 只运行在第一个索引服务上，其他链的索引服务（如修复接口）也必须使用同一把锁
+func lockDeploy(firstPinID string) func() {
+	deployLocksMu.Lock()
+	lock, ok := deployLocks[firstPinID]
+	if !ok {
+		lock = &sync.Mutex{}
+		deployLocks[firstPinID] = lock
+	}
+	deployLocksMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// processNextDeployItem 领取并处理下一个部署队列项，返回是否领取到了队列项
+func (s *IndexerService) processNextDeployItem() (bool, error) {
 	if database.DB == nil {
-		return fmt.Errorf("database not initialized")
+		return false, fmt.Errorf("database not initialized")
 	}
 
-	// 获取下一个待处理的队列项
-	queueItem, err := database.DB.GetNextDeployQueueItem()
+	// 领取下一个待处理的队列项（带租约，避免被其他 worker 重复领取）
+	queueItem, err := database.DB.GetNextDeployQueueItem(deployLeaseDuration)
 	if err != nil {
 		if err == database.ErrNotFound {
 			// 队列为空，正常情况
-			return nil
+			return false, nil
 		}
-		return err
+		return false, err
+	}
+
+	// 同一个应用（first_pin_id）的部署串行执行，因为它们共用同一个部署目录
+	lockKey := queueItem.FirstPinId
+	if lockKey == "" {
+		lockKey = queueItem.PinID
+	}
+	unlock := lockDeploy(lockKey)
+	defer unlock()
+
+	// 被禁用的应用不再部署，直接从队列中移除
+	if IsMetaAppDisabled(lockKey) {
+		log.Printf("MetaApp %s is disabled, removing from deploy queue", lockKey)
+		if err := database.DB.RemoveFromDeployQueue(queueItem.PinID); err != nil {
+			return true, err
+		}
+		return true, nil
+	}
+
+	// 队列项按 PinID 存储，锁只保证串行不保证顺序：旧版本的队列项（如重试退避后或被其他 worker 领取）可能在新版本之后才部署，
+	// 部署前确认仍是最新的可提供服务的版本，否则直接从队列中移除，避免覆盖新版本
+	if latestPinID := supersedingPinID(queueItem); latestPinID != "" {
+		log.Printf("Deploy queue item %s is superseded by %s, removing from deploy queue", queueItem.PinID, latestPinID)
+		metrics.DeployResults.WithLabelValues("skipped").Inc()
+		if err := database.DB.RemoveFromDeployQueue(queueItem.PinID); err != nil {
+			return true, err
+		}
+		notifyDeployWebhook(queueItem, DeployWebhookStatusSkipped, "superseded by "+latestPinID)
+		return true, nil
 	}
 
 	log.Printf("Processing deploy queue item: PinID=%s, Code=%s, TryCount=%d", queueItem.PinID, queueItem.Code, queueItem.TryCount)
 
 	// 处理部署
-	if err := s.deployMetaApp(queueItem); err != nil {
+	err = s.deployMetaApp(context.Background(), queueItem)
+	if errors.Is(err, ErrDeploySkipped) {
+		// 非 Web 应用，重试也不会成功，直接从队列中移除
+		log.Printf("Skipped deploy of MetaApp %s: %v", queueItem.PinID, err)
+		metrics.DeployResults.WithLabelValues("skipped").Inc()
+		if removeErr := database.DB.RemoveFromDeployQueue(queueItem.PinID); removeErr != nil {
+			return true, removeErr
+		}
+		notifyDeployWebhook(queueItem, DeployWebhookStatusSkipped, err.Error())
+		return true, nil
+	}
+	if err != nil {
 		log.Printf("Failed to deploy MetaApp %s: %v", queueItem.PinID, err)
+		metrics.DeployResults.WithLabelValues("failure").Inc()
 
 		// 增加重试次数
 		queueItem.TryCount++
-		const maxRetryCount = 3
+		maxRetryCount := conf.Cfg().MetaApp.MaxDeployRetries
+		if maxRetryCount <= 0 {
+			maxRetryCount = 3
+		}
 
 		if queueItem.TryCount >= maxRetryCount {
 			// 超过最大重试次数，从队列中移除
@@ -675,79 +1214,149 @@ func (s *IndexerService) processNextDeployItem() error {
 			if removeErr := database.DB.RemoveFromDeployQueue(queueItem.PinID); removeErr != nil {
 				log.Printf("Failed to remove from deploy queue: %v", removeErr)
 			}
+			notifyDeployWebhook(queueItem, DeployWebhookStatusFailed, err.Error())
 		} else {
-			// 更新重试次数，继续保留在队列中
+			// 更新重试次数、错误信息、计算下次重试时间并释放租约，继续保留在队列中
+			backoff := deployRetryBackoff(queueItem.TryCount)
+			queueItem.NextRetryAt = time.Now().Add(backoff)
+			queueItem.LeaseUntil = time.Time{}
+			queueItem.LastError = err.Error()
+			log.Printf("MetaApp %s will be retried in %s (attempt %d/%d)", queueItem.PinID, backoff, queueItem.TryCount+1, maxRetryCount)
 			if updateErr := database.DB.UpdateDeployQueueItem(queueItem); updateErr != nil {
 				log.Printf("Failed to update deploy queue item: %v", updateErr)
 			}
 		}
 
-		return err
+		return true, err
 	}
 
+	metrics.DeployResults.WithLabelValues("success").Inc()
+
 	// 部署成功，从队列中移除
 	if err := database.DB.RemoveFromDeployQueue(queueItem.PinID); err != nil {
 		log.Printf("Failed to remove from deploy queue: %v", err)
-		return err
+		return true, err
 	}
 
 	log.Printf("MetaApp deployed successfully: PinID=%s", queueItem.PinID)
-	return nil
+	notifyDeployWebhook(queueItem, DeployWebhookStatusCompleted, "")
+
+	// 部署目录超出配额时清理最久未访问的应用（不清理刚部署的应用）
+	go s.enforceDeployQuota(lockKey)
+	return true, nil
+}
+
+// supersedingPinID 返回取代队列项的更新版本 PinID（同一 first_pin_id 下最新的可提供服务的版本不是该队列项时），否则返回空字符串
+// 无法确定最新版本（如查询失败或没有可提供服务的版本）时不视为被取代，按原逻辑部署
+func supersedingPinID(queueItem *model.MetaAppDeployQueue) string {
+	if queueItem.FirstPinId == "" {
+		return ""
+	}
+	latest, err := GetLatestServableMetaApp(queueItem.FirstPinId)
+	if err != nil || latest.PinID == queueItem.PinID {
+		return ""
+	}
+	return latest.PinID
+}
+
+// RepairMetaApp 同步修复 first_pin_id 对应应用的部署文件（磁盘文件损坏或被误删时使用）
+// 不经过部署队列，直接重新下载最新版本、校验 ContentHash 并解压，成功后替换部署目录；失败时原有部署保持不变
+// ctx 取消（如客户端断开）时中止下载，返回更新后的部署记录
+func (s *IndexerService) RepairMetaApp(ctx context.Context, firstPinID string) (*model.MetaAppDeployFileContent, error) {
+	if database.DB == nil {
+		return nil, database.ErrDatabaseNotInitialized
+	}
+	if !conf.Cfg().MetaApp.DeployEnabled {
+		return nil, ErrDeployDisabled
+	}
+
+	// 修复最新的可提供服务的版本（跳过被禁用、revoke 和内容校验失败的版本）
+	latest, err := GetLatestServableMetaApp(firstPinID)
+	if err != nil {
+		return nil, err
+	}
+	if IsMetaAppDisabled(latest.FirstPinId) {
+		return nil, ErrMetaAppDisabled
+	}
+
+	queueItem, err := newDeployQueueItem(latest)
+	if err != nil {
+		return nil, err
+	}
+
+	// 与部署队列共用同一把锁，避免与正在进行的部署同时写部署目录
+	unlock := lockDeploy(latest.FirstPinId)
+	defer unlock()
+
+	log.Printf("Repairing MetaApp %s (PinID=%s, Code=%s)", latest.FirstPinId, queueItem.PinID, queueItem.Code)
+	if err := s.deployMetaApp(ctx, queueItem); err != nil {
+		metrics.DeployResults.WithLabelValues("failure").Inc()
+		return nil, err
+	}
+	metrics.DeployResults.WithLabelValues("success").Inc()
+	log.Printf("MetaApp repaired successfully: PinID=%s", queueItem.PinID)
+
+	return database.DB.GetDeployFileContent(queueItem.PinID)
+}
+
+// recordDeployFailed 将部署记录更新为 failed 并记录错误信息（队列项保留等待重试）
+func recordDeployFailed(metaApp *model.MetaApp, queueItem *model.MetaAppDeployQueue, appDeployDir string, deployErr error) {
+	deployContent := &model.MetaAppDeployFileContent{
+		FirstPinId:     metaApp.FirstPinId,
+		PinID:          metaApp.PinID,
+		Content:        queueItem.Content,
+		Code:           queueItem.Code,
+		ContentType:    queueItem.ContentType,
+		Version:        queueItem.Version,
+		DeployStatus:   "failed",
+		DeployFilePath: appDeployDir,
+		DeployMessage:  deployErr.Error(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	if updateErr := database.DB.CreateOrUpdateDeployFileContent(deployContent); updateErr != nil {
+		log.Printf("Failed to update deploy file content with error status: %v", updateErr)
+	}
 }
 
 // deployMetaApp 部署 MetaApp（下载文件、解压、更新状态）
-func (s *IndexerService) deployMetaApp(queueItem *model.MetaAppDeployQueue) error {
+func (s *IndexerService) deployMetaApp(ctx context.Context, queueItem *model.MetaAppDeployQueue) (err error) {
 	// 1. 获取 MetaApp 信息
 	metaApp, err := s.metaAppDAO.GetByPinID(queueItem.PinID)
 	if err != nil {
 		return fmt.Errorf("failed to get MetaApp: %w", err)
 	}
 
-	// 2. 创建部署目录（如果已存在且有文件，先清空）
-	deployBaseDir := conf.Cfg.MetaApp.DeployFilePath
+	// 非 Web 应用不下载、不解压到静态文件目录
+	if reason := webBundleSkipReason(metaApp); reason != "" {
+		recordDeploySkipped(metaApp, reason)
+		return fmt.Errorf("%w: %s", ErrDeploySkipped, reason)
+	}
+
+	// 2. 创建临时部署目录：先在临时目录中下载、校验、解压，成功后再原子替换正式目录，
+	// 避免重新部署期间线上用户访问到 404 或写了一半的文件；失败时原有部署保持不变
+	deployBaseDir := conf.Cfg().MetaApp.DeployFilePath
 	if deployBaseDir == "" {
 		deployBaseDir = "./meta_app_deploy_data"
 	}
 	appDeployDir := filepath.Join(deployBaseDir, metaApp.FirstPinId)
 
-	// 检查目录是否存在
-	if info, err := os.Stat(appDeployDir); err == nil && info.IsDir() {
-		// 目录存在，检查是否有文件
-		entries, err := os.ReadDir(appDeployDir)
-		if err != nil {
-			return fmt.Errorf("failed to read deploy directory: %w", err)
-		}
-
-		// 如果有文件，先清空目录
-		if len(entries) > 0 {
-			log.Printf("Deploy directory %s already exists with files, clearing it...", appDeployDir)
-			for _, entry := range entries {
-				entryPath := filepath.Join(appDeployDir, entry.Name())
-				if err := os.RemoveAll(entryPath); err != nil {
-					return fmt.Errorf("failed to remove existing file/directory %s: %w", entryPath, err)
-				}
-			}
-			log.Printf("Cleared deploy directory: %s", appDeployDir)
-		}
-	}
-
-	// 创建目录（如果不存在）
-	if err := os.MkdirAll(appDeployDir, 0755); err != nil {
-		return fmt.Errorf("failed to create deploy directory: %w", err)
+	// 临时目录放在部署目录下，保证与正式目录在同一文件系统，os.Rename 才是原子的
+	stagingDir := filepath.Join(deployBaseDir, deployStagingDirName, fmt.Sprintf("%s-%d", metaApp.FirstPinId, time.Now().UnixNano()))
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
 	}
+	// 未成功替换时清理临时目录（替换成功后临时目录已不存在）；开启 meta_app.keep_failed_deploys 时先移到隔离目录保留
+	defer func() {
+		if err != nil && ctx.Err() == nil && conf.Cfg().MetaApp.KeepFailedDeploys {
+			quarantineFailedDeploy(deployBaseDir, stagingDir, queueItem, err)
+		}
+		os.RemoveAll(stagingDir)
+	}()
 
 	// 3. 下载 Code 文件（优先使用 Code，如果没有则使用 Content）
-	pinIDToDownload := queueItem.Code
-	if pinIDToDownload == "" {
-		// 如果没有 Code，使用 Content，并确保有 metafile:// 前缀
-		if queueItem.Content != "" {
-			if strings.HasPrefix(queueItem.Content, "metafile://") {
-				pinIDToDownload = queueItem.Content
-			} else {
-				pinIDToDownload = "metafile://" + queueItem.Content
-			}
-		}
-	}
+	pinIDToDownload := deployPinID(queueItem.Code, queueItem.Content)
 
 	if pinIDToDownload == "" {
 		return fmt.Errorf("no pinId to download")
@@ -759,43 +1368,48 @@ func (s *IndexerService) deployMetaApp(queueItem *model.MetaAppDeployQueue) erro
 	}
 
 	// 4. 下载文件
-	filePath, err := s.downloadFileFromPinID(pinIDToDownload, appDeployDir)
+	filePath, gateway, err := s.downloadFileFromPinID(ctx, pinIDToDownload, stagingDir)
 	if err != nil {
+		// 调用方取消时不记录为部署失败，原有部署保持不变
+		if ctx.Err() != nil {
+			return fmt.Errorf("deploy canceled: %w", ctx.Err())
+		}
 		log.Printf("Failed to download file from pinId: %s, error: %v", pinIDToDownload, err)
 		// 下载失败，更新状态为 failed 并记录错误信息
-		deployContent := &model.MetaAppDeployFileContent{
-			FirstPinId:     metaApp.FirstPinId,
-			PinID:          metaApp.PinID,
-			Content:        queueItem.Content,
-			Code:           queueItem.Code,
-			ContentType:    queueItem.ContentType,
-			Version:        queueItem.Version,
-			DeployStatus:   "failed",
-			DeployFilePath: appDeployDir,
-			DeployMessage:  err.Error(),
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-		}
+		recordDeployFailed(metaApp, queueItem, appDeployDir, err)
+		return fmt.Errorf("failed to download file: %w", err)
+	}
 
-		if updateErr := database.DB.CreateOrUpdateDeployFileContent(deployContent); updateErr != nil {
-			log.Printf("Failed to update deploy file content with error status: %v", updateErr)
+	// 5. 校验下载文件的 ContentHash（未配置跳过且应用声明了 ContentHash 时）
+	if !conf.Cfg().MetaApp.SkipContentHashCheck && metaApp.ContentHash != "" {
+		if err := verifyContentHash(filePath, metaApp.ContentHash); err != nil {
+			log.Printf("Content hash verification failed for MetaApp %s: %v", metaApp.PinID, err)
+			// 校验失败，更新状态为 failed，保留队列项等待重试（下载的文件随临时目录一起清理或隔离）
+			recordDeployFailed(metaApp, queueItem, appDeployDir, err)
+			return fmt.Errorf("content hash verification failed: %w", err)
 		}
-
-		return fmt.Errorf("failed to download file: %w", err)
+		log.Printf("Content hash verified for MetaApp %s: %s", metaApp.PinID, metaApp.ContentHash)
 	}
 
-	// 5. 如果是 zip 文件，解压
-	if strings.HasSuffix(strings.ToLower(filePath), ".zip") {
-		if err := s.unzipFile(filePath, appDeployDir); err != nil {
-			log.Printf("Failed to unzip file %s: %v, continuing with original file", filePath, err)
-			// 不解压失败不影响部署，继续使用原文件
-		} else {
-			// 解压成功，删除原 zip 文件
-			os.Remove(filePath)
+	// 6. 如果是压缩包（zip / tar.gz），解压
+	if archiveType := tool.DetectArchiveType(filePath); archiveType != tool.ArchiveTypeNone {
+		if err := s.extractArchive(filePath, stagingDir, archiveType); err != nil {
+			log.Printf("Failed to extract %s file %s: %v", archiveType, filePath, err)
+			// 解压失败（包括超出解压大小或文件数限制）时部署失败，保留队列项等待重试，原有部署保持不变
+			recordDeployFailed(metaApp, queueItem, appDeployDir, err)
+			return fmt.Errorf("failed to extract %s file: %w", archiveType, err)
 		}
+		// 解压成功，删除原压缩包
+		os.Remove(filePath)
+	}
+
+	// 7. 用临时目录替换正式部署目录
+	if err := swapDeployDir(stagingDir, appDeployDir); err != nil {
+		return fmt.Errorf("failed to swap deploy directory: %w", err)
 	}
+	clearEvictedMarker(metaApp.FirstPinId)
 
-	// 6. 更新部署文件内容记录
+	// 8. 更新部署文件内容记录
 	deployContent := &model.MetaAppDeployFileContent{
 		FirstPinId:     metaApp.FirstPinId,
 		PinID:          metaApp.PinID,
@@ -805,7 +1419,7 @@ func (s *IndexerService) deployMetaApp(queueItem *model.MetaAppDeployQueue) erro
 		Version:        queueItem.Version,
 		DeployStatus:   "completed",
 		DeployFilePath: appDeployDir,
-		DeployMessage:  "",
+		DeployMessage:  "downloaded from " + gateway,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -818,6 +1432,228 @@ func (s *IndexerService) deployMetaApp(queueItem *model.MetaAppDeployQueue) erro
 	return nil
 }
 
+// swapDeployDir 用新部署目录替换正式部署目录
+// 应用目录是指向 .releases 下某个版本目录的符号链接：新版本移到 .releases 后，
+// 先创建指向它的 {app}.new 符号链接，再用 os.Rename 原子替换正式的符号链接，最后删除更早的版本目录。
+// 替换过程中应用目录始终存在，请求看到的要么是旧版本要么是新版本；
+// 上一个版本保留到下一次替换时再删除，替换前已经解析到旧版本的请求（如已 Stat 尚未打开文件）仍能读取完成
+// 旧版本部署的应用目录是普通目录（rename 不能用符号链接覆盖目录），首次替换时先把它移到 .releases，有一次短暂的不存在窗口
+func swapDeployDir(stagingDir, appDeployDir string) error {
+	releasesDir := filepath.Join(filepath.Dir(appDeployDir), deployReleasesDirName)
+	if err := os.MkdirAll(releasesDir, 0755); err != nil {
+		return fmt.Errorf("failed to create releases directory: %w", err)
+	}
+	releaseDir := filepath.Join(releasesDir, filepath.Base(stagingDir))
+	if err := os.Rename(stagingDir, releaseDir); err != nil {
+		return fmt.Errorf("failed to move new deployment into releases: %w", err)
+	}
+
+	// 符号链接使用相对路径，部署目录整体移动后仍然有效
+	newLink := appDeployDir + ".new"
+	os.Remove(newLink)
+	if err := os.Symlink(filepath.Join(deployReleasesDirName, filepath.Base(releaseDir)), newLink); err != nil {
+		os.RemoveAll(releaseDir)
+		return fmt.Errorf("failed to link new deployment: %w", err)
+	}
+
+	oldRelease, movedLegacy := "", false
+	if info, err := os.Lstat(appDeployDir); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			oldRelease = deployLinkTarget(appDeployDir)
+		} else {
+			oldRelease, movedLegacy = filepath.Join(releasesDir, filepath.Base(appDeployDir)+".legacy"), true
+			if err := os.Rename(appDeployDir, oldRelease); err != nil {
+				os.Remove(newLink)
+				os.RemoveAll(releaseDir)
+				return fmt.Errorf("failed to move old deployment aside: %w", err)
+			}
+		}
+	}
+
+	if err := os.Rename(newLink, appDeployDir); err != nil {
+		os.Remove(newLink)
+		os.RemoveAll(releaseDir)
+		if movedLegacy {
+			if restoreErr := os.Rename(oldRelease, appDeployDir); restoreErr != nil {
+				log.Printf("Failed to restore old deployment %s from %s: %v", appDeployDir, oldRelease, restoreErr)
+			}
+		}
+		return fmt.Errorf("failed to move new deployment into place: %w", err)
+	}
+
+	if err := pruneDeployReleases(releasesDir, filepath.Base(appDeployDir), releaseDir, oldRelease); err != nil {
+		log.Printf("Failed to remove old deployments of %s: %v", appDeployDir, err)
+	}
+	return nil
+}
+
+// pruneDeployReleases 删除应用在 .releases 下除 keep 以外的版本目录（{first_pin_id}-{时间戳} 和 {first_pin_id}.legacy）
+func pruneDeployReleases(releasesDir, appName string, keep ...string) error {
+	entries, err := os.ReadDir(releasesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var firstErr error
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, appName+"-") && name != appName+".legacy" {
+			continue
+		}
+		releaseDir := filepath.Join(releasesDir, name)
+		kept := false
+		for _, k := range keep {
+			if filepath.Clean(k) == releaseDir {
+				kept = true
+				break
+			}
+		}
+		if kept {
+			continue
+		}
+		if err := os.RemoveAll(releaseDir); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// deployLinkTarget 应用目录符号链接指向的版本目录（绝对或相对于部署目录的路径），不是符号链接时返回空字符串
+func deployLinkTarget(appDeployDir string) string {
+	target, err := os.Readlink(appDeployDir)
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(appDeployDir), target)
+	}
+	return target
+}
+
+// removeDeployDir 删除应用目录：符号链接和该应用的所有版本目录一起删除，旧版本部署的普通目录直接删除
+func removeDeployDir(appDeployDir string) error {
+	if err := os.RemoveAll(appDeployDir); err != nil {
+		return err
+	}
+	return pruneDeployReleases(filepath.Join(filepath.Dir(appDeployDir), deployReleasesDirName), filepath.Base(appDeployDir))
+}
+
+// cleanupDeployReleases 删除没有应用目录指向的版本目录（替换过程中异常退出遗留）
+func cleanupDeployReleases(deployBaseDir string) {
+	releasesDir := filepath.Join(deployBaseDir, deployReleasesDirName)
+	releases, err := os.ReadDir(releasesDir)
+	if err != nil {
+		return
+	}
+	linked := make(map[string]bool)
+	if entries, err := os.ReadDir(deployBaseDir); err == nil {
+		for _, entry := range entries {
+			if entry.Type()&os.ModeSymlink != 0 {
+				linked[filepath.Clean(deployLinkTarget(filepath.Join(deployBaseDir, entry.Name())))] = true
+			}
+		}
+	}
+	for _, release := range releases {
+		releaseDir := filepath.Join(releasesDir, release.Name())
+		if linked[filepath.Clean(releaseDir)] {
+			continue
+		}
+		if err := os.RemoveAll(releaseDir); err != nil {
+			log.Printf("Failed to remove unused deploy release %s: %v", releaseDir, err)
+		}
+	}
+}
+
+// verifyContentHash 校验文件哈希是否与 ContentHash 一致
+// ContentHash 支持 "算法:哈希值" 格式（如 sha256:abcd...），未指定算法时根据哈希长度推断
+func verifyContentHash(filePath, contentHash string) error {
+	algorithm, expected := parseContentHash(contentHash)
+
+	var h hash.Hash
+	switch algorithm {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported content hash algorithm: %s", algorithm)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file for hashing: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if actual != expected {
+		return fmt.Errorf("content hash mismatch (%s): expected %s, got %s", algorithm, expected, actual)
+	}
+
+	return nil
+}
+
+// parseContentHash 解析 ContentHash，返回算法和小写十六进制哈希值
+func parseContentHash(contentHash string) (string, string) {
+	contentHash = strings.TrimSpace(contentHash)
+	if idx := strings.Index(contentHash, ":"); idx > 0 {
+		algorithm := strings.ToLower(strings.ReplaceAll(contentHash[:idx], "-", ""))
+		return algorithm, strings.ToLower(contentHash[idx+1:])
+	}
+
+	// 未指定算法，根据十六进制长度推断
+	expected := strings.ToLower(contentHash)
+	switch len(expected) {
+	case 32:
+		return "md5", expected
+	case 40:
+		return "sha1", expected
+	case 128:
+		return "sha512", expected
+	default:
+		return "sha256", expected
+	}
+}
+
+// normalizeMetafilePinID 规范化 pinId 引用：去掉首尾空白，没有 metafile:// 前缀时补上，空值返回空字符串
+func normalizeMetafilePinID(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.HasPrefix(value, "metafile://") {
+		return value
+	}
+	return "metafile://" + value
+}
+
+// deployPinID 获取部署时下载的 pinId（优先使用 Code，没有 Code 时使用 Content），规范化为 metafile://<pinid>
+func deployPinID(code, content string) string {
+	if pinID := normalizeMetafilePinID(code); pinID != "" {
+		return pinID
+	}
+	return normalizeMetafilePinID(content)
+}
+
+// validateDeployPinID 校验部署时下载的 pinId 是否存在且符合 metafile://<pinid> 格式
+func validateDeployPinID(code, content string) error {
+	pinID := deployPinID(code, content)
+	if pinID == "" {
+		return fmt.Errorf("no code or content pinId to deploy")
+	}
+	if !isValidMetafilePinID(pinID) {
+		return fmt.Errorf("invalid pinId format: %s, expected format: metafile://<pinid>", pinID)
+	}
+	return nil
+}
+
 // isValidMetafilePinID 验证 pinID 是否符合 metafile:// 格式
 // 格式: metafile://<pinid>，其中 pinid 通常是 64 字符的十六进制字符串 + 'i' + 数字
 func isValidMetafilePinID(pinID string) bool {
@@ -842,21 +1678,18 @@ func isValidMetafilePinID(pinID string) bool {
 	return matched
 }
 
-// downloadFileFromPinID 从 pinId 下载文件
-func (s *IndexerService) downloadFileFromPinID(pinID, targetDir string) (string, error) {
+// downloadFileFromPinID 从 pinId 下载文件，返回文件路径和下载成功的 metafs 网关
+func (s *IndexerService) downloadFileFromPinID(ctx context.Context, pinID, targetDir string) (string, string, error) {
 	// 验证 pinID 格式
 	if !isValidMetafilePinID(pinID) {
-		return "", fmt.Errorf("invalid pinId format: %s, expected format: metafile://<pinid>", pinID)
+		return "", "", fmt.Errorf("invalid pinId format: %s, expected format: metafile://<pinid>", pinID)
 	}
 
 	// 提取实际的 pinid（去掉 metafile:// 前缀）
 	actualPinID := strings.TrimPrefix(pinID, "metafile://")
 
 	// 使用 metafs 服务下载文件
-	if conf.Cfg.Metafs.Domain == "" {
-		return "", fmt.Errorf("metafs domain not configured")
-	}
-	return s.downloadFileFromMetafs(actualPinID, targetDir)
+	return s.downloadFileFromMetafs(ctx, actualPinID, targetDir)
 }
 
 // MetafsResponse Metafs 统一响应结构
@@ -891,23 +1724,53 @@ type MetafsFileInfo struct {
 	OwnerAddress   string `json:"owner_address"`
 }
 
-// downloadFileFromMetafs 从 metafs 服务下载文件
-func (s *IndexerService) downloadFileFromMetafs(pinID, targetDir string) (string, error) {
-	domain := conf.Cfg.Metafs.Domain
-	if domain == "" {
-		return "", fmt.Errorf("metafs domain not configured")
+// downloadFileFromMetafs 从 metafs 服务下载文件，ctx 取消时中止下载
+// 按 metafs.domain、metafs.gateways 的顺序尝试，某个网关失败（超时、非 200、code != 0）时换下一个，
+// 全部失败才返回错误（计为一次部署失败）；返回文件路径和下载成功的网关
+func (s *IndexerService) downloadFileFromMetafs(ctx context.Context, pinID, targetDir string) (string, string, error) {
+	gateways := conf.Cfg().Metafs.GatewayList()
+	if len(gateways) == 0 {
+		return "", "", fmt.Errorf("metafs domain not configured")
+	}
+
+	var lastErr error
+	for i, gateway := range gateways {
+		if i > 0 {
+			log.Printf("Metafs gateway %s failed for %s (%v), trying: %s", gateways[i-1], pinID, lastErr, gateway)
+		}
+		filePath, err := s.downloadFileFromGateway(ctx, gateway, pinID, targetDir)
+		if err == nil {
+			return filePath, gateway, nil
+		}
+		if ctx.Err() != nil {
+			return "", "", err
+		}
+		lastErr = fmt.Errorf("%s: %w", gateway, err)
 	}
 
+	return "", "", lastErr
+}
+
+// downloadFileFromGateway 从一个 metafs 网关下载文件
+func (s *IndexerService) downloadFileFromGateway(ctx context.Context, domain, pinID, targetDir string) (string, error) {
 	// 1. 先获取文件信息，检查文件是否存在
-	fileInfoURL := fmt.Sprintf("%s/api/v1/files/%s", strings.TrimSuffix(domain, "/"), pinID)
+	fileInfoURL := metafsURL(domain, conf.Cfg().Metafs.FileInfoPath, pinID)
 	log.Printf("Fetching file info from metafs: %s", fileInfoURL)
 
-	resp, err := http.Get(fileInfoURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileInfoURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := tool.HTTPClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to get file info from metafs: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get file info from metafs: status %d", resp.StatusCode)
+	}
+
 	var metafsResp MetafsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&metafsResp); err != nil {
 		return "", fmt.Errorf("failed to decode file info response: %w", err)
@@ -942,41 +1805,123 @@ func (s *IndexerService) downloadFileFromMetafs(pinID, targetDir string) (string
 		}
 	}
 
-	// 5. 下载文件内容
-	downloadURL := fmt.Sprintf("%s/api/v1/files/accelerate/content/%s", strings.TrimSuffix(domain, "/"), pinID)
-	log.Printf("Downloading file from metafs: %s", downloadURL)
-
-	downloadResp, err := http.Get(downloadURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to download file from metafs: %w", err)
+	// 5. 下载文件内容：优先走加速地址，失败时回退到普通内容地址
+	filePath := filepath.Join(targetDir, fileName)
+	downloadURLs := []string{metafsURL(domain, conf.Cfg().Metafs.AccelerateContentPath, pinID)}
+	if conf.Cfg().Metafs.ContentPath != "" {
+		downloadURLs = append(downloadURLs, metafsURL(domain, conf.Cfg().Metafs.ContentPath, pinID))
 	}
-	defer downloadResp.Body.Close()
 
-	if downloadResp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("metafs returned status %d for file download", downloadResp.StatusCode)
+	var lastErr error
+	for i, downloadURL := range downloadURLs {
+		if i > 0 {
+			log.Printf("Accelerated download failed for %s (%v), falling back to: %s", pinID, lastErr, downloadURL)
+		} else {
+			log.Printf("Downloading file from metafs: %s", downloadURL)
+		}
+
+		written, err := downloadMetafsContent(ctx, downloadURL, filePath, fileInfo.FileSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", err
+			}
+			lastErr = err
+			continue
+		}
+
+		log.Printf("Downloaded file from metafs: %s (size: %d bytes, expected: %d bytes)", filePath, written, fileInfo.FileSize)
+		return filePath, nil
 	}
 
-	// 6. 保存文件
-	filePath := filepath.Join(targetDir, fileName)
+	return "", lastErr
+}
+
+// metafsURL 拼接 metafs 接口地址：domain + path + pinID
+func metafsURL(domain, path, pinID string) string {
+	return strings.TrimSuffix(domain, "/") + "/" + strings.Trim(path, "/") + "/" + pinID
+}
+
+// downloadMetafsContent 下载 metafs 文件内容到 filePath（覆盖已有文件），返回写入的字节数
+// expectedSize > 0 时，连接中断会使用 HTTP Range 从已下载位置续传，并在结束后校验文件大小；ctx 取消时不再续传
+func downloadMetafsContent(ctx context.Context, downloadURL, filePath string, expectedSize int64) (int64, error) {
 	outFile, err := os.Create(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
+		return 0, fmt.Errorf("failed to create file: %w", err)
 	}
 	defer outFile.Close()
 
-	written, err := io.Copy(outFile, downloadResp.Body)
+	var written int64
+	for attempt := 0; ; attempt++ {
+		var resumable bool
+		written, resumable, err = downloadMetafsRange(ctx, downloadURL, outFile, written)
+		if err == nil {
+			break
+		}
+		// 无法续传（未知大小、服务端错误等）或续传次数用尽
+		if !resumable || expectedSize <= 0 || attempt >= downloadResumeAttempts || ctx.Err() != nil {
+			return written, err
+		}
+		log.Printf("Download of %s interrupted at %d/%d bytes (%v), resuming (attempt %d/%d)",
+			downloadURL, written, expectedSize, err, attempt+1, downloadResumeAttempts)
+	}
+
+	// 校验文件大小
+	if expectedSize > 0 && written != expectedSize {
+		return written, fmt.Errorf("downloaded size mismatch: got %d bytes, expected %d bytes", written, expectedSize)
+	}
+
+	return written, nil
+}
+
+// downloadMetafsRange 从 offset 开始下载内容并追加写入 outFile，返回写入后的文件长度以及出错时能否续传
+// 服务端不支持 Range（返回 200）时从头重新写入
+func downloadMetafsRange(ctx context.Context, downloadURL string, outFile *os.File, offset int64) (int64, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+		return offset, false, err
 	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	downloadResp, err := tool.HTTPClient.Do(req)
+	if err != nil {
+		return offset, true, fmt.Errorf("failed to download file from metafs: %w", err)
+	}
+	defer downloadResp.Body.Close()
 
-	log.Printf("Downloaded file from metafs: %s (size: %d bytes, expected: %d bytes)", filePath, written, fileInfo.FileSize)
+	switch downloadResp.StatusCode {
+	case http.StatusPartialContent:
+		if offset == 0 {
+			return offset, false, fmt.Errorf("metafs returned unexpected partial content")
+		}
+	case http.StatusOK:
+		// 首次下载或服务端不支持 Range，从头写入
+		offset = 0
+		if err := outFile.Truncate(0); err != nil {
+			return 0, false, fmt.Errorf("failed to write file: %w", err)
+		}
+	default:
+		return offset, false, fmt.Errorf("metafs returned status %d for file download", downloadResp.StatusCode)
+	}
+	if _, err := outFile.Seek(offset, io.SeekStart); err != nil {
+		return offset, false, fmt.Errorf("failed to write file: %w", err)
+	}
 
-	return filePath, nil
+	n, err := io.Copy(outFile, downloadResp.Body)
+	if err != nil {
+		return offset + n, true, fmt.Errorf("failed to download file content: %w", err)
+	}
+	return offset + n, false, nil
 }
 
 // getFileExtensionFromContentType 根据内容类型获取文件扩展名
 func getFileExtensionFromContentType(contentType string) string {
 	contentType = strings.ToLower(contentType)
+	// gzip 需要在 zip 之前判断（"gzip" 包含 "zip"）
+	if strings.Contains(contentType, "gzip") || strings.Contains(contentType, "x-compressed-tar") || strings.Contains(contentType, "x-tar") {
+		return ".tar.gz"
+	}
 	if strings.Contains(contentType, "zip") {
 		return ".zip"
 	}
@@ -995,50 +1940,27 @@ func getFileExtensionFromContentType(contentType string) string {
 	return ""
 }
 
-// unzipFile 解压 zip 文件
-func (s *IndexerService) unzipFile(zipPath, targetDir string) error {
-	r, err := zip.OpenReader(zipPath)
-	if err != nil {
-		return err
+// extractArchive 根据压缩包类型选择解压方式
+// 解压总大小和文件数受 meta_app.max_extract_size_mb / max_extract_files 限制，超出时返回错误，
+// 由 deployMetaApp 使部署失败，已解压的部分文件随临时目录一起清理（或隔离）
+func (s *IndexerService) extractArchive(archivePath, targetDir, archiveType string) error {
+	limits := tool.ExtractLimits{
+		MaxTotalSize: int64(conf.Cfg().MetaApp.MaxExtractSizeMB) * 1024 * 1024,
+		MaxFiles:     conf.Cfg().MetaApp.MaxExtractFiles,
 	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		// 安全检查：防止路径遍历攻击
-		fpath := filepath.Join(targetDir, f.Name)
-		if !strings.HasPrefix(fpath, filepath.Clean(targetDir)+string(os.PathSeparator)) {
-			return fmt.Errorf("invalid file path: %s", fpath)
-		}
 
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, 0755)
-			continue
-		}
-
-		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
-			return err
-		}
-
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			outFile.Close()
-			return err
-		}
-
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
-
-		if err != nil {
-			return err
-		}
+	var err error
+	switch archiveType {
+	case tool.ArchiveTypeZip:
+		err = tool.ExtractZip(archivePath, targetDir, limits)
+	case tool.ArchiveTypeTarGz:
+		err = tool.ExtractTarGz(archivePath, targetDir, limits)
+	default:
+		return fmt.Errorf("unsupported archive type: %s", archiveType)
 	}
-
-	log.Printf("Unzipped file: %s to %s", zipPath, targetDir)
+	if err != nil {
+		return err
+	}
+	log.Printf("Extracted %s file: %s to %s", archiveType, archivePath, targetDir)
 	return nil
 }
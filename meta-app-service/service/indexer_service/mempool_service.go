@@ -0,0 +1,208 @@
+package indexer_service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"meta-app-service/indexer"
+	"meta-app-service/node"
+	"meta-app-service/service/common_service/metaid_protocols"
+)
+
+const (
+	// defaultMempoolScanLimit 未指定 limit 时最多解析的内存池交易数
+	defaultMempoolScanLimit = 100
+	// maxMempoolScanLimit 一次请求最多解析的内存池交易数（每笔交易需要一次 getrawtransaction）
+	maxMempoolScanLimit = 200
+	// maxMempoolCachedPins ZMQ 内存池 PIN 缓存的最大条数（满时丢弃最早的）
+	maxMempoolCachedPins = 1000
+	// mempoolPinTTL ZMQ 内存池 PIN 的缓存时长（交易被替换或移出内存池时不会收到通知，超时后丢弃）
+	mempoolPinTTL = 24 * time.Hour
+)
+
+// MempoolMetaApps 节点内存池中的 MetaApp PIN（即将上链的应用预览）
+type MempoolMetaApps struct {
+	ChainName   string               `json:"chain_name"`   // Chain name: btc, mvc
+	Source      string               `json:"source"`       // zmq: PINs pushed by ZMQ, rpc: mempool polled with getrawmempool
+	MempoolSize int                  `json:"mempool_size"` // Number of transactions in the node's mempool (rpc only)
+	Scanned     int                  `json:"scanned"`      // Number of mempool transactions fetched and parsed (at most limit, rpc only)
+	Pins        []*MempoolMetaAppPin `json:"pins"`         // MetaApp PINs found in the scanned transactions
+}
+
+// MempoolMetaAppPin 内存池交易中的 MetaApp PIN
+type MempoolMetaAppPin struct {
+	PinID          string                    `json:"pin_id"`
+	TxID           string                    `json:"tx_id"`
+	Operation      string                    `json:"operation"` // create/modify/revoke
+	Path           string                    `json:"path"`      // MetaID path (@{pinId} for modify and revoke)
+	ContentType    string                    `json:"content_type"`
+	CreatorAddress string                    `json:"creator_address"`
+	Indexed        bool                      `json:"indexed"`       // Already indexed as an unconfirmed record (ZMQ)
+	App            *metaid_protocols.MetaApp `json:"app,omitempty"` // Parsed MetaApp content, absent when encrypted or not valid JSON
+}
+
+// mempoolPinCache ZMQ 推送的内存池交易中的 MetaApp PIN，在区块中出现后移除
+type mempoolPinCache struct {
+	mu   sync.Mutex
+	pins map[string]*cachedMempoolPin // PinID -> PIN
+}
+
+// cachedMempoolPin 缓存的内存池 PIN 及收到时间
+type cachedMempoolPin struct {
+	pin    *MempoolMetaAppPin
+	seenAt time.Time
+}
+
+// trackMempoolPin 记录 ZMQ 推送的内存池 PIN（height 为 0），区块中的 PIN 从缓存中移除
+func (s *IndexerService) trackMempoolPin(metaData *indexer.MetaIDData, height int64) {
+	c := &s.mempoolPins
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if height > 0 {
+		delete(c.pins, metaData.PinID)
+		return
+	}
+	if c.pins == nil {
+		c.pins = make(map[string]*cachedMempoolPin)
+	}
+
+	now := time.Now()
+	for pinID, cached := range c.pins {
+		if now.Sub(cached.seenAt) > mempoolPinTTL {
+			delete(c.pins, pinID)
+		}
+	}
+	if _, ok := c.pins[metaData.PinID]; !ok && len(c.pins) >= maxMempoolCachedPins {
+		var oldestID string
+		var oldest time.Time
+		for pinID, cached := range c.pins {
+			if oldestID == "" || cached.seenAt.Before(oldest) {
+				oldestID, oldest = pinID, cached.seenAt
+			}
+		}
+		delete(c.pins, oldestID)
+	}
+	c.pins[metaData.PinID] = &cachedMempoolPin{pin: newMempoolMetaAppPin(metaData), seenAt: now}
+}
+
+// mempoolSnapshot 缓存的内存池 PIN，按收到时间倒序，最多 limit 条
+func (s *IndexerService) mempoolSnapshot(limit int) *MempoolMetaApps {
+	c := &s.mempoolPins
+	c.mu.Lock()
+	cached := make([]*cachedMempoolPin, 0, len(c.pins))
+	for _, p := range c.pins {
+		if time.Since(p.seenAt) <= mempoolPinTTL {
+			cached = append(cached, p)
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Slice(cached, func(i, j int) bool { return cached[i].seenAt.After(cached[j].seenAt) })
+	if len(cached) > limit {
+		cached = cached[:limit]
+	}
+	result := &MempoolMetaApps{
+		ChainName: string(s.chainType),
+		Source:    "zmq",
+		Pins:      make([]*MempoolMetaAppPin, 0, len(cached)),
+	}
+	for _, p := range cached {
+		pin := *p.pin
+		pin.Indexed = s.isPinIndexed(pin.PinID)
+		result.Pins = append(result.Pins, &pin)
+	}
+	return result
+}
+
+// GetMempoolMetaApps 获取节点内存池中匹配 MetaApp 协议的 PIN
+// ZMQ 实时监听运行时直接返回 ZMQ 推送的 PIN（不访问节点）；否则依次获取并解析内存池交易
+// （最多 limit 笔，limit <= 0 时使用默认值），获取失败的交易（例如已被打包移出内存池）跳过
+func (s *IndexerService) GetMempoolMetaApps(ctx context.Context, limit int) (*MempoolMetaApps, error) {
+	if limit <= 0 {
+		limit = defaultMempoolScanLimit
+	}
+	if limit > maxMempoolScanLimit {
+		limit = maxMempoolScanLimit
+	}
+	if s.scanner.IsZMQActive() {
+		return s.mempoolSnapshot(limit), nil
+	}
+
+	chainName := string(s.chainType)
+	txIDs, err := node.GetMempool(chainName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mempool: %w", err)
+	}
+
+	result := &MempoolMetaApps{
+		ChainName:   chainName,
+		Source:      "rpc",
+		MempoolSize: len(txIDs),
+		Pins:        make([]*MempoolMetaAppPin, 0),
+	}
+	if len(txIDs) > limit {
+		txIDs = txIDs[:limit]
+	}
+	// 接口请求中只访问节点一次（不重试），整个请求的节点调用受 nodeRequestTimeout 限制
+	ctx, cancel := context.WithTimeout(ctx, nodeRequestTimeout)
+	defer cancel()
+	for _, txID := range txIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		// 通过扫描器获取交易（有 LRU 缓存，重复请求不再访问节点）
+		txHex, err := s.scanner.GetRawTransactionContext(ctx, txID)
+		if err != nil {
+			continue
+		}
+		result.Scanned++
+
+		tx, err := indexer.DecodeRawTransaction(txHex, s.chainType)
+		if err != nil {
+			continue
+		}
+		metaDataTx, err := s.parser.ParseAllPINs(tx, s.chainType)
+		if err != nil || metaDataTx == nil {
+			continue
+		}
+		for _, metaData := range metaDataTx.MetaIDData {
+			if s.matchPinProtocol(metaData) != metaid_protocols.MetaAppProtocolPath {
+				continue
+			}
+			pin := newMempoolMetaAppPin(metaData)
+			pin.Indexed = s.isPinIndexed(pin.PinID)
+			result.Pins = append(result.Pins, pin)
+		}
+	}
+
+	return result, nil
+}
+
+// isPinIndexed PIN 是否已索引（包括 ZMQ 索引的未确认记录）
+func (s *IndexerService) isPinIndexed(pinID string) bool {
+	_, err := s.metaAppDAO.GetByPinID(pinID)
+	return err == nil
+}
+
+// newMempoolMetaAppPin 构建内存池 PIN 预览（内容能解析为 MetaApp JSON 时附带应用信息）
+func newMempoolMetaAppPin(metaData *indexer.MetaIDData) *MempoolMetaAppPin {
+	pin := &MempoolMetaAppPin{
+		PinID:          metaData.PinID,
+		TxID:           metaData.TxID,
+		Operation:      metaData.Operation,
+		Path:           metaData.Path,
+		ContentType:    metaData.ContentType,
+		CreatorAddress: metaData.CreatorAddress,
+	}
+	if metaData.Operation != "revoke" && len(metaData.Content) > 0 {
+		var app metaid_protocols.MetaApp
+		if err := json.Unmarshal(metaData.Content, &app); err == nil {
+			pin.App = &app
+		}
+	}
+	return pin
+}
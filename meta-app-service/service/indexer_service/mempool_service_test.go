@@ -0,0 +1,143 @@
+package indexer_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"meta-app-service/indexer"
+	"meta-app-service/models/dao"
+	"meta-app-service/node"
+
+	"github.com/bitcoinsv/bsvd/chaincfg/chainhash"
+	"github.com/bitcoinsv/bsvd/wire"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// mempoolNode NodeClient serving canned raw transactions by txid
+type mempoolNode struct {
+	hashNode
+	txs map[string]string // txid -> raw tx hex
+}
+
+func (n *mempoolNode) GetRawTransaction(_ context.Context, txid string) (string, error) {
+	if txHex, ok := n.txs[txid]; ok {
+		return txHex, nil
+	}
+	return "", fmt.Errorf("rpc error: No such mempool or blockchain transaction")
+}
+
+// addTx add an MVC transaction with one PIN (empty path = non-MetaID transaction), return its txid
+func (n *mempoolNode) addTx(t *testing.T, path, content string) string {
+	t.Helper()
+	tx := wire.NewMsgTx(1)
+	prevHash := chainhash.DoubleHashH([]byte(fmt.Sprintf("funding-%d", len(n.txs))))
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0), nil))
+	if path == "" {
+		tx.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_TRUE}))
+	} else {
+		script, err := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_FALSE).AddOp(txscript.OP_RETURN).
+			AddData([]byte("metaid")).AddData([]byte("create")).AddData([]byte(path)).
+			AddData([]byte("0")).AddData([]byte("1.0.0")).AddData([]byte("application/json")).
+			AddData([]byte(content)).
+			Script()
+		if err != nil {
+			t.Fatalf("failed to build PIN script: %v", err)
+		}
+		tx.AddTxOut(wire.NewTxOut(0, script))
+	}
+
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatalf("failed to serialize tx: %v", err)
+	}
+	txID := tx.TxHash().String()
+	n.txs[txID] = hex.EncodeToString(buf.Bytes())
+	return txID
+}
+
+// setMempoolRPC point the mvc node RPC client at a server answering getrawmempool with txIDs
+func setMempoolRPC(t *testing.T, txIDs []string) {
+	t.Helper()
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": txIDs, "error": nil, "id": "1"})
+	}))
+	t.Cleanup(rpc.Close)
+
+	prev := node.MyClientController
+	node.MyClientController = &node.ClientController{ClientMap: map[string]*node.Client{"mvc": node.NewClientNode(rpc.URL, "", false)}}
+	t.Cleanup(func() { node.MyClientController = prev })
+}
+
+// TestGetMempoolMetaApps returns only the MetaApp PINs of the scanned mempool transactions
+func TestGetMempoolMetaApps(t *testing.T) {
+	setupDeployQueueTest(t)
+	mempool := &mempoolNode{txs: make(map[string]string)}
+	appTxID := mempool.addTx(t, "/protocols/metaapp", `{"appName":"demo","version":"1.0.0","runtime":"browser"}`)
+	txIDs := []string{
+		mempool.addTx(t, "", ""),
+		mempool.addTx(t, "/protocols/simplebuzz", `{"content":"hello"}`),
+		"missing",
+		appTxID,
+		mempool.addTx(t, "/protocols/metaapp", `{"appName":"later"}`),
+	}
+	setMempoolRPC(t, txIDs)
+
+	scanner := indexer.NewBlockScannerWithChain("http://127.0.0.1:1", "", "", 1, 0, indexer.ChainTypeMVC)
+	scanner.SetNodeClient(mempool)
+	s := &IndexerService{scanner: scanner, parser: indexer.NewMetaIDParser(""), metaAppDAO: dao.NewMetaAppDAO(), chainType: indexer.ChainTypeMVC}
+
+	result, err := s.GetMempoolMetaApps(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("GetMempoolMetaApps failed: %v", err)
+	}
+	if result.ChainName != "mvc" || result.MempoolSize != 5 || result.Scanned != 3 {
+		t.Fatalf("got chain %s, mempool size %d, scanned %d, want mvc, 5 and 3 (limit 4, one tx gone)", result.ChainName, result.MempoolSize, result.Scanned)
+	}
+	if len(result.Pins) != 1 {
+		t.Fatalf("got %d PINs, want the MetaApp PIN within the limit only", len(result.Pins))
+	}
+	pin := result.Pins[0]
+	if pin.PinID != appTxID+"i0" || pin.TxID != appTxID || pin.Operation != "create" || pin.Indexed {
+		t.Fatalf("unexpected PIN %+v", pin)
+	}
+	if pin.App == nil || pin.App.AppName != "demo" || pin.App.Runtime != "browser" {
+		t.Fatalf("MetaApp content not parsed: %+v", pin.App)
+	}
+}
+
+// TestMempoolSnapshot serves ZMQ mempool PINs newest first and drops them once seen in a block
+func TestMempoolSnapshot(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{metaAppDAO: dao.NewMetaAppDAO(), chainType: indexer.ChainTypeMVC}
+	pin := func(id, content string) *indexer.MetaIDData {
+		return &indexer.MetaIDData{PinID: id + "i0", TxID: id, Operation: "create", Path: "/protocols/metaapp", Content: []byte(content)}
+	}
+
+	s.trackMempoolPin(pin("a", `{"appName":"first"}`), 0)
+	time.Sleep(time.Millisecond)
+	s.trackMempoolPin(pin("b", `{"appName":"second"}`), 0)
+	time.Sleep(time.Millisecond)
+	s.trackMempoolPin(pin("c", `not json`), 0)
+
+	result := s.mempoolSnapshot(2)
+	if result.Source != "zmq" || len(result.Pins) != 2 {
+		t.Fatalf("got source %q with %d PINs, want zmq and 2 (limit)", result.Source, len(result.Pins))
+	}
+	if result.Pins[0].PinID != "ci0" || result.Pins[0].App != nil || result.Pins[1].PinID != "bi0" || result.Pins[1].App.AppName != "second" {
+		t.Fatalf("unexpected PINs %+v, %+v", result.Pins[0], result.Pins[1])
+	}
+
+	s.trackMempoolPin(pin("c", ""), 100)
+	result = s.mempoolSnapshot(maxMempoolScanLimit)
+	if len(result.Pins) != 2 || result.Pins[0].PinID != "bi0" || result.Pins[1].PinID != "ai0" {
+		t.Fatalf("confirmed PIN still listed: %d PINs", len(result.Pins))
+	}
+}
@@ -0,0 +1,161 @@
+package indexer_service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"meta-app-service/database"
+	model "meta-app-service/models"
+)
+
+// ErrVersionNotInHistory 对比的 PinID 不属于该 first_pin_id 的历史版本
+var ErrVersionNotInHistory = errors.New("pinId is not a version of this metaapp")
+
+// metaAppDiffIgnoredFields 对比时忽略的字段（每个版本都不同的标识字段，以及本地记录时间）
+var metaAppDiffIgnoredFields = map[string]bool{
+	"pin_id":     true,
+	"tx_id":      true,
+	"vout":       true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// MetaAppFieldChange 一个字段的变化
+type MetaAppFieldChange struct {
+	Field string      `json:"field"` // 字段名（JSON 名称），metadata 中的键为 metadata.<key>
+	From  interface{} `json:"from"`  // from 版本的值（字段不存在时为 null）
+	To    interface{} `json:"to"`    // to 版本的值（字段不存在时为 null）
+}
+
+// MetaAppDiff 两个版本之间的差异
+type MetaAppDiff struct {
+	FirstPinID  string                `json:"first_pin_id"`
+	From        string                `json:"from"`         // from 版本 PinID
+	To          string                `json:"to"`           // to 版本 PinID
+	FromVersion string                `json:"from_version"` // from 版本号
+	ToVersion   string                `json:"to_version"`   // to 版本号
+	Changes     []*MetaAppFieldChange `json:"changes"`      // 有变化的字段（按模型字段顺序）
+}
+
+// DiffMetaAppVersions 对比同一 first_pin_id 下两个版本的字段差异
+// fromPinID、toPinID 必须都属于该应用的历史版本（包括已归档的旧版本），否则返回 ErrVersionNotInHistory
+func (s *IndexerAppService) DiffMetaAppVersions(firstPinID, fromPinID, toPinID string) (*MetaAppDiff, error) {
+	if s.metaAppDAO == nil {
+		return nil, database.ErrDatabaseNotInitialized
+	}
+
+	from, err := s.getMetaAppVersion(firstPinID, fromPinID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getMetaAppVersion(firstPinID, toPinID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetaAppDiff{
+		FirstPinID:  firstPinID,
+		From:        from.PinID,
+		To:          to.PinID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     diffMetaApps(from, to),
+	}, nil
+}
+
+// getMetaAppVersion 获取 first_pin_id 下的某个版本
+// 按 PinID 直接查询，不依赖有上限的历史记录，因此已归档的旧版本也可以对比
+func (s *IndexerAppService) getMetaAppVersion(firstPinID, pinID string) (*model.MetaApp, error) {
+	app, err := s.metaAppDAO.GetByPinID(pinID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrVersionNotInHistory, pinID)
+		}
+		return nil, err
+	}
+	appFirstPinID := app.FirstPinId
+	if appFirstPinID == "" {
+		appFirstPinID = app.PinID
+	}
+	if appFirstPinID != firstPinID {
+		return nil, fmt.Errorf("%w: %s", ErrVersionNotInHistory, pinID)
+	}
+	return app, nil
+}
+
+// diffMetaApps 逐字段对比两个版本，metadata 为 JSON 对象时按键对比
+func diffMetaApps(from, to *model.MetaApp) []*MetaAppFieldChange {
+	changes := make([]*MetaAppFieldChange, 0)
+	fromValue := reflect.ValueOf(from).Elem()
+	toValue := reflect.ValueOf(to).Elem()
+	appType := fromValue.Type()
+
+	for i := 0; i < appType.NumField(); i++ {
+		field := strings.Split(appType.Field(i).Tag.Get("json"), ",")[0]
+		if field == "" || field == "-" || metaAppDiffIgnoredFields[field] {
+			continue
+		}
+		a, b := fromValue.Field(i).Interface(), toValue.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		if field == "metadata" {
+			if metadataChanges, ok := diffMetadata(from.Metadata, to.Metadata); ok {
+				changes = append(changes, metadataChanges...)
+				continue
+			}
+		}
+		changes = append(changes, &MetaAppFieldChange{Field: field, From: a, To: b})
+	}
+	return changes
+}
+
+// diffMetadata 按键对比两个 JSON 对象形式的 metadata，任一方不是 JSON 对象时返回 false（按整个字段对比）
+func diffMetadata(from, to string) ([]*MetaAppFieldChange, bool) {
+	fromMap, ok := parseMetadataObject(from)
+	if !ok {
+		return nil, false
+	}
+	toMap, ok := parseMetadataObject(to)
+	if !ok {
+		return nil, false
+	}
+
+	keys := make(map[string]bool, len(fromMap)+len(toMap))
+	for key := range fromMap {
+		keys[key] = true
+	}
+	for key := range toMap {
+		keys[key] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	changes := make([]*MetaAppFieldChange, 0)
+	for _, key := range sortedKeys {
+		if reflect.DeepEqual(fromMap[key], toMap[key]) {
+			continue
+		}
+		changes = append(changes, &MetaAppFieldChange{Field: "metadata." + key, From: fromMap[key], To: toMap[key]})
+	}
+	return changes, true
+}
+
+// parseMetadataObject 解析 JSON 对象形式的 metadata，空字符串视为空对象
+func parseMetadataObject(metadata string) (map[string]interface{}, bool) {
+	if strings.TrimSpace(metadata) == "" {
+		return map[string]interface{}{}, true
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(metadata), &result); err != nil || result == nil {
+		return nil, false
+	}
+	return result, true
+}
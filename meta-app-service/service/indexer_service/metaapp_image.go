@@ -0,0 +1,118 @@
+package indexer_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"meta-app-service/database"
+)
+
+// MetaApp 图片类型
+const (
+	MetaAppImageIcon  = "icon"  // MetaApp.Icon
+	MetaAppImageCover = "cover" // MetaApp.CoverImg
+)
+
+// metaAppImageCacheDir 图片缓存目录（部署目录下的隐藏目录，不参与部署目录配额清理）
+const metaAppImageCacheDir = ".images"
+
+// maxMetaAppImageSize 通过索引器代理的图片大小上限
+const maxMetaAppImageSize = 10 * 1024 * 1024
+
+var (
+	// ErrMetaAppImageNotSet MetaApp 未设置该图片
+	ErrMetaAppImageNotSet = errors.New("metaapp has no such image")
+	// ErrMetaAppImageTooLarge 图片超过 maxMetaAppImageSize
+	ErrMetaAppImageTooLarge = errors.New("metaapp image is too large")
+)
+
+// GetMetaAppImage 获取 MetaApp 图标或封面图片的本地文件路径
+// 图片引用为 metafile://pinid，首次请求时从 metafs 下载（与部署共用网关列表和下载逻辑）并缓存；
+// pinid 对应的内容不会变化，缓存不过期
+// kind: MetaAppImageIcon / MetaAppImageCover
+func (s *IndexerService) GetMetaAppImage(ctx context.Context, pinID, kind string) (string, error) {
+	if database.DB == nil {
+		return "", database.ErrDatabaseNotInitialized
+	}
+
+	app, err := database.DB.GetMetaAppByPinID(pinID)
+	if err != nil {
+		return "", err
+	}
+	firstPinID := app.FirstPinId
+	if firstPinID == "" {
+		firstPinID = app.PinID
+	}
+	if IsMetaAppDisabled(firstPinID) {
+		return "", ErrMetaAppDisabled
+	}
+
+	reference := app.Icon
+	if kind == MetaAppImageCover {
+		reference = app.CoverImg
+	}
+	reference = strings.TrimSpace(reference)
+	if reference == "" {
+		return "", ErrMetaAppImageNotSet
+	}
+	if !isValidMetafilePinID(reference) {
+		return "", fmt.Errorf("invalid %s reference: %s", kind, reference)
+	}
+	imagePinID := strings.TrimPrefix(reference, "metafile://")
+
+	// 同一图片只下载一次
+	unlock := lockDeploy(metaAppImageCacheDir + "/" + imagePinID)
+	defer unlock()
+
+	cacheDir := filepath.Join(metaAppDeployBaseDir(), metaAppImageCacheDir, imagePinID)
+	if filePath, ok := cachedMetaAppImage(cacheDir); ok {
+		return filePath, nil
+	}
+
+	// 下载到临时目录，完成后整体重命名，避免提供未下载完的文件
+	if err := os.MkdirAll(filepath.Dir(cacheDir), 0755); err != nil {
+		return "", fmt.Errorf("failed to create image cache directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(filepath.Dir(cacheDir), imagePinID+".tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create image cache directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filePath, gateway, err := s.downloadFileFromPinID(ctx, reference, tempDir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxMetaAppImageSize {
+		return "", fmt.Errorf("%w: %d bytes", ErrMetaAppImageTooLarge, info.Size())
+	}
+
+	if err := os.Rename(tempDir, cacheDir); err != nil {
+		return "", fmt.Errorf("failed to cache image: %w", err)
+	}
+	log.Printf("Cached %s of MetaApp %s (%s, %d bytes) from %s", kind, pinID, imagePinID, info.Size(), gateway)
+	return filepath.Join(cacheDir, filepath.Base(filePath)), nil
+}
+
+// cachedMetaAppImage 获取缓存目录中已下载的图片文件
+func cachedMetaAppImage(cacheDir string) (string, bool) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			return filepath.Join(cacheDir, entry.Name()), true
+		}
+	}
+	return "", false
+}
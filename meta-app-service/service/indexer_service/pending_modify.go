@@ -0,0 +1,123 @@
+package indexer_service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"meta-app-service/conf"
+	"meta-app-service/indexer"
+	model "meta-app-service/models"
+)
+
+// pendingModifyPurgeInterval 清理过期暂存 modify 的最小间隔
+const pendingModifyPurgeInterval = time.Hour
+
+// pinNotIndexedError modify 引用链上的 PIN 尚未索引
+type pinNotIndexedError struct {
+	pinID string
+}
+
+func (e *pinNotIndexedError) Error() string {
+	return fmt.Sprintf("MetaApp not found for pinID %s", e.pinID)
+}
+
+// looksLikeMetaAppModify 判断被引用 PIN 尚未索引的 modify 内容是否是 MetaApp（包含 MetaApp 特有的字段）
+// 其他协议的 modify 同样引用未索引的 PIN，只暂存 MetaApp 的 modify
+func looksLikeMetaAppModify(metaData *indexer.MetaIDData) bool {
+	content, _, err := decryptPinContent(metaData)
+	if err != nil {
+		return false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return false
+	}
+	for _, key := range []string{"appName", "code", "indexFile", "runtime"} {
+		if _, ok := fields[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parkModify 暂存引用的 PIN（targetPinID）尚未索引的 modify 操作，返回是否已暂存
+// indexer.pending_modify_expire_hours 为 0 时不暂存（与之前一样丢弃该 modify）
+func (s *IndexerService) parkModify(metaData *indexer.MetaIDData, targetPinID string, height, timestamp int64) bool {
+	if !s.parkPin(metaData, targetPinID, height, timestamp, time.Now()) {
+		return false
+	}
+	log.Printf("MetaApp modify %s references %s which is not indexed yet, parked until it is indexed", metaData.PinID, targetPinID)
+	return true
+}
+
+// parkPin 将 PIN 以 targetPinID 为键暂存到待处理集合（暂存时间 parkedAt 用于过期清理），返回是否已暂存
+// indexer.pending_modify_expire_hours 为 0 时不暂存
+func (s *IndexerService) parkPin(metaData *indexer.MetaIDData, targetPinID string, height, timestamp int64, parkedAt time.Time) bool {
+	expireHours := conf.Cfg().Indexer.PendingModifyExpireHours
+	if expireHours <= 0 {
+		return false
+	}
+
+	data, err := json.Marshal(metaData)
+	if err != nil {
+		log.Printf("Failed to encode MetaApp PIN %s for parking: %v", metaData.PinID, err)
+		return false
+	}
+	pending := &model.MetaAppPendingModify{
+		TargetPinID: targetPinID,
+		PinID:       metaData.PinID,
+		Height:      height,
+		Timestamp:   timestamp,
+		Data:        data,
+		CreatedAt:   parkedAt,
+	}
+	if err := s.metaAppDAO.AddPendingModify(pending); err != nil {
+		log.Printf("Failed to park MetaApp PIN %s: %v", metaData.PinID, err)
+		return false
+	}
+
+	s.purgeExpiredPendingModifies(expireHours)
+	return true
+}
+
+// purgeExpiredPendingModifies 删除超过 expireHours 仍未处理的暂存 PIN（modify 和描述 JSON 获取失败的 PIN）（最多每 pendingModifyPurgeInterval 执行一次）
+func (s *IndexerService) purgeExpiredPendingModifies(expireHours int) {
+	now := time.Now()
+	last := s.lastPendingPurge.Load()
+	if now.Sub(time.Unix(last, 0)) < pendingModifyPurgeInterval || !s.lastPendingPurge.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+
+	purged, err := s.metaAppDAO.PurgePendingModifies(now.Add(-time.Duration(expireHours) * time.Hour))
+	if err != nil {
+		log.Printf("Failed to purge expired parked MetaApp modifies: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Dropped %d parked MetaApp PINs not processed within %d hours", purged, expireHours)
+	}
+}
+
+// processPendingModifies 重新处理引用 pinID 的暂存 modify 操作（pinID 刚被索引）
+// 重新处理的 modify 索引成功后会继续处理引用它的暂存 modify
+func (s *IndexerService) processPendingModifies(pinID string) {
+	pending, err := s.metaAppDAO.TakePendingModifies(pinID)
+	if err != nil {
+		log.Printf("Failed to load parked MetaApp modifies referencing %s: %v", pinID, err)
+		return
+	}
+
+	for _, item := range pending {
+		var metaData indexer.MetaIDData
+		if err := json.Unmarshal(item.Data, &metaData); err != nil {
+			log.Printf("Failed to decode parked MetaApp modify %s: %v", item.PinID, err)
+			continue
+		}
+		log.Printf("Reprocessing parked MetaApp modify %s now that %s is indexed", item.PinID, pinID)
+		if err := s.processMetaAppPin(&metaData, item.Height, item.Timestamp); err != nil {
+			log.Printf("Failed to process parked MetaApp modify %s: %v", item.PinID, err)
+		}
+	}
+}
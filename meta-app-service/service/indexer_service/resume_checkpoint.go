@@ -0,0 +1,85 @@
+package indexer_service
+
+import (
+	"log"
+	"time"
+
+	"meta-app-service/conf"
+	model "meta-app-service/models"
+)
+
+// reconcileStartHeight 用冗余的扫描恢复点和已索引 MetaApp 的最高区块高度校正起始高度，取其中较高且安全的值
+// 同步状态只有一条记录，丢失或损坏时会从配置的初始高度重新索引；恢复点和 MetaApp 主记录分开存储，可以避免这种情况
+//   - 恢复点：其区块哈希仍在节点当前链上时，从恢复点的下一个区块继续（无法校验时直接使用）
+//   - MetaApp 最高区块高度：该区块可能只处理了一部分，从该区块重新扫描（写入是幂等的）
+func (s *IndexerService) reconcileStartHeight(startHeight int64) int64 {
+	chainName := string(s.chainType)
+	resumeHeight := startHeight
+
+	checkpoint, err := s.syncStatusDAO.GetCheckpoint(chainName)
+	if err != nil {
+		log.Printf("Failed to read resume checkpoint (chain: %s): %v", chainName, err)
+	} else if checkpoint != nil {
+		s.lastCheckpoint.Store(checkpoint.Height)
+		if checkpoint.Height+1 > resumeHeight && s.checkpointOnChain(checkpoint) {
+			resumeHeight = checkpoint.Height + 1
+		}
+	}
+
+	maxHeight, err := s.syncStatusDAO.GetMaxMetaAppBlockHeight(chainName)
+	if err != nil {
+		log.Printf("Failed to read highest indexed MetaApp block height (chain: %s): %v", chainName, err)
+	} else if maxHeight > resumeHeight {
+		resumeHeight = maxHeight
+	}
+
+	if resumeHeight > startHeight {
+		log.Printf("Sync status is behind the indexed data (chain: %s), resuming from block %d instead of %d", chainName, resumeHeight, startHeight)
+	}
+	return resumeHeight
+}
+
+// checkpointOnChain 恢复点的区块是否仍在节点当前链上（区块哈希一致）
+// 恢复点没有区块哈希或节点不可用时无法校验，视为有效
+func (s *IndexerService) checkpointOnChain(checkpoint *model.IndexerSyncCheckpoint) bool {
+	if checkpoint.BlockHash == "" {
+		return true
+	}
+	hash, err := s.scanner.GetBlockhash(checkpoint.Height)
+	if err != nil {
+		log.Printf("Failed to verify resume checkpoint at block %d (chain: %s), using it unverified: %v", checkpoint.Height, checkpoint.ChainName, err)
+		return true
+	}
+	if hash != checkpoint.BlockHash {
+		log.Printf("Resume checkpoint block %d (chain: %s) is no longer on the node's chain (%s != %s), ignoring it",
+			checkpoint.Height, checkpoint.ChainName, checkpoint.BlockHash, hash)
+		return false
+	}
+	return true
+}
+
+// writeCheckpoint 距离上一个恢复点超过 indexer.checkpoint_interval 个区块时写入新的恢复点（区块高度和哈希）
+// 在同步状态更新成功后调用，恢复点不会超过同步状态
+func (s *IndexerService) writeCheckpoint(height int64) {
+	interval := conf.Cfg().Indexer.CheckpointInterval
+	if interval <= 0 || height-s.lastCheckpoint.Load() < interval {
+		return
+	}
+
+	hash, err := s.scanner.GetBlockhash(height)
+	if err != nil {
+		log.Printf("Failed to get block hash for resume checkpoint at %d: %v", height, err)
+		return
+	}
+	checkpoint := &model.IndexerSyncCheckpoint{
+		ChainName: string(s.chainType),
+		Height:    height,
+		BlockHash: hash,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.syncStatusDAO.SaveCheckpoint(checkpoint); err != nil {
+		log.Printf("Failed to write resume checkpoint at %d: %v", height, err)
+		return
+	}
+	s.lastCheckpoint.Store(height)
+}
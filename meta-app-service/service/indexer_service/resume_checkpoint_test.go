@@ -0,0 +1,122 @@
+package indexer_service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+	"meta-app-service/indexer"
+	model "meta-app-service/models"
+	"meta-app-service/models/dao"
+)
+
+// hashNode NodeClient answering block hashes only, prefixed so a reorg can be simulated
+type hashNode struct {
+	branch string
+}
+
+func (n *hashNode) GetBlockCount(context.Context) (int64, error) {
+	return 1000, nil
+}
+
+func (n *hashNode) GetBlockhash(_ context.Context, height int64) (string, error) {
+	return fmt.Sprintf("%s-%d", n.branch, height), nil
+}
+
+func (n *hashNode) GetBlockHex(context.Context, string) (string, error) {
+	return "", fmt.Errorf("not implemented")
+}
+
+func (n *hashNode) GetRawTransaction(context.Context, string) (string, error) {
+	return "", fmt.Errorf("not implemented")
+}
+
+func newCheckpointTestService(t *testing.T, node *hashNode) *IndexerService {
+	t.Helper()
+	setupDeployQueueTest(t)
+	scanner := indexer.NewBlockScannerWithChain("http://127.0.0.1:1", "", "", 1, 0, indexer.ChainTypeMVC)
+	scanner.SetNodeClient(node)
+	return &IndexerService{scanner: scanner, metaAppDAO: dao.NewMetaAppDAO(), syncStatusDAO: dao.NewIndexerSyncStatusDAO(), chainType: indexer.ChainTypeMVC}
+}
+
+// TestReconcileStartHeight resumes from the checkpoint or the highest indexed MetaApp when the sync status is behind
+func TestReconcileStartHeight(t *testing.T) {
+	node := &hashNode{branch: "main"}
+	s := newCheckpointTestService(t, node)
+
+	if got := s.reconcileStartHeight(100); got != 100 {
+		t.Fatalf("empty database resumes from %d, want 100", got)
+	}
+
+	// The block of the highest MetaApp is scanned again; other chains and mempool records do not count
+	for _, app := range []*model.MetaApp{
+		{PinID: "a1i0", ChainName: "mvc", BlockHeight: 150, Timestamp: 1},
+		{PinID: "b1i0", ChainName: "btc", BlockHeight: 900, Timestamp: 2},
+		{PinID: "c1i0", ChainName: "mvc", Timestamp: 3},
+	} {
+		if err := database.DB.CreateMetaApp(app); err != nil {
+			t.Fatalf("failed to create MetaApp %s: %v", app.PinID, err)
+		}
+	}
+	if got := s.reconcileStartHeight(100); got != 150 {
+		t.Fatalf("resumes from %d, want 150 (highest mvc MetaApp block)", got)
+	}
+	if got := s.reconcileStartHeight(300); got != 300 {
+		t.Fatalf("resumes from %d, want the sync status height 300", got)
+	}
+
+	// A checkpoint still on the node's chain resumes after it
+	if err := s.syncStatusDAO.SaveCheckpoint(&model.IndexerSyncCheckpoint{ChainName: "mvc", Height: 200, BlockHash: "main-200"}); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	if got := s.reconcileStartHeight(100); got != 201 {
+		t.Fatalf("resumes from %d, want 201 (after the checkpoint)", got)
+	}
+
+	// A checkpoint whose block was reorganized away is ignored
+	node.branch = "fork"
+	if got := s.reconcileStartHeight(100); got != 150 {
+		t.Fatalf("resumes from %d after a reorg, want 150", got)
+	}
+}
+
+// TestOnBlockCompleteWritesCheckpoint writes a checkpoint every indexer.checkpoint_interval blocks
+// and rebuilds a lost sync status from it on the next start
+func TestOnBlockCompleteWritesCheckpoint(t *testing.T) {
+	s := newCheckpointTestService(t, &hashNode{branch: "main"})
+	conf.Cfg().Indexer.CheckpointInterval = 10
+	if err := s.initializeSyncStatus(1); err != nil {
+		t.Fatalf("failed to initialize sync status: %v", err)
+	}
+
+	for _, height := range []int64{5, 12, 20, 25} {
+		if err := s.onBlockComplete(height); err != nil {
+			t.Fatalf("onBlockComplete(%d) failed: %v", height, err)
+		}
+	}
+	checkpoint, err := s.syncStatusDAO.GetCheckpoint("mvc")
+	if err != nil || checkpoint == nil || checkpoint.Height != 25 || checkpoint.BlockHash != "main-25" {
+		t.Fatalf("checkpoint %+v (err %v), want height 25 written after 12", checkpoint, err)
+	}
+	if err := s.onBlockComplete(30); err != nil {
+		t.Fatalf("onBlockComplete(30) failed: %v", err)
+	}
+	if checkpoint, _ = s.syncStatusDAO.GetCheckpoint("mvc"); checkpoint.Height != 25 {
+		t.Fatalf("checkpoint moved to %d within the interval, want 25", checkpoint.Height)
+	}
+
+	// The sync status falls back to an old height, the next start resumes after the checkpoint
+	if err := s.syncStatusDAO.CreateOrUpdate(&model.IndexerSyncStatus{ChainName: "mvc", CurrentSyncHeight: 3}); err != nil {
+		t.Fatalf("failed to reset sync status: %v", err)
+	}
+	startHeight := s.reconcileStartHeight(4)
+	if err := s.initializeSyncStatus(startHeight); err != nil {
+		t.Fatalf("failed to initialize sync status: %v", err)
+	}
+	status, err := s.syncStatusDAO.GetByChainName("mvc")
+	if startHeight != 26 || err != nil || status.CurrentSyncHeight != 25 {
+		t.Fatalf("resumed from %d with sync height %+v (err %v), want 26 and 25", startHeight, status, err)
+	}
+}
@@ -1,9 +1,13 @@
 package indexer_service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
+	"time"
 
 	"meta-app-service/indexer"
 	model "meta-app-service/models"
@@ -12,27 +16,95 @@ import (
 	"gorm.io/gorm"
 )
 
+// nodeRequestTimeout deadline of a node call made while serving an HTTP request (a single attempt, no retries)
+const nodeRequestTimeout = 5 * time.Second
+
 // SyncStatusService sync status service
 type SyncStatusService struct {
 	syncStatusDAO *dao.IndexerSyncStatusDAO
 	scanner       *indexer.BlockScanner
+	scanners      map[string]*indexer.BlockScanner // chain name -> scanner, used for per-chain node tips
+}
+
+// ChainSyncStatus sync status of one chain together with its node tip
+type ChainSyncStatus struct {
+	Status       *model.IndexerSyncStatus
+	LatestHeight int64 // Node tip height (0 when the chain has no scanner or the node is unreachable)
+	Lag          int64 // Blocks behind the node tip (excluding intentionally skipped confirmations)
+	SyncedTime   int64 // Block time (unix seconds) of the last synced block, 0 when unknown
+	LagSeconds   int64 // Seconds between the last synced block and now, 0 when unknown
+	Progress     SyncProgress
+	Error        string
+}
+
+// SyncProgress catch-up progress of one chain
+type SyncProgress struct {
+	BlocksRemaining int64   // Blocks left to scan to reach the node tip
+	BlocksPerSecond float64 // Recent scan rate (exponential moving average)
+	ETASeconds      int64   // Estimated seconds to catch up: 0 when caught up, -1 when no scan rate has been measured yet
+}
+
+// newSyncProgress estimate catch-up progress from the lag and the scanner's recent scan rate
+func newSyncProgress(scanner *indexer.BlockScanner, lag int64) SyncProgress {
+	progress := SyncProgress{BlocksRemaining: lag}
+	if scanner != nil {
+		progress.BlocksPerSecond = scanner.ScanRate()
+	}
+	switch {
+	case lag <= 0:
+		progress.ETASeconds = 0
+	case progress.BlocksPerSecond > 0:
+		progress.ETASeconds = int64(math.Ceil(float64(lag) / progress.BlocksPerSecond))
+	default:
+		progress.ETASeconds = -1
+	}
+	return progress
 }
 
 // NewSyncStatusService create sync status service instance
 func NewSyncStatusService() *SyncStatusService {
 	return &SyncStatusService{
 		syncStatusDAO: dao.NewIndexerSyncStatusDAO(),
+		scanners:      make(map[string]*indexer.BlockScanner),
 	}
 }
 
 // SetBlockScanner set block scanner for getting latest block height
+// The first scanner set is the default chain used by GetSyncStatus; every scanner is also registered for its chain
 func (s *SyncStatusService) SetBlockScanner(scanner *indexer.BlockScanner) {
-	s.scanner = scanner
+	if scanner == nil {
+		return
+	}
+	if s.scanner == nil {
+		s.scanner = scanner
+	}
+	s.scanners[string(scanner.ChainType())] = scanner
+}
+
+// GetBlockScanner get the scanner of a chain (the default scanner when chainName is empty), nil if not indexed
+func (s *SyncStatusService) GetBlockScanner(chainName string) *indexer.BlockScanner {
+	if chainName == "" {
+		return s.scanner
+	}
+	return s.scanners[chainName]
+}
+
+// GetNodeHealth get node reachability of every indexed chain (chain name -> health)
+func (s *SyncStatusService) GetNodeHealth() map[string]indexer.NodeHealth {
+	health := make(map[string]indexer.NodeHealth, len(s.scanners))
+	for chainName, scanner := range s.scanners {
+		health[chainName] = scanner.NodeHealth()
+	}
+	return health
 }
 
-// GetSyncStatus get sync status (default MVC chain)
+// GetSyncStatus get sync status of the default chain (the default scanner's chain, MVC when no scanner is set)
 func (s *SyncStatusService) GetSyncStatus() (*model.IndexerSyncStatus, error) {
-	return s.GetSyncStatusByChain("mvc")
+	chainName := string(indexer.ChainTypeMVC)
+	if s.scanner != nil {
+		chainName = string(s.scanner.ChainType())
+	}
+	return s.GetSyncStatusByChain(chainName)
 }
 
 // GetSyncStatusByChain get sync status by chain name
@@ -56,13 +128,15 @@ func (s *SyncStatusService) GetAllSyncStatus() ([]*model.IndexerSyncStatus, erro
 	return statuses, nil
 }
 
-// GetLatestBlockHeight get latest block height from node
-func (s *SyncStatusService) GetLatestBlockHeight() (int64, error) {
+// GetLatestBlockHeight get latest block height from node (one attempt within nodeRequestTimeout)
+func (s *SyncStatusService) GetLatestBlockHeight(ctx context.Context) (int64, error) {
 	if s.scanner == nil {
 		return 0, errors.New("scanner not available")
 	}
 
-	latestHeight, err := s.scanner.GetBlockCount()
+	ctx, cancel := context.WithTimeout(ctx, nodeRequestTimeout)
+	defer cancel()
+	latestHeight, err := s.scanner.GetBlockCountContext(ctx)
 	if err != nil {
 		log.Printf("Failed to get latest block height from node: %v", err)
 		return 0, fmt.Errorf("failed to get latest block height: %w", err)
@@ -70,3 +144,128 @@ func (s *SyncStatusService) GetLatestBlockHeight() (int64, error) {
 
 	return latestHeight, nil
 }
+
+// GetAllChainSyncStatus get sync status of every indexed chain with its node tip
+// Node errors are reported per chain instead of failing the whole request; each chain's node calls make one attempt
+// within nodeRequestTimeout
+func (s *SyncStatusService) GetAllChainSyncStatus(ctx context.Context) ([]*ChainSyncStatus, error) {
+	statuses, err := s.GetAllSyncStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ChainSyncStatus, 0, len(statuses))
+	for _, status := range statuses {
+		item := &ChainSyncStatus{Status: status}
+		scanner, ok := s.scanners[status.ChainName]
+		if !ok {
+			item.Error = "no scanner for chain"
+			result = append(result, item)
+			continue
+		}
+
+		chainCtx, cancel := context.WithTimeout(ctx, nodeRequestTimeout)
+		latestHeight, err := scanner.GetBlockCountContext(chainCtx)
+		if err != nil {
+			cancel()
+			log.Printf("Failed to get latest block height from %s node: %v", status.ChainName, err)
+			item.Error = fmt.Sprintf("failed to get latest block height: %v", err)
+			result = append(result, item)
+			continue
+		}
+
+		item.LatestHeight = latestHeight
+		item.Lag = latestHeight - scanner.Confirmations() - status.CurrentSyncHeight
+		if item.Lag < 0 {
+			item.Lag = 0
+		}
+		item.Progress = newSyncProgress(scanner, item.Lag)
+		item.SyncedTime, item.LagSeconds = syncedBlockTimeLag(chainCtx, scanner, status.CurrentSyncHeight)
+		cancel()
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// syncedBlockTimeLag get the block time of the last synced block and how far behind now it is
+// Reads only the block header (getblockheader), a failed lookup is logged and reported as unknown (0, 0)
+func syncedBlockTimeLag(ctx context.Context, scanner *indexer.BlockScanner, syncHeight int64) (blockTime, lagSeconds int64) {
+	if syncHeight <= 0 {
+		return 0, 0
+	}
+	header, err := scanner.GetBlockHeaderContext(ctx, syncHeight)
+	if err != nil {
+		log.Printf("Failed to get header of synced block %d (chain: %s): %v", syncHeight, scanner.ChainType(), err)
+		return 0, 0
+	}
+	lagSeconds = time.Now().Unix() - header.Timestamp
+	if lagSeconds < 0 {
+		lagSeconds = 0
+	}
+	return header.Timestamp, lagSeconds
+}
+
+// GetSyncProgress get catch-up progress of the default chain (blocks remaining, scan rate and ETA)
+func (s *SyncStatusService) GetSyncProgress(currentSyncHeight, latestHeight int64) SyncProgress {
+	if s.scanner == nil || latestHeight <= 0 {
+		return newSyncProgress(s.scanner, 0)
+	}
+	lag := latestHeight - s.scanner.Confirmations() - currentSyncHeight
+	if lag < 0 {
+		lag = 0
+	}
+	return newSyncProgress(s.scanner, lag)
+}
+
+// ChainSyncLag sync lag of one indexed chain behind its node tip
+type ChainSyncLag struct {
+	ChainName     string
+	CurrentHeight int64  // Current sync height
+	LatestHeight  int64  // Node tip height (0 when the node is unreachable)
+	Lag           int64  // Blocks behind the node tip (excluding intentionally skipped confirmations)
+	Error         string // Sync status missing or node unreachable
+}
+
+// GetAllSyncLag get the sync lag of every registered chain scanner, sorted by chain name
+// Each chain's node call makes one attempt within nodeRequestTimeout; failures are reported in the chain's Error
+func (s *SyncStatusService) GetAllSyncLag(ctx context.Context) []*ChainSyncLag {
+	chainNames := make([]string, 0, len(s.scanners))
+	for chainName := range s.scanners {
+		chainNames = append(chainNames, chainName)
+	}
+	sort.Strings(chainNames)
+
+	result := make([]*ChainSyncLag, 0, len(chainNames))
+	for _, chainName := range chainNames {
+		result = append(result, s.getSyncLag(ctx, chainName, s.scanners[chainName]))
+	}
+	return result
+}
+
+// getSyncLag get current sync height, latest node height and the lag between them for one chain
+func (s *SyncStatusService) getSyncLag(ctx context.Context, chainName string, scanner *indexer.BlockScanner) *ChainSyncLag {
+	item := &ChainSyncLag{ChainName: chainName}
+	status, err := s.GetSyncStatusByChain(chainName)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+	item.CurrentHeight = status.CurrentSyncHeight
+
+	ctx, cancel := context.WithTimeout(ctx, nodeRequestTimeout)
+	defer cancel()
+	latestHeight, err := scanner.GetBlockCountContext(ctx)
+	if err != nil {
+		log.Printf("Failed to get latest block height from %s node: %v", chainName, err)
+		item.Error = fmt.Sprintf("failed to get latest block height: %v", err)
+		return item
+	}
+	item.LatestHeight = latestHeight
+
+	// 最新的 confirmations 个区块有意不扫描，不计入落后区块数
+	item.Lag = latestHeight - scanner.Confirmations() - status.CurrentSyncHeight
+	if item.Lag < 0 {
+		item.Lag = 0
+	}
+	return item
+}
@@ -0,0 +1,41 @@
+package indexer_service
+
+import (
+	"context"
+	"testing"
+
+	"meta-app-service/indexer"
+	model "meta-app-service/models"
+	"meta-app-service/models/dao"
+)
+
+// TestGetAllSyncLag reports the lag of every registered chain and the error of a chain whose node is unreachable
+func TestGetAllSyncLag(t *testing.T) {
+	setupDeployQueueTest(t)
+	syncStatusDAO := dao.NewIndexerSyncStatusDAO()
+	for chainName, height := range map[string]int64{"mvc": 995, "btc": 10} {
+		if err := syncStatusDAO.CreateOrUpdate(&model.IndexerSyncStatus{ChainName: chainName, CurrentSyncHeight: height}); err != nil {
+			t.Fatalf("failed to save %s sync status: %v", chainName, err)
+		}
+	}
+
+	mvcScanner := indexer.NewBlockScannerWithChain("http://127.0.0.1:1", "", "", 1, 0, indexer.ChainTypeMVC)
+	mvcScanner.SetNodeClient(&hashNode{})
+	mvcScanner.SetConfirmations(2)
+	btcScanner := indexer.NewBlockScannerWithChain("http://127.0.0.1:1", "", "", 1, 0, indexer.ChainTypeBTC)
+
+	s := NewSyncStatusService()
+	s.SetBlockScanner(mvcScanner)
+	s.SetBlockScanner(btcScanner)
+
+	lags := s.GetAllSyncLag(context.Background())
+	if len(lags) != 2 || lags[0].ChainName != "btc" || lags[1].ChainName != "mvc" {
+		t.Fatalf("got %d chains, want btc and mvc", len(lags))
+	}
+	if btc := lags[0]; btc.Error == "" || btc.CurrentHeight != 10 || btc.LatestHeight != 0 {
+		t.Fatalf("unreachable btc node reported as %+v", btc)
+	}
+	if mvc := lags[1]; mvc.Error != "" || mvc.CurrentHeight != 995 || mvc.LatestHeight != 1000 || mvc.Lag != 3 {
+		t.Fatalf("got mvc %+v, want height 995 of 1000 and lag 3", mvc)
+	}
+}
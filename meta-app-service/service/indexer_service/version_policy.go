@@ -0,0 +1,134 @@
+package indexer_service
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"meta-app-service/conf"
+	model "meta-app-service/models"
+)
+
+// checkModifyVersion 按 meta_app.version_policy 检查 modify 的版本号是否大于当前最新版本，返回记录的状态码和原因
+// 只检查会成为最新版本的 modify（重新扫描旧区块时较早的 modify 本来就不会成为最新版本）；
+// 任一版本号不是语义化版本时不检查，不使用语义化版本的应用不受影响
+func (s *IndexerService) checkModifyVersion(pinID, firstPinID, version string, timestamp int64) (int, string) {
+	policy := conf.Cfg().MetaApp.VersionPolicy
+	if policy != conf.VersionPolicyFlag && policy != conf.VersionPolicyReject {
+		return model.MetaAppStateNormal, ""
+	}
+
+	latest, err := s.metaAppDAO.GetLatestByFirstPinID(firstPinID)
+	if err != nil || latest == nil || latest.PinID == pinID || latest.Timestamp > timestamp {
+		return model.MetaAppStateNormal, ""
+	}
+
+	current, ok := parseSemver(version)
+	if !ok {
+		return model.MetaAppStateNormal, ""
+	}
+	previous, ok := parseSemver(latest.Version)
+	if !ok || compareSemver(current, previous) > 0 {
+		return model.MetaAppStateNormal, ""
+	}
+
+	reason := fmt.Sprintf("version %s is not greater than latest version %s (pin %s)", version, latest.Version, latest.PinID)
+	if policy == conf.VersionPolicyReject {
+		log.Printf("MetaApp modify %s rejected by version policy: %s", pinID, reason)
+		return model.MetaAppStateRejected, reason
+	}
+	log.Printf("MetaApp modify %s flagged by version policy: %s", pinID, reason)
+	return model.MetaAppStateNormal, reason
+}
+
+// semver 解析后的语义化版本
+type semver struct {
+	core       [3]int64
+	prerelease []string
+}
+
+// parseSemver 解析语义化版本号，允许 "v" 前缀和省略的次版本号/修订号（"1.2" 视为 "1.2.0"），忽略构建元数据（"+build"）
+func parseSemver(version string) (semver, bool) {
+	var v semver
+	version = strings.TrimSpace(version)
+	version = strings.TrimPrefix(strings.TrimPrefix(version, "v"), "V")
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		if i == len(version)-1 {
+			return v, false
+		}
+		v.prerelease = strings.Split(version[i+1:], ".")
+		for _, identifier := range v.prerelease {
+			if identifier == "" {
+				return v, false
+			}
+		}
+		version = version[:i]
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || n < 0 || part == "" || part[0] == '+' {
+			return v, false
+		}
+		v.core[i] = n
+	}
+	return v, true
+}
+
+// compareSemver 比较两个语义化版本，a < b 返回 -1，相等返回 0，a > b 返回 1
+// 优先级规则与 semver 2.0.0 一致：先比较主/次/修订号，预发布版本低于正式版本，预发布标识逐个比较
+func compareSemver(a, b semver) int {
+	for i := range a.core {
+		if a.core[i] != b.core[i] {
+			return compareInt64(a.core[i], b.core[i])
+		}
+	}
+
+	switch {
+	case len(a.prerelease) == 0 && len(b.prerelease) == 0:
+		return 0
+	case len(a.prerelease) == 0:
+		return 1
+	case len(b.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.prerelease) && i < len(b.prerelease); i++ {
+		x, y := a.prerelease[i], b.prerelease[i]
+		xn, xErr := strconv.ParseInt(x, 10, 64)
+		yn, yErr := strconv.ParseInt(y, 10, 64)
+		switch {
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				return compareInt64(xn, yn)
+			}
+		case xErr == nil:
+			return -1 // 数字标识低于字母数字标识
+		case yErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInt64(int64(len(a.prerelease)), int64(len(b.prerelease)))
+}
+
+// compareInt64 比较两个整数
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
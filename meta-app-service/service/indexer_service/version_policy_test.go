@@ -0,0 +1,122 @@
+package indexer_service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+	"meta-app-service/indexer"
+	model "meta-app-service/models"
+	"meta-app-service/models/dao"
+)
+
+func TestCompareSemver(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.0.0", "1.0", 0},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+		{"1.0.1", "1.0.0", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"2", "1.99.99", 1},
+		{"1.0.0", "1.0.0-rc.1", 1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta", 1},
+	}
+	for _, tc := range cases {
+		a, ok := parseSemver(tc.a)
+		if !ok {
+			t.Fatalf("parseSemver(%q) failed", tc.a)
+		}
+		b, ok := parseSemver(tc.b)
+		if !ok {
+			t.Fatalf("parseSemver(%q) failed", tc.b)
+		}
+		if got := compareSemver(a, b); got != tc.want {
+			t.Errorf("compareSemver(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	for _, version := range []string{"", "latest", "1.0.0.0", "1..0", "1.0.0-", "1.0.0-rc..1", "1.-1.0"} {
+		if _, ok := parseSemver(version); ok {
+			t.Errorf("parseSemver(%q) succeeded, want failure", version)
+		}
+	}
+}
+
+// TestModifyVersionPolicy indexes modifies that do not bump the version under each policy
+func TestModifyVersionPolicy(t *testing.T) {
+	setupDeployQueueTest(t)
+	s := &IndexerService{metaAppDAO: dao.NewMetaAppDAO()}
+
+	index := func(pinID, operation, version string, timestamp int64) *model.MetaApp {
+		t.Helper()
+		data, _ := json.Marshal(map[string]interface{}{
+			"appName":   "app",
+			"runtime":   "browser",
+			"indexFile": "index.html",
+			"version":   version,
+			"content":   testCodePinID,
+		})
+		metaData := &indexer.MetaIDData{PinID: pinID, Operation: operation, Content: data}
+		var err error
+		if operation == "create" {
+			err = s.processMetaAppContent(metaData, 100, timestamp)
+		} else {
+			err = s.processMetaAppModify(metaData, "app1i0", 100, timestamp)
+		}
+		if err != nil {
+			t.Fatalf("failed to index %s: %v", pinID, err)
+		}
+		app, err := database.DB.GetMetaAppByPinID(pinID)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", pinID, err)
+		}
+		return app
+	}
+	latestPinID := func() string {
+		t.Helper()
+		latest, err := database.DB.GetLatestMetaAppByFirstPinID("app1i0")
+		if err != nil {
+			t.Fatalf("failed to load latest: %v", err)
+		}
+		return latest.PinID
+	}
+
+	index("app1i0", "create", "1.1.0", 1700000000)
+
+	conf.Cfg().MetaApp.VersionPolicy = conf.VersionPolicyReject
+	app := index("mod1i0", "modify", "1.0.9", 1700000100)
+	if app.State != model.MetaAppStateRejected || app.StateReason == "" {
+		t.Fatalf("downgrade indexed with state %d (%q), want rejected", app.State, app.StateReason)
+	}
+	if got := latestPinID(); got != "app1i0" {
+		t.Fatalf("latest is %s after rejected modify, want app1i0", got)
+	}
+	history, _, err := database.DB.GetMetaAppHistoryPage("app1i0", "", 10)
+	if err != nil || len(history) != 2 {
+		t.Fatalf("history has %d versions (err %v), want rejected modify recorded", len(history), err)
+	}
+
+	// Versions that are not semantic are not checked
+	if app = index("mod2i0", "modify", "nightly", 1700000200); app.State != model.MetaAppStateNormal || latestPinID() != "mod2i0" {
+		t.Fatalf("non-semver modify indexed with state %d, latest %s", app.State, latestPinID())
+	}
+
+	conf.Cfg().MetaApp.VersionPolicy = conf.VersionPolicyFlag
+	index("mod3i0", "modify", "2.0.0", 1700000300)
+	app = index("mod4i0", "modify", "2.0.0", 1700000400)
+	if app.State != model.MetaAppStateNormal || app.StateReason == "" || latestPinID() != "mod4i0" {
+		t.Fatalf("repeated version indexed with state %d (%q), latest %s; want flagged and latest", app.State, app.StateReason, latestPinID())
+	}
+
+	conf.Cfg().MetaApp.VersionPolicy = conf.VersionPolicyOff
+	if app = index("mod5i0", "modify", "1.0.0", 1700000500); app.StateReason != "" || latestPinID() != "mod5i0" {
+		t.Fatalf("downgrade with policy off indexed with reason %q, latest %s", app.StateReason, latestPinID())
+	}
+}
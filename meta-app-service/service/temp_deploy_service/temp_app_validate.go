@@ -0,0 +1,145 @@
+package temp_deploy_service
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"meta-app-service/conf"
+)
+
+// maxValidateListedFiles 校验结果中最多列出的文件数（超出时只统计不列出）
+const maxValidateListedFiles = 1000
+
+// TempAppValidationFile 校验结果中的文件条目
+type TempAppValidationFile struct {
+	Path string // 相对于应用根目录的路径（使用 / 分隔）
+	Size int64  // 文件大小（字节）
+}
+
+// TempAppValidation 临时应用压缩包校验结果
+type TempAppValidation struct {
+	Valid          bool                    // 是否可以作为临时应用部署
+	IndexFile      string                  // 检查的入口文件
+	HasIndex       bool                    // 根目录是否存在入口文件
+	TotalSize      int64                   // 解压后总大小（字节）
+	FileCount      int                     // 解压后文件数
+	Files          []TempAppValidationFile // 文件列表（按路径排序，最多 maxValidateListedFiles 个）
+	FilesTruncated bool                    // 文件列表是否被截断
+	Errors         []string                // 校验失败原因
+}
+
+// ValidateTempApp 校验临时应用压缩包（dry run）
+// 解压到临时目录，检查根目录是否包含入口文件并统计文件列表和大小，完成后删除临时目录，不创建部署记录
+// 压缩包本身的问题（格式错误、超出解压限制、缺少入口文件）记录在返回结果的 Errors 中，只有服务端错误才返回 error
+func (s *TempDeployService) ValidateTempApp(file io.Reader, filename, indexFile string) (*TempAppValidation, error) {
+	if indexFile == "" {
+		indexFile = "index.html"
+	}
+	result := &TempAppValidation{IndexFile: indexFile}
+
+	// 1. 在部署目录下创建临时目录（与正式部署使用同一磁盘），结束后删除
+	deployBaseDir := conf.Cfg().TempApp.DeployFilePath
+	if deployBaseDir == "" {
+		deployBaseDir = "./temp_app_deploy_data"
+	}
+	if err := os.MkdirAll(deployBaseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create deploy directory: %w", err)
+	}
+	workDir, err := os.MkdirTemp(deployBaseDir, ".validate-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validate directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// 2. 保存压缩包（保留原始扩展名，用于识别压缩包类型）
+	archiveExt := ".zip"
+	if lowerName := strings.ToLower(filename); strings.HasSuffix(lowerName, ".tar.gz") {
+		archiveExt = ".tar.gz"
+	} else if strings.HasSuffix(lowerName, ".tgz") {
+		archiveExt = ".tgz"
+	}
+	archivePath := filepath.Join(workDir, "upload"+archiveExt)
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	_, err = io.Copy(archiveFile, file)
+	archiveFile.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save archive file: %w", err)
+	}
+
+	// 3. 解压（与正式上传使用相同的解压限制）
+	appDir := filepath.Join(workDir, "app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create app directory: %w", err)
+	}
+	if err := s.extractArchive(archivePath, appDir); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to extract archive: %v", err))
+		return result, nil
+	}
+
+	// 4. 统计文件列表和大小
+	err = filepath.Walk(appDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(appDir, path)
+		if err != nil {
+			return err
+		}
+		result.FileCount++
+		result.TotalSize += info.Size()
+		result.Files = append(result.Files, TempAppValidationFile{
+			Path: filepath.ToSlash(relPath),
+			Size: info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk extracted files: %w", err)
+	}
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Path < result.Files[j].Path
+	})
+
+	// 5. 检查入口文件（临时应用从解压根目录提供服务）
+	if info, err := os.Stat(filepath.Join(appDir, filepath.FromSlash(indexFile))); err == nil && !info.IsDir() {
+		result.HasIndex = true
+	} else if nested := findNestedIndex(result.Files, indexFile); nested != "" {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s not found at archive root (found %s, zip the contents of that directory instead)", indexFile, nested))
+	} else {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s not found at archive root", indexFile))
+	}
+	if result.FileCount == 0 {
+		result.Errors = append(result.Errors, "archive is empty")
+	}
+
+	if len(result.Files) > maxValidateListedFiles {
+		result.Files = result.Files[:maxValidateListedFiles]
+		result.FilesTruncated = true
+	}
+	result.Valid = len(result.Errors) == 0
+	return result, nil
+}
+
+// findNestedIndex 查找子目录中的入口文件（常见错误：打包时包含了 dist/ 等外层目录），返回最浅的一个
+func findNestedIndex(files []TempAppValidationFile, indexFile string) string {
+	nested := ""
+	for _, file := range files {
+		if !strings.HasSuffix(file.Path, "/"+indexFile) {
+			continue
+		}
+		if nested == "" || strings.Count(file.Path, "/") < strings.Count(nested, "/") {
+			nested = file.Path
+		}
+	}
+	return nested
+}
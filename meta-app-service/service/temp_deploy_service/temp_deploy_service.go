@@ -1,15 +1,20 @@
 package temp_deploy_service
 
 import (
-	"archive/zip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"meta-app-service/conf"
+	"meta-app-service/database"
 	model "meta-app-service/models"
 	"meta-app-service/models/dao"
 	"meta-app-service/tool"
@@ -20,6 +25,17 @@ type TempDeployService struct {
 	tempAppDAO *dao.TempAppDAO
 }
 
+var (
+	// ErrInvalidChunkMD5 分片 MD5 参数不合法（格式错误或数量与分片数不一致）
+	ErrInvalidChunkMD5 = errors.New("invalid chunk md5")
+	// ErrChunkChecksumMismatch 分片校验失败（MD5 不匹配或合并后大小不一致）
+	ErrChunkChecksumMismatch = errors.New("chunk checksum mismatch")
+	// ErrTooManyActiveTempApps 未过期临时应用数量已达到 temp_app.max_active
+	ErrTooManyActiveTempApps = errors.New("too many active temp apps")
+	// ErrChunkUploadTooLarge 分片上传声明的总大小或分片数超出 temp_app.max_chunk_upload_size_mb / temp_app.max_chunk_count，或单个分片超出分片大小
+	ErrChunkUploadTooLarge = errors.New("chunk upload too large")
+)
+
 // NewTempDeployService 创建临时应用部署服务实例
 func NewTempDeployService() *TempDeployService {
 	return &TempDeployService{
@@ -27,10 +43,15 @@ func NewTempDeployService() *TempDeployService {
 	}
 }
 
-// UploadTempApp 上传并解压临时应用 zip 包
+// UploadTempApp 上传并解压临时应用压缩包（zip / tar.gz）
 // file: 上传的 zip 文件
 // 返回 TempAppDeploy 和错误
 func (s *TempDeployService) UploadTempApp(file io.Reader, filename string) (*model.TempAppDeploy, error) {
+	// 先检查数量上限，超过时不落盘
+	if err := s.checkActiveLimit(); err != nil {
+		return nil, err
+	}
+
 	// 1. 生成唯一 tokenID
 	tokenID, err := tool.GetUUID()
 	if err != nil {
@@ -40,7 +61,7 @@ func (s *TempDeployService) UploadTempApp(file io.Reader, filename string) (*mod
 	tokenID = strings.ReplaceAll(tokenID, "-", "_")
 
 	// 2. 获取部署基础目录
-	deployBaseDir := conf.Cfg.TempApp.DeployFilePath
+	deployBaseDir := conf.Cfg().TempApp.DeployFilePath
 	if deployBaseDir == "" {
 		deployBaseDir = "./temp_app_deploy_data"
 	}
@@ -51,32 +72,46 @@ func (s *TempDeployService) UploadTempApp(file io.Reader, filename string) (*mod
 		return nil, fmt.Errorf("failed to create deploy directory: %w", err)
 	}
 
-	// 4. 保存 zip 文件
-	zipFilePath := filepath.Join(appDeployDir, "upload.zip")
+	// 4. 保存压缩包（保留原始扩展名，用于识别压缩包类型）
+	archiveExt := ".zip"
+	if lowerName := strings.ToLower(filename); strings.HasSuffix(lowerName, ".tar.gz") {
+		archiveExt = ".tar.gz"
+	} else if strings.HasSuffix(lowerName, ".tgz") {
+		archiveExt = ".tgz"
+	}
+	zipFilePath := filepath.Join(appDeployDir, "upload"+archiveExt)
 	zipFile, err := os.Create(zipFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create zip file: %w", err)
 	}
 	defer zipFile.Close()
 
-	// 复制文件内容
-	if _, err := io.Copy(zipFile, file); err != nil {
+	// 复制文件内容（同时计算内容哈希）
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(zipFile, hasher), file); err != nil {
 		os.RemoveAll(appDeployDir) // 清理目录
 		return nil, fmt.Errorf("failed to save zip file: %w", err)
 	}
 	zipFile.Close()
+	contentHash := hex.EncodeToString(hasher.Sum(nil))
 
-	// 5. 解压 zip 文件
-	if err := s.extractZip(zipFilePath, appDeployDir); err != nil {
+	// 相同内容的临时应用未过期时直接复用，不再重复解压
+	if existing := s.findDuplicateTempApp(contentHash); existing != nil {
 		os.RemoveAll(appDeployDir) // 清理目录
-		return nil, fmt.Errorf("failed to extract zip file: %w", err)
+		return existing, nil
+	}
+
+	// 5. 解压压缩包
+	if err := s.extractArchive(zipFilePath, appDeployDir); err != nil {
+		os.RemoveAll(appDeployDir) // 清理目录
+		return nil, fmt.Errorf("failed to extract archive: %w", err)
 	}
 
-	// 6. 删除 zip 文件（解压后不再需要）
+	// 6. 删除压缩包（解压后不再需要）
 	os.Remove(zipFilePath)
 
 	// 7. 计算过期时间
-	expireHours := conf.Cfg.TempApp.ExpireHours
+	expireHours := conf.Cfg().TempApp.ExpireHours
 	if expireHours == 0 {
 		expireHours = 24 // 默认 24 小时
 	}
@@ -89,6 +124,7 @@ func (s *TempDeployService) UploadTempApp(file io.Reader, filename string) (*mod
 		ExpiresAt:      expiresAt,
 		Status:         "completed",
 		Message:        "",
+		ContentHash:    contentHash,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -101,103 +137,260 @@ func (s *TempDeployService) UploadTempApp(file io.Reader, filename string) (*mod
 	return deploy, nil
 }
 
-// extractZip 解压 zip 文件到目标目录
-func (s *TempDeployService) extractZip(zipPath, destDir string) error {
-	// 打开 zip 文件
-	r, err := zip.OpenReader(zipPath)
+// checkActiveLimit 检查未过期临时应用数量是否已达到 temp_app.max_active（0 表示不限制）
+func (s *TempDeployService) checkActiveLimit() error {
+	maxActive := conf.Cfg().TempApp.MaxActive
+	if maxActive <= 0 {
+		return nil
+	}
+	active, err := s.tempAppDAO.CountActive()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to count active temp apps: %w", err)
 	}
-	defer r.Close()
-
-	// 遍历 zip 文件中的所有文件
-	for _, f := range r.File {
-		// 构建目标文件路径
-		fpath := filepath.Join(destDir, f.Name)
+	if active >= maxActive {
+		return fmt.Errorf("%w: %d/%d, please retry after some temp apps expire", ErrTooManyActiveTempApps, active, maxActive)
+	}
+	return nil
+}
 
-		// 安全检查：防止路径遍历攻击
-		if !strings.HasPrefix(fpath, filepath.Clean(destDir)+string(os.PathSeparator)) {
-			return fmt.Errorf("invalid file path: %s", f.Name)
-		}
+// findDuplicateTempApp 查找内容哈希相同、未过期且部署目录仍存在的临时应用，不存在时返回 nil
+// 返回的记录 Message 标明为复用（不写回数据库）
+func (s *TempDeployService) findDuplicateTempApp(contentHash string) *model.TempAppDeploy {
+	existing, err := s.tempAppDAO.GetByContentHash(contentHash)
+	if err != nil {
+		return nil
+	}
+	if existing.Status != "completed" || !existing.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	if info, err := os.Stat(existing.DeployFilePath); err != nil || !info.IsDir() {
+		return nil
+	}
 
-		// 如果是目录，创建目录
-		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(fpath, 0755); err != nil {
-				return err
-			}
-			continue
-		}
+	log.Printf("Temp app upload matches existing temp app %s (sha256 %s), reusing it", existing.TokenID, contentHash)
+	existing.Message = "duplicate upload, reused existing temp app"
+	return existing
+}
 
-		// 确保父目录存在
-		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
-			return err
-		}
+// extractArchive 解压压缩包到目标目录（根据扩展名和文件头识别 zip / tar.gz）
+// 解压总大小和文件数受 temp_app.max_extract_size_mb / max_extract_files 限制，调用方在出错时清理目标目录
+func (s *TempDeployService) extractArchive(archivePath, destDir string) error {
+	limits := tool.ExtractLimits{
+		MaxTotalSize: int64(conf.Cfg().TempApp.MaxExtractSizeMB) * 1024 * 1024,
+		MaxFiles:     conf.Cfg().TempApp.MaxExtractFiles,
+	}
 
-		// 打开 zip 中的文件
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
+	switch tool.DetectArchiveType(archivePath) {
+	case tool.ArchiveTypeZip:
+		return tool.ExtractZip(archivePath, destDir, limits)
+	case tool.ArchiveTypeTarGz:
+		return tool.ExtractTarGz(archivePath, destDir, limits)
+	default:
+		return fmt.Errorf("unsupported archive format, only zip and tar.gz are supported")
+	}
+}
 
-		// 创建目标文件
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			rc.Close()
-			return err
-		}
+// GetTempAppByTokenID 根据 TokenID 获取临时应用部署记录
+func (s *TempDeployService) GetTempAppByTokenID(tokenID string) (*model.TempAppDeploy, error) {
+	return s.tempAppDAO.GetByTokenID(tokenID)
+}
 
-		// 复制文件内容
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
+// DeleteTempApp 立即删除临时应用（不等待过期清理）
+// 删除对应的文件夹和数据库记录，记录不存在时返回 database.ErrNotFound
+func (s *TempDeployService) DeleteTempApp(tokenID string) error {
+	deploy, err := s.tempAppDAO.GetByTokenID(tokenID)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			return err
+	// 删除文件夹
+	if deploy.DeployFilePath != "" {
+		if err := os.RemoveAll(deploy.DeployFilePath); err != nil {
+			return fmt.Errorf("failed to remove directory %s: %w", deploy.DeployFilePath, err)
 		}
 	}
 
+	// 删除数据库记录
+	if err := s.tempAppDAO.Delete(tokenID); err != nil {
+		return fmt.Errorf("failed to delete record %s: %w", tokenID, err)
+	}
+
 	return nil
 }
 
-// GetTempAppByTokenID 根据 TokenID 获取临时应用部署记录
-func (s *TempDeployService) GetTempAppByTokenID(tokenID string) (*model.TempAppDeploy, error) {
-	return s.tempAppDAO.GetByTokenID(tokenID)
+// CleanupResult 清理结果统计
+type CleanupResult struct {
+	DeletedRecords int   // 删除（或 dry run 时将删除）的记录数
+	FreedBytes     int64 // 释放（或 dry run 时将释放）的磁盘空间（字节）
+	Failed         int   // 删除失败的记录数
 }
 
 // CleanupExpiredTempApps 清理过期的临时应用
 // 删除数据库记录和对应的文件夹
-func (s *TempDeployService) CleanupExpiredTempApps() error {
+// dryRun: 为 true 时只记录将要删除的内容，不实际删除
+func (s *TempDeployService) CleanupExpiredTempApps(dryRun bool) (*CleanupResult, error) {
 	// 获取所有过期的记录
 	expired, err := s.tempAppDAO.ListExpired()
 	if err != nil {
-		return fmt.Errorf("failed to list expired temp apps: %w", err)
+		return nil, fmt.Errorf("failed to list expired temp apps: %w", err)
 	}
 
+	result := &CleanupResult{}
+
 	// 删除每个过期的记录和文件夹
 	for _, deploy := range expired {
+		var size int64
+		if deploy.DeployFilePath != "" {
+			size = dirSize(deploy.DeployFilePath)
+		}
+
+		if dryRun {
+			log.Printf("[dry run] Would delete expired temp app %s (path: %s, size: %d bytes, expired at: %s)",
+				deploy.TokenID, deploy.DeployFilePath, size, deploy.ExpiresAt.Format(time.RFC3339))
+			result.DeletedRecords++
+			result.FreedBytes += size
+			continue
+		}
+
 		// 删除文件夹
 		if deploy.DeployFilePath != "" {
 			if err := os.RemoveAll(deploy.DeployFilePath); err != nil {
 				// 记录错误但继续处理其他记录
-				fmt.Printf("Failed to remove directory %s: %v\n", deploy.DeployFilePath, err)
+				log.Printf("Failed to remove directory %s: %v", deploy.DeployFilePath, err)
+				result.Failed++
+				continue
 			}
 		}
 
 		// 删除数据库记录
 		if err := s.tempAppDAO.Delete(deploy.TokenID); err != nil {
 			// 记录错误但继续处理其他记录
-			fmt.Printf("Failed to delete record %s: %v\n", deploy.TokenID, err)
+			log.Printf("Failed to delete record %s: %v", deploy.TokenID, err)
+			result.Failed++
+			continue
 		}
+
+		result.DeletedRecords++
+		result.FreedBytes += size
 	}
 
-	return nil
+	return result, nil
+}
+
+// CleanupStaleChunkUploads 清理废弃的分片上传（客户端未调用合并）
+// 删除最后活动时间超过配置阈值的分片上传记录和对应的分片目录，
+// 同时删除没有对应记录且同样超过阈值的孤立分片目录
+// dryRun: 为 true 时只记录将要删除的内容，不实际删除
+func (s *TempDeployService) CleanupStaleChunkUploads(dryRun bool) (*CleanupResult, error) {
+	expireHours := conf.Cfg().TempApp.ChunkUploadExpireHours
+	if expireHours <= 0 {
+		expireHours = 24 // 默认 24 小时
+	}
+	before := time.Now().Add(-time.Duration(expireHours) * time.Hour)
+
+	stale, err := s.tempAppDAO.ListStaleChunkUploads(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale chunk uploads: %w", err)
+	}
+
+	deployBaseDir := conf.Cfg().TempApp.DeployFilePath
+	if deployBaseDir == "" {
+		deployBaseDir = "./temp_app_deploy_data"
+	}
+	chunksBaseDir := filepath.Join(deployBaseDir, "chunks")
+
+	result := &CleanupResult{}
+
+	// 删除过期的分片上传记录及其分片目录
+	for _, upload := range stale {
+		chunksDir := filepath.Join(chunksBaseDir, upload.UploadID)
+		size := dirSize(chunksDir)
+
+		if dryRun {
+			log.Printf("[dry run] Would delete stale chunk upload %s (path: %s, size: %d bytes, status: %s, last active: %s)",
+				upload.UploadID, chunksDir, size, upload.Status, upload.UpdatedAt.Format(time.RFC3339))
+			result.DeletedRecords++
+			result.FreedBytes += size
+			continue
+		}
+
+		if err := os.RemoveAll(chunksDir); err != nil {
+			log.Printf("Failed to remove chunk directory %s: %v", chunksDir, err)
+			result.Failed++
+			continue
+		}
+		if err := s.tempAppDAO.DeleteChunkUpload(upload.UploadID); err != nil {
+			log.Printf("Failed to delete chunk upload record %s: %v", upload.UploadID, err)
+			result.Failed++
+			continue
+		}
+
+		result.DeletedRecords++
+		result.FreedBytes += size
+	}
+
+	// 删除没有对应记录的孤立分片目录
+	entries, err := os.ReadDir(chunksBaseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, fmt.Errorf("failed to read chunks directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		// 只处理确认没有记录的目录
+		if _, err := s.tempAppDAO.GetChunkUploadByUploadID(entry.Name()); !errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+
+		chunksDir := filepath.Join(chunksBaseDir, entry.Name())
+		size := dirSize(chunksDir)
+		if dryRun {
+			log.Printf("[dry run] Would delete orphaned chunk directory %s (size: %d bytes)", chunksDir, size)
+			result.FreedBytes += size
+			continue
+		}
+		if err := os.RemoveAll(chunksDir); err != nil {
+			log.Printf("Failed to remove orphaned chunk directory %s: %v", chunksDir, err)
+			result.Failed++
+			continue
+		}
+		result.FreedBytes += size
+	}
+
+	return result, nil
+}
+
+// dirSize 计算目录（或文件）占用的字节数，无法访问的文件忽略
+func dirSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
 }
 
 // InitChunkUpload 初始化分片上传
 // totalSize: 文件总大小（字节）
 // filename: 文件名
+// chunkMD5s: 每个分片的 MD5（可选，为空时不预先声明，数量必须与分片数一致）
 // 返回 TempAppChunkUpload 和错误
-func (s *TempDeployService) InitChunkUpload(totalSize int64, filename string) (*model.TempAppChunkUpload, error) {
+func (s *TempDeployService) InitChunkUpload(totalSize int64, filename string, chunkMD5s []string) (*model.TempAppChunkUpload, error) {
+	// 数量已达上限时不再接受新的分片上传
+	if err := s.checkActiveLimit(); err != nil {
+		return nil, err
+	}
+
 	// 1. 生成唯一 uploadID
 	uploadID, err := tool.GetUUID()
 	if err != nil {
@@ -207,16 +400,38 @@ func (s *TempDeployService) InitChunkUpload(totalSize int64, filename string) (*
 	uploadID = strings.ReplaceAll(uploadID, "-", "_")
 
 	// 2. 获取分片大小
-	chunkSize := conf.Cfg.TempApp.ChunkSize
+	chunkSize := conf.Cfg().TempApp.ChunkSize
 	if chunkSize == 0 {
 		chunkSize = 5 * 1024 * 1024 // 默认 5MB
 	}
 
-	// 3. 计算总分片数
-	totalChunks := int((totalSize + chunkSize - 1) / chunkSize) // 向上取整
+	// 3. 校验总大小并计算总分片数（先校验再计算，避免声明超大 total_size 时创建大量分片记录）
+	maxSize := int64(conf.Cfg().TempApp.MaxChunkUploadSizeMB) * 1024 * 1024
+	if maxSize > 0 && totalSize > maxSize {
+		return nil, fmt.Errorf("%w: total_size %d exceeds limit of %d MB", ErrChunkUploadTooLarge, totalSize, conf.Cfg().TempApp.MaxChunkUploadSizeMB)
+	}
+	chunkCount := (totalSize + chunkSize - 1) / chunkSize // 向上取整
+	if maxChunks := conf.Cfg().TempApp.MaxChunkCount; maxChunks > 0 && chunkCount > int64(maxChunks) {
+		return nil, fmt.Errorf("%w: %d chunks of %d bytes exceeds limit of %d chunks", ErrChunkUploadTooLarge, chunkCount, chunkSize, maxChunks)
+	}
+	totalChunks := int(chunkCount)
+
+	// 校验预先声明的分片 MD5
+	if len(chunkMD5s) > 0 {
+		if len(chunkMD5s) != totalChunks {
+			return nil, fmt.Errorf("%w: got %d md5s, expected %d", ErrInvalidChunkMD5, len(chunkMD5s), totalChunks)
+		}
+		for i, sum := range chunkMD5s {
+			sum = strings.ToLower(strings.TrimSpace(sum))
+			if !isValidMD5Hex(sum) {
+				return nil, fmt.Errorf("%w: chunk %d: %s", ErrInvalidChunkMD5, i, chunkMD5s[i])
+			}
+			chunkMD5s[i] = sum
+		}
+	}
 
 	// 4. 获取部署基础目录
-	deployBaseDir := conf.Cfg.TempApp.DeployFilePath
+	deployBaseDir := conf.Cfg().TempApp.DeployFilePath
 	if deployBaseDir == "" {
 		deployBaseDir = "./temp_app_deploy_data"
 	}
@@ -235,6 +450,7 @@ func (s *TempDeployService) InitChunkUpload(totalSize int64, filename string) (*
 		TotalChunks:    totalChunks,
 		ChunkSize:      chunkSize,
 		UploadedChunks: make(map[int]bool),
+		ChunkMD5s:      chunkMD5s,
 		Status:         "uploading",
 		Message:        "",
 		CreatedAt:      time.Now(),
@@ -253,8 +469,9 @@ func (s *TempDeployService) InitChunkUpload(totalSize int64, filename string) (*
 // uploadID: 上传 ID
 // chunkIndex: 分片索引（从 0 开始）
 // chunkData: 分片数据
+// chunkMD5: 分片的 MD5（可选，为空时使用初始化时声明的 MD5，都没有则不校验）
 // 返回错误
-func (s *TempDeployService) UploadChunk(uploadID string, chunkIndex int, chunkData io.Reader) error {
+func (s *TempDeployService) UploadChunk(uploadID string, chunkIndex int, chunkData io.Reader, chunkMD5 string) error {
 	// 1. 获取分片上传记录
 	upload, err := s.tempAppDAO.GetChunkUploadByUploadID(uploadID)
 	if err != nil {
@@ -266,8 +483,22 @@ func (s *TempDeployService) UploadChunk(uploadID string, chunkIndex int, chunkDa
 		return fmt.Errorf("invalid chunk index: %d, total chunks: %d", chunkIndex, upload.TotalChunks)
 	}
 
+	// 确定期望的分片 MD5
+	expectedMD5 := strings.ToLower(strings.TrimSpace(chunkMD5))
+	if expectedMD5 != "" && !isValidMD5Hex(expectedMD5) {
+		return fmt.Errorf("%w: %s", ErrInvalidChunkMD5, chunkMD5)
+	}
+	if chunkIndex < len(upload.ChunkMD5s) {
+		declaredMD5 := upload.ChunkMD5s[chunkIndex]
+		if expectedMD5 == "" {
+			expectedMD5 = declaredMD5
+		} else if expectedMD5 != declaredMD5 {
+			return fmt.Errorf("%w: chunk %d md5 %s does not match declared md5 %s", ErrChunkChecksumMismatch, chunkIndex, expectedMD5, declaredMD5)
+		}
+	}
+
 	// 3. 获取部署基础目录
-	deployBaseDir := conf.Cfg.TempApp.DeployFilePath
+	deployBaseDir := conf.Cfg().TempApp.DeployFilePath
 	if deployBaseDir == "" {
 		deployBaseDir = "./temp_app_deploy_data"
 	}
@@ -283,12 +514,28 @@ func (s *TempDeployService) UploadChunk(uploadID string, chunkIndex int, chunkDa
 	}
 	defer chunkFile.Close()
 
-	// 6. 复制分片数据
-	if _, err := io.Copy(chunkFile, chunkData); err != nil {
+	// 6. 复制分片数据（同时计算 MD5）
+	// 最多读取分片大小 + 1 字节：最后一个分片可以更短，读到超出分片大小的数据时拒绝，不把超大的请求体写入磁盘
+	hasher := md5.New()
+	written, err := io.Copy(io.MultiWriter(chunkFile, hasher), io.LimitReader(chunkData, upload.ChunkSize+1))
+	if err != nil {
 		os.Remove(chunkFilePath) // 清理失败的分片
 		return fmt.Errorf("failed to save chunk data: %w", err)
 	}
 	chunkFile.Close()
+	if written > upload.ChunkSize {
+		os.Remove(chunkFilePath)
+		return fmt.Errorf("%w: chunk %d exceeds chunk size of %d bytes", ErrChunkUploadTooLarge, chunkIndex, upload.ChunkSize)
+	}
+
+	// 校验分片 MD5
+	if expectedMD5 != "" {
+		actualMD5 := hex.EncodeToString(hasher.Sum(nil))
+		if actualMD5 != expectedMD5 {
+			os.Remove(chunkFilePath) // 清理损坏的分片
+			return fmt.Errorf("%w: chunk %d expected md5 %s, got %s", ErrChunkChecksumMismatch, chunkIndex, expectedMD5, actualMD5)
+		}
+	}
 
 	// 7. 更新已上传分片记录
 	upload.UploadedChunks[chunkIndex] = true
@@ -331,14 +578,15 @@ func (s *TempDeployService) MergeChunks(uploadID string) (*model.TempAppDeploy,
 	}
 
 	// 5. 获取部署基础目录
-	deployBaseDir := conf.Cfg.TempApp.DeployFilePath
+	deployBaseDir := conf.Cfg().TempApp.DeployFilePath
 	if deployBaseDir == "" {
 		deployBaseDir = "./temp_app_deploy_data"
 	}
 
 	// 6. 构建路径
 	chunksDir := filepath.Join(deployBaseDir, "chunks", uploadID)
-	zipFilePath := filepath.Join(chunksDir, "merged.zip")
+	// 合并后的文件不带扩展名，解压时通过文件头识别压缩包类型
+	zipFilePath := filepath.Join(chunksDir, "merged")
 
 	// 7. 合并分片为完整 zip 文件
 	zipFile, err := os.Create(zipFilePath)
@@ -350,7 +598,9 @@ func (s *TempDeployService) MergeChunks(uploadID string) (*model.TempAppDeploy,
 	}
 	defer zipFile.Close()
 
-	// 按顺序合并所有分片
+	// 按顺序合并所有分片（同时计算内容哈希）
+	hasher := sha256.New()
+	mergedWriter := io.MultiWriter(zipFile, hasher)
 	for i := 0; i < upload.TotalChunks; i++ {
 		chunkFilePath := filepath.Join(chunksDir, fmt.Sprintf("chunk_%d", i))
 		chunkFile, err := os.Open(chunkFilePath)
@@ -361,7 +611,7 @@ func (s *TempDeployService) MergeChunks(uploadID string) (*model.TempAppDeploy,
 			return nil, fmt.Errorf("failed to open chunk %d: %w", i, err)
 		}
 
-		if _, err := io.Copy(zipFile, chunkFile); err != nil {
+		if _, err := io.Copy(mergedWriter, chunkFile); err != nil {
 			chunkFile.Close()
 			upload.Status = "failed"
 			upload.Message = fmt.Sprintf("failed to merge chunk %d: %v", i, err)
@@ -372,6 +622,43 @@ func (s *TempDeployService) MergeChunks(uploadID string) (*model.TempAppDeploy,
 	}
 	zipFile.Close()
 
+	// 校验合并后的文件大小
+	if info, err := os.Stat(zipFilePath); err != nil || info.Size() != upload.TotalSize {
+		var mergedSize int64
+		if info != nil {
+			mergedSize = info.Size()
+		}
+		os.Remove(zipFilePath)
+		upload.Status = "failed"
+		upload.Message = fmt.Sprintf("merged file size %d does not match total size %d, please re-upload the missing or corrupted chunks", mergedSize, upload.TotalSize)
+		s.tempAppDAO.UpdateChunkUpload(upload)
+		return nil, fmt.Errorf("%w: %s", ErrChunkChecksumMismatch, upload.Message)
+	}
+	contentHash := hex.EncodeToString(hasher.Sum(nil))
+
+	// 相同内容的临时应用未过期时直接复用，不再重复解压
+	if existing := s.findDuplicateTempApp(contentHash); existing != nil {
+		upload.TokenID = existing.TokenID
+		upload.Status = "completed"
+		upload.UpdatedAt = time.Now()
+		if err := s.tempAppDAO.UpdateChunkUpload(upload); err != nil {
+			// 记录错误但不影响主流程
+			log.Printf("Failed to update chunk upload record: %v", err)
+		}
+		os.RemoveAll(chunksDir)
+		s.tempAppDAO.DeleteChunkUpload(uploadID)
+		return existing, nil
+	}
+
+	// 初始化之后可能已有其他上传完成，创建前再检查一次数量上限（分片保留，可稍后重试合并）
+	if err := s.checkActiveLimit(); err != nil {
+		upload.Status = "uploading"
+		upload.UpdatedAt = time.Now()
+		s.tempAppDAO.UpdateChunkUpload(upload)
+		os.Remove(zipFilePath)
+		return nil, err
+	}
+
 	// 8. 生成 tokenID
 	tokenID, err := tool.GetUUID()
 	if err != nil {
@@ -391,8 +678,8 @@ func (s *TempDeployService) MergeChunks(uploadID string) (*model.TempAppDeploy,
 		return nil, fmt.Errorf("failed to create deploy directory: %w", err)
 	}
 
-	// 10. 解压 zip 文件
-	if err := s.extractZip(zipFilePath, appDeployDir); err != nil {
+	// 10. 解压压缩包
+	if err := s.extractArchive(zipFilePath, appDeployDir); err != nil {
 		os.RemoveAll(appDeployDir) // 清理目录
 		upload.Status = "failed"
 		upload.Message = fmt.Sprintf("failed to extract zip: %v", err)
@@ -400,11 +687,11 @@ func (s *TempDeployService) MergeChunks(uploadID string) (*model.TempAppDeploy,
 		return nil, fmt.Errorf("failed to extract zip file: %w", err)
 	}
 
-	// 11. 删除 zip 文件（解压后不再需要）
+	// 11. 删除压缩包（解压后不再需要）
 	os.Remove(zipFilePath)
 
 	// 12. 计算过期时间
-	expireHours := conf.Cfg.TempApp.ExpireHours
+	expireHours := conf.Cfg().TempApp.ExpireHours
 	if expireHours == 0 {
 		expireHours = 24 // 默认 24 小时
 	}
@@ -417,6 +704,7 @@ func (s *TempDeployService) MergeChunks(uploadID string) (*model.TempAppDeploy,
 		ExpiresAt:      expiresAt,
 		Status:         "completed",
 		Message:        "",
+		ContentHash:    contentHash,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -451,3 +739,12 @@ func (s *TempDeployService) MergeChunks(uploadID string) (*model.TempAppDeploy,
 func (s *TempDeployService) GetChunkUploadStatus(uploadID string) (*model.TempAppChunkUpload, error) {
 	return s.tempAppDAO.GetChunkUploadByUploadID(uploadID)
 }
+
+// isValidMD5Hex 检查是否为合法的 MD5 十六进制字符串
+func isValidMD5Hex(sum string) bool {
+	if len(sum) != md5.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(sum)
+	return err == nil
+}
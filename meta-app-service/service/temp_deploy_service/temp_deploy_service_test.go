@@ -0,0 +1,62 @@
+package temp_deploy_service
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"meta-app-service/conf"
+	"meta-app-service/database"
+)
+
+func setupTempDeployTest(t *testing.T, chunkSize int64) *TempDeployService {
+	t.Helper()
+	db, err := database.NewPebbleDatabase(&database.PebbleConfig{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	prevDB, prevCfg := database.DB, conf.Cfg()
+	database.DB = db
+	conf.SetCfg(&conf.Config{TempApp: conf.TempAppConfig{Enable: true, DeployFilePath: t.TempDir(), ChunkSize: chunkSize}})
+	t.Cleanup(func() {
+		db.Close()
+		database.DB = prevDB
+		conf.SetCfg(prevCfg)
+	})
+	return NewTempDeployService()
+}
+
+// TestUploadChunkRejectsOversizedChunk accepts chunks up to the chunk size (the last one shorter)
+// and rejects a chunk with more data without keeping it on disk
+func TestUploadChunkRejectsOversizedChunk(t *testing.T) {
+	s := setupTempDeployTest(t, 1024)
+	upload, err := s.InitChunkUpload(1500, "app.zip", nil)
+	if err != nil {
+		t.Fatalf("InitChunkUpload failed: %v", err)
+	}
+	if upload.TotalChunks != 2 {
+		t.Fatalf("got %d chunks, want 2", upload.TotalChunks)
+	}
+	chunkPath := filepath.Join(conf.Cfg().TempApp.DeployFilePath, "chunks", upload.UploadID, "chunk_0")
+
+	err = s.UploadChunk(upload.UploadID, 0, bytes.NewReader(make([]byte, 1025)), "")
+	if !errors.Is(err, ErrChunkUploadTooLarge) {
+		t.Fatalf("got err %v for a 1025-byte chunk, want ErrChunkUploadTooLarge", err)
+	}
+	if _, err := os.Stat(chunkPath); !os.IsNotExist(err) {
+		t.Fatalf("oversized chunk left on disk (stat err %v)", err)
+	}
+
+	if err := s.UploadChunk(upload.UploadID, 0, bytes.NewReader(make([]byte, 1024)), ""); err != nil {
+		t.Fatalf("full chunk rejected: %v", err)
+	}
+	if err := s.UploadChunk(upload.UploadID, 1, bytes.NewReader(make([]byte, 476)), ""); err != nil {
+		t.Fatalf("short last chunk rejected: %v", err)
+	}
+	if info, err := os.Stat(chunkPath); err != nil || info.Size() != 1024 {
+		t.Fatalf("chunk 0 on disk %v (err %v), want 1024 bytes", info, err)
+	}
+}
@@ -0,0 +1,252 @@
+package tool
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Archive types
+const (
+	ArchiveTypeNone  = ""
+	ArchiveTypeZip   = "zip"
+	ArchiveTypeTarGz = "tar.gz"
+)
+
+var (
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// DetectArchiveType detect archive type by file extension, fallback to magic bytes
+func DetectArchiveType(filePath string) string {
+	lowerPath := strings.ToLower(filePath)
+	switch {
+	case strings.HasSuffix(lowerPath, ".zip"):
+		return ArchiveTypeZip
+	case strings.HasSuffix(lowerPath, ".tar.gz"), strings.HasSuffix(lowerPath, ".tgz"):
+		return ArchiveTypeTarGz
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return ArchiveTypeNone
+	}
+	defer f.Close()
+
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return ArchiveTypeZip
+	case bytes.HasPrefix(header, gzipMagic):
+		return ArchiveTypeTarGz
+	}
+	return ArchiveTypeNone
+}
+
+// IsArchiveFileName check whether file name has a supported archive extension
+func IsArchiveFileName(fileName string) bool {
+	lowerName := strings.ToLower(fileName)
+	return strings.HasSuffix(lowerName, ".zip") ||
+		strings.HasSuffix(lowerName, ".tar.gz") ||
+		strings.HasSuffix(lowerName, ".tgz")
+}
+
+// SafeJoin join name onto destDir, rejecting paths that escape destDir (path traversal)
+// A name that cleans to destDir itself (the "./" root entry written by `tar czf app.tgz .`) returns destDir
+func SafeJoin(destDir, name string) (string, error) {
+	fpath := filepath.Join(destDir, name)
+	cleanDest := filepath.Clean(destDir)
+	if fpath == cleanDest {
+		return fpath, nil
+	}
+	if !strings.HasPrefix(fpath, cleanDest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid file path: %s", name)
+	}
+	return fpath, nil
+}
+
+// ErrExtractLimitExceeded archive expands beyond the configured extraction limits
+var ErrExtractLimitExceeded = errors.New("archive exceeds extraction limits")
+
+// ExtractLimits limits applied while extracting an archive, zero means unlimited
+type ExtractLimits struct {
+	MaxTotalSize int64 // Max total uncompressed size in bytes
+	MaxFiles     int   // Max number of regular files
+}
+
+// extractGuard tracks extracted files and bytes against ExtractLimits
+type extractGuard struct {
+	limits ExtractLimits
+	files  int
+	total  int64
+}
+
+// checkEntry reject absolute paths and symlinks, and count regular files against MaxFiles
+func (g *extractGuard) checkEntry(name string, mode os.FileMode) error {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") || strings.HasPrefix(name, "\\") {
+		return fmt.Errorf("absolute path not allowed in archive: %s", name)
+	}
+	if mode&os.ModeSymlink != 0 {
+		return fmt.Errorf("symlink not allowed in archive: %s", name)
+	}
+	if mode.IsRegular() {
+		g.files++
+		if g.limits.MaxFiles > 0 && g.files > g.limits.MaxFiles {
+			return fmt.Errorf("%w: more than %d files", ErrExtractLimitExceeded, g.limits.MaxFiles)
+		}
+	}
+	return nil
+}
+
+// copy copy src into dst, aborting once the total uncompressed size exceeds MaxTotalSize
+// The declared entry size is not trusted, the actual decompressed bytes are counted
+func (g *extractGuard) copy(dst io.Writer, src io.Reader) error {
+	if g.limits.MaxTotalSize <= 0 {
+		n, err := io.Copy(dst, src)
+		g.total += n
+		return err
+	}
+	remaining := g.limits.MaxTotalSize - g.total
+	n, err := io.Copy(dst, io.LimitReader(src, remaining+1))
+	g.total += n
+	if err != nil {
+		return err
+	}
+	if g.total > g.limits.MaxTotalSize {
+		return fmt.Errorf("%w: uncompressed size over %d bytes", ErrExtractLimitExceeded, g.limits.MaxTotalSize)
+	}
+	return nil
+}
+
+// ExtractZip extract zip archive into destDir within limits
+// Absolute paths and symlinks are rejected; the caller is responsible for removing destDir on error
+func ExtractZip(archivePath, destDir string, limits ExtractLimits) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	guard := &extractGuard{limits: limits}
+	for _, f := range r.File {
+		if err := guard.checkEntry(f.Name, f.Mode()); err != nil {
+			return err
+		}
+
+		fpath, err := SafeJoin(destDir, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(fpath, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			return err
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		mode := f.Mode().Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			rc.Close()
+			return err
+		}
+
+		err = guard.copy(outFile, rc)
+		outFile.Close()
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExtractTarGz extract tar.gz archive into destDir within limits
+// Only directories and regular files are extracted, absolute paths and symlinks are rejected,
+// other links and special files are skipped; the caller is responsible for removing destDir on error
+func ExtractTarGz(archivePath, destDir string, limits ExtractLimits) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	guard := &extractGuard{limits: limits}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := guard.checkEntry(hdr.Name, hdr.FileInfo().Mode()); err != nil {
+			return err
+		}
+
+		fpath, err := SafeJoin(destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(fpath, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+				return err
+			}
+
+			mode := hdr.FileInfo().Mode().Perm()
+			if mode == 0 {
+				mode = 0644
+			}
+			outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+
+			err = guard.copy(outFile, tr)
+			outFile.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
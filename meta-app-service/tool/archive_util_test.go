@@ -0,0 +1,71 @@
+package tool
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafeJoin(t *testing.T) {
+	destDir := filepath.Join(t.TempDir(), "app")
+	cases := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "./", want: destDir},
+		{name: ".", want: destDir},
+		{name: "./index.html", want: filepath.Join(destDir, "index.html")},
+		{name: "assets/app.js", want: filepath.Join(destDir, "assets", "app.js")},
+		{name: "../x", wantErr: true},
+		{name: "./../app-other/x", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := SafeJoin(destDir, c.name)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("SafeJoin(%q) = %q, want error", c.name, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("SafeJoin(%q) = %q, %v, want %q", c.name, got, err, c.want)
+		}
+	}
+}
+
+// TestExtractTarGzRootEntry extracts a tarball built with `tar czf app.tgz .`, whose first entry is "./"
+func TestExtractTarGzRootEntry(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "app.tgz")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	content := []byte("<html></html>")
+	for _, hdr := range []*tar.Header{
+		{Name: "./", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "./index.html", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tw.Write(content)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	destDir := t.TempDir()
+	if err := ExtractTarGz(archivePath, destDir, ExtractLimits{}); err != nil {
+		t.Fatalf("ExtractTarGz failed: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(destDir, "index.html")); err != nil || string(got) != string(content) {
+		t.Fatalf("index.html = %q, %v", got, err)
+	}
+}
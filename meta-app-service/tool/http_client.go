@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Version indexer version reported in the User-Agent (override with -ldflags "-X meta-app-service/tool.Version=...")
+var Version = "1.0.0"
+
+// DefaultMaxIdleConnsPerHost default idle connections kept per host (metafs, node RPC)
+const DefaultMaxIdleConnsPerHost = 32
+
+var (
+	userAgent = DefaultUserAgent()
+
+	// sharedTransport shared connection pool for all outbound HTTP calls
+	sharedTransport = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	// HTTPClient shared client without overall timeout (large downloads), use NewHTTPClient for calls that need one
+	HTTPClient = NewHTTPClient(0)
+)
+
+// DefaultUserAgent default User-Agent identifying the indexer and its version
+func DefaultUserAgent() string {
+	return "meta-app-service/" + Version
+}
+
+// ConfigureHTTPClient set User-Agent and per-host idle connections of the shared transport
+// Empty userAgent keeps the default, maxIdleConnsPerHost <= 0 keeps the default; call it before any outbound request
+func ConfigureHTTPClient(ua string, maxIdleConnsPerHost int) {
+	if ua != "" {
+		userAgent = ua
+	}
+	if maxIdleConnsPerHost > 0 {
+		sharedTransport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		if sharedTransport.MaxIdleConns < maxIdleConnsPerHost {
+			sharedTransport.MaxIdleConns = maxIdleConnsPerHost
+		}
+	}
+}
+
+// NewHTTPClient create client on the shared transport with the given timeout (0 means no timeout)
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: userAgentTransport{},
+		Timeout:   timeout,
+	}
+}
+
+// userAgentTransport set User-Agent on requests that don't have one, then delegate to the shared transport
+type userAgentTransport struct{}
+
+func (userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", userAgent)
+	}
+	return sharedTransport.RoundTrip(req)
+}
@@ -30,8 +30,7 @@ func PostUrl(url string, data interface{}, headers map[string]string) (string, e
 			request.Header.Set(key, headers[key])
 		}
 	}
-	client := http.Client{}
-	response, err := client.Do(request)
+	response, err := HTTPClient.Do(request)
 	if err != nil {
 		return "", err
 	}
@@ -63,8 +62,7 @@ func PostUrlAndCode(url string, data interface{}, headers map[string]string) (st
 			request.Header.Set(key, headers[key])
 		}
 	}
-	client := http.Client{}
-	response, err := client.Do(request)
+	response, err := HTTPClient.Do(request)
 	if err != nil {
 		return "", 500, err
 	}
@@ -84,8 +82,7 @@ func GetUrlForSingle(url string) (string, error) {
 	}
 
 	request.Header.Set("Content-type", "application/json;charset=UTF-8")
-	client := http.Client{}
-	response, err := client.Do(request)
+	response, err := HTTPClient.Do(request)
 	if err != nil {
 		fmt.Println("client.Do Err:", err)
 		return "", nil
@@ -102,9 +99,7 @@ func GetUrlForSingle(url string) (string, error) {
 }
 
 func GetUrl(domain string, query, headers map[string]string) (string, error) {
-	client := &http.Client{
-		Timeout: time.Second * 30,
-	}
+	client := NewHTTPClient(time.Second * 30)
 	req, err := http.NewRequest("GET", domain, nil)
 	if err != nil {
 		return "", err
@@ -140,9 +135,7 @@ func GetUrl(domain string, query, headers map[string]string) (string, error) {
 }
 
 func GetUrlAndCode(domain string, query, headers map[string]string) (string, int, error) {
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	client := NewHTTPClient(time.Second * 10)
 	req, err := http.NewRequest("GET", domain, nil)
 	if err != nil {
 		return "", 500, err
@@ -438,12 +438,12 @@ async function loadMoreApps() {
     try {
         let url;
         if (currentView === 'all') {
-            url = `${API_BASE}/api/v1/metaapps?cursor=${appsCursor}&size=20`;
+            url = `${API_BASE}/api/v1/metaapps?cursor=${encodeURIComponent(appsCursor)}&size=20`;
         } else {
             if (!currentMetaID) {
                 throw new Error('MetaID not available');
             }
-            url = `${API_BASE}/api/v1/metaapps/creator/${currentMetaID}?cursor=${appsCursor}&size=20`;
+            url = `${API_BASE}/api/v1/metaapps/creator/${currentMetaID}?cursor=${encodeURIComponent(appsCursor)}&size=20`;
         }
         
         const response = await fetch(url);